@@ -0,0 +1,92 @@
+package event
+
+import (
+	"sync"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// subscriptionBuffer is the number of events which are buffered per subscription.
+const subscriptionBuffer = 64
+
+// Bus is a typed in-process event bus. Events are delivered asynchronously
+// to all subscriptions which are interested in the event type.
+// A nil bus is valid and drops all events.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+}
+
+// Subscription receives the published events via its channel.
+type Subscription struct {
+	bus   *Bus
+	types map[Type]bool
+	c     chan Event
+	once  sync.Once
+}
+
+// NewBus creates a new event bus.
+func NewBus() *Bus {
+	return &Bus{subscriptions: make(map[*Subscription]struct{})}
+}
+
+// Publish delivers the event to all interested subscriptions.
+// It never blocks. Events for subscriptions which are not able
+// to keep up are dropped.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subscriptions {
+		if len(s.types) > 0 && !s.types[e.Type] {
+			continue
+		}
+		select {
+		case s.c <- e:
+		default:
+			gaia.Cfg.Logger.Warn("event subscription is full. Event has been dropped", "type", e.Type)
+		}
+	}
+}
+
+// Subscribe creates a new subscription for the given event types.
+// If no types are given, the subscription receives all events.
+// The subscription must be closed when it is not needed anymore.
+func (b *Bus) Subscribe(types ...Type) *Subscription {
+	s := &Subscription{
+		bus:   b,
+		types: make(map[Type]bool, len(types)),
+		c:     make(chan Event, subscriptionBuffer),
+	}
+	for _, t := range types {
+		s.types[t] = true
+	}
+
+	if b != nil {
+		b.mu.Lock()
+		b.subscriptions[s] = struct{}{}
+		b.mu.Unlock()
+	}
+	return s
+}
+
+// Events returns the channel which receives the events.
+// The channel is closed when the subscription is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.c
+}
+
+// Close removes the subscription from the bus and closes the events channel.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		if s.bus != nil {
+			s.bus.mu.Lock()
+			delete(s.bus.subscriptions, s)
+			s.bus.mu.Unlock()
+		}
+		close(s.c)
+	})
+}
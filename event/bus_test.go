@@ -0,0 +1,74 @@
+package event
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestBus(t *testing.T) {
+	gaia.Cfg = &gaia.Config{Logger: hclog.NewNullLogger()}
+	bus := NewBus()
+
+	all := bus.Subscribe()
+	defer all.Close()
+	runs := bus.Subscribe(RunStarted, RunFinished)
+
+	r := &gaia.PipelineRun{UniqueID: "unique", ID: 2, PipelineID: 1, Status: gaia.RunRunning}
+	bus.Publish(NewRunEvent(RunStarted, r))
+	bus.Publish(NewJobEvent(JobStarted, r, &gaia.Job{ID: 3, Title: "build", Status: gaia.JobRunning}))
+
+	e := <-runs.Events()
+	if e.Type != RunStarted || e.PipelineID != 1 || e.RunID != 2 || e.RunUniqueID != "unique" || e.RunStatus != gaia.RunRunning {
+		t.Fatalf("unexpected run event: %+v", e)
+	}
+	if e := <-all.Events(); e.Type != RunStarted {
+		t.Fatalf("expected run started event but got %s", e.Type)
+	}
+	if e := <-all.Events(); e.Type != JobStarted || e.JobID != 3 || e.JobTitle != "build" || e.JobStatus != gaia.JobRunning {
+		t.Fatalf("unexpected job event: %+v", e)
+	}
+	select {
+	case e := <-runs.Events():
+		t.Fatalf("expected no further event but got %s", e.Type)
+	default:
+	}
+
+	// Closed subscriptions do not receive events anymore
+	runs.Close()
+	runs.Close()
+	bus.Publish(NewRunEvent(RunFinished, r))
+	if _, ok := <-runs.Events(); ok {
+		t.Fatal("expected closed events channel")
+	}
+	if e := <-all.Events(); e.Type != RunFinished {
+		t.Fatalf("expected run finished event but got %s", e.Type)
+	}
+}
+
+func TestBusDropsEventsOfFullSubscriptions(t *testing.T) {
+	gaia.Cfg = &gaia.Config{Logger: hclog.NewNullLogger()}
+	bus := NewBus()
+	sub := bus.Subscribe()
+	defer sub.Close()
+
+	w := &gaia.Worker{UniqueID: "worker", Name: "name", Status: gaia.WorkerInactive}
+	for i := 0; i < subscriptionBuffer+10; i++ {
+		bus.Publish(NewWorkerEvent(WorkerInactive, w))
+	}
+	if len(sub.Events()) != subscriptionBuffer {
+		t.Fatalf("expected %d buffered events but got %d", subscriptionBuffer, len(sub.Events()))
+	}
+	if e := <-sub.Events(); e.WorkerID != "worker" || e.WorkerName != "name" || e.WorkerStatus != gaia.WorkerInactive {
+		t.Fatalf("unexpected worker event: %+v", e)
+	}
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Event{Type: RunStarted})
+	sub := bus.Subscribe()
+	sub.Close()
+}
@@ -0,0 +1,85 @@
+package event
+
+import (
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// Type represents the different lifecycle events.
+type Type string
+
+const (
+	// RunScheduled is published when a new pipeline run has been created.
+	RunScheduled Type = "run_scheduled"
+
+	// RunStarted is published when the execution of a pipeline run has been started.
+	RunStarted Type = "run_started"
+
+	// RunFinished is published when a pipeline run has reached a final status.
+	RunFinished Type = "run_finished"
+
+	// JobStarted is published when the execution of a job has been started.
+	JobStarted Type = "job_started"
+
+	// JobFinished is published when a job has reached a final status.
+	JobFinished Type = "job_finished"
+
+	// WorkerRegistered is published when a worker has been registered.
+	WorkerRegistered Type = "worker_registered"
+
+	// WorkerInactive is published when a worker has not been seen for a while.
+	WorkerInactive Type = "worker_inactive"
+)
+
+// Types holds all known event types.
+var Types = []Type{RunScheduled, RunStarted, RunFinished, JobStarted, JobFinished, WorkerRegistered, WorkerInactive}
+
+// Event represents a single lifecycle event. Only the fields
+// related to the event type are set.
+type Event struct {
+	Type         Type                   `json:"type"`
+	Time         time.Time              `json:"time"`
+	PipelineID   int                    `json:"pipelineid,omitempty"`
+	RunID        int                    `json:"runid,omitempty"`
+	RunUniqueID  string                 `json:"rununiqueid,omitempty"`
+	RunStatus    gaia.PipelineRunStatus `json:"runstatus,omitempty"`
+	JobID        uint32                 `json:"jobid,omitempty"`
+	JobTitle     string                 `json:"jobtitle,omitempty"`
+	JobStatus    gaia.JobStatus         `json:"jobstatus,omitempty"`
+	WorkerID     string                 `json:"workerid,omitempty"`
+	WorkerName   string                 `json:"workername,omitempty"`
+	WorkerStatus gaia.WorkerStatus      `json:"workerstatus,omitempty"`
+}
+
+// NewRunEvent creates a new event of the given type for the pipeline run.
+func NewRunEvent(t Type, r *gaia.PipelineRun) Event {
+	return Event{
+		Type:        t,
+		Time:        time.Now(),
+		PipelineID:  r.PipelineID,
+		RunID:       r.ID,
+		RunUniqueID: r.UniqueID,
+		RunStatus:   r.Status,
+	}
+}
+
+// NewJobEvent creates a new event of the given type for the job of the pipeline run.
+func NewJobEvent(t Type, r *gaia.PipelineRun, j *gaia.Job) Event {
+	e := NewRunEvent(t, r)
+	e.JobID = j.ID
+	e.JobTitle = j.Title
+	e.JobStatus = j.Status
+	return e
+}
+
+// NewWorkerEvent creates a new event of the given type for the worker.
+func NewWorkerEvent(t Type, w *gaia.Worker) Event {
+	return Event{
+		Type:         t,
+		Time:         time.Now(),
+		WorkerID:     w.UniqueID,
+		WorkerName:   w.Name,
+		WorkerStatus: w.Status,
+	}
+}
@@ -2,6 +2,8 @@ package gaia
 
 import (
 	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -23,6 +25,27 @@ type PipelineRunStatus string
 // JobStatus represents the different status a job can have
 type JobStatus string
 
+// JobRunCondition represents the condition under which a job is executed
+type JobRunCondition string
+
+// ConcurrencyPolicy represents what happens with a new run if its concurrency group is busy
+type ConcurrencyPolicy string
+
+// RecoveryPolicy represents what happens with runs which have been interrupted by a restart
+type RecoveryPolicy string
+
+// NotificationType represents the different notifier types
+type NotificationType string
+
+// NotificationEvent represents the pipeline run events which trigger a notification
+type NotificationEvent string
+
+// NotificationDeliveryStatus represents the different status a notification delivery can have
+type NotificationDeliveryStatus string
+
+// ParameterType represents the different types of pipeline parameters
+type ParameterType string
+
 // Mode represents the different modes for Gaia
 type Mode string
 
@@ -81,6 +104,10 @@ const (
 	// RunReschedule status
 	RunReschedule PipelineRunStatus = "reschedule"
 
+	// RunWaitingApproval status of runs which have been paused
+	// until the approval of a job has been decided.
+	RunWaitingApproval PipelineRunStatus = "waiting for approval"
+
 	// JobWaitingExec status
 	JobWaitingExec JobStatus = "waiting for execution"
 
@@ -93,6 +120,81 @@ const (
 	// JobRunning status
 	JobRunning JobStatus = "running"
 
+	// JobTimedOut status
+	JobTimedOut JobStatus = "timed out"
+
+	// JobSkipped status
+	JobSkipped JobStatus = "skipped"
+
+	// JobWaitingApproval status
+	JobWaitingApproval JobStatus = "waiting for approval"
+
+	// RunConditionOnSuccess executes the job only if no other job
+	// of the run has failed. This is the default run condition.
+	RunConditionOnSuccess JobRunCondition = "on_success"
+
+	// RunConditionOnFailure executes the job only if another job of the run has failed
+	RunConditionOnFailure JobRunCondition = "on_failure"
+
+	// RunConditionAlways executes the job regardless of failed jobs
+	RunConditionAlways JobRunCondition = "always"
+
+	// ConcurrencyQueue lets new runs wait until a run of the concurrency
+	// group has been finished. This is the default policy.
+	ConcurrencyQueue ConcurrencyPolicy = "queue"
+
+	// ConcurrencyCancelOlder cancels the oldest runs of the concurrency group to make room for new runs
+	ConcurrencyCancelOlder ConcurrencyPolicy = "cancel_older"
+
+	// ConcurrencySkipNew cancels new runs as long as the concurrency group is busy
+	ConcurrencySkipNew ConcurrencyPolicy = "skip_new"
+
+	// RecoveryFail marks interrupted runs as failed. This is the default policy.
+	RecoveryFail RecoveryPolicy = "fail"
+
+	// RecoveryReschedule schedules interrupted runs again. Finished jobs are not executed again.
+	RecoveryReschedule RecoveryPolicy = "reschedule"
+
+	// NotificationSlack posts the notification to a slack incoming webhook
+	NotificationSlack NotificationType = "slack"
+
+	// NotificationEmail sends the notification via email
+	NotificationEmail NotificationType = "email"
+
+	// NotificationWebhook posts the notification as json to a generic webhook
+	NotificationWebhook NotificationType = "webhook"
+
+	// NotificationEventFailed is triggered when a pipeline run failed
+	NotificationEventFailed NotificationEvent = "failed"
+
+	// NotificationEventRecovered is triggered when a pipeline run succeeded
+	// and the previous finished run of the pipeline failed
+	NotificationEventRecovered NotificationEvent = "recovered"
+
+	// NotificationEventSucceeded is triggered when a pipeline run succeeded
+	NotificationEventSucceeded NotificationEvent = "succeeded"
+
+	// NotificationDelivered status
+	NotificationDelivered NotificationDeliveryStatus = "delivered"
+
+	// NotificationFailed status
+	NotificationFailed NotificationDeliveryStatus = "failed"
+
+	// ParameterString accepts any value
+	ParameterString ParameterType = "string"
+
+	// ParameterBool accepts true and false
+	ParameterBool ParameterType = "bool"
+
+	// ParameterNumber accepts integer and decimal numbers
+	ParameterNumber ParameterType = "number"
+
+	// ParameterChoice accepts one of the allowed values of the parameter
+	ParameterChoice ParameterType = "choice"
+
+	// ParameterSecret accepts any value which is treated as confidential
+	ParameterSecret ParameterType = "secret"
+
 	// ModeServer mode
 	ModeServer Mode = "server"
 
@@ -114,6 +216,16 @@ const (
 	// LogsFileName represents the file name of the logs output
 	LogsFileName = "output.log"
 
+	// ArtifactsFolderName represents the name of the artifacts folder in pipeline run folder
+	ArtifactsFolderName = "artifacts"
+
+	// ArtifactsDirArg is the key of the argument which is passed to every job.
+	// It points the job to the folder where it can publish its artifacts.
+	ArtifactsDirArg = "GAIA_ARTIFACTS_DIR"
+
+	// JobLogsFileExt represents the file extension of the logs output of a single job
+	JobLogsFileExt = ".log"
+
 	// APIVersion represents the current API version
 	APIVersion = "v1"
 
@@ -144,6 +256,10 @@ const (
 	// WorkerRegisterKey is the used key for worker registration secret
 	WorkerRegisterKey = "WORKER_REGISTER_KEY"
 
+	// SecretParameterPrefix is the prefix of the vault keys which hold
+	// the values of secret parameters of pipeline runs.
+	SecretParameterPrefix = "GAIA_SECRET_PARAMETER_"
+
 	// ExecutablePermission is the permission used for gaia created executables.
 	ExecutablePermission = 0700
 
@@ -156,6 +272,9 @@ const (
 	// StartReasonScheduled label for pipelines which were triggered automated process, i.e. cron job.
 	StartReasonScheduled = "scheduled"
 
+	// StartReasonRerun label for pipelines which were triggered as rerun of a previous run.
+	StartReasonRerun = "rerun"
+
 	// SecretNamePrefix defines the prefix for github secrets for pipelines.
 	SecretNamePrefix = "GITHUB_WEBHOOK_SECRET_"
 
@@ -215,21 +334,29 @@ type UserRoleEndpoint struct {
 
 // Pipeline represents a single pipeline
 type Pipeline struct {
-	ID                int          `json:"id,omitempty"`
-	Name              string       `json:"name,omitempty"`
-	Repo              *GitRepo     `json:"repo,omitempty"`
-	Type              PipelineType `json:"type,omitempty"`
-	ExecPath          string       `json:"execpath,omitempty"`
-	SHA256Sum         []byte       `json:"sha256sum,omitempty"`
-	Jobs              []*Job       `json:"jobs,omitempty"`
-	Created           time.Time    `json:"created,omitempty"`
-	UUID              string       `json:"uuid,omitempty"`
-	IsNotValid        bool         `json:"notvalid,omitempty"`
-	PeriodicSchedules []string     `json:"periodicschedules,omitempty"`
-	TriggerToken      string       `json:"trigger_token,omitempty"`
-	Tags              []string     `json:"tags,omitempty"`
-	Docker            bool         `json:"docker"`
-	CronInst          *cron.Cron   `json:"-"`
+	ID                int              `json:"id,omitempty"`
+	Name              string           `json:"name,omitempty"`
+	Repo              *GitRepo         `json:"repo,omitempty"`
+	Type              PipelineType     `json:"type,omitempty"`
+	ExecPath          string           `json:"execpath,omitempty"`
+	SHA256Sum         []byte           `json:"sha256sum,omitempty"`
+	Jobs              []*Job           `json:"jobs,omitempty"`
+	Created           time.Time        `json:"created,omitempty"`
+	UUID              string           `json:"uuid,omitempty"`
+	IsNotValid        bool             `json:"notvalid,omitempty"`
+	PeriodicSchedules []string         `json:"periodicschedules,omitempty"`
+	TriggerToken      string           `json:"trigger_token,omitempty"`
+	Tags              []string         `json:"tags,omitempty"`
+	Docker            bool             `json:"docker"`
+	JobTimeout        int              `json:"jobtimeout,omitempty"`
+	RunTimeout        int              `json:"runtimeout,omitempty"`
+	Retention         *RetentionPolicy `json:"retention,omitempty"`
+	Notifications     []*Notification  `json:"notifications,omitempty"`
+	Parameters        []*Parameter     `json:"parameters,omitempty"`
+	Concurrency       *Concurrency     `json:"concurrency,omitempty"`
+	Priority          int              `json:"priority,omitempty"`
+	Recovery          *Recovery        `json:"recovery,omitempty"`
+	CronInst          *cron.Cron       `json:"-"`
 }
 
 // GitRepo represents a single git repository
@@ -245,13 +372,84 @@ type GitRepo struct {
 
 // Job represents a single job of a pipeline
 type Job struct {
-	ID           uint32      `json:"id,omitempty"`
-	Title        string      `json:"title,omitempty"`
-	Description  string      `json:"desc,omitempty"`
-	DependsOn    []*Job      `json:"dependson,omitempty"`
-	Status       JobStatus   `json:"status,omitempty"`
-	Args         []*Argument `json:"args,omitempty"`
-	FailPipeline bool        `json:"failpipeline,omitempty"`
+	ID           uint32          `json:"id,omitempty"`
+	Title        string          `json:"title,omitempty"`
+	Description  string          `json:"desc,omitempty"`
+	DependsOn    []*Job          `json:"dependson,omitempty"`
+	Status       JobStatus       `json:"status,omitempty"`
+	Args         []*Argument     `json:"args,omitempty"`
+	FailPipeline bool            `json:"failpipeline,omitempty"`
+	Retry        *RetryPolicy    `json:"retry,omitempty"`
+	Attempts     []*JobAttempt   `json:"attempts,omitempty"`
+	RunCondition JobRunCondition `json:"runcondition,omitempty"`
+	Artifacts    []*Artifact     `json:"artifacts,omitempty"`
+	Outputs      []*Argument     `json:"outputs,omitempty"`
+	Approval     *ApprovalPolicy `json:"approval,omitempty"`
+	ApprovalInfo *JobApproval    `json:"approvalinfo,omitempty"`
+}
+
+// RetryPolicy defines if and how often a failed job is retried.
+// MaxAttempts is the maximum number of executions including the first one.
+// Backoff is the delay in seconds before the first retry which is doubled
+// with every further retry. If InfraErrorsOnly is set, only jobs which failed
+// because of an infrastructure error are retried.
+type RetryPolicy struct {
+	MaxAttempts     int  `json:"maxattempts,omitempty"`
+	Backoff         int  `json:"backoff,omitempty"`
+	InfraErrorsOnly bool `json:"infraerrorsonly,omitempty"`
+}
+
+const (
+	// RetryMaxAttempts is the maximum number of executions of a job
+	// which can be configured by a retry policy.
+	RetryMaxAttempts = 10
+
+	// RetryMaxBackoff is the maximum delay in seconds between two
+	// executions of a job.
+	RetryMaxBackoff = 60 * 60
+)
+
+// ApprovalPolicy defines that a job has to be approved by a user before
+// it is executed. If Timeout in seconds is greater than zero, the job is
+// rejected automatically when it has not been approved in time.
+type ApprovalPolicy struct {
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// JobApproval records the approval of a job. The job has been approved or rejected
+// once DecisionDate is set. User is empty if the job has been rejected automatically
+// because the timeout of the approval policy has been exceeded.
+type JobApproval struct {
+	RequestDate  time.Time `json:"requestdate,omitempty"`
+	DecisionDate time.Time `json:"decisiondate,omitempty"`
+	Approved     bool      `json:"approved"`
+	User         string    `json:"user,omitempty"`
+}
+
+// Decided returns true if the job has been approved or rejected.
+func (a *JobApproval) Decided() bool {
+	return a != nil && !a.DecisionDate.IsZero()
+}
+
+// JobAttempt represents a single execution attempt of a job.
+type JobAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StartDate  time.Time `json:"startdate,omitempty"`
+	FinishDate time.Time `json:"finishdate,omitempty"`
+	Status     JobStatus `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	InfraError bool      `json:"infraerror,omitempty"`
+}
+
+// Artifact represents a single file published by a job.
+// Name is the slash separated path of the file relative to the
+// artifacts folder of the job.
+type Artifact struct {
+	JobID  uint32    `json:"jobid"`
+	Name   string    `json:"name"`
+	Size   int64     `json:"size"`
+	SHA256 string    `json:"sha256"`
+	Date   time.Time `json:"date,omitempty"`
 }
 
 // Argument represents a single argument of a job
@@ -262,6 +460,17 @@ type Argument struct {
 	Value       string `json:"value,omitempty"`
 }
 
+// Parameter declares an input of a pipeline which is given when a pipeline run is started.
+// The default is used if no value is given. Choice parameters only accept the allowed values.
+type Parameter struct {
+	Name        string        `json:"name"`
+	Type        ParameterType `json:"type"`
+	Description string        `json:"desc,omitempty"`
+	Default     string        `json:"default,omitempty"`
+	Required    bool          `json:"required,omitempty"`
+	Allowed     []string      `json:"allowed,omitempty"`
+}
+
 // CreatePipeline represents a pipeline which is not yet
 // compiled.
 type CreatePipeline struct {
@@ -296,6 +505,53 @@ type PipelineRun struct {
 	PipelineTags   []string          `json:"pipelinetags,omitempty"`
 	Docker         bool              `json:"docker,omitempty"`
 	DockerWorkerID string            `json:"dockerworkerid,omitempty"`
+	JobTimeout     int               `json:"jobtimeout,omitempty"`
+	RunTimeout     int               `json:"runtimeout,omitempty"`
+	TraceID        string            `json:"traceid,omitempty"`
+	TraceSpanID    string            `json:"tracespanid,omitempty"`
+	Parameters     []*Argument       `json:"parameters,omitempty"`
+	SHA256Sum      []byte            `json:"sha256sum,omitempty"`
+	RerunOf        int               `json:"rerunof,omitempty"`
+	Concurrency    *Concurrency      `json:"concurrency,omitempty"`
+	Priority       int               `json:"priority,omitempty"`
+	WorkerID       string            `json:"workerid,omitempty"`
+	Recovery       *Recovery         `json:"recovery,omitempty"`
+	Reschedules    int               `json:"reschedules,omitempty"`
+}
+
+// PipelineRunFilter defines which pipeline runs are returned
+// when listing the runs of a pipeline. Empty fields match all runs.
+type PipelineRunFilter struct {
+	PipelineID    int
+	Status        []PipelineRunStatus
+	StartReason   string
+	StartedAfter  time.Time
+	StartedBefore time.Time
+}
+
+// Page defines a single page of a paginated list.
+// Page numbers start at 1.
+type Page struct {
+	Number  int
+	PerPage int
+}
+
+// PipelineRunList represents a single page of pipeline runs
+// ordered from the newest to the oldest run.
+type PipelineRunList struct {
+	Runs    []PipelineRun `json:"runs"`
+	Total   int           `json:"total"`
+	Page    int           `json:"page"`
+	PerPage int           `json:"perpage"`
+}
+
+// RunQueuePosition represents the position of a pipeline run in the queue of
+// runs waiting for their execution. The position starts at 1 and is 0 if the
+// run does not wait for its execution.
+type RunQueuePosition struct {
+	Position int `json:"position"`
+	Length   int `json:"length"`
+	Priority int `json:"priority"`
 }
 
 // Worker represents a single registered worker.
@@ -350,6 +606,14 @@ type Config struct {
 	DockerWorkerGRPCHostURL string
 	RBACEnabled             bool
 	RBACDebug               bool
+	JobTimeout              time.Duration
+	RunTimeout              time.Duration
+	RecoveryPolicyRaw       string
+	RecoveryPolicy          RecoveryPolicy
+	ShutdownGracePeriod     time.Duration
+	MetricsEnabled          bool
+	TracingEndpoint         string
+	TracingInsecure         bool
 
 	// Worker
 	WorkerName        string
@@ -361,6 +625,46 @@ type Config struct {
 	Bolt struct {
 		Mode os.FileMode
 	}
+
+	Store struct {
+		Type string
+		DSN  string
+	}
+
+	Storage struct {
+		Type        string
+		S3Endpoint  string
+		S3Bucket    string
+		S3Region    string
+		S3AccessKey string
+		S3SecretKey string
+		S3Insecure  bool
+	}
+
+	SMTP struct {
+		Addr     string
+		Username string
+		Password string
+		From     string
+	}
+}
+
+// Concurrency limits the number of runs which are executed at the same time.
+// Runs of pipelines with the same group share the limit. Without a group, the
+// limit applies to the runs of the pipeline only. A limit of zero allows one run.
+type Concurrency struct {
+	Group  string            `json:"group,omitempty"`
+	Limit  int               `json:"limit,omitempty"`
+	Policy ConcurrencyPolicy `json:"policy,omitempty"`
+}
+
+// Recovery defines what happens with the runs of a pipeline which have been
+// interrupted by a restart or by the loss of their worker. Without a policy,
+// the configured recovery policy of the instance applies. A run is rescheduled
+// at most MaxReschedules times, zero means the default limit.
+type Recovery struct {
+	Policy         RecoveryPolicy `json:"policy,omitempty"`
+	MaxReschedules int            `json:"max_reschedules,omitempty"`
 }
 
 // StoreConfig defines config settings to be stored in DB.
@@ -368,9 +672,58 @@ type StoreConfig struct {
 	ID          int
 	Poll        bool
 	RBACEnabled bool
+	Retention   RetentionPolicy
+}
+
+// RetentionPolicy defines which finished pipeline runs are kept.
+// A run is kept if it is one of the last KeepRuns runs or if it is younger
+// than KeepDays days. Zero disables the rule. The last successful run is always kept.
+type RetentionPolicy struct {
+	KeepRuns int `json:"keepruns"`
+	KeepDays int `json:"keepdays"`
+}
+
+// Enabled returns true if the retention policy prunes any runs.
+func (r RetentionPolicy) Enabled() bool {
+	return r.KeepRuns > 0 || r.KeepDays > 0
+}
+
+// Notification configures a single notifier of a pipeline. The target is the
+// webhook url for slack and webhook notifiers and a comma separated list of
+// recipients for email notifiers. If no events are given, the notifier is
+// triggered for failed and recovered runs.
+type Notification struct {
+	Type   NotificationType    `json:"type"`
+	Target string              `json:"target"`
+	Events []NotificationEvent `json:"events,omitempty"`
+}
+
+// NotificationDelivery represents the delivery of a notification
+// for a single pipeline run including all delivery attempts.
+type NotificationDelivery struct {
+	ID         int                        `json:"id"`
+	PipelineID int                        `json:"pipelineid"`
+	RunID      int                        `json:"runid"`
+	Type       NotificationType           `json:"type"`
+	Target     string                     `json:"target"`
+	Event      NotificationEvent          `json:"event"`
+	Status     NotificationDeliveryStatus `json:"status"`
+	Attempts   int                        `json:"attempts"`
+	Error      string                     `json:"error,omitempty"`
+	Date       time.Time                  `json:"date"`
 }
 
 // String returns a pipeline type string back
 func (p PipelineType) String() string {
 	return string(p)
 }
+
+// JobArtifactsPath returns the artifacts folder of the given job of a pipeline run.
+func JobArtifactsPath(pipelineID, runID int, jobID uint32) string {
+	return filepath.Join(Cfg.WorkspacePath, strconv.Itoa(pipelineID), strconv.Itoa(runID), ArtifactsFolderName, strconv.FormatUint(uint64(jobID), 10))
+}
+
+// JobLogsFileName returns the file name of the logs output of the job with the given id.
+func JobLogsFileName(jobID uint32) string {
+	return strconv.FormatUint(uint64(jobID), 10) + JobLogsFileExt
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/gaia-pipeline/gaia/workers/scheduler/service"
+)
+
+type adminHandler struct {
+	scheduler service.GaiaScheduler
+}
+
+func newAdminHandler(scheduler service.GaiaScheduler) *adminHandler {
+	return &adminHandler{scheduler: scheduler}
+}
+
+// @Summary Drain the instance
+// @Description Stops this instance from starting new pipeline runs. Running pipeline runs are finished.
+// @Tags admin
+// @Produce plain
+// @Security ApiKeyAuth
+// @Success 200 {string} string "Instance is draining."
+// @Router /admin/drain [post]
+func (h *adminHandler) drain(c echo.Context) error {
+	h.scheduler.Drain()
+	return c.String(http.StatusOK, "Instance is draining.")
+}
+
+// @Summary Resume the instance
+// @Description Lets this instance start new pipeline runs again after it has been drained.
+// @Tags admin
+// @Produce plain
+// @Security ApiKeyAuth
+// @Success 200 {string} string "Instance has been resumed."
+// @Router /admin/drain [delete]
+func (h *adminHandler) resume(c echo.Context) error {
+	h.scheduler.Resume()
+	return c.String(http.StatusOK, "Instance has been resumed.")
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/workers/scheduler/service"
+)
+
+type mockDrainScheduler struct {
+	service.GaiaScheduler
+	draining bool
+}
+
+func (ms *mockDrainScheduler) Drain()  { ms.draining = true }
+func (ms *mockDrainScheduler) Resume() { ms.draining = false }
+
+func Test_AdminHandler_Drain(t *testing.T) {
+	gaia.Cfg = &gaia.Config{
+		Logger: hclog.NewNullLogger(),
+	}
+
+	e := echo.New()
+	scheduler := &mockDrainScheduler{}
+	adminHandler := newAdminHandler(scheduler)
+
+	req := httptest.NewRequest(echo.POST, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/api/" + gaia.APIVersion + "/admin/drain")
+
+	_ = adminHandler.drain(c)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, scheduler.draining)
+}
+
+func Test_AdminHandler_Resume(t *testing.T) {
+	gaia.Cfg = &gaia.Config{
+		Logger: hclog.NewNullLogger(),
+	}
+
+	e := echo.New()
+	scheduler := &mockDrainScheduler{draining: true}
+	adminHandler := newAdminHandler(scheduler)
+
+	req := httptest.NewRequest(echo.DELETE, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/api/" + gaia.APIVersion + "/admin/drain")
+
+	_ = adminHandler.resume(c)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, scheduler.draining)
+}
@@ -60,6 +60,9 @@ func authMiddleware(authCfg *AuthConfig) echo.MiddlewareFunc {
 						gaia.Cfg.Logger.Error("rbacEnforcer error", "error", err.Error())
 						return c.String(http.StatusInternalServerError, "Unknown error has occurred while validating permissions.")
 					}
+
+					// Make the authenticated user available to the handlers
+					c.Set("username", username)
 				}
 				return next(c)
 			}
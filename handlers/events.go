@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/event"
+	"github.com/gaia-pipeline/gaia/security/rbac"
+)
+
+// eventsKeepAliveInterval is the interval in which a comment is sent
+// to keep idle event streams open.
+var eventsKeepAliveInterval = 30 * time.Second
+
+const (
+	// eventsPipelineGetPath is the endpoint whose permission is
+	// required to receive the events of a pipeline.
+	eventsPipelineGetPath = "/api/" + gaia.APIVersion + "/pipeline/:pipelineid"
+
+	// eventsWorkerListPath is the endpoint whose permission is
+	// required to receive the events of workers.
+	eventsWorkerListPath = "/api/" + gaia.APIVersion + "/worker"
+)
+
+type eventsHandler struct {
+	bus          *event.Bus
+	rbacEnforcer rbac.EndpointEnforcer
+}
+
+func newEventsHandler(bus *event.Bus, rbacEnforcer rbac.EndpointEnforcer) *eventsHandler {
+	return &eventsHandler{bus: bus, rbacEnforcer: rbacEnforcer}
+}
+
+// @Summary Stream lifecycle events
+// @Description Streams the run, job and worker lifecycle events as server-sent events. Only the events of pipelines and workers the user is allowed to get are streamed.
+// @Tags events
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Param types query string false "Comma separated list of event types. All events are streamed by default."
+// @Success 200 {object} event.Event "lifecycle events"
+// @Failure 400 {string} string "Unknown event type."
+// @Router /events [get]
+func (h *eventsHandler) stream(c echo.Context) error {
+	var types []event.Type
+	if raw := c.QueryParam("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			t := event.Type(strings.TrimSpace(t))
+			if !knownEventType(t) {
+				return c.String(http.StatusBadRequest, fmt.Sprintf("unknown event type: %s", t))
+			}
+			types = append(types, t)
+		}
+	}
+
+	username, _ := c.Get("username").(string)
+
+	sub := h.bus.Subscribe(types...)
+	defer sub.Close()
+
+	// Start event stream
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case e, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			if !h.allowed(username, e) {
+				continue
+			}
+			payload, err := json.Marshal(e)
+			if err != nil {
+				gaia.Cfg.Logger.Error("failed to marshal event", "error", err.Error(), "type", e.Type)
+				continue
+			}
+			if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", e.Type, payload); err != nil {
+				return nil
+			}
+			res.Flush()
+		}
+	}
+}
+
+// allowed returns true if the user with the given name may receive the given event.
+// Run and job events require the permission to get the pipeline and worker
+// events require the permission to list the workers.
+func (h *eventsHandler) allowed(username string, e event.Event) bool {
+	var err error
+	switch e.Type {
+	case event.WorkerRegistered, event.WorkerInactive:
+		err = h.rbacEnforcer.Enforce(username, http.MethodGet, eventsWorkerListPath, nil)
+	default:
+		err = h.rbacEnforcer.Enforce(username, http.MethodGet, eventsPipelineGetPath, map[string]string{"pipelineid": strconv.Itoa(e.PipelineID)})
+	}
+	if err != nil {
+		if _, permDenied := err.(*rbac.ErrPermissionDenied); !permDenied {
+			gaia.Cfg.Logger.Error("rbacEnforcer error", "error", err.Error(), "type", e.Type)
+		}
+		return false
+	}
+	return true
+}
+
+// knownEventType returns true if the given event type exists.
+func knownEventType(t event.Type) bool {
+	for _, known := range event.Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/event"
+	"github.com/gaia-pipeline/gaia/security/rbac"
+)
+
+type mockEventsEnforcer struct{}
+
+func (m *mockEventsEnforcer) Enforce(username, method, path string, params map[string]string) error {
+	if params["pipelineid"] == "3" {
+		return rbac.NewErrPermissionDenied("pipelines", "get", "3")
+	}
+	return nil
+}
+
+func TestEventsStream(t *testing.T) {
+	gaia.Cfg = &gaia.Config{Logger: hclog.NewNullLogger()}
+	bus := event.NewBus()
+	handler := newEventsHandler(bus, &mockEventsEnforcer{})
+
+	e := echo.New()
+	e.GET("/events", handler.stream)
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	t.Run("invalid type", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/events?types=run_finished,unknown")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("filtered stream", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events?types=run_finished,job_finished", nil)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+
+		// The subscription exists as soon as the headers have been sent
+		r := &gaia.PipelineRun{ID: 1, PipelineID: 2, Status: gaia.RunSuccess}
+		bus.Publish(event.NewRunEvent(event.RunStarted, r))
+		bus.Publish(event.NewRunEvent(event.RunFinished, r))
+
+		reader := bufio.NewReader(resp.Body)
+		line, _ := reader.ReadString('\n')
+		assert.Equal(t, "event: run_finished\n", line)
+		line, _ = reader.ReadString('\n')
+		assert.True(t, strings.HasPrefix(line, "data: "))
+
+		received := event.Event{}
+		assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &received))
+		assert.Equal(t, event.RunFinished, received.Type)
+		assert.Equal(t, 2, received.PipelineID)
+		assert.Equal(t, 1, received.RunID)
+		assert.Equal(t, gaia.RunSuccess, received.RunStatus)
+	})
+
+	t.Run("events of denied pipelines are skipped", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events?types=run_finished", nil)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		bus.Publish(event.NewRunEvent(event.RunFinished, &gaia.PipelineRun{ID: 1, PipelineID: 3, Status: gaia.RunSuccess}))
+		bus.Publish(event.NewRunEvent(event.RunFinished, &gaia.PipelineRun{ID: 2, PipelineID: 4, Status: gaia.RunSuccess}))
+
+		reader := bufio.NewReader(resp.Body)
+		_, _ = reader.ReadString('\n')
+		line, _ := reader.ReadString('\n')
+
+		received := event.Event{}
+		assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &received))
+		assert.Equal(t, 4, received.PipelineID)
+		assert.Equal(t, 2, received.RunID)
+	})
+}
@@ -8,6 +8,7 @@ import (
 	rice "github.com/GeertJohan/go.rice"
 	"github.com/gaia-pipeline/gaia"
 	"github.com/gaia-pipeline/gaia/helper/rolehelper"
+	"github.com/gaia-pipeline/gaia/metrics"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
@@ -58,10 +59,11 @@ func (s *GaiaHandler) InitHandlers(e *echo.Echo) error {
 		apiAuthGrp.POST("pipeline/:pipelineid/start", s.deps.PipelineProvider.PipelineStart)
 		apiAuthGrp.PUT("pipeline/:pipelineid/reset-trigger-token", s.deps.PipelineProvider.PipelineResetToken)
 		apiAuthGrp.POST("pipeline/:pipelineid/pull", s.deps.PipelineProvider.PipelinePull)
+		apiAuthGrp.GET("pipeline/:pipelineid/notifications", s.deps.PipelineProvider.PipelineGetNotificationDeliveries)
 		apiAuthGrp.GET("pipeline/latest", s.deps.PipelineProvider.PipelineGetAllWithLatestRun)
 		apiAuthGrp.POST("pipeline/periodicschedules", s.deps.PipelineProvider.PipelineCheckPeriodicSchedules)
-		apiGrp.POST("pipeline/githook", s.deps.PipelineProvider.GitWebHook)
-		apiGrp.POST("pipeline/:pipelineid/:pipelinetoken/trigger", s.deps.PipelineProvider.PipelineTrigger)
+		apiGrp.POST("pipeline/githook", s.deps.PipelineProvider.GitWebHook, metrics.CountRequests(metrics.WebhookRequests))
+		apiGrp.POST("pipeline/:pipelineid/:pipelinetoken/trigger", s.deps.PipelineProvider.PipelineTrigger, metrics.CountRequests(metrics.TriggerRequests))
 
 		// Settings
 		settingsHandler := newSettingsHandler(s.deps.Store)
@@ -70,13 +72,33 @@ func (s *GaiaHandler) InitHandlers(e *echo.Echo) error {
 		apiAuthGrp.GET("settings/poll", s.deps.PipelineProvider.SettingsPollGet)
 		apiAuthGrp.GET("settings/rbac", settingsHandler.rbacGet)
 		apiAuthGrp.PUT("settings/rbac", settingsHandler.rbacPut)
+		apiAuthGrp.GET("settings/retention", settingsHandler.retentionGet)
+		apiAuthGrp.PUT("settings/retention", settingsHandler.retentionPut)
+
+		// Admin
+		adminHandler := newAdminHandler(s.deps.Scheduler)
+		apiAuthGrp.POST("admin/drain", adminHandler.drain)
+		apiAuthGrp.DELETE("admin/drain", adminHandler.resume)
+
+		// Events
+		eventsHandler := newEventsHandler(s.deps.Events, s.deps.RBACService)
+		apiAuthGrp.GET("events", eventsHandler.stream)
 
 		// PipelineRun
 		apiAuthGrp.POST("pipelinerun/:pipelineid/:runid/stop", s.deps.PipelineProvider.PipelineStop)
+		apiAuthGrp.POST("pipelinerun/:pipelineid/:runid/rerun", s.deps.PipelineProvider.PipelineRerun)
+		apiAuthGrp.POST("pipelinerun/:pipelineid/:runid/rerun-failed", s.deps.PipelineProvider.PipelineRerunFailed)
+		apiAuthGrp.POST("pipelinerun/:pipelineid/:runid/job/:jobid/approve", s.deps.PipelineProvider.PipelineJobApprove)
+		apiAuthGrp.POST("pipelinerun/:pipelineid/:runid/job/:jobid/reject", s.deps.PipelineProvider.PipelineJobReject)
 		apiAuthGrp.GET("pipelinerun/:pipelineid/:runid", s.deps.PipelineProvider.PipelineRunGet)
+		apiAuthGrp.GET("pipelinerun/:pipelineid/:runid/queue", s.deps.PipelineProvider.PipelineRunQueuePosition)
 		apiAuthGrp.GET("pipelinerun/:pipelineid", s.deps.PipelineProvider.PipelineGetAllRuns)
 		apiAuthGrp.GET("pipelinerun/:pipelineid/latest", s.deps.PipelineProvider.PipelineGetLatestRun)
 		apiAuthGrp.GET("pipelinerun/:pipelineid/:runid/log", s.deps.PipelineProvider.GetJobLogs)
+		apiAuthGrp.GET("pipelinerun/:pipelineid/:runid/log/stream", s.deps.PipelineProvider.GetJobLogsStream)
+		apiAuthGrp.GET("pipelinerun/:pipelineid/:runid/job/:jobid/log", s.deps.PipelineProvider.GetSingleJobLogs)
+		apiAuthGrp.GET("pipelinerun/:pipelineid/:runid/artifacts", s.deps.PipelineProvider.GetArtifacts)
+		apiAuthGrp.GET("pipelinerun/:pipelineid/:runid/artifacts/:jobid/*", s.deps.PipelineProvider.GetArtifact)
 
 		// Secrets
 		apiAuthGrp.GET("secrets", ListSecrets)
@@ -106,6 +128,11 @@ func (s *GaiaHandler) InitHandlers(e *echo.Echo) error {
 	apiAuthGrp.POST("worker/secret", s.deps.WorkerProvider.ResetWorkerRegisterSecret)
 	apiGrp.POST("worker/register", s.deps.WorkerProvider.RegisterWorker)
 
+	// Metrics
+	if gaia.Cfg.MetricsEnabled {
+		e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+	}
+
 	// Middleware
 	e.Use(middleware.Recover())
 	// e.Use(middleware.Logger())
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"github.com/gaia-pipeline/gaia/event"
 	"github.com/gaia-pipeline/gaia/providers"
 	"github.com/gaia-pipeline/gaia/providers/pipelines"
 	"github.com/gaia-pipeline/gaia/providers/workers"
@@ -22,6 +23,7 @@ type Dependencies struct {
 	Certificate      security.CAAPI
 	RBACService      rbac.Service
 	Store            store.GaiaStore
+	Events           *event.Bus
 }
 
 // GaiaHandler defines handler functions throughout Gaia.
@@ -86,3 +86,58 @@ func (h *settingsHandler) rbacGet(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, rbacGetResponse{Enabled: settings.RBACEnabled})
 }
+
+// @Summary Put retention settings
+// @Description Save the given global retention policy for pipeline runs and their logs.
+// @Tags settings
+// @Accept json
+// @Produce plain
+// @Security ApiKeyAuth
+// @Param RetentionPolicy body gaia.RetentionPolicy true "Retention setting details."
+// @Success 200 {string} string "Settings have been updated."
+// @Failure 400 {string} string "Invalid body."
+// @Failure 500 {string} string "Something went wrong while saving or retrieving retention settings."
+// @Router /settings/retention [put]
+func (h *settingsHandler) retentionPut(c echo.Context) error {
+	var request gaia.RetentionPolicy
+	if err := c.Bind(&request); err != nil {
+		gaia.Cfg.Logger.Error("failed to bind body", "error", err.Error())
+		return c.String(http.StatusBadRequest, "Invalid body provided.")
+	}
+	if request.KeepRuns < 0 || request.KeepDays < 0 {
+		return c.String(http.StatusBadRequest, "Retention values must not be negative.")
+	}
+
+	settings, err := h.store.SettingsGet()
+	if err != nil {
+		gaia.Cfg.Logger.Error("failed to get store settings", "error", err.Error())
+		return c.String(http.StatusInternalServerError, msgSomethingWentWrong)
+	}
+
+	settings.Retention = request
+
+	if err := h.store.SettingsPut(settings); err != nil {
+		gaia.Cfg.Logger.Error("failed to put store settings", "error", err.Error())
+		return c.String(http.StatusInternalServerError, "An error occurred while saving the settings.")
+	}
+
+	return c.String(http.StatusOK, "Settings have been updated.")
+}
+
+// @Summary Get retention settings
+// @Description Get the global retention policy for pipeline runs and their logs.
+// @Tags settings
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} gaia.RetentionPolicy
+// @Failure 500 {string} string "Something went wrong while retrieving retention settings."
+// @Router /settings/retention [get]
+func (h *settingsHandler) retentionGet(c echo.Context) error {
+	settings, err := h.store.SettingsGet()
+	if err != nil {
+		gaia.Cfg.Logger.Error("failed to get store settings", "error", err.Error())
+		return c.String(http.StatusInternalServerError, msgSomethingWentWrong)
+	}
+
+	return c.JSON(http.StatusOK, settings.Retention)
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/go-hclog"
@@ -405,3 +406,73 @@ func Test_SettingsHandler_RBACPut(t *testing.T) {
 		assert.Equal(t, rec.Body.String(), "Settings have been updated.")
 	})
 }
+
+func Test_SettingsHandler_RetentionGet(t *testing.T) {
+	gaia.Cfg = &gaia.Config{
+		Logger: hclog.NewNullLogger(),
+	}
+
+	e := echo.New()
+	m := &mockSettingStoreService{}
+	settingsHandler := newSettingsHandler(m)
+
+	m.get = func() (*gaia.StoreConfig, error) {
+		return &gaia.StoreConfig{
+			Retention: gaia.RetentionPolicy{KeepRuns: 10, KeepDays: 30},
+		}, nil
+	}
+
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/api/" + gaia.APIVersion + "/setttings/retention")
+
+	_ = settingsHandler.retentionGet(c)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "{\"keepruns\":10,\"keepdays\":30}\n", rec.Body.String())
+}
+
+func Test_SettingsHandler_RetentionPut(t *testing.T) {
+	gaia.Cfg = &gaia.Config{
+		Logger: hclog.NewNullLogger(),
+	}
+
+	e := echo.New()
+	m := &mockSettingStoreService{}
+	settingsHandler := newSettingsHandler(m)
+
+	var saved *gaia.StoreConfig
+	m.get = func() (*gaia.StoreConfig, error) {
+		return &gaia.StoreConfig{RBACEnabled: true}, nil
+	}
+	m.put = func(config *gaia.StoreConfig) error {
+		saved = config
+		return nil
+	}
+
+	newContext := func(body string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/setttings/retention")
+		return c, rec
+	}
+
+	t.Run("negative values return 400", func(t *testing.T) {
+		c, rec := newContext(`{"keepruns":-1}`)
+		_ = settingsHandler.retentionPut(c)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("store success returns 200", func(t *testing.T) {
+		c, rec := newContext(`{"keepruns":5,"keepdays":7}`)
+		_ = settingsHandler.retentionPut(c)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, &gaia.StoreConfig{
+			RBACEnabled: true,
+			Retention:   gaia.RetentionPolicy{KeepRuns: 5, KeepDays: 7},
+		}, saved)
+	})
+}
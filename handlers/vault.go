@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
 	"github.com/gaia-pipeline/gaia/helper/stringhelper"
 
 	"github.com/gaia-pipeline/gaia/services"
@@ -67,10 +68,15 @@ func UpdateSecret(c echo.Context) error {
 	return upsertSecret(c, key, value)
 }
 
+// isReservedVaultKey returns true if the given vault key is managed by gaia itself.
+func isReservedVaultKey(key string) bool {
+	return stringhelper.IsContainedInSlice(ignoredVaultKeys, key, true) || pipelinehelper.IsSecretParameterKey(key)
+}
+
 // updates or creates a secret
 func upsertSecret(c echo.Context, key string, value string) error {
 	// Handle ignored special keys
-	if stringhelper.IsContainedInSlice(ignoredVaultKeys, key, true) {
+	if isReservedVaultKey(key) {
 		return c.String(http.StatusBadRequest, "key is reserved and cannot be set/changed")
 	}
 
@@ -112,7 +118,7 @@ func ListSecrets(c echo.Context) error {
 	kvs := v.GetAll()
 	for _, k := range kvs {
 		// Handle ignored special keys
-		if stringhelper.IsContainedInSlice(ignoredVaultKeys, k, true) {
+		if isReservedVaultKey(k) {
 			continue
 		}
 
@@ -140,7 +146,7 @@ func RemoveSecret(c echo.Context) error {
 	}
 
 	// Handle ignored special keys
-	if stringhelper.IsContainedInSlice(ignoredVaultKeys, key, true) {
+	if isReservedVaultKey(key) {
 		return c.String(http.StatusBadRequest, "key is reserved and cannot be deleted")
 	}
 
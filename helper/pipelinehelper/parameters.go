@@ -0,0 +1,112 @@
+package pipelinehelper
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// ValidateParameters checks if the given parameter declarations of a pipeline are valid.
+func ValidateParameters(params []*gaia.Parameter) error {
+	names := make(map[string]bool, len(params))
+	for _, param := range params {
+		if param.Name == "" {
+			return errors.New("parameter name must not be empty")
+		}
+		if names[param.Name] {
+			return fmt.Errorf("parameter %q is declared more than once", param.Name)
+		}
+		names[param.Name] = true
+
+		switch param.Type {
+		case gaia.ParameterString, gaia.ParameterBool, gaia.ParameterNumber, gaia.ParameterSecret:
+			if len(param.Allowed) > 0 {
+				return fmt.Errorf("parameter %q declares allowed values but is not of type %s", param.Name, gaia.ParameterChoice)
+			}
+		case gaia.ParameterChoice:
+			if len(param.Allowed) == 0 {
+				return fmt.Errorf("parameter %q of type %s must declare allowed values", param.Name, gaia.ParameterChoice)
+			}
+		default:
+			return fmt.Errorf("parameter %q has unknown type %q, must be one of string, bool, number, choice or secret", param.Name, param.Type)
+		}
+
+		if param.Default != "" {
+			if _, err := parameterValue(param, param.Default); err != nil {
+				return fmt.Errorf("invalid default of parameter %q: %s", param.Name, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveParameters validates the given arguments against the given parameter
+// declarations and returns the effective value of every parameter.
+// The default of a parameter is used if no value has been given.
+// Arguments which are not declared as parameters are ignored.
+func ResolveParameters(params []*gaia.Parameter, args []*gaia.Argument) ([]*gaia.Argument, error) {
+	given := make(map[string]*gaia.Argument, len(args))
+	for _, arg := range args {
+		given[arg.Key] = arg
+	}
+
+	values := make([]*gaia.Argument, 0, len(params))
+	for _, param := range params {
+		value := param.Default
+		if arg, ok := given[param.Name]; ok {
+			if arg.Type != "" && arg.Type != string(param.Type) {
+				return nil, fmt.Errorf("parameter %q is of type %s but %s has been given", param.Name, param.Type, arg.Type)
+			}
+			if arg.Value != "" {
+				value = arg.Value
+			}
+		}
+
+		if value == "" {
+			if param.Required {
+				return nil, fmt.Errorf("parameter %q is required", param.Name)
+			}
+		} else {
+			var err error
+			if value, err = parameterValue(param, value); err != nil {
+				return nil, fmt.Errorf("invalid value of parameter %q: %s", param.Name, err.Error())
+			}
+		}
+
+		values = append(values, &gaia.Argument{
+			Description: param.Description,
+			Type:        string(param.Type),
+			Key:         param.Name,
+			Value:       value,
+		})
+	}
+	return values, nil
+}
+
+// parameterValue validates the given value against the type of the given
+// parameter and returns the normalized value.
+func parameterValue(param *gaia.Parameter, value string) (string, error) {
+	switch param.Type {
+	case gaia.ParameterBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a bool", value)
+		}
+		return strconv.FormatBool(b), nil
+	case gaia.ParameterNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("%q is not a number", value)
+		}
+	case gaia.ParameterChoice:
+		for _, allowed := range param.Allowed {
+			if value == allowed {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("%q is not one of %s", value, strings.Join(param.Allowed, ", "))
+	}
+	return value, nil
+}
@@ -0,0 +1,89 @@
+package pipelinehelper
+
+import (
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestValidateParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		params []*gaia.Parameter
+		valid  bool
+	}{
+		{
+			name: "valid parameters",
+			params: []*gaia.Parameter{
+				{Name: "version", Type: gaia.ParameterString, Required: true},
+				{Name: "dryrun", Type: gaia.ParameterBool, Default: "false"},
+				{Name: "replicas", Type: gaia.ParameterNumber, Default: "3"},
+				{Name: "env", Type: gaia.ParameterChoice, Default: "staging", Allowed: []string{"staging", "prod"}},
+				{Name: "token", Type: gaia.ParameterSecret},
+			},
+			valid: true,
+		},
+		{name: "missing name", params: []*gaia.Parameter{{Type: gaia.ParameterString}}},
+		{name: "duplicate name", params: []*gaia.Parameter{{Name: "a", Type: gaia.ParameterString}, {Name: "a", Type: gaia.ParameterBool}}},
+		{name: "unknown type", params: []*gaia.Parameter{{Name: "a", Type: "textfield"}}},
+		{name: "choice without allowed values", params: []*gaia.Parameter{{Name: "a", Type: gaia.ParameterChoice}}},
+		{name: "allowed values for string", params: []*gaia.Parameter{{Name: "a", Type: gaia.ParameterString, Allowed: []string{"b"}}}},
+		{name: "invalid default", params: []*gaia.Parameter{{Name: "a", Type: gaia.ParameterNumber, Default: "many"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateParameters(tt.params)
+			if tt.valid && err != nil {
+				t.Fatalf("expected parameters to be valid but got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected parameters to be invalid")
+			}
+		})
+	}
+}
+
+func TestResolveParameters(t *testing.T) {
+	params := []*gaia.Parameter{
+		{Name: "version", Type: gaia.ParameterString, Required: true},
+		{Name: "dryrun", Type: gaia.ParameterBool, Default: "false"},
+		{Name: "replicas", Type: gaia.ParameterNumber},
+		{Name: "env", Type: gaia.ParameterChoice, Default: "staging", Allowed: []string{"staging", "prod"}},
+	}
+
+	values, err := ResolveParameters(params, []*gaia.Argument{
+		{Key: "version", Value: "1.2.3"},
+		{Key: "dryrun", Type: "bool", Value: "1"},
+		{Key: "other", Value: "ignored"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"version": "1.2.3", "dryrun": "true", "replicas": "", "env": "staging"}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %d values but got %d", len(expected), len(values))
+	}
+	for i, value := range values {
+		if value.Key != params[i].Name || value.Value != expected[value.Key] || value.Type != string(params[i].Type) {
+			t.Fatalf("unexpected value %#v", value)
+		}
+	}
+
+	invalid := []struct {
+		name string
+		args []*gaia.Argument
+	}{
+		{name: "missing required", args: nil},
+		{name: "invalid bool", args: []*gaia.Argument{{Key: "version", Value: "1"}, {Key: "dryrun", Value: "maybe"}}},
+		{name: "invalid number", args: []*gaia.Argument{{Key: "version", Value: "1"}, {Key: "replicas", Value: "two"}}},
+		{name: "invalid choice", args: []*gaia.Argument{{Key: "version", Value: "1"}, {Key: "env", Value: "dev"}}},
+		{name: "mismatching type", args: []*gaia.Argument{{Key: "version", Type: "number", Value: "1"}}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ResolveParameters(params, tt.args); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package pipelinehelper
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// PriorityAgingInterval is the time after which the priority of a waiting
+// pipeline run is raised by one so that low priority runs are not starved.
+const PriorityAgingInterval = 5 * time.Minute
+
+// EffectivePriority returns the priority of the given run raised by the
+// time the run has been waiting for its execution.
+func EffectivePriority(r *gaia.PipelineRun, now time.Time) int {
+	waiting := now.Sub(r.ScheduleDate)
+	if waiting <= 0 {
+		return r.Priority
+	}
+	return r.Priority + int(waiting/PriorityAgingInterval)
+}
+
+// RunBefore returns true if the run a has to be executed before the run b.
+// Runs with a higher effective priority are executed first. Runs with the
+// same effective priority are executed in the order they have been scheduled.
+func RunBefore(a, b *gaia.PipelineRun, now time.Time) bool {
+	pa, pb := EffectivePriority(a, now), EffectivePriority(b, now)
+	if pa != pb {
+		return pa > pb
+	}
+	return a.ScheduleDate.Before(b.ScheduleDate)
+}
+
+// SortRunsByPriority sorts the given runs in the order they have to be executed.
+func SortRunsByPriority(runs []*gaia.PipelineRun, now time.Time) {
+	sort.SliceStable(runs, func(i, j int) bool {
+		return RunBefore(runs[i], runs[j], now)
+	})
+}
@@ -0,0 +1,31 @@
+package pipelinehelper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestSortRunsByPriority(t *testing.T) {
+	now := time.Now()
+	runs := []*gaia.PipelineRun{
+		{ID: 1, ScheduleDate: now.Add(-time.Minute)},
+		{ID: 2, ScheduleDate: now.Add(-2 * time.Minute)},
+		{ID: 3, ScheduleDate: now, Priority: 5},
+		{ID: 4, ScheduleDate: now.Add(-10 * PriorityAgingInterval), Priority: -3},
+	}
+
+	SortRunsByPriority(runs, now)
+
+	// Run 4 has been raised to a priority of 7 while waiting
+	expected := []int{4, 3, 2, 1}
+	for i, r := range runs {
+		if r.ID != expected[i] {
+			t.Fatalf("expected run %d at position %d but got %d", expected[i], i, r.ID)
+		}
+	}
+	if p := EffectivePriority(runs[0], now); p != 7 {
+		t.Fatalf("expected effective priority 7 but got %d", p)
+	}
+}
@@ -0,0 +1,129 @@
+package pipelinehelper
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/security"
+)
+
+// MaskedSecretValue replaces the value of secret parameters in API responses.
+const MaskedSecretValue = "**********"
+
+// SecretParameterKey returns the vault key which holds the value of the secret
+// parameter with the given name of the pipeline run with the given unique id.
+func SecretParameterKey(uniqueID, name string) string {
+	return gaia.SecretParameterPrefix + uniqueID + "_" + name
+}
+
+// IsSecretParameterKey returns true if the given vault key holds the value of a secret parameter.
+func IsSecretParameterKey(key string) bool {
+	return strings.HasPrefix(key, gaia.SecretParameterPrefix)
+}
+
+// isSecretParameter returns true if the given argument is a secret parameter.
+func isSecretParameter(arg *gaia.Argument) bool {
+	return arg.Type == string(gaia.ParameterSecret)
+}
+
+// StoreSecretParameters moves the values of the secret parameters in the given
+// arguments into the vault under the pipeline run with the given unique id.
+// The values are removed from the arguments so that they are never stored
+// together with the pipeline run.
+func StoreSecretParameters(v security.GaiaVault, uniqueID string, args []*gaia.Argument) error {
+	secrets := make([]*gaia.Argument, 0)
+	for _, arg := range args {
+		if isSecretParameter(arg) && arg.Value != "" {
+			secrets = append(secrets, arg)
+		}
+	}
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	if err := v.LoadSecrets(); err != nil {
+		return err
+	}
+	for _, arg := range secrets {
+		// The vault file separates keys and values by '=' and entries by
+		// new lines which both might be part of the value.
+		value := base64.RawStdEncoding.EncodeToString([]byte(arg.Value))
+		v.Add(SecretParameterKey(uniqueID, arg.Key), []byte(value))
+	}
+	if err := v.SaveSecrets(); err != nil {
+		return err
+	}
+
+	for _, arg := range secrets {
+		arg.Value = ""
+	}
+	return nil
+}
+
+// ResolveSecretParameters sets the values of the secret parameters in the given
+// arguments which have been stored in the vault for the pipeline run with the
+// given unique id. Secret parameters which already carry a value are kept.
+func ResolveSecretParameters(v security.GaiaVault, uniqueID string, args []*gaia.Argument) error {
+	loaded := false
+	for _, arg := range args {
+		if !isSecretParameter(arg) || arg.Value != "" {
+			continue
+		}
+		if !loaded {
+			if err := v.LoadSecrets(); err != nil {
+				return err
+			}
+			loaded = true
+		}
+
+		// Optional secret parameters without a value are not stored
+		value, err := v.Get(SecretParameterKey(uniqueID, arg.Key))
+		if err != nil {
+			continue
+		}
+		decoded, err := base64.RawStdEncoding.DecodeString(string(value))
+		if err != nil {
+			return err
+		}
+		arg.Value = string(decoded)
+	}
+	return nil
+}
+
+// RemoveSecretParameters removes the values of the secret parameters
+// of the pipeline run with the given unique id from the vault.
+func RemoveSecretParameters(v security.GaiaVault, uniqueID string) error {
+	if err := v.LoadSecrets(); err != nil {
+		return err
+	}
+	prefix := SecretParameterKey(uniqueID, "")
+	removed := false
+	for _, key := range v.GetAll() {
+		if strings.HasPrefix(key, prefix) {
+			v.Remove(key)
+			removed = true
+		}
+	}
+	if !removed {
+		return nil
+	}
+	return v.SaveSecrets()
+}
+
+// MaskSecretParameters replaces the values of the secret parameters in the
+// given arguments. It returns a copy and does not modify the given arguments.
+func MaskSecretParameters(args []*gaia.Argument) []*gaia.Argument {
+	if args == nil {
+		return nil
+	}
+	masked := make([]*gaia.Argument, 0, len(args))
+	for _, arg := range args {
+		a := *arg
+		if isSecretParameter(&a) && a.Value != "" {
+			a.Value = MaskedSecretValue
+		}
+		masked = append(masked, &a)
+	}
+	return masked
+}
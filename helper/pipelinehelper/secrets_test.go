@@ -0,0 +1,76 @@
+package pipelinehelper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+type vaultFake struct {
+	data map[string][]byte
+}
+
+func (v *vaultFake) LoadSecrets() error { return nil }
+func (v *vaultFake) GetAll() []string {
+	keys := make([]string, 0, len(v.data))
+	for k := range v.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+func (v *vaultFake) SaveSecrets() error           { return nil }
+func (v *vaultFake) Add(key string, value []byte) { v.data[key] = value }
+func (v *vaultFake) Remove(key string)            { delete(v.data, key) }
+func (v *vaultFake) Get(key string) ([]byte, error) {
+	value, ok := v.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	return value, nil
+}
+
+func TestSecretParameters(t *testing.T) {
+	v := &vaultFake{data: map[string][]byte{"other": []byte("value")}}
+	args := []*gaia.Argument{
+		{Type: string(gaia.ParameterSecret), Key: "token", Value: "a=b\nc"},
+		{Type: string(gaia.ParameterSecret), Key: "optional"},
+		{Type: string(gaia.ParameterString), Key: "version", Value: "1.2.3"},
+	}
+
+	// Secret values are moved into the vault
+	if err := StoreSecretParameters(v, "run", args); err != nil {
+		t.Fatal(err)
+	}
+	if args[0].Value != "" || args[2].Value != "1.2.3" {
+		t.Fatalf("expected only the secret value to be removed but got %v", args)
+	}
+	if len(v.data) != 2 || !IsSecretParameterKey(SecretParameterKey("run", "token")) {
+		t.Fatalf("expected secret value to be stored in vault but got %v", v.data)
+	}
+
+	// Secret values are resolved from the vault
+	if err := ResolveSecretParameters(v, "run", args); err != nil {
+		t.Fatal(err)
+	}
+	if args[0].Value != "a=b\nc" || args[1].Value != "" {
+		t.Fatalf("expected secret value to be resolved but got %v", args)
+	}
+
+	// Secret values are masked
+	masked := MaskSecretParameters(args)
+	if masked[0].Value != MaskedSecretValue || masked[2].Value != "1.2.3" || args[0].Value != "a=b\nc" {
+		t.Fatalf("expected masked copy of arguments but got %v", masked)
+	}
+
+	// Secret values of other runs are kept
+	if err := RemoveSecretParameters(v, "other-run"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveSecretParameters(v, "run"); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.data) != 1 {
+		t.Fatalf("expected secret value to be removed from vault but got %v", v.data)
+	}
+}
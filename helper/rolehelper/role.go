@@ -91,10 +91,27 @@ var (
 					},
 					Description: "Stop running pipelines.",
 				},
+				{
+					Name: "Rerun",
+					APIEndpoint: []*gaia.UserRoleEndpoint{
+						NewUserRoleEndpoint("POST", "/api/v1/pipelinerun/:pipelineid/:runid/rerun"),
+						NewUserRoleEndpoint("POST", "/api/v1/pipelinerun/:pipelineid/:runid/rerun-failed"),
+					},
+					Description: "Rerun finished pipeline runs.",
+				},
+				{
+					Name: "Approve",
+					APIEndpoint: []*gaia.UserRoleEndpoint{
+						NewUserRoleEndpoint("POST", "/api/v1/pipelinerun/:pipelineid/:runid/job/:jobid/approve"),
+						NewUserRoleEndpoint("POST", "/api/v1/pipelinerun/:pipelineid/:runid/job/:jobid/reject"),
+					},
+					Description: "Approve or reject jobs waiting for approval.",
+				},
 				{
 					Name: "Get",
 					APIEndpoint: []*gaia.UserRoleEndpoint{
 						NewUserRoleEndpoint("GET", "/api/v1/pipelinerun/:pipelineid/:runid"),
+						NewUserRoleEndpoint("GET", "/api/v1/pipelinerun/:pipelineid/:runid/queue"),
 						NewUserRoleEndpoint("GET", "/api/v1/pipelinerun/:pipelineid/latest"),
 					},
 					Description: "Get pipeline runs.",
@@ -110,9 +127,19 @@ var (
 					Name: "Logs",
 					APIEndpoint: []*gaia.UserRoleEndpoint{
 						NewUserRoleEndpoint("GET", "/api/v1/pipelinerun/:pipelineid/:runid/latest"),
+						NewUserRoleEndpoint("GET", "/api/v1/pipelinerun/:pipelineid/:runid/log/stream"),
+						NewUserRoleEndpoint("GET", "/api/v1/pipelinerun/:pipelineid/:runid/job/:jobid/log"),
 					},
 					Description: "Get logs for pipeline runs.",
 				},
+				{
+					Name: "Artifacts",
+					APIEndpoint: []*gaia.UserRoleEndpoint{
+						NewUserRoleEndpoint("GET", "/api/v1/pipelinerun/:pipelineid/:runid/artifacts"),
+						NewUserRoleEndpoint("GET", "/api/v1/pipelinerun/:pipelineid/:runid/artifacts/:jobid/*"),
+					},
+					Description: "Get and download artifacts of pipeline runs.",
+				},
 			},
 		},
 		{
@@ -244,5 +271,25 @@ var (
 				},
 			},
 		},
+		{
+			Name:        "Admin",
+			Description: "Maintenance of the Gaia primary instance.",
+			Roles: []*gaia.UserRole{
+				{
+					Name: "Drain",
+					APIEndpoint: []*gaia.UserRoleEndpoint{
+						NewUserRoleEndpoint("POST", "/api/v1/admin/drain"),
+					},
+					Description: "Stop the Gaia primary instance from starting new pipeline runs.",
+				},
+				{
+					Name: "Resume",
+					APIEndpoint: []*gaia.UserRoleEndpoint{
+						NewUserRoleEndpoint("DELETE", "/api/v1/admin/drain"),
+					},
+					Description: "Let the drained Gaia primary instance start new pipeline runs again.",
+				},
+			},
+		},
 	}
 )
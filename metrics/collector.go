@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/event"
+	"github.com/gaia-pipeline/gaia/store"
+	"github.com/gaia-pipeline/gaia/store/memdb"
+	"github.com/gaia-pipeline/gaia/workers/scheduler/service"
+)
+
+var (
+	scheduledRunsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scheduler", "scheduled_runs"),
+		"Number of pipeline runs in the scheduler buffer.",
+		nil, nil,
+	)
+	scheduledRunsLimitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scheduler", "scheduled_runs_limit"),
+		"Maximum number of pipeline runs in the scheduler buffer.",
+		nil, nil,
+	)
+	freeWorkersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scheduler", "free_workers"),
+		"Number of free local workers.",
+		nil, nil,
+	)
+	queuedRunsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "queue", "runs"),
+		"Number of pipeline runs waiting for a remote worker by required tag.",
+		[]string{"tag"}, nil,
+	)
+	workerSlotsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "worker", "slots"),
+		"Number of execution slots of a remote worker.",
+		[]string{"worker", "name", "status"}, nil,
+	)
+	workerLastContactDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "worker", "last_contact_age_seconds"),
+		"Seconds since the last contact of a remote worker.",
+		[]string{"worker", "name", "status"}, nil,
+	)
+	agentSlotsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "agent", "slots"),
+		"Number of execution slots of the worker agent.",
+		nil, nil,
+	)
+	agentSlotsInUseDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "agent", "slots_in_use"),
+		"Number of execution slots of the worker agent which are in use.",
+		nil, nil,
+	)
+)
+
+// Dependencies defines the dependencies of the collector.
+type Dependencies struct {
+	Scheduler   service.GaiaScheduler
+	BufferLimit int
+	DB          memdb.GaiaMemDB
+	Store       store.GaiaStore
+}
+
+// Collector collects the scheduler, queue and worker state on every
+// scrape and observes the durations of finished pipeline runs and jobs.
+type Collector struct {
+	deps Dependencies
+}
+
+// NewCollector creates a new collector.
+func NewCollector(deps Dependencies) *Collector {
+	return &Collector{deps: deps}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- scheduledRunsDesc
+	ch <- scheduledRunsLimitDesc
+	ch <- freeWorkersDesc
+	ch <- queuedRunsDesc
+	ch <- workerSlotsDesc
+	ch <- workerLastContactDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(scheduledRunsDesc, prometheus.GaugeValue, float64(c.deps.Scheduler.CountScheduledRuns()))
+	ch <- prometheus.MustNewConstMetric(scheduledRunsLimitDesc, prometheus.GaugeValue, float64(c.deps.BufferLimit))
+	ch <- prometheus.MustNewConstMetric(freeWorkersDesc, prometheus.GaugeValue, float64(c.deps.Scheduler.GetFreeWorkers()))
+
+	for tag, count := range c.deps.DB.CountPipelineRunsByTag() {
+		ch <- prometheus.MustNewConstMetric(queuedRunsDesc, prometheus.GaugeValue, float64(count), tag)
+	}
+
+	for _, w := range c.deps.DB.GetAllWorker() {
+		labels := []string{w.UniqueID, w.Name, string(w.Status)}
+		ch <- prometheus.MustNewConstMetric(workerSlotsDesc, prometheus.GaugeValue, float64(w.Slots), labels...)
+		ch <- prometheus.MustNewConstMetric(workerLastContactDesc, prometheus.GaugeValue, time.Since(w.LastContact).Seconds(), labels...)
+	}
+}
+
+// Start observes the run and job durations of all pipeline runs
+// which are published as finished on the given event bus.
+func (c *Collector) Start(bus *event.Bus) {
+	sub := bus.Subscribe(event.RunFinished)
+	go func() {
+		for e := range sub.Events() {
+			r, err := c.deps.Store.PipelineGetRunByID(e.RunUniqueID)
+			if err != nil || r == nil {
+				gaia.Cfg.Logger.Error("cannot get finished pipeline run for metrics", "error", err, "pipelinerun", e.RunUniqueID)
+				continue
+			}
+			c.observeRun(r)
+		}
+	}()
+}
+
+// observeRun records the duration of the given finished pipeline run and its jobs.
+func (c *Collector) observeRun(r *gaia.PipelineRun) {
+	pipelineName := c.pipelineName(r.PipelineID)
+	if !r.StartDate.IsZero() && !r.FinishDate.IsZero() {
+		runDuration.WithLabelValues(pipelineName, string(r.Status)).Observe(r.FinishDate.Sub(r.StartDate).Seconds())
+	}
+
+	for _, j := range r.Jobs {
+		// Skipped jobs have never been executed
+		if len(j.Attempts) == 0 {
+			continue
+		}
+		first, last := j.Attempts[0], j.Attempts[len(j.Attempts)-1]
+		if first.StartDate.IsZero() || last.FinishDate.IsZero() {
+			continue
+		}
+		jobDuration.WithLabelValues(pipelineName, string(j.Status)).Observe(last.FinishDate.Sub(first.StartDate).Seconds())
+	}
+}
+
+// pipelineName returns the name of the pipeline with the given id.
+// The id is returned if the pipeline cannot be found.
+func (c *Collector) pipelineName(id int) string {
+	p, err := c.deps.Store.PipelineGet(id)
+	if err != nil || p == nil {
+		return strconv.Itoa(id)
+	}
+	return p.Name
+}
+
+// AgentCollector collects the slot usage of a worker agent on every scrape.
+type AgentCollector struct {
+	scheduler service.GaiaScheduler
+}
+
+// NewAgentCollector creates a new agent collector.
+func NewAgentCollector(scheduler service.GaiaScheduler) *AgentCollector {
+	return &AgentCollector{scheduler: scheduler}
+}
+
+// Describe implements prometheus.Collector.
+func (c *AgentCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- agentSlotsDesc
+	ch <- agentSlotsInUseDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *AgentCollector) Collect(ch chan<- prometheus.Metric) {
+	slots := gaia.Cfg.Worker
+	ch <- prometheus.MustNewConstMetric(agentSlotsDesc, prometheus.GaugeValue, float64(slots))
+	ch <- prometheus.MustNewConstMetric(agentSlotsInUseDesc, prometheus.GaugeValue, float64(slots-int(c.scheduler.GetFreeWorkers())))
+}
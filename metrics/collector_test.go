@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/store"
+	"github.com/gaia-pipeline/gaia/store/memdb"
+)
+
+type mockScheduler struct {
+	scheduledRuns int
+	freeWorkers   int32
+}
+
+func (m *mockScheduler) Init() {}
+func (m *mockScheduler) SchedulePipeline(p *gaia.Pipeline, startReason string, args []*gaia.Argument) (*gaia.PipelineRun, error) {
+	return nil, nil
+}
+func (m *mockScheduler) RerunPipeline(p *gaia.Pipeline, run *gaia.PipelineRun, failedOnly bool) (*gaia.PipelineRun, error) {
+	return nil, nil
+}
+func (m *mockScheduler) DecideApproval(p *gaia.Pipeline, runID int, jobID uint32, username string, approved bool) error {
+	return nil
+}
+func (m *mockScheduler) RecoverWorkerRuns(workerID string) error           { return nil }
+func (m *mockScheduler) Drain()                                            {}
+func (m *mockScheduler) Resume()                                           {}
+func (m *mockScheduler) Draining() bool                                    { return false }
+func (m *mockScheduler) Shutdown(gracePeriod time.Duration)                {}
+func (m *mockScheduler) SetPipelineJobs(p *gaia.Pipeline) error            { return nil }
+func (m *mockScheduler) StopPipelineRun(p *gaia.Pipeline, runID int) error { return nil }
+func (m *mockScheduler) GetFreeWorkers() int32                             { return m.freeWorkers }
+func (m *mockScheduler) CountScheduledRuns() int                           { return m.scheduledRuns }
+
+type mockMemDB struct {
+	memdb.GaiaMemDB
+	workers []*gaia.Worker
+	queue   map[string]int
+}
+
+func (m *mockMemDB) GetAllWorker() []*gaia.Worker           { return m.workers }
+func (m *mockMemDB) CountPipelineRunsByTag() map[string]int { return m.queue }
+
+func TestCollect(t *testing.T) {
+	c := NewCollector(Dependencies{
+		Scheduler:   &mockScheduler{scheduledRuns: 3, freeWorkers: 1},
+		BufferLimit: 50,
+		DB: &mockMemDB{
+			workers: []*gaia.Worker{
+				{UniqueID: "worker-id", Name: "worker", Status: gaia.WorkerActive, Slots: 4, LastContact: time.Now()},
+			},
+			queue: map[string]int{"golang": 2},
+		},
+	})
+
+	expected := `
+# HELP gaia_queue_runs Number of pipeline runs waiting for a remote worker by required tag.
+# TYPE gaia_queue_runs gauge
+gaia_queue_runs{tag="golang"} 2
+# HELP gaia_scheduler_free_workers Number of free local workers.
+# TYPE gaia_scheduler_free_workers gauge
+gaia_scheduler_free_workers 1
+# HELP gaia_scheduler_scheduled_runs Number of pipeline runs in the scheduler buffer.
+# TYPE gaia_scheduler_scheduled_runs gauge
+gaia_scheduler_scheduled_runs 3
+# HELP gaia_scheduler_scheduled_runs_limit Maximum number of pipeline runs in the scheduler buffer.
+# TYPE gaia_scheduler_scheduled_runs_limit gauge
+gaia_scheduler_scheduled_runs_limit 50
+# HELP gaia_worker_slots Number of execution slots of a remote worker.
+# TYPE gaia_worker_slots gauge
+gaia_worker_slots{name="worker",status="active",worker="worker-id"} 4
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"gaia_queue_runs", "gaia_scheduler_free_workers", "gaia_scheduler_scheduled_runs",
+		"gaia_scheduler_scheduled_runs_limit", "gaia_worker_slots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := testutil.CollectAndCount(c, "gaia_worker_last_contact_age_seconds"); count != 1 {
+		t.Fatalf("expected one last contact age metric but got %d", count)
+	}
+}
+
+func TestAgentCollect(t *testing.T) {
+	gaia.Cfg = &gaia.Config{Logger: hclog.NewNullLogger(), Worker: 4}
+	c := NewAgentCollector(&mockScheduler{freeWorkers: 1})
+
+	expected := `
+# HELP gaia_agent_slots Number of execution slots of the worker agent.
+# TYPE gaia_agent_slots gauge
+gaia_agent_slots 4
+# HELP gaia_agent_slots_in_use Number of execution slots of the worker agent which are in use.
+# TYPE gaia_agent_slots_in_use gauge
+gaia_agent_slots_in_use 3
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestObserveRun(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestObserveRun")
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = &gaia.Config{Logger: hclog.NewNullLogger()}
+	gaia.Cfg.Bolt.Mode = 0600
+	dataStore := store.NewBoltStore()
+	if err := dataStore.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	defer dataStore.Close()
+	if err := dataStore.PipelinePut(&gaia.Pipeline{ID: 1, Name: "observed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now().Add(-time.Minute)
+	r := &gaia.PipelineRun{
+		PipelineID: 1,
+		Status:     gaia.RunFailed,
+		StartDate:  start,
+		FinishDate: start.Add(30 * time.Second),
+		Jobs: []*gaia.Job{
+			{Status: gaia.JobSuccess, Attempts: []*gaia.JobAttempt{{StartDate: start, FinishDate: start.Add(time.Second)}}},
+			{Status: gaia.JobFailed, Attempts: []*gaia.JobAttempt{
+				{StartDate: start, FinishDate: start.Add(time.Second)},
+				{StartDate: start.Add(2 * time.Second), FinishDate: start.Add(3 * time.Second)},
+			}},
+			{Status: gaia.JobSkipped},
+		},
+	}
+	NewCollector(Dependencies{Store: dataStore}).observeRun(r)
+	NewCollector(Dependencies{Store: dataStore}).observeRun(&gaia.PipelineRun{PipelineID: 2, Status: gaia.RunSuccess, StartDate: start, FinishDate: start})
+
+	body := scrape(t)
+	for _, expected := range []string{
+		`gaia_pipeline_run_duration_seconds_sum{pipeline="observed",status="failed"} 30`,
+		`gaia_pipeline_run_duration_seconds_count{pipeline="2",status="success"} 1`,
+		`gaia_job_duration_seconds_sum{pipeline="observed",status="success"} 1`,
+		`gaia_job_duration_seconds_sum{pipeline="observed",status="failed"} 3`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("expected metrics to contain %q:\n%s", expected, body)
+		}
+	}
+	if strings.Contains(body, `status="skipped"`) {
+		t.Fatalf("expected no duration of skipped jobs:\n%s", body)
+	}
+}
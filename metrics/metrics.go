@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// namespace is the prefix of all Gaia metrics.
+const namespace = "gaia"
+
+// Build status label values.
+const (
+	statusSuccess = "success"
+	statusFailed  = "failed"
+)
+
+// durationBuckets are the histogram buckets in seconds used for
+// run, job and build durations.
+var durationBuckets = []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600, 7200}
+
+var (
+	runDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pipeline_run_duration_seconds",
+		Help:      "Duration of finished pipeline runs.",
+		Buckets:   durationBuckets,
+	}, []string{"pipeline", "status"})
+
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Duration of finished jobs including all retries.",
+		Buckets:   durationBuckets,
+	}, []string{"pipeline", "status"})
+
+	buildDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "pipeline_build_duration_seconds",
+		Help:      "Duration of pipeline builds.",
+		Buckets:   durationBuckets,
+	}, []string{"type", "status"})
+
+	// WebhookRequests counts the received git webhook requests by response status code.
+	WebhookRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_requests_total",
+		Help:      "Number of received git webhook requests.",
+	}, []string{"code"})
+
+	// TriggerRequests counts the received remote trigger requests by response status code.
+	TriggerRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "trigger_requests_total",
+		Help:      "Number of received remote pipeline trigger requests.",
+	}, []string{"code"})
+
+	// AgentLastContact holds the unix time of the last successful contact
+	// of the worker agent with the primary instance.
+	// It is only registered in worker mode.
+	AgentLastContact = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "agent_last_contact_timestamp_seconds",
+		Help:      "Unix time of the last successful contact with the primary instance.",
+	})
+)
+
+// registry holds all Gaia metrics.
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		runDuration,
+		jobDuration,
+		buildDuration,
+		WebhookRequests,
+		TriggerRequests,
+	)
+}
+
+// Register registers the given collector at the Gaia metrics registry.
+func Register(c prometheus.Collector) error {
+	return registry.Register(c)
+}
+
+// Handler returns the http handler which exposes all registered
+// metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveBuild records the duration of a pipeline build which
+// has been started at the given time.
+func ObserveBuild(t gaia.PipelineType, start time.Time, err error) {
+	status := statusSuccess
+	if err != nil {
+		status = statusFailed
+	}
+	buildDuration.WithLabelValues(t.String(), status).Observe(time.Since(start).Seconds())
+}
+
+// CountRequests returns a middleware which counts all requests
+// handled by the route in the given counter by response status code.
+func CountRequests(counter *prometheus.CounterVec) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			code := c.Response().Status
+			if err != nil {
+				code = http.StatusInternalServerError
+				if he, ok := err.(*echo.HTTPError); ok {
+					code = he.Code
+				}
+			}
+			counter.WithLabelValues(strconv.Itoa(code)).Inc()
+			return err
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestCountRequests(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_requests_total"}, []string{"code"})
+	e := echo.New()
+	e.POST("/ok", func(c echo.Context) error {
+		return c.String(http.StatusOK, "ok")
+	}, CountRequests(counter))
+	e.POST("/forbidden", func(c echo.Context) error {
+		return c.String(http.StatusForbidden, "forbidden")
+	}, CountRequests(counter))
+	e.POST("/error", func(c echo.Context) error {
+		return echo.NewHTTPError(http.StatusBadRequest, "bad request")
+	}, CountRequests(counter))
+	e.POST("/failed", func(c echo.Context) error {
+		return errors.New("failed")
+	}, CountRequests(counter))
+
+	for _, path := range []string{"/ok", "/ok", "/forbidden", "/error", "/failed"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	expected := map[string]float64{"200": 2, "403": 1, "400": 1, "500": 1}
+	for code, count := range expected {
+		if got := testutil.ToFloat64(counter.WithLabelValues(code)); got != count {
+			t.Fatalf("expected %v requests with code %s but got %v", count, code, got)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ObserveBuild(gaia.PTypeGolang, time.Now().Add(-time.Minute), nil)
+	ObserveBuild(gaia.PTypeGolang, time.Now(), errors.New("compile error"))
+
+	body := scrape(t)
+	for _, expected := range []string{
+		`gaia_pipeline_build_duration_seconds_count{status="success",type="golang"} 1`,
+		`gaia_pipeline_build_duration_seconds_count{status="failed",type="golang"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Fatalf("expected metrics to contain %q:\n%s", expected, body)
+		}
+	}
+}
+
+// scrape returns all metrics exposed by the metrics handler.
+func scrape(t *testing.T) string {
+	ts := httptest.NewServer(Handler())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
@@ -0,0 +1,186 @@
+package notification
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/event"
+	"github.com/gaia-pipeline/gaia/store"
+)
+
+var (
+	// deliveryAttempts is the maximum number of attempts to deliver a single notification.
+	deliveryAttempts = 3
+
+	// deliveryBackoff is the delay before the first retry of a failed delivery.
+	// It is doubled with every further retry.
+	deliveryBackoff = 5 * time.Second
+)
+
+// previousRunsPerPage is the page size used to look up the previous finished run.
+const previousRunsPerPage = 10
+
+// Dispatcher sends the notifications of finished pipeline runs
+// to the notifiers configured at the pipeline and records every delivery.
+type Dispatcher struct {
+	store store.GaiaStore
+
+	notifiersLock sync.RWMutex
+	notifiers     map[gaia.NotificationType]Notifier
+
+	// wg keeps track of all running deliveries.
+	wg sync.WaitGroup
+}
+
+// NewDispatcher creates a new dispatcher with the slack,
+// email and webhook notifiers registered.
+func NewDispatcher(s store.GaiaStore) *Dispatcher {
+	d := &Dispatcher{
+		store:     s,
+		notifiers: make(map[gaia.NotificationType]Notifier),
+	}
+	d.Register(gaia.NotificationSlack, NewSlackNotifier())
+	d.Register(gaia.NotificationEmail, NewEmailNotifier())
+	d.Register(gaia.NotificationWebhook, NewWebhookNotifier())
+	return d
+}
+
+// Register registers the notifier for the given notification type.
+// An already registered notifier of the same type is replaced.
+func (d *Dispatcher) Register(t gaia.NotificationType, n Notifier) {
+	d.notifiersLock.Lock()
+	defer d.notifiersLock.Unlock()
+	d.notifiers[t] = n
+}
+
+// Start sends the notifications for all pipeline runs
+// which are published as finished on the given event bus.
+func (d *Dispatcher) Start(bus *event.Bus) {
+	sub := bus.Subscribe(event.RunFinished)
+	go func() {
+		for e := range sub.Events() {
+			r, err := d.store.PipelineGetRunByID(e.RunUniqueID)
+			if err != nil || r == nil {
+				gaia.Cfg.Logger.Error("cannot get finished pipeline run for notifications", "error", err, "pipelinerun", e.RunUniqueID)
+				continue
+			}
+			d.RunFinished(r)
+		}
+	}()
+}
+
+// RunFinished sends the notifications for the given finished pipeline run
+// in the background. It returns immediately.
+func (d *Dispatcher) RunFinished(r *gaia.PipelineRun) {
+	run := *r
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.notify(&run)
+	}()
+}
+
+// Wait blocks until all running deliveries are finished.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// notify sends the notifications for the given finished pipeline run.
+func (d *Dispatcher) notify(r *gaia.PipelineRun) {
+	p, err := d.store.PipelineGet(r.PipelineID)
+	if err != nil {
+		gaia.Cfg.Logger.Error("cannot get pipeline for notifications", "error", err.Error(), "pipelineid", r.PipelineID)
+		return
+	}
+	if p == nil || len(p.Notifications) == 0 {
+		return
+	}
+
+	previous, err := d.previousRun(r)
+	if err != nil {
+		gaia.Cfg.Logger.Error("cannot get previous pipeline run for notifications", "error", err.Error(), "pipeline", p.Name)
+		return
+	}
+
+	events := runEvents(r, previous)
+	for _, n := range p.Notifications {
+		event := matchEvent(n, events)
+		if event == "" {
+			continue
+		}
+		d.deliver(n, newMessage(event, p, r))
+	}
+}
+
+// previousRun returns the last finished run of the pipeline
+// before the given run. Returns nil if there is none.
+func (d *Dispatcher) previousRun(r *gaia.PipelineRun) (*gaia.PipelineRun, error) {
+	filter := gaia.PipelineRunFilter{
+		PipelineID: r.PipelineID,
+		Status:     []gaia.PipelineRunStatus{gaia.RunSuccess, gaia.RunFailed},
+	}
+
+	// Runs are listed with the newest run first
+	for page := 1; ; page++ {
+		list, err := d.store.PipelineListRuns(filter, gaia.Page{Number: page, PerPage: previousRunsPerPage})
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Runs {
+			if list.Runs[i].ID < r.ID {
+				return &list.Runs[i], nil
+			}
+		}
+		if page*previousRunsPerPage >= list.Total {
+			return nil, nil
+		}
+	}
+}
+
+// deliver sends the message to the notifier of the given notification.
+// Failed deliveries are retried with an exponential backoff. The result
+// is recorded in the delivery history of the pipeline.
+func (d *Dispatcher) deliver(n *gaia.Notification, m *Message) {
+	delivery := &gaia.NotificationDelivery{
+		PipelineID: m.PipelineID,
+		RunID:      m.RunID,
+		Type:       n.Type,
+		Target:     MaskTarget(n.Type, n.Target),
+		Event:      m.Event,
+		Status:     gaia.NotificationDelivered,
+	}
+
+	d.notifiersLock.RLock()
+	notifier, ok := d.notifiers[n.Type]
+	d.notifiersLock.RUnlock()
+
+	if !ok {
+		delivery.Status = gaia.NotificationFailed
+		delivery.Error = errUnknownType.Error()
+	} else {
+		backoff := deliveryBackoff
+		for delivery.Attempts < deliveryAttempts {
+			if delivery.Attempts > 0 {
+				time.Sleep(backoff)
+				backoff *= 2
+			}
+			delivery.Attempts++
+
+			err := notifier.Notify(n, m)
+			if err == nil {
+				delivery.Status = gaia.NotificationDelivered
+				delivery.Error = ""
+				break
+			}
+			delivery.Status = gaia.NotificationFailed
+			delivery.Error = err.Error()
+			gaia.Cfg.Logger.Warn("failed to deliver notification", "error", err.Error(), "type", n.Type, "pipeline", m.PipelineName, "attempt", delivery.Attempts)
+		}
+	}
+
+	delivery.Date = time.Now()
+	if err := d.store.NotificationDeliveryPut(delivery); err != nil {
+		gaia.Cfg.Logger.Error("cannot store notification delivery", "error", err.Error(), "pipeline", m.PipelineName)
+	}
+}
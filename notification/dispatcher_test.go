@@ -0,0 +1,251 @@
+package notification
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/store"
+)
+
+// smtpStandIn is a minimal SMTP server which records all received mails.
+type smtpStandIn struct {
+	listener net.Listener
+
+	mu    sync.Mutex
+	mails []string
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStandIn{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+		case "EHLO", "HELO", "MAIL", "RCPT", "RSET", "NOOP":
+			reply("250 OK")
+		case "DATA":
+			reply("354 Send data")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.mu.Lock()
+			s.mails = append(s.mails, data.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func (s *smtpStandIn) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.mails...)
+}
+
+func TestDispatcher(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestDispatcher")
+	defer os.RemoveAll(tmp)
+
+	smtpServer := newSMTPStandIn(t)
+	defer smtpServer.listener.Close()
+
+	gaia.Cfg = &gaia.Config{
+		Logger:   hclog.NewNullLogger(),
+		DataPath: tmp,
+		Hostname: "https://gaia.example.com",
+	}
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.SMTP.Addr = smtpServer.listener.Addr().String()
+	gaia.Cfg.SMTP.From = "gaia@example.com"
+
+	defer func(backoff time.Duration) { deliveryBackoff = backoff }(deliveryBackoff)
+	deliveryBackoff = time.Millisecond
+
+	// The webhook records all messages
+	var mu sync.Mutex
+	var webhookMessages []Message
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := Message{}
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		webhookMessages = append(webhookMessages, m)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	// Slack fails the first request
+	var slackRequests int
+	var slackTexts []string
+	slack := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		slackRequests++
+		if slackRequests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		m := slackMessage{}
+		_ = json.NewDecoder(r.Body).Decode(&m)
+		slackTexts = append(slackTexts, m.Text)
+	}))
+	defer slack.Close()
+
+	// Broken always fails
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	dataStore := store.NewBoltStore()
+	if err := dataStore.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	defer dataStore.Close()
+
+	p := &gaia.Pipeline{
+		Name: "deploy",
+		Notifications: []*gaia.Notification{
+			{Type: gaia.NotificationWebhook, Target: webhook.URL, Events: []gaia.NotificationEvent{gaia.NotificationEventFailed, gaia.NotificationEventRecovered}},
+			{Type: gaia.NotificationSlack, Target: slack.URL, Events: []gaia.NotificationEvent{gaia.NotificationEventSucceeded}},
+			{Type: gaia.NotificationEmail, Target: "ops@example.com"},
+			{Type: gaia.NotificationWebhook, Target: broken.URL, Events: []gaia.NotificationEvent{gaia.NotificationEventSucceeded}},
+		},
+	}
+	if err := dataStore.PipelinePut(p); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDispatcher(dataStore)
+	finish := func(id int, status gaia.PipelineRunStatus) {
+		r := &gaia.PipelineRun{
+			UniqueID:   fmt.Sprintf("run-%d", id),
+			ID:         id,
+			PipelineID: p.ID,
+			Status:     status,
+			StartDate:  time.Now(),
+			FinishDate: time.Now(),
+		}
+		if err := dataStore.PipelinePutRun(r); err != nil {
+			t.Fatal(err)
+		}
+		d.RunFinished(r)
+		d.Wait()
+	}
+
+	finish(1, gaia.RunFailed)
+	finish(2, gaia.RunSuccess)
+
+	// Webhook received the failed and the recovered run
+	if len(webhookMessages) != 2 {
+		t.Fatalf("expected 2 webhook messages but got %d", len(webhookMessages))
+	}
+	if m := webhookMessages[0]; m.Event != gaia.NotificationEventFailed || m.RunID != 1 || m.PipelineName != "deploy" {
+		t.Fatalf("unexpected first webhook message: %+v", m)
+	}
+	if m := webhookMessages[1]; m.Event != gaia.NotificationEventRecovered || m.RunID != 2 {
+		t.Fatalf("unexpected second webhook message: %+v", m)
+	}
+	if expected := fmt.Sprintf("https://gaia.example.com/#/pipeline/log?pipelineid=%d&runid=2", p.ID); webhookMessages[1].URL != expected {
+		t.Fatalf("expected url %s but got %s", expected, webhookMessages[1].URL)
+	}
+
+	// Slack received the succeeded run with the second attempt
+	if slackRequests != 2 || len(slackTexts) != 1 || !strings.Contains(slackTexts[0], "run #2 succeeded") {
+		t.Fatalf("unexpected slack requests %d with texts %v", slackRequests, slackTexts)
+	}
+
+	// Email received the failed and the recovered run
+	mails := smtpServer.received()
+	if len(mails) != 2 {
+		t.Fatalf("expected 2 mails but got %d", len(mails))
+	}
+	if !strings.Contains(mails[0], "Subject: [gaia] Pipeline deploy run #1 failed") || !strings.Contains(mails[0], "To: ops@example.com") {
+		t.Fatalf("unexpected first mail: %s", mails[0])
+	}
+	if !strings.Contains(mails[1], "Subject: [gaia] Pipeline deploy run #2 recovered") {
+		t.Fatalf("unexpected second mail: %s", mails[1])
+	}
+
+	// Check delivery history
+	deliveries, err := dataStore.NotificationDeliveryList(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 6 {
+		t.Fatalf("expected 6 deliveries but got %d", len(deliveries))
+	}
+	var failed []*gaia.NotificationDelivery
+	for _, delivery := range deliveries {
+		if delivery.Status == gaia.NotificationFailed {
+			failed = append(failed, delivery)
+			continue
+		}
+		if delivery.Target == MaskTarget(gaia.NotificationSlack, slack.URL) && delivery.Attempts != 2 {
+			t.Fatalf("expected 2 attempts for the slack delivery but got %d", delivery.Attempts)
+		}
+	}
+	if len(failed) != 1 || failed[0].Target != MaskTarget(gaia.NotificationWebhook, broken.URL) || failed[0].Attempts != deliveryAttempts || failed[0].Error == "" {
+		t.Fatalf("expected only the broken webhook delivery to fail after %d attempts: %+v", deliveryAttempts, failed)
+	}
+}
+
+func TestEmailNotifierWithoutSMTP(t *testing.T) {
+	gaia.Cfg = &gaia.Config{Logger: hclog.NewNullLogger()}
+	err := NewEmailNotifier().Notify(&gaia.Notification{Type: gaia.NotificationEmail, Target: "ops@example.com"}, &Message{})
+	if err != errSMTPNotConfigured {
+		t.Fatalf("expected error %v but got %v", errSMTPNotConfigured, err)
+	}
+}
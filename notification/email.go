@@ -0,0 +1,71 @@
+package notification
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// errSMTPNotConfigured is thrown when an email notification should be sent without a configured SMTP server
+var errSMTPNotConfigured = errors.New("no smtp server has been configured")
+
+// EmailNotifier sends the message via the configured SMTP server.
+type EmailNotifier struct{}
+
+// NewEmailNotifier creates a new email notifier.
+func NewEmailNotifier() *EmailNotifier {
+	return &EmailNotifier{}
+}
+
+// Notify sends the message to all recipients of the notification.
+func (e *EmailNotifier) Notify(n *gaia.Notification, m *Message) error {
+	cfg := gaia.Cfg.SMTP
+	if cfg.Addr == "" {
+		return errSMTPNotConfigured
+	}
+
+	to, err := recipients(n.Target)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		host, _, err := net.SplitHostPort(cfg.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", m.Subject())
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n", strings.ReplaceAll(m.Text(), "\n", "\r\n"))
+
+	return smtp.SendMail(cfg.Addr, auth, cfg.From, to, msg.Bytes())
+}
+
+// recipients parses the comma separated list of email addresses.
+func recipients(target string) ([]string, error) {
+	list, err := mail.ParseAddressList(target)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, 0, len(list))
+	for _, a := range list {
+		addresses = append(addresses, a.Address)
+	}
+	return addresses, nil
+}
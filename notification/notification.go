@@ -0,0 +1,188 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+var (
+	// errUnknownType is thrown when a notification with an unknown notifier type has been given
+	errUnknownType = errors.New("notification type must be one of slack, email or webhook")
+
+	// errUnknownEvent is thrown when a notification with an unknown event has been given
+	errUnknownEvent = errors.New("notification events must be one of failed, recovered or succeeded")
+
+	// errInvalidURL is thrown when a slack or webhook notification has no valid http(s) url as target
+	errInvalidURL = errors.New("notification target must be a valid http or https url")
+
+	// errMaskedTarget is thrown when a masked target does not belong to a current notification
+	errMaskedTarget = errors.New("notification target is masked, the complete url must be given")
+
+	// errInvalidRecipients is thrown when an email notification has no valid recipients as target
+	errInvalidRecipients = errors.New("notification target must be a comma separated list of email addresses")
+)
+
+// defaultEvents are the events which trigger a notification without configured events.
+var defaultEvents = []gaia.NotificationEvent{gaia.NotificationEventFailed, gaia.NotificationEventRecovered}
+
+// Notifier delivers a notification message to the target of the given notification.
+type Notifier interface {
+	Notify(n *gaia.Notification, m *Message) error
+}
+
+// Message represents the content of a notification about a finished pipeline run.
+type Message struct {
+	Event        gaia.NotificationEvent `json:"event"`
+	PipelineID   int                    `json:"pipelineid"`
+	PipelineName string                 `json:"pipelinename"`
+	RunID        int                    `json:"runid"`
+	Status       gaia.PipelineRunStatus `json:"status"`
+	StartReason  string                 `json:"startreason,omitempty"`
+	StartDate    time.Time              `json:"startdate"`
+	FinishDate   time.Time              `json:"finishdate"`
+	URL          string                 `json:"url"`
+}
+
+// newMessage creates the message for the given event of the pipeline run.
+func newMessage(event gaia.NotificationEvent, p *gaia.Pipeline, r *gaia.PipelineRun) *Message {
+	return &Message{
+		Event:        event,
+		PipelineID:   p.ID,
+		PipelineName: p.Name,
+		RunID:        r.ID,
+		Status:       r.Status,
+		StartReason:  r.StartReason,
+		StartDate:    r.StartDate,
+		FinishDate:   r.FinishDate,
+		URL:          fmt.Sprintf("%s/#/pipeline/log?pipelineid=%d&runid=%d", strings.TrimSuffix(gaia.Cfg.Hostname, "/"), p.ID, r.ID),
+	}
+}
+
+// Subject returns a short summary of the message.
+func (m *Message) Subject() string {
+	return fmt.Sprintf("[gaia] Pipeline %s run #%d %s", m.PipelineName, m.RunID, m.Event)
+}
+
+// Text returns the human readable content of the message.
+func (m *Message) Text() string {
+	return fmt.Sprintf("Pipeline %s run #%d %s after %s.\n%s",
+		m.PipelineName, m.RunID, m.Event, m.FinishDate.Sub(m.StartDate).Round(time.Second), m.URL)
+}
+
+// Validate checks if the given notification configuration is valid.
+func Validate(n *gaia.Notification) error {
+	for _, event := range n.Events {
+		switch event {
+		case gaia.NotificationEventFailed, gaia.NotificationEventRecovered, gaia.NotificationEventSucceeded:
+		default:
+			return errUnknownEvent
+		}
+	}
+
+	switch n.Type {
+	case gaia.NotificationSlack, gaia.NotificationWebhook:
+		u, err := url.Parse(n.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errInvalidURL
+		}
+		if strings.HasSuffix(n.Target, maskedPath) {
+			return errMaskedTarget
+		}
+	case gaia.NotificationEmail:
+		if _, err := recipients(n.Target); err != nil {
+			return errInvalidRecipients
+		}
+	default:
+		return errUnknownType
+	}
+	return nil
+}
+
+// maskedPath replaces the path of slack and webhook urls which carries their credentials.
+const maskedPath = "/**********"
+
+// MaskTarget returns the target of a notification of the given type without its credentials.
+// Slack and webhook urls are reduced to their scheme and host.
+func MaskTarget(t gaia.NotificationType, target string) string {
+	switch t {
+	case gaia.NotificationSlack, gaia.NotificationWebhook:
+		u, err := url.Parse(target)
+		if err != nil {
+			return maskedPath
+		}
+		return u.Scheme + "://" + u.Host + maskedPath
+	}
+	return target
+}
+
+// Mask returns a copy of the given notifications with masked targets.
+func Mask(notifications []*gaia.Notification) []*gaia.Notification {
+	if notifications == nil {
+		return nil
+	}
+	masked := make([]*gaia.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		m := *n
+		m.Target = MaskTarget(n.Type, n.Target)
+		masked = append(masked, &m)
+	}
+	return masked
+}
+
+// Unmask restores the targets of the given updated notifications which have been
+// masked by Mask and not changed since. The targets are taken from the current
+// notifications of the same type with the same masked target.
+func Unmask(updated, current []*gaia.Notification) {
+	used := make([]bool, len(current))
+	for _, n := range updated {
+		if !strings.HasSuffix(n.Target, maskedPath) {
+			continue
+		}
+		for i, c := range current {
+			if !used[i] && c.Type == n.Type && MaskTarget(c.Type, c.Target) == n.Target {
+				n.Target = c.Target
+				used[i] = true
+				break
+			}
+		}
+	}
+}
+
+// runEvents returns the events of the finished pipeline run in the order
+// of their precedence. The previous run is the last finished run before
+// the given run and might be nil.
+func runEvents(r, previous *gaia.PipelineRun) []gaia.NotificationEvent {
+	switch r.Status {
+	case gaia.RunFailed:
+		return []gaia.NotificationEvent{gaia.NotificationEventFailed}
+	case gaia.RunSuccess:
+		if previous != nil && previous.Status == gaia.RunFailed {
+			return []gaia.NotificationEvent{gaia.NotificationEventRecovered, gaia.NotificationEventSucceeded}
+		}
+		return []gaia.NotificationEvent{gaia.NotificationEventSucceeded}
+	}
+	return nil
+}
+
+// matchEvent returns the first of the given events the notification
+// is subscribed to. Returns an empty event if there is none.
+func matchEvent(n *gaia.Notification, events []gaia.NotificationEvent) gaia.NotificationEvent {
+	subscribed := n.Events
+	if len(subscribed) == 0 {
+		subscribed = defaultEvents
+	}
+
+	for _, event := range events {
+		for _, s := range subscribed {
+			if event == s {
+				return event
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package notification
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		notification gaia.Notification
+		expected     error
+	}{
+		{name: "slack", notification: gaia.Notification{Type: gaia.NotificationSlack, Target: "https://hooks.slack.com/services/T0/B0/X"}},
+		{name: "webhook with events", notification: gaia.Notification{Type: gaia.NotificationWebhook, Target: "http://localhost:8080/hook", Events: []gaia.NotificationEvent{gaia.NotificationEventSucceeded}}},
+		{name: "email", notification: gaia.Notification{Type: gaia.NotificationEmail, Target: "ops@example.com, Dev <dev@example.com>"}},
+		{name: "unknown type", notification: gaia.Notification{Type: "pager", Target: "https://example.com"}, expected: errUnknownType},
+		{name: "unknown event", notification: gaia.Notification{Type: gaia.NotificationSlack, Target: "https://example.com", Events: []gaia.NotificationEvent{"started"}}, expected: errUnknownEvent},
+		{name: "invalid url", notification: gaia.Notification{Type: gaia.NotificationWebhook, Target: "ftp://example.com"}, expected: errInvalidURL},
+		{name: "masked url", notification: gaia.Notification{Type: gaia.NotificationSlack, Target: "https://hooks.slack.com/**********"}, expected: errMaskedTarget},
+		{name: "missing url", notification: gaia.Notification{Type: gaia.NotificationSlack}, expected: errInvalidURL},
+		{name: "invalid recipients", notification: gaia.Notification{Type: gaia.NotificationEmail, Target: "ops"}, expected: errInvalidRecipients},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(&tt.notification); err != tt.expected {
+				t.Fatalf("expected error %v but got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestRunEvents(t *testing.T) {
+	failed := &gaia.PipelineRun{Status: gaia.RunFailed}
+	success := &gaia.PipelineRun{Status: gaia.RunSuccess}
+	cancelled := &gaia.PipelineRun{Status: gaia.RunCancelled}
+
+	tests := []struct {
+		name     string
+		run      *gaia.PipelineRun
+		previous *gaia.PipelineRun
+		expected []gaia.NotificationEvent
+	}{
+		{name: "failed", run: failed, previous: success, expected: []gaia.NotificationEvent{gaia.NotificationEventFailed}},
+		{name: "first success", run: success, expected: []gaia.NotificationEvent{gaia.NotificationEventSucceeded}},
+		{name: "success", run: success, previous: success, expected: []gaia.NotificationEvent{gaia.NotificationEventSucceeded}},
+		{name: "recovered", run: success, previous: failed, expected: []gaia.NotificationEvent{gaia.NotificationEventRecovered, gaia.NotificationEventSucceeded}},
+		{name: "cancelled", run: cancelled, previous: failed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if events := runEvents(tt.run, tt.previous); !reflect.DeepEqual(events, tt.expected) {
+				t.Fatalf("expected events %v but got %v", tt.expected, events)
+			}
+		})
+	}
+}
+
+func TestMatchEvent(t *testing.T) {
+	recovered := []gaia.NotificationEvent{gaia.NotificationEventRecovered, gaia.NotificationEventSucceeded}
+
+	n := &gaia.Notification{}
+	if event := matchEvent(n, recovered); event != gaia.NotificationEventRecovered {
+		t.Fatalf("expected default events to match recovered but got %q", event)
+	}
+	if event := matchEvent(n, []gaia.NotificationEvent{gaia.NotificationEventSucceeded}); event != "" {
+		t.Fatalf("expected default events not to match succeeded but got %q", event)
+	}
+
+	n.Events = []gaia.NotificationEvent{gaia.NotificationEventSucceeded}
+	if event := matchEvent(n, recovered); event != gaia.NotificationEventSucceeded {
+		t.Fatalf("expected succeeded to match but got %q", event)
+	}
+}
+
+func TestMaskAndUnmask(t *testing.T) {
+	current := []*gaia.Notification{
+		{Type: gaia.NotificationSlack, Target: "https://hooks.slack.com/services/T0/B0/X"},
+		{Type: gaia.NotificationWebhook, Target: "https://hooks.slack.com/services/T0/B0/Y"},
+		{Type: gaia.NotificationEmail, Target: "ops@example.com"},
+	}
+
+	masked := Mask(current)
+	expected := []string{"https://hooks.slack.com/**********", "https://hooks.slack.com/**********", "ops@example.com"}
+	for i, n := range masked {
+		if n.Target != expected[i] {
+			t.Fatalf("expected masked target %q but got %q", expected[i], n.Target)
+		}
+	}
+	if current[0].Target != "https://hooks.slack.com/services/T0/B0/X" {
+		t.Fatalf("expected current notifications not to be modified but got %q", current[0].Target)
+	}
+
+	// Masked targets are restored and changed targets are kept
+	masked[1].Target = "https://example.com/hook"
+	masked = append(masked, &gaia.Notification{Type: gaia.NotificationSlack, Target: "https://hooks.slack.com/**********"})
+	Unmask(masked, current)
+	expected = []string{current[0].Target, "https://example.com/hook", current[2].Target, "https://hooks.slack.com/**********"}
+	for i, n := range masked {
+		if n.Target != expected[i] {
+			t.Fatalf("expected unmasked target %q but got %q", expected[i], n.Target)
+		}
+	}
+}
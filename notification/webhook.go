@@ -0,0 +1,72 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// httpTimeout is the maximum duration of a single notification http request.
+const httpTimeout = 10 * time.Second
+
+// WebhookNotifier posts the message as json to a generic webhook.
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+// NewWebhookNotifier creates a new webhook notifier.
+func NewWebhookNotifier() *WebhookNotifier {
+	return &WebhookNotifier{Client: &http.Client{Timeout: httpTimeout}}
+}
+
+// Notify posts the message to the webhook url.
+func (w *WebhookNotifier) Notify(n *gaia.Notification, m *Message) error {
+	return postJSON(w.Client, n.Target, m)
+}
+
+// SlackNotifier posts the message to a slack incoming webhook.
+type SlackNotifier struct {
+	Client *http.Client
+}
+
+// slackMessage is the payload of a slack incoming webhook.
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// NewSlackNotifier creates a new slack notifier.
+func NewSlackNotifier() *SlackNotifier {
+	return &SlackNotifier{Client: &http.Client{Timeout: httpTimeout}}
+}
+
+// Notify posts the message text to the slack incoming webhook url.
+func (s *SlackNotifier) Notify(n *gaia.Notification, m *Message) error {
+	return postJSON(s.Client, n.Target, &slackMessage{Text: m.Subject() + "\n" + m.Text()})
+}
+
+// postJSON posts the given payload as json to the given url.
+// Every response status other than 2xx is treated as an error.
+func postJSON(client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+	return nil
+}
@@ -12,7 +12,7 @@ import (
 // with the plugin.
 type GaiaPlugin interface {
 	GetJobs() (proto.Plugin_GetJobsClient, error)
-	ExecuteJob(job *proto.Job) (*proto.JobResult, error)
+	ExecuteJob(ctx context.Context, job *proto.Job) (*proto.JobResult, error)
 }
 
 // GaiaPluginClient represents gRPC client
@@ -47,6 +47,7 @@ func (m *GaiaPluginClient) GetJobs() (proto.Plugin_GetJobsClient, error) {
 }
 
 // ExecuteJob triggers the execution of the given job in the plugin.
-func (m *GaiaPluginClient) ExecuteJob(job *proto.Job) (*proto.JobResult, error) {
-	return m.client.ExecuteJob(context.Background(), job)
+// The execution is aborted when the given context is done.
+func (m *GaiaPluginClient) ExecuteJob(ctx context.Context, job *proto.Job) (*proto.JobResult, error) {
+	return m.client.ExecuteJob(ctx, job)
 }
@@ -3,16 +3,17 @@ package plugin
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"os/exec"
 	"sync"
 	"time"
 
 	"github.com/gaia-pipeline/gaia"
 	"github.com/gaia-pipeline/gaia/security"
+	"github.com/gaia-pipeline/gaia/tracing"
 	proto "github.com/gaia-pipeline/protobuf"
 	"github.com/hashicorp/go-plugin"
 )
@@ -41,31 +42,59 @@ var pluginMap = map[string]plugin.Plugin{
 const timeFormat = "2006/01/02 15:04:05"
 
 // GaiaLogWriter represents a concurrent safe log writer which can be shared with go-plugin.
+// If a combined writer is set, everything is written to the combined writer as well.
+// Flushing the writer also flushes the underlying writer if it supports flushing.
 type GaiaLogWriter struct {
-	mu     sync.RWMutex
-	buffer *bytes.Buffer
-	writer *bufio.Writer
+	mu       sync.RWMutex
+	buffer   *bytes.Buffer
+	out      io.Writer
+	writer   *bufio.Writer
+	combined io.Writer
+}
+
+// flusher is implemented by writers which buffer data themselves.
+type flusher interface {
+	Flush() error
+}
+
+// NewGaiaLogWriter creates a new buffered log writer which writes to the given writer.
+func NewGaiaLogWriter(w io.Writer) *GaiaLogWriter {
+	return &GaiaLogWriter{out: w, writer: bufio.NewWriter(w)}
 }
 
 // Write locks and writes to the underlying writer.
 func (g *GaiaLogWriter) Write(p []byte) (n int, err error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	return g.writer.Write(p)
+	n, err = g.writer.Write(p)
+	if err == nil && g.combined != nil {
+		_, err = g.combined.Write(p)
+	}
+	return
 }
 
 // Flush locks and flushes the underlying writer.
 func (g *GaiaLogWriter) Flush() error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	return g.writer.Flush()
+	if err := g.writer.Flush(); err != nil {
+		return err
+	}
+	if f, ok := g.out.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
 }
 
 // WriteString locks and passes on the string to write to the underlying writer.
-func (g *GaiaLogWriter) WriteString(s string) (int, error) {
+func (g *GaiaLogWriter) WriteString(s string) (n int, err error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
-	return g.writer.WriteString(s)
+	n, err = g.writer.WriteString(s)
+	if err == nil && g.combined != nil {
+		_, err = io.WriteString(g.combined, s)
+	}
+	return
 }
 
 // GoPlugin represents a single plugin instance which uses gRPC
@@ -80,8 +109,8 @@ type GoPlugin struct {
 	// Interface to the connected plugin.
 	pluginConn GaiaPlugin
 
-	// Log file where all output is stored.
-	logFile *os.File
+	// Log where all output is stored.
+	log io.WriteCloser
 
 	// Writer used to write logs from execution to file or buffer
 	logger GaiaLogWriter
@@ -103,13 +132,16 @@ type Plugin interface {
 
 	// Init initializes the go-plugin client and generates a
 	// new certificate pair for gaia and the plugin/pipeline.
-	Init(command *exec.Cmd, logPath *string) error
+	Init(command *exec.Cmd, log io.WriteCloser, combinedLog io.Writer) error
 
 	// Validate validates the plugin interface.
 	Validate() error
 
 	// Execute executes one job of a pipeline.
-	Execute(j *gaia.Job) error
+	// The execution is aborted when the given context is done.
+	// An error is returned if the job failed because of an
+	// infrastructure error instead of the job itself.
+	Execute(ctx context.Context, j *gaia.Job) error
 
 	// GetJobs returns all real jobs from the pipeline.
 	GetJobs() ([]*gaia.Job, error)
@@ -130,32 +162,22 @@ func (p *GoPlugin) NewPlugin(ca security.CAAPI) Plugin {
 // Init prepares the log path, set's up new certificates for both gaia and
 // plugin, and prepares the go-plugin client.
 //
-// It expects the start command for the plugin and the path where
-// the log file should be stored.
+// It expects the start command for the plugin and the log where
+// all output should be stored. The log is closed by the close method.
+// If combinedLog is given, all output is written to combinedLog as well.
 //
 // It's up to the caller to call plugin.Close to shutdown the plugin
 // and close the gRPC connection.
-func (p *GoPlugin) Init(command *exec.Cmd, logPath *string) error {
+func (p *GoPlugin) Init(command *exec.Cmd, log io.WriteCloser, combinedLog io.Writer) error {
 	// Initialise the logger
-	p.logger = GaiaLogWriter{}
-
-	// Create log file and open it.
-	// We will close this file in the close method.
-	if logPath != nil {
-		var err error
-		p.logFile, err = os.OpenFile(
-			*logPath,
-			os.O_CREATE|os.O_WRONLY,
-			0666,
-		)
-		if err != nil {
-			return err
-		}
+	p.logger = GaiaLogWriter{combined: combinedLog}
 
-		// Create new writer
-		p.logger.writer = bufio.NewWriter(p.logFile)
+	if log != nil {
+		p.log = log
+		p.logger.out = log
+		p.logger.writer = bufio.NewWriter(log)
 	} else {
-		// If no path is provided, write output to buffer
+		// If no log is provided, write output to buffer
 		p.logger.buffer = new(bytes.Buffer)
 		p.logger.writer = bufio.NewWriter(p.logger.buffer)
 	}
@@ -194,6 +216,8 @@ func (p *GoPlugin) Init(command *exec.Cmd, logPath *string) error {
 		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
 		Stderr:           &p.logger,
 		TLSConfig:        tlsConfig,
+		// Propagate the trace of the job execution to the plugin
+		GRPCDialOptions: tracing.DialOptions(),
 	})
 
 	// Connect via gRPC
@@ -225,7 +249,11 @@ func (p *GoPlugin) Validate() error {
 
 // Execute triggers the execution of one single job
 // for the given plugin.
-func (p *GoPlugin) Execute(j *gaia.Job) error {
+// If the given context expires before the job has been finished,
+// the job is marked as timed out. If the job could not be executed
+// because of an infrastructure error (e.g. the plugin connection broke),
+// the job is marked as failed and the error is returned.
+func (p *GoPlugin) Execute(ctx context.Context, j *gaia.Job) error {
 	// Transform arguments
 	var args []*proto.Argument
 	for _, arg := range j.Args {
@@ -244,10 +272,18 @@ func (p *GoPlugin) Execute(j *gaia.Job) error {
 	}
 
 	// Execute the job
-	resultObj, err := p.pluginConn.ExecuteJob(job)
+	resultObj, err := p.pluginConn.ExecuteJob(ctx, job)
 
 	// Check and set job status
-	if resultObj != nil && resultObj.ExitPipeline {
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		// The job took too long and has been aborted.
+		j.Status = gaia.JobTimedOut
+		j.FailPipeline = true
+
+		// Generate error message and attach it to logs.
+		timeString := time.Now().Format(timeFormat)
+		_, _ = p.logger.WriteString(fmt.Sprintf("%s Job '%s' timed out\n", timeString, j.Title))
+	} else if resultObj != nil && resultObj.ExitPipeline {
 		// ExitPipeline is true that indicates that the job failed.
 		j.Status = gaia.JobFailed
 
@@ -268,6 +304,7 @@ func (p *GoPlugin) Execute(j *gaia.Job) error {
 		// Generate error message and attach it to logs.
 		timeString := time.Now().Format(timeFormat)
 		_, _ = p.logger.WriteString(fmt.Sprintf("%s Job '%s' threw an error: %s\n", timeString, j.Title, err.Error()))
+		return err
 	} else {
 		j.Status = gaia.JobSuccess
 	}
@@ -365,8 +402,10 @@ func (p *GoPlugin) Close() {
 		// Flush the writer
 		_ = p.logger.Flush()
 
-		// Close log file
-		_ = p.logFile.Close()
+		// Close log
+		if p.log != nil {
+			_ = p.log.Close()
+		}
 
 		// Cleanup certificates
 		_ = p.ca.CleanupCerts(p.certPath, p.keyPath)
@@ -5,8 +5,10 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"errors"
 	"io"
 	"io/ioutil"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
@@ -42,10 +44,35 @@ type fakeGaiaPlugin struct{}
 func (p *fakeGaiaPlugin) GetJobs() (proto.Plugin_GetJobsClient, error) {
 	return &fakeJobsClient{}, nil
 }
-func (p *fakeGaiaPlugin) ExecuteJob(job *proto.Job) (*proto.JobResult, error) {
+func (p *fakeGaiaPlugin) ExecuteJob(ctx context.Context, job *proto.Job) (*proto.JobResult, error) {
 	return &proto.JobResult{}, nil
 }
 
+type fakeHangingGaiaPlugin struct {
+	fakeGaiaPlugin
+}
+
+func (p *fakeHangingGaiaPlugin) ExecuteJob(ctx context.Context, job *proto.Job) (*proto.JobResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type fakeBrokenGaiaPlugin struct {
+	fakeGaiaPlugin
+}
+
+func (p *fakeBrokenGaiaPlugin) ExecuteJob(ctx context.Context, job *proto.Job) (*proto.JobResult, error) {
+	return nil, errors.New("connection broken")
+}
+
+type fakeExitGaiaPlugin struct {
+	fakeGaiaPlugin
+}
+
+func (p *fakeExitGaiaPlugin) ExecuteJob(ctx context.Context, job *proto.Job) (*proto.JobResult, error) {
+	return &proto.JobResult{ExitPipeline: true, Failed: true, Message: "job failed"}, nil
+}
+
 type fakeJobsClient struct {
 	counter int
 }
@@ -88,8 +115,8 @@ func TestInit(t *testing.T) {
 	})
 	emptyPlugin := &GoPlugin{}
 	p := emptyPlugin.NewPlugin(new(fakeCAAPI))
-	logpath := filepath.Join(tmp, "test")
-	err := p.Init(exec.Command("echo", "world"), &logpath)
+	logFile, _ := os.Create(filepath.Join(tmp, "test"))
+	err := p.Init(exec.Command("echo", "world"), logFile, nil)
 	if err == nil {
 		t.Fatal("was expecting an error. non happened")
 	}
@@ -134,12 +161,76 @@ func TestExecute(t *testing.T) {
 			},
 		},
 	}
-	err := p.Execute(j)
+	err := p.Execute(context.Background(), j)
 	if err != nil {
 		t.Fatal(err)
 	}
 }
 
+func TestExecuteTimeout(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	gaia.Cfg.Logger = hclog.New(&hclog.LoggerOptions{
+		Level:  hclog.Trace,
+		Output: hclog.DefaultOutput,
+		Name:   "Gaia",
+	})
+	p := &GoPlugin{pluginConn: new(fakeHangingGaiaPlugin)}
+	buf := new(bytes.Buffer)
+	p.logger = GaiaLogWriter{}
+	p.logger.writer = bufio.NewWriter(buf)
+	j := &gaia.Job{Title: "hanging job"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Execute(ctx, j); err != nil {
+		t.Fatal(err)
+	}
+	if j.Status != gaia.JobTimedOut {
+		t.Fatalf("expected job status %s but got %s", gaia.JobTimedOut, j.Status)
+	}
+	if !j.FailPipeline {
+		t.Fatal("expected job to fail the pipeline")
+	}
+	_ = p.logger.Flush()
+	if !strings.Contains(buf.String(), "Job 'hanging job' timed out") {
+		t.Fatalf("expected timeout message in logs but got '%s'", buf.String())
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	t.Run("infrastructure error", func(t *testing.T) {
+		p := &GoPlugin{pluginConn: new(fakeBrokenGaiaPlugin)}
+		p.logger = GaiaLogWriter{}
+		p.logger.writer = bufio.NewWriter(new(bytes.Buffer))
+		j := &gaia.Job{Title: "broken job"}
+		if err := p.Execute(context.Background(), j); err == nil {
+			t.Fatal("expected infrastructure error but got none")
+		}
+		if j.Status != gaia.JobFailed {
+			t.Fatalf("expected job status %s but got %s", gaia.JobFailed, j.Status)
+		}
+	})
+
+	t.Run("job error", func(t *testing.T) {
+		p := &GoPlugin{pluginConn: new(fakeExitGaiaPlugin)}
+		p.logger = GaiaLogWriter{}
+		p.logger.writer = bufio.NewWriter(new(bytes.Buffer))
+		j := &gaia.Job{Title: "failing job"}
+		if err := p.Execute(context.Background(), j); err != nil {
+			t.Fatal(err)
+		}
+		if j.Status != gaia.JobFailed {
+			t.Fatalf("expected job status %s but got %s", gaia.JobFailed, j.Status)
+		}
+		if !j.FailPipeline {
+			t.Fatal("expected job to fail the pipeline")
+		}
+	})
+}
+
 func TestGetJobs(t *testing.T) {
 	gaia.Cfg = &gaia.Config{}
 	gaia.Cfg.Logger = hclog.New(&hclog.LoggerOptions{
@@ -167,8 +258,8 @@ func TestClose(t *testing.T) {
 	})
 	emptyPlugin := &GoPlugin{}
 	p := emptyPlugin.NewPlugin(new(fakeCAAPI))
-	logpath := filepath.Join(tmp, "test")
-	_ = p.Init(exec.Command("echo", "world"), &logpath)
+	logFile, _ := os.Create(filepath.Join(tmp, "test"))
+	_ = p.Init(exec.Command("echo", "world"), logFile, nil)
 	p.Close()
 }
 
@@ -182,10 +273,63 @@ func TestFlushLogs(t *testing.T) {
 	})
 	emptyPlugin := &GoPlugin{}
 	p := emptyPlugin.NewPlugin(new(fakeCAAPI))
-	logpath := filepath.Join(tmp, "test")
-	_ = p.Init(exec.Command("echo", "world"), &logpath)
+	logFile, _ := os.Create(filepath.Join(tmp, "test"))
+	_ = p.Init(exec.Command("echo", "world"), logFile, nil)
 	err := p.FlushLogs()
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestGaiaLogWriterCombined(t *testing.T) {
+	jobBuf := new(bytes.Buffer)
+	combinedBuf := new(bytes.Buffer)
+	combined := NewGaiaLogWriter(combinedBuf)
+	w := NewGaiaLogWriter(jobBuf)
+	w.combined = combined
+
+	if _, err := w.Write([]byte("first line\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.WriteString("second line\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := combined.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "first line\nsecond line\n"
+	if jobBuf.String() != expected {
+		t.Fatalf("expected job log '%s' but got '%s'", expected, jobBuf.String())
+	}
+	if combinedBuf.String() != expected {
+		t.Fatalf("expected combined log '%s' but got '%s'", expected, combinedBuf.String())
+	}
+}
+
+type flushCounter struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (f *flushCounter) Flush() error {
+	f.flushes++
+	return nil
+}
+
+func TestGaiaLogWriterFlushesUnderlyingWriter(t *testing.T) {
+	out := &flushCounter{}
+	w := NewGaiaLogWriter(out)
+	if _, err := w.WriteString("line\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "line\n" || out.flushes != 1 {
+		t.Fatalf("expected flushed log 'line' but got '%s' with %d flushes", out.String(), out.flushes)
+	}
+}
@@ -2,6 +2,7 @@ package pipelines
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/gaia-pipeline/gaia"
 	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
 	"github.com/gaia-pipeline/gaia/helper/stringhelper"
+	"github.com/gaia-pipeline/gaia/notification"
 	"github.com/gaia-pipeline/gaia/security"
 	"github.com/gaia-pipeline/gaia/services"
 	"github.com/gaia-pipeline/gaia/workers/pipeline"
@@ -33,6 +35,30 @@ var (
 
 	// errWrongDockerValue is thrown when docker has been specified for a pipeline run but the value is invalid
 	errWrongDockerValue = errors.New("invalid value for docker parameter")
+
+	// errInvalidTimeout is thrown when a negative job or run timeout has been given
+	errInvalidTimeout = errors.New("job and run timeout must not be negative")
+
+	// errInvalidRetryPolicy is thrown when a job retry policy with out of range values has been given
+	errInvalidRetryPolicy = fmt.Errorf("max attempts of a retry policy must be between 1 and %d and backoff between 0 and %d seconds", gaia.RetryMaxAttempts, gaia.RetryMaxBackoff)
+
+	// errInvalidRunCondition is thrown when an unknown job run condition has been given
+	errInvalidRunCondition = errors.New("run condition must be one of on_success, on_failure or always")
+
+	// errInvalidConcurrency is thrown when an invalid concurrency limit or policy has been given
+	errInvalidConcurrency = errors.New("concurrency limit must not be negative and policy must be one of queue, cancel_older or skip_new")
+
+	// errInvalidRecovery is thrown when an invalid recovery policy or reschedule limit has been given
+	errInvalidRecovery = errors.New("max reschedules must not be negative and recovery policy must be one of fail or reschedule")
+
+	// errInvalidApprovalPolicy is thrown when an approval policy with a negative timeout has been given
+	errInvalidApprovalPolicy = errors.New("timeout of an approval policy must not be negative")
+
+	// errInvalidRetentionPolicy is thrown when a retention policy with negative values has been given
+	errInvalidRetentionPolicy = errors.New("retention policy values must not be negative")
+
+	// errInvalidPriority is thrown when the priority of a pipeline start is not an integer
+	errInvalidPriority = errors.New("priority must be an integer")
 )
 
 // PipelineGitLSRemote checks for available git remote branches.
@@ -247,43 +273,24 @@ func (pp *PipelineProvider) PipelineUpdate(c echo.Context) error {
 		return c.String(http.StatusNotFound, errPipelineNotFound.Error())
 	}
 
-	// Check if the pipeline name was changed.
-	if foundPipeline.Name != p.Name {
-		// Pipeline name has been changed
-		currentName := foundPipeline.Name
+	// Targets of notifications which are still masked are kept from the current notifications
+	notification.Unmask(p.Notifications, foundPipeline.Notifications)
 
-		// Rename binary
-		err := pipeline.RenameBinary(foundPipeline, p.Name)
-		if err != nil {
-			return c.String(http.StatusInternalServerError, errPipelineRename.Error())
-		}
-
-		// Update name and exec path
-		foundPipeline.Name = p.Name
-		foundPipeline.ExecPath = pipeline.GetExecPath(p)
-
-		// Update pipeline in store
-		err = storeService.PipelinePut(&foundPipeline)
-		if err != nil {
-			return c.String(http.StatusInternalServerError, err.Error())
-		}
-
-		// Update active pipelines
-		pipeline.GlobalActivePipelines.ReplaceByName(currentName, foundPipeline)
+	// Validate all settings before anything is changed
+	if err := validatePipelineUpdate(&p); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
 	}
 
 	// Check if the periodic scheduling has been changed.
-	if !stringSliceEqual(foundPipeline.PeriodicSchedules, p.PeriodicSchedules) {
-		// We prevent side effects here and make sure
-		// that no scheduling is already running.
-		if foundPipeline.CronInst != nil {
-			foundPipeline.CronInst.Stop()
-		}
-		foundPipeline.CronInst = cron.New()
+	// The new schedules are validated by the new cron instance.
+	var cronInst *cron.Cron
+	schedulesChanged := !stringSliceEqual(foundPipeline.PeriodicSchedules, p.PeriodicSchedules)
+	if schedulesChanged {
+		cronInst = cron.New()
 
 		// Iterate over all cron schedules.
 		for _, schedule := range p.PeriodicSchedules {
-			err := foundPipeline.CronInst.AddFunc(schedule, func() {
+			err := cronInst.AddFunc(schedule, func() {
 				_, err := pp.deps.Scheduler.SchedulePipeline(&foundPipeline, gaia.StartReasonScheduled, []*gaia.Argument{})
 				if err != nil {
 					gaia.Cfg.Logger.Error("cannot schedule pipeline from periodic schedule", "error", err, "pipeline", foundPipeline)
@@ -298,37 +305,125 @@ func (pp *PipelineProvider) PipelineUpdate(c echo.Context) error {
 				return c.String(http.StatusBadRequest, err.Error())
 			}
 		}
+	}
 
-		// Update pipeline in store
-		err := storeService.PipelinePut(&foundPipeline)
+	// Check if the pipeline name was changed.
+	currentName := foundPipeline.Name
+	if foundPipeline.Name != p.Name {
+		// Rename binary
+		err := pipeline.RenameBinary(foundPipeline, p.Name)
 		if err != nil {
-			return c.String(http.StatusInternalServerError, err.Error())
+			return c.String(http.StatusInternalServerError, errPipelineRename.Error())
 		}
 
-		// Start schedule process.
-		foundPipeline.CronInst.Start()
+		// Update name and exec path
+		foundPipeline.Name = p.Name
+		foundPipeline.ExecPath = pipeline.GetExecPath(p)
+	}
 
-		// Update active pipelines
-		pipeline.GlobalActivePipelines.Replace(foundPipeline)
+	// Apply all other settings
+	foundPipeline.PeriodicSchedules = p.PeriodicSchedules
+	foundPipeline.Docker = p.Docker
+	foundPipeline.JobTimeout = p.JobTimeout
+	foundPipeline.RunTimeout = p.RunTimeout
+	foundPipeline.Retention = p.Retention
+	foundPipeline.Priority = p.Priority
+	foundPipeline.Concurrency = p.Concurrency
+	foundPipeline.Recovery = p.Recovery
+	foundPipeline.Notifications = p.Notifications
+	foundPipeline.Parameters = p.Parameters
+
+	// Apply retry policies, run conditions and approval policies for the jobs if they have been given
+	if len(p.Jobs) > 0 {
+		jobSettings := make(map[uint32]*gaia.Job, len(p.Jobs))
+		for _, job := range p.Jobs {
+			jobSettings[job.ID] = job
+		}
+		for _, job := range foundPipeline.Jobs {
+			if settings, ok := jobSettings[job.ID]; ok {
+				job.Retry = settings.Retry
+				job.RunCondition = settings.RunCondition
+				job.Approval = settings.Approval
+			}
+		}
 	}
 
-	// Check if docker option has been updated
-	if p.Docker != foundPipeline.Docker {
-		foundPipeline.Docker = p.Docker
+	// Update pipeline in store
+	if err := storeService.PipelinePut(&foundPipeline); err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
 
-		// Update pipeline in store
-		err := storeService.PipelinePut(&foundPipeline)
-		if err != nil {
-			return c.String(http.StatusInternalServerError, err.Error())
+	// We prevent side effects here and make sure
+	// that no scheduling is already running.
+	if schedulesChanged {
+		if foundPipeline.CronInst != nil {
+			foundPipeline.CronInst.Stop()
 		}
+		foundPipeline.CronInst = cronInst
 
-		// Update active pipelines
-		pipeline.GlobalActivePipelines.Replace(foundPipeline)
+		// Start schedule process.
+		foundPipeline.CronInst.Start()
 	}
 
+	// Update active pipelines
+	pipeline.GlobalActivePipelines.ReplaceByName(currentName, foundPipeline)
+
 	return c.String(http.StatusOK, "Pipeline has been updated")
 }
 
+// validatePipelineUpdate checks if the settings of the given pipeline are valid.
+func validatePipelineUpdate(p *gaia.Pipeline) error {
+	if p.JobTimeout < 0 || p.RunTimeout < 0 {
+		return errInvalidTimeout
+	}
+	if p.Retention != nil && (p.Retention.KeepRuns < 0 || p.Retention.KeepDays < 0) {
+		return errInvalidRetentionPolicy
+	}
+	if p.Concurrency != nil {
+		switch p.Concurrency.Policy {
+		case "", gaia.ConcurrencyQueue, gaia.ConcurrencyCancelOlder, gaia.ConcurrencySkipNew:
+		default:
+			return errInvalidConcurrency
+		}
+		if p.Concurrency.Limit < 0 {
+			return errInvalidConcurrency
+		}
+	}
+	if p.Recovery != nil {
+		switch p.Recovery.Policy {
+		case "", gaia.RecoveryFail, gaia.RecoveryReschedule:
+		default:
+			return errInvalidRecovery
+		}
+		if p.Recovery.MaxReschedules < 0 {
+			return errInvalidRecovery
+		}
+	}
+	for _, n := range p.Notifications {
+		if err := notification.Validate(n); err != nil {
+			return err
+		}
+	}
+	if err := pipelinehelper.ValidateParameters(p.Parameters); err != nil {
+		return err
+	}
+	for _, job := range p.Jobs {
+		if job.Retry != nil && (job.Retry.MaxAttempts < 1 || job.Retry.MaxAttempts > gaia.RetryMaxAttempts ||
+			job.Retry.Backoff < 0 || job.Retry.Backoff > gaia.RetryMaxBackoff) {
+			return errInvalidRetryPolicy
+		}
+		switch job.RunCondition {
+		case "", gaia.RunConditionOnSuccess, gaia.RunConditionOnFailure, gaia.RunConditionAlways:
+		default:
+			return errInvalidRunCondition
+		}
+		if job.Approval != nil && job.Approval.Timeout < 0 {
+			return errInvalidApprovalPolicy
+		}
+	}
+	return nil
+}
+
 // stringSliceEqual is a small helper function
 // which determines if two string slices are equal.
 func stringSliceEqual(a, b []string) bool {
@@ -418,6 +513,7 @@ func (pp *PipelineProvider) PipelineDelete(c echo.Context) error {
 // @Produce plain
 // @Param pipelineid query string true "The ID of the pipeline."
 // @Param pipelinetoken query string true "The trigger token for this pipeline."
+// @Param priority query int false "Overwrites the priority of the pipeline for this run."
 // @Success 200 {string} string "Trigger successful for pipeline: {pipelinename}"
 // @Failure 400 {string} string "Error while triggering pipeline"
 // @Failure 403 {string} string "Invalid trigger token"
@@ -455,6 +551,11 @@ func (pp *PipelineProvider) PipelineTrigger(c echo.Context) error {
 		return c.String(http.StatusForbidden, "Invalid remote trigger token.")
 	}
 
+	// Overwrite priority setting
+	if err := overwritePriority(c, &foundPipeline); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
 	var args []*gaia.Argument
 	_ = c.Bind(&args)
 	pipelineRun, err := pp.deps.Scheduler.SchedulePipeline(&foundPipeline, gaia.StartReasonRemote, args)
@@ -540,7 +641,8 @@ func (pp *PipelineProvider) PipelineTriggerAuth(c echo.Context) error {
 }
 
 // PipelineStart starts a pipeline by the given id.
-// It accepts arguments for the given pipeline.
+// It accepts arguments for the given pipeline which are validated against
+// the declared parameters of the pipeline.
 // Afterwards it returns the created/scheduled pipeline run.
 // @Summary Start a pipeline.
 // @Description Starts a pipeline with a given ID and arguments for that pipeline and returns created/scheduled status.
@@ -549,9 +651,10 @@ func (pp *PipelineProvider) PipelineTriggerAuth(c echo.Context) error {
 // @Produce json
 // @Security ApiKeyAuth
 // @Param pipelineid query string true "The ID of the pipeline."
+// @Param priority query int false "Overwrites the priority of the pipeline for this run."
 // @Param args body gaia.Argument false "Optional arguments of the pipeline."
 // @Success 200 {object} gaia.PipelineRun
-// @Failure 400 {string} string "Various failures regarding starting the pipeline like: invalid id, invalid docker value and schedule errors"
+// @Failure 400 {string} string "Various failures regarding starting the pipeline like: invalid id, invalid docker value, invalid priority, invalid parameters and schedule errors"
 // @Failure 404 {string} string "Pipeline not found"
 // @Router /pipeline/{pipelineid}/start [post]
 func (pp *PipelineProvider) PipelineStart(c echo.Context) error {
@@ -584,6 +687,11 @@ func (pp *PipelineProvider) PipelineStart(c echo.Context) error {
 		}
 	}
 
+	// Overwrite priority setting
+	if err := overwritePriority(c, &foundPipeline); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
 	if foundPipeline.Name != "" {
 		pipelineRun, err := pp.deps.Scheduler.SchedulePipeline(&foundPipeline, gaia.StartReasonManual, args)
 		if err != nil {
@@ -597,6 +705,21 @@ func (pp *PipelineProvider) PipelineStart(c echo.Context) error {
 	return c.String(http.StatusNotFound, errPipelineNotFound.Error())
 }
 
+// overwritePriority overwrites the priority of the given pipeline
+// with the optional priority query parameter of the request.
+func overwritePriority(c echo.Context, p *gaia.Pipeline) error {
+	raw := c.QueryParam("priority")
+	if raw == "" {
+		return nil
+	}
+	priority, err := strconv.Atoi(raw)
+	if err != nil {
+		return errInvalidPriority
+	}
+	p.Priority = priority
+	return nil
+}
+
 // PipelinePull does a pull on the remote repository
 // which contains the code for this pipeline. This is so the user
 // won't have to wait for polling or a hook.
@@ -666,6 +789,39 @@ func (pp *PipelineProvider) PipelinePull(c echo.Context) error {
 	return c.String(http.StatusNotFound, errPipelineNotFound.Error())
 }
 
+// PipelineGetNotificationDeliveries returns the notification delivery history of a pipeline.
+// @Summary Get the notification deliveries of a pipeline.
+// @Description Returns the latest notification deliveries of a pipeline. The newest delivery comes first.
+// @Tags pipelines
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pipelineid query string true "The ID of the pipeline."
+// @Success 200 {array} gaia.NotificationDelivery
+// @Failure 400 {string} string "The given pipeline id is not valid"
+// @Failure 500 {string} string "Internal error while getting the notification deliveries"
+// @Router /pipeline/{pipelineid}/notifications [get]
+func (pp *PipelineProvider) PipelineGetNotificationDeliveries(c echo.Context) error {
+	pipelineID, err := strconv.Atoi(c.Param("pipelineid"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, errInvalidPipelineID.Error())
+	}
+
+	storeService, _ := services.StorageService()
+	deliveries, err := storeService.NotificationDeliveryList(pipelineID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if deliveries == nil {
+		deliveries = []*gaia.NotificationDelivery{}
+	}
+
+	// Deliveries recorded by previous versions carry the unmasked target
+	for _, d := range deliveries {
+		d.Target = notification.MaskTarget(d.Type, d.Target)
+	}
+	return c.JSON(http.StatusOK, deliveries)
+}
+
 type getAllWithLatestRun struct {
 	Pipeline    gaia.Pipeline    `json:"p"`
 	PipelineRun gaia.PipelineRun `json:"r"`
@@ -700,6 +856,7 @@ func (pp *PipelineProvider) PipelineGetAllWithLatestRun(c echo.Context) error {
 		obscurePipelineData(&p)
 		g.Pipeline = p
 		if run != nil {
+			obscureRunData(run)
 			g.PipelineRun = *run
 		}
 
@@ -744,4 +901,14 @@ func (pp *PipelineProvider) PipelineCheckPeriodicSchedules(c echo.Context) error
 // obscurePipelineData obscures pipeline data from the given pipeline object
 func obscurePipelineData(p *gaia.Pipeline) {
 	p.ExecPath = ""
+	p.Notifications = notification.Mask(p.Notifications)
+}
+
+// obscureRunData obscures the values of secret parameters from the given pipeline run object.
+// Runs created by previous versions still carry these values.
+func obscureRunData(r *gaia.PipelineRun) {
+	r.Parameters = pipelinehelper.MaskSecretParameters(r.Parameters)
+	for _, job := range r.Jobs {
+		job.Args = pipelinehelper.MaskSecretParameters(job.Args)
+	}
 }
@@ -31,6 +31,7 @@ type PipelineProviderer interface {
 	PipelineGetAll(c echo.Context) error
 	PipelineUpdate(c echo.Context) error
 	PipelinePull(c echo.Context) error
+	PipelineGetNotificationDeliveries(c echo.Context) error
 	PipelineDelete(c echo.Context) error
 	PipelineTrigger(c echo.Context) error
 	PipelineResetToken(c echo.Context) error
@@ -39,10 +40,19 @@ type PipelineProviderer interface {
 	PipelineGetAllWithLatestRun(c echo.Context) error
 	PipelineCheckPeriodicSchedules(c echo.Context) error
 	PipelineStop(c echo.Context) error
+	PipelineRerun(c echo.Context) error
+	PipelineRerunFailed(c echo.Context) error
+	PipelineJobApprove(c echo.Context) error
+	PipelineJobReject(c echo.Context) error
 	PipelineRunGet(c echo.Context) error
+	PipelineRunQueuePosition(c echo.Context) error
 	PipelineGetAllRuns(c echo.Context) error
 	PipelineGetLatestRun(c echo.Context) error
 	GetJobLogs(c echo.Context) error
+	GetJobLogsStream(c echo.Context) error
+	GetSingleJobLogs(c echo.Context) error
+	GetArtifacts(c echo.Context) error
+	GetArtifact(c echo.Context) error
 	GitWebHook(c echo.Context) error
 	SettingsPollOn(c echo.Context) error
 	SettingsPollOff(c echo.Context) error
@@ -1,16 +1,22 @@
 package pipelines
 
 import (
+	"encoding/json"
 	"errors"
-	"io/ioutil"
+	"fmt"
+	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/labstack/echo/v4"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
+	"github.com/gaia-pipeline/gaia/runstorage"
 	"github.com/gaia-pipeline/gaia/services"
 	"github.com/gaia-pipeline/gaia/workers/pipeline"
 )
@@ -20,6 +26,16 @@ var (
 	errPipelineRunNotFound = errors.New("pipeline run not found with the given id")
 )
 
+const (
+	// logStreamChunkSize is the maximum size of a single log event sent by GetJobLogsStream.
+	logStreamChunkSize = 64 * 1024 // 64 KiB
+)
+
+var (
+	// logStreamInterval is the interval in which GetJobLogsStream checks for new logs.
+	logStreamInterval = 500 * time.Millisecond
+)
+
 // jobLogs represents the json format which is returned
 // by GetJobLogs.
 type jobLogs struct {
@@ -27,6 +43,24 @@ type jobLogs struct {
 	Finished bool   `json:"finished"`
 }
 
+// jobLogsEvent represents the json format of a log event
+// which is sent by GetJobLogsStream.
+type jobLogsEvent struct {
+	Offset int64  `json:"offset"`
+	Chunk  string `json:"chunk"`
+}
+
+// jobLogsFinishedEvent represents the json format of the last event
+// which is sent by GetJobLogsStream.
+type jobLogsFinishedEvent struct {
+	Status gaia.PipelineRunStatus `json:"status"`
+}
+
+// runFinished returns true if the given pipeline run has been finalized.
+func runFinished(run *gaia.PipelineRun) bool {
+	return run.Status == gaia.RunFailed || run.Status == gaia.RunSuccess || run.Status == gaia.RunCancelled
+}
+
 // PipelineRunGet returns details about a specific pipeline run.
 // Required parameters are pipelineid and runid.
 // @Summary Get Pipeline run.
@@ -65,9 +99,69 @@ func (pp *PipelineProvider) PipelineRunGet(c echo.Context) error {
 	}
 
 	// Return pipeline run
+	obscureRunData(pipelineRun)
 	return c.JSON(http.StatusOK, pipelineRun)
 }
 
+// PipelineRunQueuePosition returns the position of a pipeline run in the queue
+// of runs which wait for their execution.
+// @Summary Get the queue position of a pipeline run.
+// @Description Returns the position of a pipeline run in the queue of waiting runs ordered by their effective priority. The position is 0 if the run does not wait for its execution.
+// @Tags pipelinerun
+// @Accept plain
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pipelineid query string true "ID of the pipeline"
+// @Param runid query string true "ID of the pipeline run"
+// @Success 200 {object} gaia.RunQueuePosition
+// @Failure 400 {string} string "Invalid pipeline id or run id"
+// @Failure 404 {string} string "Pipeline Run not found."
+// @Failure 500 {string} string "Something went wrong while getting the queue."
+// @Router /pipelinerun/{pipelineid}/{runid}/queue [get]
+func (pp *PipelineProvider) PipelineRunQueuePosition(c echo.Context) error {
+	storeService, _ := services.StorageService()
+	pipelineID, err := strconv.Atoi(c.Param("pipelineid"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, errInvalidPipelineID.Error())
+	}
+	runID, err := strconv.Atoi(c.Param("runid"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid pipeline run id given")
+	}
+
+	// Find pipeline run in store
+	pipelineRun, err := storeService.PipelineGetRunByPipelineIDAndID(pipelineID, runID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	} else if pipelineRun == nil {
+		return c.String(http.StatusNotFound, errPipelineRunNotFound.Error())
+	}
+
+	// Collect all runs which wait for their execution
+	var queue []*gaia.PipelineRun
+	for _, status := range []gaia.PipelineRunStatus{gaia.RunNotScheduled, gaia.RunScheduled} {
+		runs, err := storeService.PipelineGetRunsByStatus(status)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		queue = append(queue, runs...)
+	}
+	now := time.Now()
+	pipelinehelper.SortRunsByPriority(queue, now)
+
+	position := gaia.RunQueuePosition{
+		Length:   len(queue),
+		Priority: pipelinehelper.EffectivePriority(pipelineRun, now),
+	}
+	for i, r := range queue {
+		if r.UniqueID == pipelineRun.UniqueID {
+			position.Position = i + 1
+			break
+		}
+	}
+	return c.JSON(http.StatusOK, position)
+}
+
 // PipelineStop stops a running pipeline.
 // @Summary Stop a pipeline run.
 // @Description Stops a pipeline run.
@@ -119,16 +213,190 @@ func (pp *PipelineProvider) PipelineStop(c echo.Context) error {
 	return c.String(http.StatusNotFound, errPipelineNotFound.Error())
 }
 
-// PipelineGetAllRuns returns all runs about the given pipeline.
-// @Summary Get all pipeline runs.
-// @Description Returns all runs about the given pipeline.
+// PipelineRerun schedules a new run which replays the given pipeline run
+// with the same arguments, pipeline binary and docker setting.
+// @Summary Rerun a pipeline run.
+// @Description Schedules a new run which replays the given pipeline run and links back to it.
 // @Tags pipelinerun
 // @Accept plain
 // @Produce json
 // @Security ApiKeyAuth
 // @Param pipelineid query string true "ID of the pipeline"
-// @Success 200 {array} gaia.PipelineRun "a list of pipeline runes"
-// @Failure 400 {string} string "Invalid pipeline id"
+// @Param runid query string true "ID of the pipeline run"
+// @Success 201 {object} gaia.PipelineRun
+// @Failure 400 {string} string "Invalid pipeline id or run id, or the run cannot be rerun"
+// @Failure 404 {string} string "Pipeline or pipeline run not found."
+// @Failure 500 {string} string "Something went wrong while getting pipeline run."
+// @Router /pipelinerun/{pipelineid}/{runid}/rerun [post]
+func (pp *PipelineProvider) PipelineRerun(c echo.Context) error {
+	return pp.rerun(c, false)
+}
+
+// PipelineRerunFailed schedules a new run of the given pipeline run which only
+// executes the failed jobs and the jobs which depend on them.
+// @Summary Rerun the failed jobs of a pipeline run.
+// @Description Schedules a new run which only executes the failed jobs of the given pipeline run and their dependents. All other jobs are taken over as successful.
+// @Tags pipelinerun
+// @Accept plain
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pipelineid query string true "ID of the pipeline"
+// @Param runid query string true "ID of the pipeline run"
+// @Success 201 {object} gaia.PipelineRun
+// @Failure 400 {string} string "Invalid pipeline id or run id, or the run cannot be rerun"
+// @Failure 404 {string} string "Pipeline or pipeline run not found."
+// @Failure 500 {string} string "Something went wrong while getting pipeline run."
+// @Router /pipelinerun/{pipelineid}/{runid}/rerun-failed [post]
+func (pp *PipelineProvider) PipelineRerunFailed(c echo.Context) error {
+	return pp.rerun(c, true)
+}
+
+// rerun schedules a new run of the pipeline run given by the request.
+func (pp *PipelineProvider) rerun(c echo.Context, failedOnly bool) error {
+	storeService, _ := services.StorageService()
+	pipelineID, err := strconv.Atoi(c.Param("pipelineid"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, errInvalidPipelineID.Error())
+	}
+	runID, err := strconv.Atoi(c.Param("runid"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid pipeline run id given")
+	}
+
+	// Find pipeline run in store
+	pipelineRun, err := storeService.PipelineGetRunByPipelineIDAndID(pipelineID, runID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	} else if pipelineRun == nil {
+		return c.String(http.StatusNotFound, errPipelineRunNotFound.Error())
+	}
+
+	// Look up pipeline for the given id
+	var foundPipeline gaia.Pipeline
+	for _, pipe := range pipeline.GlobalActivePipelines.GetAll() {
+		if pipe.ID == pipelineID {
+			foundPipeline = pipe
+			break
+		}
+	}
+	if foundPipeline.Name == "" {
+		return c.String(http.StatusNotFound, errPipelineNotFound.Error())
+	}
+
+	rerun, err := pp.deps.Scheduler.RerunPipeline(&foundPipeline, pipelineRun, failedOnly)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	return c.JSON(http.StatusCreated, rerun)
+}
+
+// PipelineJobApprove approves the given job which waits for approval in the given
+// pipeline run. The approval is recorded together with the authenticated user.
+// @Summary Approve a job of a pipeline run.
+// @Description Approves a job waiting for approval. Paused pipeline runs continue with the approved job.
+// @Tags pipelinerun
+// @Accept plain
+// @Produce plain
+// @Security ApiKeyAuth
+// @Param pipelineid query string true "ID of the pipeline"
+// @Param runid query string true "ID of the pipeline run"
+// @Param jobid query string true "ID of the job"
+// @Success 200 {string} string "job approved"
+// @Failure 400 {string} string "Invalid ids given or the job is not waiting for approval"
+// @Failure 404 {string} string "Pipeline or pipeline run not found."
+// @Failure 500 {string} string "Something went wrong while getting pipeline run."
+// @Router /pipelinerun/{pipelineid}/{runid}/job/{jobid}/approve [post]
+func (pp *PipelineProvider) PipelineJobApprove(c echo.Context) error {
+	return pp.decideApproval(c, true)
+}
+
+// PipelineJobReject rejects the given job which waits for approval in the given
+// pipeline run which fails the pipeline run. The rejection is recorded together
+// with the authenticated user.
+// @Summary Reject a job of a pipeline run.
+// @Description Rejects a job waiting for approval which fails the pipeline run.
+// @Tags pipelinerun
+// @Accept plain
+// @Produce plain
+// @Security ApiKeyAuth
+// @Param pipelineid query string true "ID of the pipeline"
+// @Param runid query string true "ID of the pipeline run"
+// @Param jobid query string true "ID of the job"
+// @Success 200 {string} string "job rejected"
+// @Failure 400 {string} string "Invalid ids given or the job is not waiting for approval"
+// @Failure 404 {string} string "Pipeline or pipeline run not found."
+// @Failure 500 {string} string "Something went wrong while getting pipeline run."
+// @Router /pipelinerun/{pipelineid}/{runid}/job/{jobid}/reject [post]
+func (pp *PipelineProvider) PipelineJobReject(c echo.Context) error {
+	return pp.decideApproval(c, false)
+}
+
+// decideApproval approves or rejects the job of the pipeline run given by the request.
+func (pp *PipelineProvider) decideApproval(c echo.Context, approved bool) error {
+	storeService, _ := services.StorageService()
+	pipelineID, err := strconv.Atoi(c.Param("pipelineid"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, errInvalidPipelineID.Error())
+	}
+	runID, err := strconv.Atoi(c.Param("runid"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid pipeline run id given")
+	}
+	jobID, err := strconv.ParseUint(c.Param("jobid"), 10, 32)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid job id given")
+	}
+
+	// Find pipeline run in store
+	pipelineRun, err := storeService.PipelineGetRunByPipelineIDAndID(pipelineID, runID)
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	} else if pipelineRun == nil {
+		return c.String(http.StatusNotFound, errPipelineRunNotFound.Error())
+	}
+
+	// Look up pipeline for the given id
+	var foundPipeline gaia.Pipeline
+	for _, pipe := range pipeline.GlobalActivePipelines.GetAll() {
+		if pipe.ID == pipelineID {
+			foundPipeline = pipe
+			break
+		}
+	}
+	if foundPipeline.Name == "" {
+		return c.String(http.StatusNotFound, errPipelineNotFound.Error())
+	}
+
+	// The username is set by the auth middleware
+	username, _ := c.Get("username").(string)
+	err = pp.deps.Scheduler.DecideApproval(&foundPipeline, runID, uint32(jobID), username, approved)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if approved {
+		return c.String(http.StatusOK, "job approved")
+	}
+	return c.String(http.StatusOK, "job rejected")
+}
+
+// PipelineGetAllRuns returns a single page of the runs of the given pipeline.
+// The runs are ordered from the newest to the oldest run. Without filter
+// and page parameters all runs are returned as an array like before.
+// @Summary Get pipeline runs.
+// @Description Returns a single page of the runs of the given pipeline. The runs can be filtered by status, start reason and start date. Without filter and page parameters, all runs are returned as an array.
+// @Tags pipelinerun
+// @Accept plain
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pipelineid query string true "ID of the pipeline"
+// @Param status query string false "Comma separated list of run statuses"
+// @Param reason query string false "Start reason of the runs"
+// @Param startedafter query string false "Only runs started at or after this time (RFC3339)"
+// @Param startedbefore query string false "Only runs started before this time (RFC3339)"
+// @Param page query int false "Page number starting at 1"
+// @Param perpage query int false "Number of runs per page"
+// @Success 200 {object} gaia.PipelineRunList "a single page of pipeline runs or an array of all runs"
+// @Failure 400 {string} string "Invalid pipeline id or filter"
 // @Failure 500 {string} string "Error retrieving all pipeline runs."
 // @Router /pipelinerun/{pipelineid} [get]
 func (pp *PipelineProvider) PipelineGetAllRuns(c echo.Context) error {
@@ -139,15 +407,85 @@ func (pp *PipelineProvider) PipelineGetAllRuns(c echo.Context) error {
 		return c.String(http.StatusBadRequest, errInvalidPipelineID.Error())
 	}
 
-	// Get all runs by the given pipeline id
-	runs, err := storeService.PipelineGetAllRunsByPipelineID(pipelineID)
+	// Keep the response of clients which do not know about paging
+	if !hasRunListParams(c) {
+		runs, err := storeService.PipelineGetAllRunsByPipelineID(pipelineID)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, err.Error())
+		}
+		for i := range runs {
+			obscureRunData(&runs[i])
+		}
+		return c.JSON(http.StatusOK, runs)
+	}
+
+	filter, page, err := runListParams(c)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	filter.PipelineID = pipelineID
+
+	// Get the requested page of runs by the given pipeline id
+	runs, err := storeService.PipelineListRuns(filter, page)
 	if err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	for i := range runs.Runs {
+		obscureRunData(&runs.Runs[i])
+	}
 
 	return c.JSON(http.StatusOK, runs)
 }
 
+// runListParamNames are the names of the filter and page query parameters of PipelineGetAllRuns.
+var runListParamNames = []string{"status", "reason", "startedafter", "startedbefore", "page", "perpage"}
+
+// hasRunListParams returns true if a filter or page query parameter has been given.
+func hasRunListParams(c echo.Context) bool {
+	query := c.QueryParams()
+	for _, name := range runListParamNames {
+		if _, ok := query[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// runListParams parses the filter and page query parameters of PipelineGetAllRuns.
+func runListParams(c echo.Context) (gaia.PipelineRunFilter, gaia.Page, error) {
+	filter := gaia.PipelineRunFilter{StartReason: c.QueryParam("reason")}
+	page := gaia.Page{}
+
+	if status := c.QueryParam("status"); status != "" {
+		for _, s := range strings.Split(status, ",") {
+			filter.Status = append(filter.Status, gaia.PipelineRunStatus(strings.TrimSpace(s)))
+		}
+	}
+
+	var err error
+	if v := c.QueryParam("startedafter"); v != "" {
+		if filter.StartedAfter, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, page, errors.New("invalid startedafter date given")
+		}
+	}
+	if v := c.QueryParam("startedbefore"); v != "" {
+		if filter.StartedBefore, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, page, errors.New("invalid startedbefore date given")
+		}
+	}
+	if v := c.QueryParam("page"); v != "" {
+		if page.Number, err = strconv.Atoi(v); err != nil {
+			return filter, page, errors.New("invalid page given")
+		}
+	}
+	if v := c.QueryParam("perpage"); v != "" {
+		if page.PerPage, err = strconv.Atoi(v); err != nil {
+			return filter, page, errors.New("invalid perpage given")
+		}
+	}
+	return filter, page, nil
+}
+
 // PipelineGetLatestRun returns the latest run of a pipeline, given by id.
 // @Summary Get latest pipeline runs.
 // @Description Returns the latest run of a pipeline, given by id.
@@ -173,11 +511,14 @@ func (pp *PipelineProvider) PipelineGetLatestRun(c echo.Context) error {
 	if err != nil {
 		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	if run != nil {
+		obscureRunData(run)
+	}
 
 	return c.JSON(http.StatusOK, run)
 }
 
-// GetJobLogs returns logs from a pipeline run.
+// GetJobLogs returns the combined logs of all jobs from a pipeline run.
 //
 // Required parameters:
 // pipelineid - Related pipeline id
@@ -197,6 +538,7 @@ func (pp *PipelineProvider) PipelineGetLatestRun(c echo.Context) error {
 func (pp *PipelineProvider) GetJobLogs(c echo.Context) error {
 	// Get parameters and validate
 	storeService, _ := services.StorageService()
+	runStorage, _ := services.RunStorageService()
 	pipelineID := c.Param("pipelineid")
 	pipelineRunID := c.Param("runid")
 
@@ -221,22 +563,383 @@ func (pp *PipelineProvider) GetJobLogs(c echo.Context) error {
 	jL := jobLogs{}
 
 	// Determine if job has been finished
-	if run.Status == gaia.RunFailed || run.Status == gaia.RunSuccess || run.Status == gaia.RunCancelled {
-		jL.Finished = true
+	jL.Finished = runFinished(run)
+
+	// Read log file if it exists
+	content, err := runstorage.ReadAll(runStorage, runstorage.LogKey(p, r, 0))
+	if err != nil && err != runstorage.ErrNotExist {
+		return c.String(http.StatusInternalServerError, "cannot read pipeline run log file")
 	}
 
-	// Check if log file exists
-	logFilePath := filepath.Join(gaia.Cfg.WorkspacePath, pipelineID, pipelineRunID, gaia.LogsFolderName, gaia.LogsFileName)
-	if _, err := os.Stat(logFilePath); err == nil {
-		content, err := ioutil.ReadFile(logFilePath)
-		if err != nil {
-			return c.String(http.StatusInternalServerError, "cannot read pipeline run log file")
+	// Convert logs
+	jL.Log = string(content)
+
+	// Return logs
+	return c.JSON(http.StatusOK, jL)
+}
+
+// GetSingleJobLogs returns the logs of a single job from a pipeline run.
+//
+// Required parameters:
+// pipelineid - Related pipeline id
+// pipelinerunid - Related pipeline run id
+// jobid - Related job id
+// @Summary Get logs for a job of a pipeline run.
+// @Description Returns the logs of a single job from a pipeline run.
+// @Tags pipelinerun
+// @Accept plain
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pipelineid query string true "ID of the pipeline"
+// @Param runid query string true "ID of the run"
+// @Param jobid query string true "ID of the job"
+// @Success 200 {object} jobLogs "logs"
+// @Failure 400 {string} string "Invalid pipeline id or run id or job id or pipeline not found"
+// @Failure 404 {string} string "Job not found"
+// @Failure 500 {string} string "cannot read job log file"
+// @Router /pipelinerun/{pipelineid}/{runid}/job/{jobid}/log [get]
+func (pp *PipelineProvider) GetSingleJobLogs(c echo.Context) error {
+	// Get parameters and validate
+	storeService, _ := services.StorageService()
+	runStorage, _ := services.RunStorageService()
+	pipelineID := c.Param("pipelineid")
+	pipelineRunID := c.Param("runid")
+
+	// Transform pipelineid to int
+	p, err := strconv.Atoi(pipelineID)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid pipeline id given")
+	}
+
+	// Transform pipelinerunid to int
+	r, err := strconv.Atoi(pipelineRunID)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid pipeline run id given")
+	}
+
+	// Transform jobid to uint32
+	jobID, err := strconv.ParseUint(c.Param("jobid"), 10, 32)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid job id given")
+	}
+
+	run, err := storeService.PipelineGetRunByPipelineIDAndID(p, r)
+	if err != nil || run == nil {
+		return c.String(http.StatusBadRequest, "cannot find pipeline run with given pipeline id and pipeline run id")
+	}
+
+	// Look up the job in the pipeline run
+	var job *gaia.Job
+	for _, j := range run.Jobs {
+		if j.ID == uint32(jobID) {
+			job = j
+			break
 		}
+	}
+	if job == nil {
+		return c.String(http.StatusNotFound, "cannot find job with given job id in pipeline run")
+	}
 
-		// Convert logs
-		jL.Log = string(content)
+	// Create return object
+	jL := jobLogs{}
+
+	// Determine if job has been finished
+	jL.Finished = runFinished(run) || job.Status == gaia.JobSuccess || job.Status == gaia.JobFailed || job.Status == gaia.JobTimedOut
+
+	// Read log file if it exists
+	content, err := runstorage.ReadAll(runStorage, runstorage.LogKey(p, r, job.ID))
+	if err != nil && err != runstorage.ErrNotExist {
+		return c.String(http.StatusInternalServerError, "cannot read job log file")
 	}
 
+	// Convert logs
+	jL.Log = string(content)
+
 	// Return logs
 	return c.JSON(http.StatusOK, jL)
 }
+
+// GetJobLogsStream streams the logs of a pipeline run as server-sent events
+// while the pipeline run is running.
+//
+// Every log event carries the offset of the chunk in the log file. The event id
+// is the offset right after the chunk so that clients can resume the stream via
+// the Last-Event-ID header or the offset query parameter.
+// The stream is closed with a finished event once the pipeline run has been finalized.
+// @Summary Stream logs for pipeline run.
+// @Description Streams logs from a pipeline run as server-sent events.
+// @Tags pipelinerun
+// @Accept plain
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Param pipelineid query string true "ID of the pipeline"
+// @Param runid query string true "ID of the run"
+// @Param offset query int false "Offset in bytes to resume from"
+// @Success 200 {object} jobLogsEvent "log events"
+// @Failure 400 {string} string "Invalid pipeline id or run id or offset or pipeline not found"
+// @Router /pipelinerun/{pipelineid}/{runid}/log/stream [get]
+func (pp *PipelineProvider) GetJobLogsStream(c echo.Context) error {
+	// Get parameters and validate
+	storeService, _ := services.StorageService()
+	runStorage, _ := services.RunStorageService()
+	pipelineID := c.Param("pipelineid")
+	pipelineRunID := c.Param("runid")
+
+	// Transform pipelineid to int
+	p, err := strconv.Atoi(pipelineID)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid pipeline id given")
+	}
+
+	// Transform pipelinerunid to int
+	r, err := strconv.Atoi(pipelineRunID)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid pipeline run id given")
+	}
+
+	run, err := storeService.PipelineGetRunByPipelineIDAndID(p, r)
+	if err != nil || run == nil {
+		return c.String(http.StatusBadRequest, "cannot find pipeline run with given pipeline id and pipeline run id")
+	}
+
+	// Determine the offset to resume from. The Last-Event-ID header
+	// is set automatically by browsers on reconnect.
+	var offset int64
+	resumeFrom := c.Request().Header.Get("Last-Event-ID")
+	if resumeFrom == "" {
+		resumeFrom = c.QueryParam("offset")
+	}
+	if resumeFrom != "" {
+		offset, err = strconv.ParseInt(resumeFrom, 10, 64)
+		if err != nil || offset < 0 {
+			return c.String(http.StatusBadRequest, "invalid offset given")
+		}
+	}
+
+	// Start event stream
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	logKey := runstorage.LogKey(p, r, 0)
+	buffer := make([]byte, logStreamChunkSize)
+	ticker := time.NewTicker(logStreamInterval)
+	defer ticker.Stop()
+	for {
+		// The status must be determined before reading the logs.
+		// Otherwise, we might miss the last written logs.
+		finished := runFinished(run)
+
+		offset, err = streamLogFile(res, runStorage, logKey, offset, buffer)
+		if err != nil {
+			gaia.Cfg.Logger.Debug("failed to stream pipeline run logs", "error", err.Error(), "pipelinerun", run.UniqueID)
+			return nil
+		}
+
+		if finished {
+			return writeEvent(res, "", "finished", jobLogsFinishedEvent{Status: run.Status})
+		}
+
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		// Refresh pipeline run status
+		run, err = storeService.PipelineGetRunByID(run.UniqueID)
+		if err != nil || run == nil {
+			gaia.Cfg.Logger.Error("failed to refresh pipeline run during log stream", "pipelinerun", pipelineRunID)
+			return nil
+		}
+	}
+}
+
+// streamLogFile sends the content of the log file with the given key, starting at
+// the given offset, as log events to the client. It returns the new offset.
+// A log file which does not exist yet is not an error.
+func streamLogFile(res *echo.Response, runStorage runstorage.GaiaRunStorage, logKey string, offset int64, buffer []byte) (int64, error) {
+	file, err := runStorage.Reader(logKey, offset)
+	if err != nil {
+		if err == runstorage.ErrNotExist {
+			return offset, nil
+		}
+		return offset, err
+	}
+	defer file.Close()
+
+	// Only complete UTF-8 encoded characters are sent. The incomplete
+	// rest of a chunk is carried over to the next chunk. At the end of
+	// the file it is read again by the next call.
+	carried := 0
+	for {
+		bytesRead, err := file.Read(buffer[carried:])
+		available := carried + bytesRead
+		if complete := completeRunes(buffer[:available]); complete > 0 {
+			event := jobLogsEvent{
+				Offset: offset,
+				Chunk:  string(buffer[:complete]),
+			}
+			offset += int64(complete)
+			if err := writeEvent(res, strconv.FormatInt(offset, 10), "log", event); err != nil {
+				return offset, err
+			}
+			carried = copy(buffer, buffer[complete:available])
+		} else {
+			carried = available
+		}
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+	}
+}
+
+// completeRunes returns the length of the given bytes without
+// an incomplete UTF-8 encoded character at their end.
+func completeRunes(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if utf8.FullRune(p[i:]) {
+				return len(p)
+			}
+			return i
+		}
+	}
+	return len(p)
+}
+
+// writeEvent writes a single server-sent event and flushes it to the client.
+func writeEvent(res *echo.Response, id, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err = fmt.Fprintf(res, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err = fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
+
+// GetArtifacts returns the artifacts published by the jobs of a pipeline run.
+//
+// Required parameters:
+// pipelineid - Related pipeline id
+// pipelinerunid - Related pipeline run id
+// @Summary Get artifacts of a pipeline run.
+// @Description Returns the metadata of all artifacts published by the jobs of a pipeline run.
+// @Tags pipelinerun
+// @Accept plain
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pipelineid query string true "ID of the pipeline"
+// @Param runid query string true "ID of the run"
+// @Success 200 {array} gaia.Artifact
+// @Failure 400 {string} string "Invalid pipeline id or run id or pipeline run not found"
+// @Router /pipelinerun/{pipelineid}/{runid}/artifacts [get]
+func (pp *PipelineProvider) GetArtifacts(c echo.Context) error {
+	// Get parameters and validate
+	storeService, _ := services.StorageService()
+	p, err := strconv.Atoi(c.Param("pipelineid"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid pipeline id given")
+	}
+	r, err := strconv.Atoi(c.Param("runid"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid pipeline run id given")
+	}
+
+	run, err := storeService.PipelineGetRunByPipelineIDAndID(p, r)
+	if err != nil || run == nil {
+		return c.String(http.StatusBadRequest, "cannot find pipeline run with given pipeline id and pipeline run id")
+	}
+
+	artifacts := make([]*gaia.Artifact, 0)
+	for _, j := range run.Jobs {
+		artifacts = append(artifacts, j.Artifacts...)
+	}
+	return c.JSON(http.StatusOK, artifacts)
+}
+
+// GetArtifact downloads a single artifact published by a job of a pipeline run.
+//
+// Required parameters:
+// pipelineid - Related pipeline id
+// pipelinerunid - Related pipeline run id
+// jobid - Id of the job which published the artifact
+// * - Name of the artifact
+// @Summary Download an artifact of a pipeline run.
+// @Description Downloads a single artifact published by a job of a pipeline run.
+// @Tags pipelinerun
+// @Accept plain
+// @Produce octet-stream
+// @Security ApiKeyAuth
+// @Param pipelineid query string true "ID of the pipeline"
+// @Param runid query string true "ID of the run"
+// @Param jobid query string true "ID of the job"
+// @Param name path string true "Name of the artifact"
+// @Success 200 {file} file "artifact"
+// @Failure 400 {string} string "Invalid pipeline id or run id or job id or pipeline run not found"
+// @Failure 404 {string} string "Artifact not found"
+// @Router /pipelinerun/{pipelineid}/{runid}/artifacts/{jobid}/{name} [get]
+func (pp *PipelineProvider) GetArtifact(c echo.Context) error {
+	// Get parameters and validate
+	storeService, _ := services.StorageService()
+	runStorage, _ := services.RunStorageService()
+	p, err := strconv.Atoi(c.Param("pipelineid"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid pipeline id given")
+	}
+	r, err := strconv.Atoi(c.Param("runid"))
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid pipeline run id given")
+	}
+	jobID, err := strconv.ParseUint(c.Param("jobid"), 10, 32)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid job id given")
+	}
+
+	run, err := storeService.PipelineGetRunByPipelineIDAndID(p, r)
+	if err != nil || run == nil {
+		return c.String(http.StatusBadRequest, "cannot find pipeline run with given pipeline id and pipeline run id")
+	}
+
+	// Only artifacts recorded at the job can be downloaded
+	name := c.Param("*")
+	for _, j := range run.Jobs {
+		if j.ID != uint32(jobID) {
+			continue
+		}
+		for _, a := range j.Artifacts {
+			if a.Name != name {
+				continue
+			}
+			key, err := runstorage.ArtifactKey(p, r, j.ID, a.Name)
+			if err != nil {
+				return c.String(http.StatusBadRequest, "invalid artifact name")
+			}
+			content, err := runStorage.Reader(key, 0)
+			if err == runstorage.ErrNotExist {
+				return c.String(http.StatusNotFound, "artifact does not exist anymore")
+			}
+			if err != nil {
+				return c.String(http.StatusInternalServerError, "cannot read artifact")
+			}
+			defer content.Close()
+			c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", path.Base(a.Name)))
+			return c.Stream(http.StatusOK, echo.MIMEOctetStream, content)
+		}
+	}
+	return c.String(http.StatusNotFound, "cannot find artifact with given job id and name in pipeline run")
+}
@@ -0,0 +1,776 @@
+package pipelines
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/labstack/echo/v4"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/runstorage"
+	"github.com/gaia-pipeline/gaia/services"
+	"github.com/gaia-pipeline/gaia/workers/pipeline"
+)
+
+func TestGetJobLogsStream(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestGetJobLogsStream")
+	defer os.RemoveAll(tmp)
+
+	gaia.Cfg = &gaia.Config{
+		Logger:        hclog.NewNullLogger(),
+		HomePath:      tmp,
+		DataPath:      tmp,
+		WorkspacePath: tmp,
+	}
+
+	// Initialize store
+	dataStore, err := services.StorageService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { services.MockStorageService(nil) }()
+
+	// Speed up log polling
+	oldInterval := logStreamInterval
+	logStreamInterval = 10 * time.Millisecond
+	defer func() { logStreamInterval = oldInterval }()
+
+	pp := NewPipelineProvider(Dependencies{})
+	e := echo.New()
+
+	run := &gaia.PipelineRun{
+		UniqueID:   "first-run",
+		ID:         1,
+		PipelineID: 1,
+		Status:     gaia.RunRunning,
+	}
+	if err := dataStore.PipelinePutRun(run); err != nil {
+		t.Fatal(err)
+	}
+
+	logFolderPath := filepath.Join(tmp, "1", "1", gaia.LogsFolderName)
+	if err := os.MkdirAll(logFolderPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	logFilePath := filepath.Join(logFolderPath, gaia.LogsFileName)
+	if err := ioutil.WriteFile(logFilePath, []byte("first line\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	newContext := func(req *http.Request, rec *httptest.ResponseRecorder) echo.Context {
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipelinerun/:pipelineid/:runid/log/stream")
+		c.SetParamNames("pipelineid", "runid")
+		c.SetParamValues("1", "1")
+		return c
+	}
+
+	t.Run("streams until run finished", func(t *testing.T) {
+		// Append logs and finish the run while the stream is open
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			f, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_WRONLY, 0600)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_, _ = f.WriteString("second line\n")
+			_ = f.Close()
+
+			run.Status = gaia.RunSuccess
+			if err := dataStore.PipelinePutRun(run); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		rec := httptest.NewRecorder()
+		if err := pp.GetJobLogsStream(newContext(req, rec)); err != nil {
+			t.Fatal(err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		if rec.Header().Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("expected event stream content type but got %s", rec.Header().Get("Content-Type"))
+		}
+		body := rec.Body.String()
+		expected := []string{
+			"id: 11\nevent: log\ndata: {\"offset\":0,\"chunk\":\"first line\\n\"}\n\n",
+			"id: 23\nevent: log\ndata: {\"offset\":11,\"chunk\":\"second line\\n\"}\n\n",
+			"event: finished\ndata: {\"status\":\"success\"}\n\n",
+		}
+		for _, e := range expected {
+			if !strings.Contains(body, e) {
+				t.Fatalf("expected body to contain %q but got %q", e, body)
+			}
+		}
+	})
+
+	t.Run("resumes from last event id", func(t *testing.T) {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		req.Header.Set("Last-Event-ID", "11")
+		rec := httptest.NewRecorder()
+		if err := pp.GetJobLogsStream(newContext(req, rec)); err != nil {
+			t.Fatal(err)
+		}
+
+		body := rec.Body.String()
+		if strings.Contains(body, "first line") {
+			t.Fatalf("expected stream to resume after first line but got %q", body)
+		}
+		if !strings.Contains(body, "second line") {
+			t.Fatalf("expected stream to contain second line but got %q", body)
+		}
+	})
+
+	t.Run("fails for invalid offset", func(t *testing.T) {
+		req := httptest.NewRequest(echo.GET, "/?offset=abc", nil)
+		rec := httptest.NewRecorder()
+		_ = pp.GetJobLogsStream(newContext(req, rec))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestGetSingleJobLogs(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestGetSingleJobLogs")
+	defer os.RemoveAll(tmp)
+
+	gaia.Cfg = &gaia.Config{
+		Logger:        hclog.NewNullLogger(),
+		HomePath:      tmp,
+		DataPath:      tmp,
+		WorkspacePath: tmp,
+	}
+
+	// Initialize store
+	dataStore, err := services.StorageService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { services.MockStorageService(nil) }()
+
+	pp := NewPipelineProvider(Dependencies{})
+	e := echo.New()
+
+	run := &gaia.PipelineRun{
+		UniqueID:   "first-run",
+		ID:         1,
+		PipelineID: 1,
+		Status:     gaia.RunRunning,
+		Jobs: []*gaia.Job{
+			{ID: 1, Title: "first-job", Status: gaia.JobSuccess},
+			{ID: 2, Title: "second-job", Status: gaia.JobRunning},
+		},
+	}
+	if err := dataStore.PipelinePutRun(run); err != nil {
+		t.Fatal(err)
+	}
+
+	logFolderPath := filepath.Join(tmp, "1", "1", gaia.LogsFolderName)
+	if err := os.MkdirAll(logFolderPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(logFolderPath, gaia.JobLogsFileName(1)), []byte("first job"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(logFolderPath, gaia.JobLogsFileName(2)), []byte("second job"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	getLogs := func(jobID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipelinerun/:pipelineid/:runid/job/:jobid/log")
+		c.SetParamNames("pipelineid", "runid", "jobid")
+		c.SetParamValues("1", "1", jobID)
+		_ = pp.GetSingleJobLogs(c)
+		return rec
+	}
+
+	t.Run("returns logs of finished job", func(t *testing.T) {
+		rec := getLogs("1")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		expected := `{"log":"first job","finished":true}`
+		if strings.TrimSpace(rec.Body.String()) != expected {
+			t.Fatalf("expected body %s but got %s", expected, rec.Body.String())
+		}
+	})
+
+	t.Run("returns logs of running job", func(t *testing.T) {
+		rec := getLogs("2")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		expected := `{"log":"second job","finished":false}`
+		if strings.TrimSpace(rec.Body.String()) != expected {
+			t.Fatalf("expected body %s but got %s", expected, rec.Body.String())
+		}
+	})
+
+	t.Run("fails for unknown job", func(t *testing.T) {
+		rec := getLogs("3")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected response code %v got %v", http.StatusNotFound, rec.Code)
+		}
+	})
+
+	t.Run("fails for invalid job id", func(t *testing.T) {
+		rec := getLogs("abc")
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestGetArtifacts(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestGetArtifacts")
+	defer os.RemoveAll(tmp)
+
+	gaia.Cfg = &gaia.Config{
+		Logger:        hclog.NewNullLogger(),
+		HomePath:      tmp,
+		DataPath:      tmp,
+		WorkspacePath: tmp,
+	}
+
+	// Initialize store
+	dataStore, err := services.StorageService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { services.MockStorageService(nil) }()
+
+	pp := NewPipelineProvider(Dependencies{})
+	e := echo.New()
+
+	run := &gaia.PipelineRun{
+		UniqueID:   "first-run",
+		ID:         1,
+		PipelineID: 1,
+		Status:     gaia.RunSuccess,
+		Jobs: []*gaia.Job{
+			{ID: 1, Title: "build", Status: gaia.JobSuccess, Artifacts: []*gaia.Artifact{
+				{JobID: 1, Name: "bin/app", Size: 5, SHA256: "checksum"},
+			}},
+			{ID: 2, Title: "test", Status: gaia.JobSuccess},
+		},
+	}
+	if err := dataStore.PipelinePutRun(run); err != nil {
+		t.Fatal(err)
+	}
+	artifactsPath := gaia.JobArtifactsPath(1, 1, 1)
+	if err := os.MkdirAll(filepath.Join(artifactsPath, "bin"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(artifactsPath, "bin", "app"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("lists artifacts of all jobs", func(t *testing.T) {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipelinerun/:pipelineid/:runid/artifacts")
+		c.SetParamNames("pipelineid", "runid")
+		c.SetParamValues("1", "1")
+		_ = pp.GetArtifacts(c)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		expected := `[{"jobid":1,"name":"bin/app","size":5,"sha256":"checksum","date":"0001-01-01T00:00:00Z"}]`
+		if strings.TrimSpace(rec.Body.String()) != expected {
+			t.Fatalf("expected body %s but got %s", expected, rec.Body.String())
+		}
+	})
+
+	getArtifact := func(jobID, name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipelinerun/:pipelineid/:runid/artifacts/:jobid/*")
+		c.SetParamNames("pipelineid", "runid", "jobid", "*")
+		c.SetParamValues("1", "1", jobID, name)
+		_ = pp.GetArtifact(c)
+		return rec
+	}
+
+	t.Run("downloads artifact", func(t *testing.T) {
+		rec := getArtifact("1", "bin/app")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		if rec.Body.String() != "hello" {
+			t.Fatalf("expected body hello but got %s", rec.Body.String())
+		}
+		if !strings.Contains(rec.Header().Get(echo.HeaderContentDisposition), "app") {
+			t.Fatalf("expected attachment app but got %s", rec.Header().Get(echo.HeaderContentDisposition))
+		}
+	})
+
+	t.Run("fails for unknown artifact", func(t *testing.T) {
+		for _, params := range [][]string{{"2", "bin/app"}, {"1", "../../logs/output.log"}} {
+			if rec := getArtifact(params[0], params[1]); rec.Code != http.StatusNotFound {
+				t.Fatalf("expected response code %v got %v", http.StatusNotFound, rec.Code)
+			}
+		}
+	})
+
+	t.Run("fails for recorded artifact outside the artifacts folder", func(t *testing.T) {
+		run.Jobs[1].Artifacts = []*gaia.Artifact{{JobID: 2, Name: "../../../../etc/passwd"}}
+		if err := dataStore.PipelinePutRun(run); err != nil {
+			t.Fatal(err)
+		}
+		if rec := getArtifact("2", "../../../../etc/passwd"); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestPipelineGetAllRuns(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPipelineGetAllRuns")
+	defer os.RemoveAll(tmp)
+
+	gaia.Cfg = &gaia.Config{
+		Logger:   hclog.NewNullLogger(),
+		HomePath: tmp,
+		DataPath: tmp,
+	}
+
+	// Initialize store
+	dataStore, err := services.StorageService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { services.MockStorageService(nil) }()
+
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 5; i++ {
+		run := &gaia.PipelineRun{
+			UniqueID:    fmt.Sprintf("run-%d", i),
+			ID:          i,
+			PipelineID:  1,
+			Status:      gaia.RunSuccess,
+			StartReason: gaia.StartReasonManual,
+			StartDate:   start.Add(time.Duration(i) * time.Hour),
+		}
+		if i%2 == 0 {
+			run.Status = gaia.RunFailed
+			run.StartReason = gaia.StartReasonRemote
+		}
+		if err := dataStore.PipelinePutRun(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pp := NewPipelineProvider(Dependencies{})
+	e := echo.New()
+
+	get := func(query string) (*httptest.ResponseRecorder, *gaia.PipelineRunList) {
+		req := httptest.NewRequest(echo.GET, "/api/"+gaia.APIVersion+"/pipelinerun/1?"+query, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+		if err := pp.PipelineGetAllRuns(c); err != nil {
+			t.Fatal(err)
+		}
+
+		list := &gaia.PipelineRunList{}
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), list); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec, list
+	}
+
+	runIDs := func(list *gaia.PipelineRunList) []int {
+		var ids []int
+		for _, r := range list.Runs {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	t.Run("paginated", func(t *testing.T) {
+		rec, list := get("page=2&perpage=2")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d but got %d", http.StatusOK, rec.Code)
+		}
+		if list.Total != 5 || !reflect.DeepEqual(runIDs(list), []int{3, 2}) {
+			t.Fatalf("expected runs [3 2] of 5 but got %v of %d", runIDs(list), list.Total)
+		}
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		_, list := get("status=success&reason=manual&startedafter=2021-01-01T02:00:00Z&startedbefore=2021-01-01T05:00:00Z")
+		if list.Total != 1 || !reflect.DeepEqual(runIDs(list), []int{3}) {
+			t.Fatalf("expected run [3] but got %v of %d", runIDs(list), list.Total)
+		}
+
+		_, list = get("status=failed,success")
+		if list.Total != 5 {
+			t.Fatalf("expected 5 runs but got %d", list.Total)
+		}
+	})
+
+	t.Run("without parameters", func(t *testing.T) {
+		req := httptest.NewRequest(echo.GET, "/api/"+gaia.APIVersion+"/pipelinerun/1", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+		if err := pp.PipelineGetAllRuns(c); err != nil {
+			t.Fatal(err)
+		}
+
+		// All runs are returned as an array
+		var runs []gaia.PipelineRun
+		if err := json.Unmarshal(rec.Body.Bytes(), &runs); err != nil {
+			t.Fatalf("expected an array of runs but got %s: %v", rec.Body.String(), err)
+		}
+		if len(runs) != 5 {
+			t.Fatalf("expected 5 runs but got %d", len(runs))
+		}
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"page=abc", "perpage=abc", "startedafter=yesterday", "startedbefore=1"} {
+			if rec, _ := get(query); rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d for %s but got %d", http.StatusBadRequest, query, rec.Code)
+			}
+		}
+	})
+}
+
+func TestPipelineRerun(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPipelineRerun")
+	defer os.RemoveAll(tmp)
+
+	gaia.Cfg = &gaia.Config{
+		Logger:   hclog.NewNullLogger(),
+		HomePath: tmp,
+		DataPath: tmp,
+	}
+
+	// Initialize store
+	dataStore, err := services.StorageService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { services.MockStorageService(nil) }()
+
+	// Initialize global active pipelines
+	ap := pipeline.NewActivePipelines()
+	pipeline.GlobalActivePipelines = ap
+	ap.Append(gaia.Pipeline{ID: 1, Name: "Pipeline A", Type: gaia.PTypeGolang})
+
+	run := &gaia.PipelineRun{UniqueID: "first-run", ID: 1, PipelineID: 1, Status: gaia.RunFailed}
+	if err := dataStore.PipelinePutRun(run); err != nil {
+		t.Fatal(err)
+	}
+
+	ms := &mockScheduleService{pipelineRun: &gaia.PipelineRun{ID: 2, PipelineID: 1, RerunOf: 1}}
+	pp := NewPipelineProvider(Dependencies{Scheduler: ms})
+	e := echo.New()
+
+	rerun := func(handler echo.HandlerFunc, pipelineID, runID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.POST, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("pipelineid", "runid")
+		c.SetParamValues(pipelineID, runID)
+		_ = handler(c)
+		return rec
+	}
+
+	t.Run("reruns the whole run", func(t *testing.T) {
+		rec := rerun(pp.PipelineRerun, "1", "1")
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected response code %v got %v", http.StatusCreated, rec.Code)
+		}
+		if ms.failedOnly {
+			t.Fatal("expected all jobs to be rerun")
+		}
+		var r gaia.PipelineRun
+		if err := json.Unmarshal(rec.Body.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.RerunOf != 1 {
+			t.Fatalf("expected rerun of 1 but got %d", r.RerunOf)
+		}
+	})
+
+	t.Run("reruns the failed jobs", func(t *testing.T) {
+		rec := rerun(pp.PipelineRerunFailed, "1", "1")
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected response code %v got %v", http.StatusCreated, rec.Code)
+		}
+		if !ms.failedOnly {
+			t.Fatal("expected only failed jobs to be rerun")
+		}
+	})
+
+	t.Run("fails for unknown run or pipeline", func(t *testing.T) {
+		if err := dataStore.PipelinePutRun(&gaia.PipelineRun{UniqueID: "other-run", ID: 1, PipelineID: 2, Status: gaia.RunFailed}); err != nil {
+			t.Fatal(err)
+		}
+		for _, params := range [][]string{{"1", "5"}, {"2", "1"}} {
+			if rec := rerun(pp.PipelineRerun, params[0], params[1]); rec.Code != http.StatusNotFound {
+				t.Fatalf("expected response code %v got %v", http.StatusNotFound, rec.Code)
+			}
+		}
+	})
+
+	t.Run("fails if the run cannot be rerun", func(t *testing.T) {
+		ms.err = errors.New("pipeline run has no failed jobs")
+		rec := rerun(pp.PipelineRerunFailed, "1", "1")
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+		if rec.Body.String() != ms.err.Error() {
+			t.Fatalf("expected body %s but got %s", ms.err.Error(), rec.Body.String())
+		}
+	})
+}
+
+func TestPipelineJobApproval(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPipelineJobApproval")
+	defer os.RemoveAll(tmp)
+
+	gaia.Cfg = &gaia.Config{
+		Logger:   hclog.NewNullLogger(),
+		HomePath: tmp,
+		DataPath: tmp,
+	}
+
+	// Initialize store
+	dataStore, err := services.StorageService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { services.MockStorageService(nil) }()
+
+	// Initialize global active pipelines
+	ap := pipeline.NewActivePipelines()
+	pipeline.GlobalActivePipelines = ap
+	ap.Append(gaia.Pipeline{ID: 1, Name: "Pipeline A", Type: gaia.PTypeGolang})
+
+	run := &gaia.PipelineRun{UniqueID: "first-run", ID: 1, PipelineID: 1, Status: gaia.RunWaitingApproval}
+	if err := dataStore.PipelinePutRun(run); err != nil {
+		t.Fatal(err)
+	}
+
+	ms := &mockScheduleService{}
+	pp := NewPipelineProvider(Dependencies{Scheduler: ms})
+	e := echo.New()
+
+	decide := func(handler echo.HandlerFunc, pipelineID, runID, jobID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.POST, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("pipelineid", "runid", "jobid")
+		c.SetParamValues(pipelineID, runID, jobID)
+		c.Set("username", "admin")
+		_ = handler(c)
+		return rec
+	}
+
+	t.Run("approves the job", func(t *testing.T) {
+		rec := decide(pp.PipelineJobApprove, "1", "1", "42")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		if *ms.approval != (mockApproval{jobID: 42, username: "admin", approved: true}) {
+			t.Fatalf("unexpected approval %#v", ms.approval)
+		}
+	})
+
+	t.Run("rejects the job", func(t *testing.T) {
+		rec := decide(pp.PipelineJobReject, "1", "1", "42")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		if ms.approval.approved {
+			t.Fatal("expected job to be rejected")
+		}
+	})
+
+	t.Run("fails with invalid job id", func(t *testing.T) {
+		if rec := decide(pp.PipelineJobApprove, "1", "1", "job"); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("fails for unknown run or pipeline", func(t *testing.T) {
+		if err := dataStore.PipelinePutRun(&gaia.PipelineRun{UniqueID: "other-run", ID: 1, PipelineID: 2, Status: gaia.RunWaitingApproval}); err != nil {
+			t.Fatal(err)
+		}
+		for _, params := range [][]string{{"1", "5"}, {"2", "1"}} {
+			if rec := decide(pp.PipelineJobApprove, params[0], params[1], "42"); rec.Code != http.StatusNotFound {
+				t.Fatalf("expected response code %v got %v", http.StatusNotFound, rec.Code)
+			}
+		}
+	})
+
+	t.Run("fails if the job is not waiting for approval", func(t *testing.T) {
+		ms.err = errors.New("job is not waiting for approval")
+		rec := decide(pp.PipelineJobApprove, "1", "1", "42")
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+}
+
+func TestPipelineRunQueuePosition(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPipelineRunQueuePosition")
+	defer os.RemoveAll(tmp)
+
+	gaia.Cfg = &gaia.Config{
+		Logger:   hclog.NewNullLogger(),
+		HomePath: tmp,
+		DataPath: tmp,
+	}
+
+	// Initialize store
+	dataStore, err := services.StorageService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { services.MockStorageService(nil) }()
+
+	now := time.Now()
+	runs := []*gaia.PipelineRun{
+		{UniqueID: "nightly", ID: 1, PipelineID: 1, Status: gaia.RunScheduled, ScheduleDate: now.Add(-time.Minute)},
+		{UniqueID: "second-nightly", ID: 2, PipelineID: 1, Status: gaia.RunNotScheduled, ScheduleDate: now},
+		{UniqueID: "hotfix", ID: 1, PipelineID: 2, Status: gaia.RunNotScheduled, ScheduleDate: now, Priority: 10},
+		{UniqueID: "finished", ID: 3, PipelineID: 1, Status: gaia.RunSuccess, ScheduleDate: now},
+	}
+	for _, r := range runs {
+		if err := dataStore.PipelinePutRun(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pp := NewPipelineProvider(Dependencies{})
+	e := echo.New()
+
+	tests := []struct {
+		pipelineID string
+		runID      string
+		code       int
+		position   gaia.RunQueuePosition
+	}{
+		{pipelineID: "2", runID: "1", code: http.StatusOK, position: gaia.RunQueuePosition{Position: 1, Length: 3, Priority: 10}},
+		{pipelineID: "1", runID: "1", code: http.StatusOK, position: gaia.RunQueuePosition{Position: 2, Length: 3}},
+		{pipelineID: "1", runID: "2", code: http.StatusOK, position: gaia.RunQueuePosition{Position: 3, Length: 3}},
+		{pipelineID: "1", runID: "3", code: http.StatusOK, position: gaia.RunQueuePosition{Position: 0, Length: 3}},
+		{pipelineID: "1", runID: "5", code: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("pipelineid", "runid")
+		c.SetParamValues(tt.pipelineID, tt.runID)
+
+		_ = pp.PipelineRunQueuePosition(c)
+
+		if rec.Code != tt.code {
+			t.Fatalf("expected response code %v got %v", tt.code, rec.Code)
+		}
+		if tt.code != http.StatusOK {
+			continue
+		}
+		var position gaia.RunQueuePosition
+		if err := json.Unmarshal(rec.Body.Bytes(), &position); err != nil {
+			t.Fatal(err)
+		}
+		if position != tt.position {
+			t.Fatalf("expected %#v for run %s/%s but got %#v", tt.position, tt.pipelineID, tt.runID, position)
+		}
+	}
+}
+
+func TestStreamLogFileSplitsCompleteRunes(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestStreamLogFileSplitsCompleteRunes")
+	defer os.RemoveAll(tmp)
+
+	runStorage := runstorage.NewLocalStorage(tmp)
+	content := "a€b€"
+	w, err := runStorage.Writer("log", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The last character is incomplete until the next write
+	if _, err := w.Write([]byte(content[:len(content)-1])); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	res := e.NewContext(httptest.NewRequest(echo.GET, "/", nil), rec).Response()
+
+	// The buffer ends within the first euro sign
+	offset, err := streamLogFile(res, runStorage, "log", 0, make([]byte, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != int64(len("a€b")) {
+		t.Fatalf("expected offset %d before the incomplete character but got %d", len("a€b"), offset)
+	}
+
+	if _, err := w.Write([]byte(content[len(content)-1:])); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if offset, err = streamLogFile(res, runStorage, "log", offset, make([]byte, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if offset != int64(len(content)) {
+		t.Fatalf("expected offset %d but got %d", len(content), offset)
+	}
+
+	var chunks string
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event jobLogsEvent
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+			t.Fatal(err)
+		}
+		if !utf8.ValidString(event.Chunk) {
+			t.Fatalf("expected valid UTF-8 chunk but got %q", event.Chunk)
+		}
+		chunks += event.Chunk
+	}
+	if chunks != content {
+		t.Fatalf("expected chunks to form %q but got %q", content, chunks)
+	}
+}
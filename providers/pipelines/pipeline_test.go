@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -27,12 +28,32 @@ type mockScheduleService struct {
 	service.GaiaScheduler
 	pipelineRun *gaia.PipelineRun
 	err         error
+	failedOnly  bool
+	approval    *mockApproval
+	priority    int
+}
+
+type mockApproval struct {
+	jobID    uint32
+	username string
+	approved bool
 }
 
 func (ms *mockScheduleService) SchedulePipeline(p *gaia.Pipeline, startReason string, args []*gaia.Argument) (*gaia.PipelineRun, error) {
+	ms.priority = p.Priority
+	return ms.pipelineRun, ms.err
+}
+
+func (ms *mockScheduleService) RerunPipeline(p *gaia.Pipeline, run *gaia.PipelineRun, failedOnly bool) (*gaia.PipelineRun, error) {
+	ms.failedOnly = failedOnly
 	return ms.pipelineRun, ms.err
 }
 
+func (ms *mockScheduleService) DecideApproval(p *gaia.Pipeline, runID int, jobID uint32, username string, approved bool) error {
+	ms.approval = &mockApproval{jobID: jobID, username: username, approved: approved}
+	return ms.err
+}
+
 func TestPipelineGitLSRemote(t *testing.T) {
 	dataDir, _ := ioutil.TempDir("", "TestPipelineGitLSRemote")
 
@@ -222,6 +243,7 @@ func TestPipelineUpdate(t *testing.T) {
 		Type:              gaia.PTypeGolang,
 		Created:           time.Now(),
 		PeriodicSchedules: []string{"0 30 * * * *"},
+		Jobs:              []*gaia.Job{{ID: 1, Title: "first-job"}},
 	}
 
 	pipeline2 := gaia.Pipeline{
@@ -331,6 +353,551 @@ func TestPipelineUpdate(t *testing.T) {
 			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
 		}
 	})
+
+	t.Run("update timeouts success", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			JobTimeout:        60,
+			RunTimeout:        600,
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		stored, err := dataStore.PipelineGet(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.JobTimeout != 60 || stored.RunTimeout != 600 {
+			t.Fatalf("expected timeouts 60 and 600 but got %d and %d", stored.JobTimeout, stored.RunTimeout)
+		}
+	})
+
+	t.Run("update timeouts failed", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			JobTimeout:        -1,
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("update retry policy success", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Jobs: []*gaia.Job{
+				{ID: 1, Retry: &gaia.RetryPolicy{MaxAttempts: 3, Backoff: 10, InfraErrorsOnly: true}},
+			},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		stored, err := dataStore.PipelineGet(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		retry := stored.Jobs[0].Retry
+		if retry == nil || retry.MaxAttempts != 3 || retry.Backoff != 10 || !retry.InfraErrorsOnly {
+			t.Fatalf("expected stored retry policy but got %#v", retry)
+		}
+	})
+
+	t.Run("update retry policy failed", func(t *testing.T) {
+		for _, retry := range []*gaia.RetryPolicy{
+			{MaxAttempts: -1},
+			{MaxAttempts: 0, Backoff: 10},
+			{MaxAttempts: gaia.RetryMaxAttempts + 1},
+			{MaxAttempts: 2, Backoff: gaia.RetryMaxBackoff + 1},
+		} {
+			p := gaia.Pipeline{
+				ID:                1,
+				Name:              "newname",
+				PeriodicSchedules: []string{"0 */1 * * * *"},
+				Jobs:              []*gaia.Job{{ID: 1, Retry: retry}},
+			}
+			bodyBytes, _ := json.Marshal(p)
+			req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+			c.SetParamNames("pipelineid")
+			c.SetParamValues("1")
+
+			_ = pp.PipelineUpdate(c)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected response code %v for retry policy %#v got %v", http.StatusBadRequest, retry, rec.Code)
+			}
+		}
+	})
+
+	t.Run("update retention policy success", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Retention:         &gaia.RetentionPolicy{KeepRuns: 10, KeepDays: 30},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		stored, err := dataStore.PipelineGet(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Retention == nil || *stored.Retention != *p.Retention {
+			t.Fatalf("expected stored retention policy but got %#v", stored.Retention)
+		}
+	})
+
+	t.Run("update retention policy failed", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Retention:         &gaia.RetentionPolicy{KeepDays: -1},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("update concurrency success", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Concurrency:       &gaia.Concurrency{Group: "deploy", Limit: 1, Policy: gaia.ConcurrencyCancelOlder},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		stored, err := dataStore.PipelineGet(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Concurrency == nil || *stored.Concurrency != *p.Concurrency {
+			t.Fatalf("expected stored concurrency but got %#v", stored.Concurrency)
+		}
+	})
+
+	t.Run("update concurrency failed", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Concurrency:       &gaia.Concurrency{Policy: "wait"},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("update recovery success", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Recovery:          &gaia.Recovery{Policy: gaia.RecoveryReschedule, MaxReschedules: 2},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		stored, err := dataStore.PipelineGet(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Recovery == nil || *stored.Recovery != *p.Recovery {
+			t.Fatalf("expected stored recovery but got %#v", stored.Recovery)
+		}
+	})
+
+	t.Run("update recovery failed", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Recovery:          &gaia.Recovery{Policy: "retry"},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("update notifications success", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Notifications: []*gaia.Notification{
+				{Type: gaia.NotificationSlack, Target: "https://hooks.slack.com/services/T0/B0/X"},
+				{Type: gaia.NotificationEmail, Target: "ops@example.com", Events: []gaia.NotificationEvent{gaia.NotificationEventSucceeded}},
+			},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		stored, err := dataStore.PipelineGet(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(stored.Notifications, p.Notifications) {
+			t.Fatalf("expected stored notifications but got %#v", stored.Notifications)
+		}
+	})
+
+	t.Run("update notifications with masked target", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Notifications: []*gaia.Notification{
+				{Type: gaia.NotificationSlack, Target: "https://hooks.slack.com/**********"},
+				{Type: gaia.NotificationEmail, Target: "dev@example.com"},
+			},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		stored, err := dataStore.PipelineGet(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Notifications[0].Target != "https://hooks.slack.com/services/T0/B0/X" || stored.Notifications[1].Target != "dev@example.com" {
+			t.Fatalf("expected masked target to be kept but got %#v", stored.Notifications)
+		}
+	})
+
+	t.Run("update notifications failed", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Notifications: []*gaia.Notification{
+				{Type: gaia.NotificationWebhook, Target: "not a url"},
+			},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("update parameters success", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Parameters: []*gaia.Parameter{
+				{Name: "env", Type: gaia.ParameterChoice, Default: "staging", Allowed: []string{"staging", "prod"}},
+				{Name: "version", Type: gaia.ParameterString, Required: true},
+			},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		stored, err := dataStore.PipelineGet(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(stored.Parameters, p.Parameters) {
+			t.Fatalf("expected stored parameters but got %#v", stored.Parameters)
+		}
+	})
+
+	t.Run("update parameters failed", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Parameters: []*gaia.Parameter{
+				{Name: "env", Type: gaia.ParameterChoice},
+			},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("get notification deliveries", func(t *testing.T) {
+		delivery := &gaia.NotificationDelivery{PipelineID: 1, RunID: 1, Type: gaia.NotificationSlack, Status: gaia.NotificationDelivered}
+		if err := dataStore.NotificationDeliveryPut(delivery); err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(echo.GET, "/", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid/notifications")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineGetNotificationDeliveries(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		var deliveries []*gaia.NotificationDelivery
+		if err := json.Unmarshal(rec.Body.Bytes(), &deliveries); err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) != 1 || deliveries[0].ID != delivery.ID {
+			t.Fatalf("expected the stored delivery but got %#v", deliveries)
+		}
+	})
+
+	t.Run("update run condition success", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Jobs: []*gaia.Job{
+				{ID: 1, RunCondition: gaia.RunConditionAlways},
+			},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected response code %v got %v", http.StatusOK, rec.Code)
+		}
+		stored, err := dataStore.PipelineGet(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Jobs[0].RunCondition != gaia.RunConditionAlways {
+			t.Fatalf("expected run condition %s but got %s", gaia.RunConditionAlways, stored.Jobs[0].RunCondition)
+		}
+	})
+
+	t.Run("update run condition failed", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Jobs: []*gaia.Job{
+				{ID: 1, RunCondition: "sometimes"},
+			},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
+
+	t.Run("update with invalid setting stores nothing", func(t *testing.T) {
+		p := gaia.Pipeline{
+			ID:                1,
+			Name:              "newname",
+			PeriodicSchedules: []string{"0 */1 * * * *"},
+			Docker:            true,
+			Priority:          5,
+			Recovery:          &gaia.Recovery{Policy: "unknown"},
+		}
+		bodyBytes, _ := json.Marshal(p)
+		req := httptest.NewRequest(echo.PUT, "/", bytes.NewBuffer(bodyBytes))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetPath("/api/" + gaia.APIVersion + "/pipeline/:pipelineid")
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineUpdate(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+		stored, err := dataStore.PipelineGet(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Docker || stored.Priority == 5 {
+			t.Fatalf("expected pipeline not to be updated but got docker %v and priority %d", stored.Docker, stored.Priority)
+		}
+		active := pipeline.GlobalActivePipelines.GetByName("newname")
+		if active == nil || active.Docker || active.Priority == 5 {
+			t.Fatalf("expected active pipeline not to be updated but got %v", active)
+		}
+	})
 }
 
 func TestPipelineDelete(t *testing.T) {
@@ -530,6 +1097,43 @@ func TestPipelineStart(t *testing.T) {
 			t.Fatalf("expected response code %v got %v", http.StatusNotFound, rec.Code)
 		}
 	})
+
+	t.Run("can start a pipeline with priority", func(t *testing.T) {
+		req := httptest.NewRequest(echo.POST, "/api/"+gaia.APIVersion+"/pipeline/:pipelineid/start?priority=10", bytes.NewBufferString(`[{"key":"priority","value":"high"}]`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		scheduler := &mockScheduleService{pipelineRun: &gaia.PipelineRun{ID: 999}}
+		pp := NewPipelineProvider(Dependencies{
+			Scheduler:       scheduler,
+			PipelineService: pipelineService,
+		})
+		_ = pp.PipelineStart(c)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected response code %v got %v", http.StatusCreated, rec.Code)
+		}
+		if scheduler.priority != 10 {
+			t.Fatalf("expected priority 10 but got %d", scheduler.priority)
+		}
+	})
+
+	t.Run("fails with invalid priority", func(t *testing.T) {
+		req := httptest.NewRequest(echo.POST, "/api/"+gaia.APIVersion+"/pipeline/:pipelineid/start?priority=high", nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.SetParamNames("pipelineid")
+		c.SetParamValues("1")
+
+		_ = pp.PipelineStart(c)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected response code %v got %v", http.StatusBadRequest, rec.Code)
+		}
+	})
 }
 
 type mockPipelineService struct {
@@ -11,6 +11,7 @@ import (
 	"github.com/labstack/echo/v4"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/event"
 	"github.com/gaia-pipeline/gaia/security"
 	"github.com/gaia-pipeline/gaia/services"
 )
@@ -127,6 +128,7 @@ func (wp *WorkerProvider) RegisterWorker(c echo.Context) error {
 	if err = db.UpsertWorker(&w, true); err != nil {
 		return c.String(http.StatusInternalServerError, "failed to store worker in memdb/store")
 	}
+	services.EventBus().Publish(event.NewWorkerEvent(event.WorkerRegistered, &w))
 
 	return c.JSON(http.StatusOK, registerResponse{
 		UniqueID: w.UniqueID,
@@ -173,6 +175,12 @@ func (wp *WorkerProvider) DeregisterWorker(c echo.Context) error {
 		return c.String(http.StatusInternalServerError, "failed to delete worker")
 	}
 
+	// Recover the pipeline runs which the worker has been executing
+	if err := wp.deps.Scheduler.RecoverWorkerRuns(w.UniqueID); err != nil {
+		gaia.Cfg.Logger.Error("failed to recover pipeline runs of deregistered worker", "error", err.Error())
+		return c.String(http.StatusInternalServerError, "failed to recover pipeline runs of worker")
+	}
+
 	return c.String(http.StatusOK, "worker has been successfully deregistered")
 }
 
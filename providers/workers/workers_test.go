@@ -17,8 +17,19 @@ import (
 	"github.com/gaia-pipeline/gaia/services"
 	gStore "github.com/gaia-pipeline/gaia/store"
 	"github.com/gaia-pipeline/gaia/workers/scheduler/gaiascheduler"
+	"github.com/gaia-pipeline/gaia/workers/scheduler/service"
 )
 
+type mockScheduler struct {
+	service.GaiaScheduler
+	recovered []string
+}
+
+func (ms *mockScheduler) RecoverWorkerRuns(workerID string) error {
+	ms.recovered = append(ms.recovered, workerID)
+	return nil
+}
+
 type mockStorageService struct {
 	worker gaia.Worker
 	gStore.GaiaStore
@@ -214,7 +225,8 @@ func TestDeregisterWorker(t *testing.T) {
 	ca, _ := security.InitCA()
 	// Initialize echo
 	e := echo.New()
-	wp := NewWorkerProvider(Dependencies{Scheduler: nil, Certificate: ca})
+	ms := &mockScheduler{}
+	wp := NewWorkerProvider(Dependencies{Scheduler: ms, Certificate: ca})
 
 	// Test with non-existing worker
 	t.Run("non-existing worker", func(t *testing.T) {
@@ -291,6 +303,11 @@ func TestDeregisterWorker(t *testing.T) {
 		if worker != nil {
 			t.Fatal("worker has been deregistered but is still in cache/store")
 		}
+
+		// The runs of the worker have been recovered
+		if len(ms.recovered) != 1 || ms.recovered[0] != resp.UniqueID {
+			t.Fatalf("expected runs of worker %s to be recovered but got %v", resp.UniqueID, ms.recovered)
+		}
 	})
 }
 
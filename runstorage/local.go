@@ -0,0 +1,109 @@
+package runstorage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gaia-pipeline/gaia/helper/filehelper"
+)
+
+// LocalStorage stores all objects as files in a local folder.
+type LocalStorage struct {
+	root string
+}
+
+// NewLocalStorage creates a new local storage located in the given folder.
+func NewLocalStorage(root string) *LocalStorage {
+	return &LocalStorage{root: root}
+}
+
+// path returns the path of the file of the object with the given key.
+func (s *LocalStorage) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+// localWriter writes directly into the file of an object.
+type localWriter struct {
+	*os.File
+}
+
+// Flush is a no-op because all data is written to the file directly.
+func (w localWriter) Flush() error {
+	return nil
+}
+
+// Writer implements GaiaRunStorage.
+func (s *LocalStorage) Writer(key string, offset int64) (Writer, error) {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return localWriter{File: f}, nil
+}
+
+// Reader implements GaiaRunStorage.
+func (s *LocalStorage) Reader(key string, offset int64) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Size implements GaiaRunStorage.
+func (s *LocalStorage) Size(key string) (int64, error) {
+	info, err := os.Stat(s.path(key))
+	if os.IsNotExist(err) {
+		return 0, ErrNotExist
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// Put implements GaiaRunStorage.
+// Nothing is copied if the file is already located at the path of the object.
+func (s *LocalStorage) Put(key, filePath string) error {
+	p := s.path(key)
+	src, err := filepath.Abs(filePath)
+	if err != nil {
+		return err
+	}
+	dst, err := filepath.Abs(p)
+	if err != nil {
+		return err
+	}
+	if src == dst {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	return filehelper.CopyFileContents(filePath, p)
+}
+
+// Delete implements GaiaRunStorage.
+func (s *LocalStorage) Delete(prefix string) error {
+	return os.RemoveAll(s.path(prefix))
+}
@@ -0,0 +1,166 @@
+package runstorage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestLocalStorage")
+	defer os.RemoveAll(tmp)
+
+	s := NewLocalStorage(tmp)
+	testStorage(t, s)
+
+	// Objects are stored in the workspace layout
+	if _, err := os.Stat(filepath.Join(tmp, "1", "22", "logs", "output.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Files which are already located at the object path are kept
+	artifactPath := filepath.Join(tmp, "1", "3", "artifacts", "1", "app")
+	if err := os.MkdirAll(filepath.Dir(artifactPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(artifactPath, []byte("binary"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := ArtifactKey(1, 3, 1, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(key, artifactPath); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := ReadAll(s, key); err != nil || string(content) != "binary" {
+		t.Fatalf("expected artifact content 'binary' but got %q, %v", content, err)
+	}
+}
+
+// testStorage verifies the behavior which all run storages have in common.
+func testStorage(t *testing.T, s GaiaRunStorage) {
+	key := LogKey(1, 2, 0)
+
+	if _, err := s.Size(key); err != ErrNotExist {
+		t.Fatalf("expected %v but got %v", ErrNotExist, err)
+	}
+	if _, err := s.Reader(key, 0); err != ErrNotExist {
+		t.Fatalf("expected %v but got %v", ErrNotExist, err)
+	}
+
+	// Write and flush
+	w, err := s.Writer(key, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("first line\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if size, err := s.Size(key); err != nil || size != 11 {
+		t.Fatalf("expected size 11 but got %d, %v", size, err)
+	}
+	if _, err := w.Write([]byte("second line\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Append
+	w, err = Append(s, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("third line\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ReadAll(s, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "first line\nsecond line\nthird line\n" {
+		t.Fatalf("unexpected content %q", content)
+	}
+
+	// Read from offset
+	r, err := s.Reader(key, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err = ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(content) != "second line\nthird line\n" {
+		t.Fatalf("unexpected content from offset %q, %v", content, err)
+	}
+	r, err = s.Reader(key, int64(len("first line\nsecond line\nthird line\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err = ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || len(content) != 0 {
+		t.Fatalf("expected no content at the end but got %q, %v", content, err)
+	}
+
+	// Writing at an offset truncates the rest
+	w, err = s.Writer(key, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("replaced\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if content, err = ReadAll(s, key); err != nil || string(content) != "first line\nreplaced\n" {
+		t.Fatalf("unexpected content after truncate %q, %v", content, err)
+	}
+
+	// Put local file
+	tmp, _ := ioutil.TempDir("", "testStorage")
+	defer os.RemoveAll(tmp)
+	localFile := filepath.Join(tmp, "coverage.out")
+	if err := ioutil.WriteFile(localFile, []byte("coverage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	artifactKey, err := ArtifactKey(1, 2, 1, "reports/coverage.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(artifactKey, localFile); err != nil {
+		t.Fatal(err)
+	}
+	if content, err = ReadAll(s, artifactKey); err != nil || string(content) != "coverage" {
+		t.Fatalf("unexpected artifact content %q, %v", content, err)
+	}
+
+	// Delete all objects of the run
+	otherKey := LogKey(1, 22, 0)
+	w, err = s.Writer(otherKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(RunPrefix(1, 2)); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{key, artifactKey} {
+		if _, err := s.Size(k); err != ErrNotExist {
+			t.Fatalf("expected %s to be deleted but got %v", k, err)
+		}
+	}
+	if size, err := s.Size(otherKey); err != nil || size != 0 {
+		t.Fatalf("expected objects of other runs to be kept but got %d, %v", size, err)
+	}
+}
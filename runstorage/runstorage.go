@@ -0,0 +1,143 @@
+package runstorage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+const (
+	// LocalType is the storage type which keeps everything in the workspace folder.
+	LocalType = "local"
+
+	// S3Type is the storage type which keeps everything in an S3 compatible bucket.
+	S3Type = "s3"
+)
+
+var (
+	// ErrNotExist is returned when the requested object does not exist.
+	ErrNotExist = errors.New("object does not exist")
+
+	// ErrInvalidArtifactName is returned when the name of an artifact
+	// points outside the artifacts folder of its job.
+	ErrInvalidArtifactName = errors.New("invalid artifact name")
+)
+
+// GaiaRunStorage stores the logs and artifacts of pipeline runs.
+// Objects are identified by slash separated keys which are
+// created via LogKey, ArtifactKey and RunPrefix.
+type GaiaRunStorage interface {
+	// Writer opens the object with the given key for writing. The object is
+	// created if it does not exist and truncated to the given offset.
+	// Written data is appended after the offset and can be read right away.
+	// The object is guaranteed to be stored after Close.
+	Writer(key string, offset int64) (Writer, error)
+
+	// Reader opens the object with the given key for reading
+	// starting at the given offset.
+	Reader(key string, offset int64) (io.ReadCloser, error)
+
+	// Size returns the size of the object with the given key.
+	Size(key string) (int64, error)
+
+	// Put stores the local file at the given path under the given key.
+	Put(key, filePath string) error
+
+	// Delete removes all objects located below the given prefix,
+	// e.g. all objects of a pipeline run.
+	Delete(prefix string) error
+}
+
+// Writer writes an object of the run storage.
+type Writer interface {
+	io.WriteCloser
+
+	// Flush stores the data written so far. Storages which upload
+	// objects may defer the upload of small amounts of data until Close.
+	Flush() error
+}
+
+// New creates a new run storage of the given type.
+// Local storages are located in the workspace folder while
+// S3 storages are configured via the storage settings of the config.
+func New(storageType string) (GaiaRunStorage, error) {
+	switch storageType {
+	case "", LocalType:
+		return NewLocalStorage(gaia.Cfg.WorkspacePath), nil
+	case S3Type:
+		return NewS3Storage(S3Options{
+			Endpoint:  gaia.Cfg.Storage.S3Endpoint,
+			Bucket:    gaia.Cfg.Storage.S3Bucket,
+			Region:    gaia.Cfg.Storage.S3Region,
+			AccessKey: gaia.Cfg.Storage.S3AccessKey,
+			SecretKey: gaia.Cfg.Storage.S3SecretKey,
+			Insecure:  gaia.Cfg.Storage.S3Insecure,
+		})
+	default:
+		return nil, fmt.Errorf("unknown run storage type %q", storageType)
+	}
+}
+
+// RunPrefix returns the prefix of the keys of all objects of a pipeline run.
+func RunPrefix(pipelineID, runID int) string {
+	return path.Join(strconv.Itoa(pipelineID), strconv.Itoa(runID))
+}
+
+// LogKey returns the key of the log file of the given job of a pipeline run.
+// A job id of zero returns the key of the combined log file of the pipeline run.
+func LogKey(pipelineID, runID int, jobID uint32) string {
+	if jobID == 0 {
+		return path.Join(RunPrefix(pipelineID, runID), gaia.LogsFolderName, gaia.LogsFileName)
+	}
+	return path.Join(RunPrefix(pipelineID, runID), gaia.LogsFolderName, gaia.JobLogsFileName(jobID))
+}
+
+// ArtifactKey returns the key of the artifact with the given name
+// which has been published by the given job of a pipeline run.
+// An error is returned if the name points outside the artifacts folder of the job.
+func ArtifactKey(pipelineID, runID int, jobID uint32, name string) (string, error) {
+	name, err := CleanArtifactName(name)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(RunPrefix(pipelineID, runID), gaia.ArtifactsFolderName, strconv.FormatUint(uint64(jobID), 10), name), nil
+}
+
+// CleanArtifactName returns the slash separated shortest form of the given artifact name.
+// An error is returned if the name points outside the artifacts folder of its job.
+func CleanArtifactName(name string) (string, error) {
+	name = path.Clean(filepath.ToSlash(name))
+	if name == "." || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return "", ErrInvalidArtifactName
+	}
+	return name, nil
+}
+
+// Append opens the object with the given key for appending.
+// The object is created if it does not exist.
+func Append(s GaiaRunStorage, key string) (Writer, error) {
+	size, err := s.Size(key)
+	if err == ErrNotExist {
+		size = 0
+	} else if err != nil {
+		return nil, err
+	}
+	return s.Writer(key, size)
+}
+
+// ReadAll returns the content of the object with the given key.
+func ReadAll(s GaiaRunStorage, key string) ([]byte, error) {
+	r, err := s.Reader(key, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
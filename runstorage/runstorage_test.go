@@ -0,0 +1,27 @@
+package runstorage
+
+import (
+	"testing"
+)
+
+func TestArtifactKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		err      error
+	}{
+		{name: "app", expected: "1/2/artifacts/3/app"},
+		{name: "reports/../coverage.out", expected: "1/2/artifacts/3/coverage.out"},
+		{name: "", err: ErrInvalidArtifactName},
+		{name: "..", err: ErrInvalidArtifactName},
+		{name: "../../../../etc/passwd", err: ErrInvalidArtifactName},
+		{name: "reports/../../app", err: ErrInvalidArtifactName},
+		{name: "/etc/passwd", err: ErrInvalidArtifactName},
+	}
+	for _, tt := range tests {
+		key, err := ArtifactKey(1, 2, 3, tt.name)
+		if err != tt.err || key != tt.expected {
+			t.Fatalf("expected key %q with error %v for %q but got %q with error %v", tt.expected, tt.err, tt.name, key, err)
+		}
+	}
+}
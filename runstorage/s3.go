@@ -0,0 +1,495 @@
+package runstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Options defines the connection settings of an S3 compatible storage.
+type S3Options struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+
+	// Insecure disables TLS for the connection to the endpoint.
+	Insecure bool
+}
+
+// S3Storage stores all objects in a bucket of an S3 compatible storage.
+type S3Storage struct {
+	client *minio.Client
+	bucket string
+
+	// writers holds the open writers by the key of their object.
+	writersMu sync.Mutex
+	writers   map[string]*s3Writer
+}
+
+// NewS3Storage connects to the given S3 compatible storage.
+// An error is returned if the bucket does not exist.
+func NewS3Storage(opts S3Options) (*S3Storage, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, errors.New("endpoint and bucket of the s3 storage must be set")
+	}
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, ""),
+		Secure: !opts.Insecure,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(context.Background(), opts.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("bucket %q does not exist", opts.Bucket)
+	}
+	return &S3Storage{client: client, bucket: opts.Bucket, writers: make(map[string]*s3Writer)}, nil
+}
+
+// s3PartSize is the size from which the buffered data of a writer is
+// uploaded as a new part of the object. All parts except the last one
+// have at least this size so that they can be composed into one object.
+const s3PartSize = 5 * 1024 * 1024 // 5 MiB
+
+// s3FlushInterval defines how often the buffered data of a writer is
+// uploaded on flush before the part size has been reached.
+var s3FlushInterval = 10 * time.Second
+
+// s3Part is a part of an object written by a s3Writer.
+type s3Part struct {
+	key   string
+	start int64
+	size  int64
+}
+
+// partsPrefix returns the prefix of the parts of the object with the given key.
+func partsPrefix(key string) string {
+	return key + ".parts/"
+}
+
+// partKey returns the key of the part of the object with the given key
+// which starts at the given offset. The offset is padded so that the
+// parts are listed in the order of their offsets.
+func partKey(key string, start int64) string {
+	return fmt.Sprintf("%s%020d", partsPrefix(key), start)
+}
+
+// s3Writer writes an object as a sequence of parts. Objects cannot be
+// modified in S3, therefore the buffered data is uploaded as the last part
+// of the object which is replaced by every further upload until it reached
+// the part size. Closing the writer composes all parts into a single object.
+// Buffered data is served to the readers of this instance.
+type s3Writer struct {
+	mu      sync.Mutex
+	storage *S3Storage
+	key     string
+
+	// start is the offset of the buffered data.
+	start   int64
+	pending []byte
+
+	// uploaded is the number of buffered bytes which are already stored in the last part.
+	uploaded int
+
+	// flushed is the time of the last upload.
+	flushed time.Time
+
+	// stored is true if at least one part of the object has been stored.
+	stored bool
+
+	// closed is true if the writer has been closed.
+	closed bool
+}
+
+// Write implements io.Writer.
+func (w *s3Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, p...)
+	return len(p), nil
+}
+
+// Flush uploads the buffered data once it reached the part size
+// or the flush interval has passed since the last upload.
+func (w *s3Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.pending) < s3PartSize && time.Since(w.flushed) < s3FlushInterval {
+		return nil
+	}
+	return w.upload()
+}
+
+// Close uploads the buffered data and composes all parts into a single object.
+func (w *s3Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.storage.unregister(w)
+
+	if err := w.upload(); err != nil {
+		return err
+	}
+	return w.storage.compact(w.key)
+}
+
+// upload uploads the buffered data as the last part. The part is completed
+// and a new one is started once it reached the part size. An empty part is
+// uploaded for empty objects. The lock must be held.
+func (w *s3Writer) upload() error {
+	if len(w.pending) == w.uploaded && w.stored {
+		return nil
+	}
+	content := bytes.NewReader(w.pending)
+	if _, err := w.storage.client.PutObject(context.Background(), w.storage.bucket, partKey(w.key, w.start), content, int64(len(w.pending)), minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	w.flushed = time.Now()
+	w.stored = true
+	w.uploaded = len(w.pending)
+	if len(w.pending) >= s3PartSize {
+		w.start += int64(len(w.pending))
+		w.pending = w.pending[:0]
+		w.uploaded = 0
+	}
+	return nil
+}
+
+// compact composes the parts of the object with the given key into a single object
+// and removes the parts afterwards.
+func (s *S3Storage) compact(key string) error {
+	parts, err := s.parts(key)
+	if err != nil || len(parts) == 0 {
+		return err
+	}
+
+	ctx := context.Background()
+	dst := minio.CopyDestOptions{Bucket: s.bucket, Object: key}
+	if len(parts) == 1 {
+		_, err = s.client.CopyObject(ctx, dst, minio.CopySrcOptions{Bucket: s.bucket, Object: parts[0].key})
+	} else {
+		srcs := make([]minio.CopySrcOptions, 0, len(parts))
+		for _, part := range parts {
+			srcs = append(srcs, minio.CopySrcOptions{Bucket: s.bucket, Object: part.key})
+		}
+		_, err = s.client.ComposeObject(ctx, dst, srcs...)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, part := range parts {
+		if err := s.client.RemoveObject(ctx, s.bucket, part.key, minio.RemoveObjectOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writer implements GaiaRunStorage.
+// The parts after the given offset are removed. A part which contains the
+// offset or the last part which is smaller than the part size is downloaded
+// and uploaded again together with the new data. An object which has been
+// composed before is continued the same way or copied as the first part.
+func (s *S3Storage) Writer(key string, offset int64) (Writer, error) {
+	parts, err := s.parts(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		parts, err = s.composedPart(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w := &s3Writer{storage: s, key: key, start: offset, flushed: time.Now()}
+	var size int64
+	for i, part := range parts {
+		end := part.start + part.size
+		switch {
+		case part.start >= offset:
+			if err := s.client.RemoveObject(context.Background(), s.bucket, part.key, minio.RemoveObjectOptions{}); err != nil {
+				return nil, err
+			}
+		case end > offset || (i == len(parts)-1 && part.size < s3PartSize):
+			length := part.size
+			if end > offset {
+				length = offset - part.start
+			}
+			buf := &bytes.Buffer{}
+			if err := s.download(part.key, length, buf); err != nil {
+				return nil, err
+			}
+			w.start = part.start
+			w.pending = buf.Bytes()
+			size = part.start + length
+
+			// The whole part is buffered and does not have to be uploaded again
+			if end <= offset && part.key == partKey(key, part.start) {
+				w.uploaded = len(w.pending)
+				w.stored = true
+			}
+		default:
+			size = end
+			w.stored = true
+		}
+	}
+
+	// Fill the gap up to the offset like a truncated file
+	if size < offset {
+		if len(w.pending) == 0 {
+			w.start = size
+		}
+		w.pending = append(w.pending, make([]byte, offset-size)...)
+	}
+
+	s.writersMu.Lock()
+	s.writers[key] = w
+	s.writersMu.Unlock()
+	return w, nil
+}
+
+// composedPart returns the object with the given key which has been composed
+// before as the first part. Objects which reached the part size are copied
+// to the first part so that they do not have to be downloaded.
+func (s *S3Storage) composedPart(key string) ([]s3Part, error) {
+	size, err := s.objectSize(key)
+	if err == ErrNotExist {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if size < s3PartSize {
+		return []s3Part{{key: key, start: 0, size: size}}, nil
+	}
+
+	dst := minio.CopyDestOptions{Bucket: s.bucket, Object: partKey(key, 0)}
+	if _, err := s.client.CopyObject(context.Background(), dst, minio.CopySrcOptions{Bucket: s.bucket, Object: key}); err != nil {
+		return nil, err
+	}
+	return []s3Part{{key: partKey(key, 0), start: 0, size: size}}, nil
+}
+
+// writer returns the open writer of the object with the given key or nil.
+func (s *S3Storage) writer(key string) *s3Writer {
+	s.writersMu.Lock()
+	defer s.writersMu.Unlock()
+	return s.writers[key]
+}
+
+// unregister removes the given writer from the open writers.
+func (s *S3Storage) unregister(w *s3Writer) {
+	s.writersMu.Lock()
+	defer s.writersMu.Unlock()
+	if s.writers[w.key] == w {
+		delete(s.writers, w.key)
+	}
+}
+
+// parts returns the uploaded parts of the object with the given key ordered by their offset.
+func (s *S3Storage) parts(key string) ([]s3Part, error) {
+	prefix := partsPrefix(key)
+	var parts []s3Part
+	for object := range s.client.ListObjects(context.Background(), s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		start, err := strconv.ParseInt(strings.TrimPrefix(object.Key, prefix), 10, 64)
+		if err != nil {
+			continue
+		}
+		parts = append(parts, s3Part{key: object.Key, start: start, size: object.Size})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].start < parts[j].start })
+	return parts, nil
+}
+
+// download copies at most the given number of bytes of the object into the given writer.
+func (s *S3Storage) download(key string, length int64, w io.Writer) error {
+	r, err := s.client.GetObject(context.Background(), s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.CopyN(w, r, length)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// Reader implements GaiaRunStorage.
+// Objects written by a writer are read from their parts followed by
+// the data which is still buffered by an open writer of this instance.
+func (s *S3Storage) Reader(key string, offset int64) (io.ReadCloser, error) {
+	w := s.writer(key)
+	if w != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+	}
+	parts, err := s.parts(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 && w == nil {
+		return s.objectReader(key, offset)
+	}
+
+	r := &multiReadCloser{}
+	for _, part := range parts {
+		if w != nil && part.start >= w.start {
+			break
+		}
+		if part.start+part.size <= offset {
+			continue
+		}
+		opts := minio.GetObjectOptions{}
+		if offset > part.start {
+			if err := opts.SetRange(offset-part.start, 0); err != nil {
+				return nil, err
+			}
+		}
+		object, err := s.client.GetObject(context.Background(), s.bucket, part.key, opts)
+		if err != nil {
+			_ = r.Close()
+			return nil, err
+		}
+		r.readers = append(r.readers, object)
+	}
+	if w != nil && w.start+int64(len(w.pending)) > offset {
+		from := offset - w.start
+		if from < 0 {
+			from = 0
+		}
+		pending := make([]byte, int64(len(w.pending))-from)
+		copy(pending, w.pending[from:])
+		r.readers = append(r.readers, ioutil.NopCloser(bytes.NewReader(pending)))
+	}
+	return r, nil
+}
+
+// objectReader opens the object with the given key which has been stored
+// as a whole for reading starting at the given offset.
+func (s *S3Storage) objectReader(key string, offset int64) (io.ReadCloser, error) {
+	size, err := s.objectSize(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requesting a range after the end of the object is an error in S3
+	if offset >= size {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+	opts := minio.GetObjectOptions{}
+	if offset > 0 {
+		if err := opts.SetRange(offset, 0); err != nil {
+			return nil, err
+		}
+	}
+	return s.client.GetObject(context.Background(), s.bucket, key, opts)
+}
+
+// multiReadCloser reads the given readers one after another.
+type multiReadCloser struct {
+	readers []io.ReadCloser
+	current int
+}
+
+// Read implements io.Reader.
+func (m *multiReadCloser) Read(p []byte) (int, error) {
+	for m.current < len(m.readers) {
+		n, err := m.readers[m.current].Read(p)
+		if err == io.EOF {
+			m.current++
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+	return 0, io.EOF
+}
+
+// Close implements io.Closer.
+func (m *multiReadCloser) Close() error {
+	var err error
+	for _, r := range m.readers {
+		if cerr := r.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Size implements GaiaRunStorage.
+func (s *S3Storage) Size(key string) (int64, error) {
+	if w := s.writer(key); w != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.start + int64(len(w.pending)), nil
+	}
+	parts, err := s.parts(key)
+	if err != nil {
+		return 0, err
+	}
+	if len(parts) > 0 {
+		last := parts[len(parts)-1]
+		return last.start + last.size, nil
+	}
+	return s.objectSize(key)
+}
+
+// objectSize returns the size of the object with the given key which has been stored as a whole.
+func (s *S3Storage) objectSize(key string) (int64, error) {
+	info, err := s.client.StatObject(context.Background(), s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return 0, ErrNotExist
+		}
+		return 0, err
+	}
+	return info.Size, nil
+}
+
+// Put implements GaiaRunStorage.
+func (s *S3Storage) Put(key, filePath string) error {
+	_, err := s.client.FPutObject(context.Background(), s.bucket, key, filePath, minio.PutObjectOptions{})
+	return err
+}
+
+// Delete implements GaiaRunStorage.
+func (s *S3Storage) Delete(prefix string) error {
+	ctx := context.Background()
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix + "/", Recursive: true}) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if err := s.client.RemoveObject(ctx, s.bucket, object.Key, minio.RemoveObjectOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,353 @@
+package runstorage
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is a minimal in-memory stand-in for an S3 compatible
+// storage like MinIO which serves a single bucket.
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+
+	// uploads holds the parts of the multipart uploads by their upload id.
+	uploads map[string]map[int][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if parts[0] != f.bucket {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := ""
+	if len(parts) == 2 {
+		key = parts[1]
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case key == "" && r.Method == http.MethodGet:
+		f.list(w, r.URL.Query())
+	case r.Method == http.MethodPost:
+		f.multipart(w, r, key)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		f.copy(w, r, key)
+	case r.Method == http.MethodPut:
+		body, err := readBody(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = body
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		content, ok := f.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				fmt.Fprintf(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message><Key>%s</Key></Error>`, key)
+			}
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		status := http.StatusOK
+		if rng := r.Header.Get("Range"); rng != "" {
+			start, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, len(content)-1, len(content)))
+			content = content[start:]
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(content)
+		}
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// copy copies the requested source object or a range of it into
+// the given object or into a part of a multipart upload.
+func (f *fakeS3) copy(w http.ResponseWriter, r *http.Request, key string) {
+	source, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	content, ok := f.objects[strings.TrimPrefix(strings.TrimPrefix(source, "/"), f.bucket+"/")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if rng := r.Header.Get("X-Amz-Copy-Source-Range"); rng != "" {
+		bounds := strings.SplitN(strings.TrimPrefix(rng, "bytes="), "-", 2)
+		start, _ := strconv.Atoi(bounds[0])
+		end, _ := strconv.Atoi(bounds[1])
+		content = content[start : end+1]
+	}
+	content = append([]byte(nil), content...)
+
+	lastModified := time.Now().UTC().Format(time.RFC3339)
+	w.Header().Set("Content-Type", "application/xml")
+	if uploadID := r.URL.Query().Get("uploadId"); uploadID != "" {
+		number, _ := strconv.Atoi(r.URL.Query().Get("partNumber"))
+		f.uploads[uploadID][number] = content
+		fmt.Fprintf(w, `<CopyPartResult><ETag>"etag"</ETag><LastModified>%s</LastModified></CopyPartResult>`, lastModified)
+		return
+	}
+	f.objects[key] = content
+	fmt.Fprintf(w, `<CopyObjectResult><ETag>"etag"</ETag><LastModified>%s</LastModified></CopyObjectResult>`, lastModified)
+}
+
+// multipart initiates or completes a multipart upload of the given object.
+func (f *fakeS3) multipart(w http.ResponseWriter, r *http.Request, key string) {
+	w.Header().Set("Content-Type", "application/xml")
+	query := r.URL.Query()
+	if _, ok := query["uploads"]; ok {
+		uploadID := strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[uploadID] = make(map[int][]byte)
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, f.bucket, key, uploadID)
+		return
+	}
+
+	parts := f.uploads[query.Get("uploadId")]
+	var content []byte
+	for number := 1; number <= len(parts); number++ {
+		content = append(content, parts[number]...)
+	}
+	f.objects[key] = content
+	delete(f.uploads, query.Get("uploadId"))
+	fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`, f.bucket, key)
+}
+
+// list returns all objects with the requested prefix.
+func (f *fakeS3) list(w http.ResponseWriter, query url.Values) {
+	type content struct {
+		Key  string
+		Size int
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		IsTruncated bool
+		Contents    []content
+	}{Name: f.bucket, Prefix: query.Get("prefix")}
+	for key, data := range f.objects {
+		if strings.HasPrefix(key, result.Prefix) {
+			result.Contents = append(result.Contents, content{Key: key, Size: len(data)})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	result.KeyCount = len(result.Contents)
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+// readBody returns the uploaded object. Uploads via plain HTTP
+// are sent with the aws-chunked encoding of signature version 4.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Header.Get("X-Amz-Content-Sha256") != "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+		return ioutil.ReadAll(r.Body)
+	}
+	var body []byte
+	reader := bufio.NewReader(r.Body)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.ParseInt(strings.SplitN(header, ";", 2)[0], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return body, nil
+		}
+		body = append(body, chunk[:size]...)
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	fake := &fakeS3{bucket: "gaia", objects: make(map[string][]byte), uploads: make(map[string]map[int][]byte)}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	opts := S3Options{
+		Endpoint:  strings.TrimPrefix(ts.URL, "http://"),
+		Bucket:    "gaia",
+		Region:    "us-east-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+		Insecure:  true,
+	}
+	s, err := NewS3Storage(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, s)
+
+	// Written data is read from the buffer until the flush interval has passed
+	key := LogKey(2, 1, 0)
+	w, err := s.Writer(key, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("buffered")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.objects[partKey(key, 0)]; ok {
+		t.Fatal("expected object not to be uploaded before the flush interval has passed")
+	}
+	if content, err := ReadAll(s, key); err != nil || string(content) != "buffered" {
+		t.Fatalf("expected buffered content but got %q (%v)", content, err)
+	}
+
+	// Every flush replaces the last part until it reached the part size
+	defer func(interval time.Duration) { s3FlushInterval = interval }(s3FlushInterval)
+	s3FlushInterval = 0
+	for _, data := range []string{"", "!"} {
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if string(fake.objects[partKey(key, 0)]) != "buffered"+data || len(fake.objects) != 2 {
+			t.Fatalf("expected a single part with the flushed content but got %v", fake.objects)
+		}
+	}
+
+	// Closing composes the parts into a single object
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertObject := func(content string) {
+		t.Helper()
+		for k := range fake.objects {
+			if strings.HasPrefix(k, partsPrefix(key)) {
+				t.Fatalf("expected parts to be removed but found %s", k)
+			}
+		}
+		if string(fake.objects[key]) != content {
+			t.Fatalf("expected object %q but got %q", content, fake.objects[key])
+		}
+	}
+	assertObject("buffered!")
+
+	// Appending continues the object
+	w, err = Append(s, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(" more")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertObject("buffered! more")
+	r, err := s.Reader(key, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadAll(r)
+	_ = r.Close()
+	if string(content) != "ered! more" {
+		t.Fatalf("expected content from offset but got %q", content)
+	}
+
+	// Truncating keeps the data before the offset
+	w, err = s.Writer(key, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("!")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertObject("buffered! m!")
+
+	// Parts which reached the part size are completed
+	large := strings.Repeat("a", s3PartSize)
+	w, err = s.Writer(key, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(large)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.objects[partKey(key, 0)]) != s3PartSize || string(fake.objects[partKey(key, s3PartSize)]) != "b" {
+		t.Fatal("expected a completed part followed by the last part")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertObject(large + "b")
+
+	// Large objects are continued without downloading them
+	w, err = Append(s, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("c")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertObject(large + "bc")
+	if err := s.Delete(RunPrefix(2, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Size(key); err != ErrNotExist {
+		t.Fatalf("expected all parts to be deleted but got %v", err)
+	}
+
+	// Missing buckets are detected
+	opts.Bucket = "missing"
+	if _, err := NewS3Storage(opts); err == nil {
+		t.Fatal("expected error for missing bucket")
+	}
+}
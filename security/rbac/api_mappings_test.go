@@ -55,6 +55,11 @@ func Test_RBACAPIMappings(t *testing.T) {
 			method:       http.MethodGet,
 			expectedPerm: "pipelines/get",
 		},
+		{
+			path:         "/api/v1/pipeline/:pipelineid/notifications",
+			method:       http.MethodGet,
+			expectedPerm: "pipelines/get",
+		},
 		{
 			path:         "/api/v1/pipeline/:pipelineid",
 			method:       http.MethodPut,
@@ -85,6 +90,26 @@ func Test_RBACAPIMappings(t *testing.T) {
 			method:       http.MethodPost,
 			expectedPerm: "pipelines:runs/stop",
 		},
+		{
+			path:         "/api/v1/pipelinerun/:pipelineid/:runid/rerun",
+			method:       http.MethodPost,
+			expectedPerm: "pipelines:runs/rerun",
+		},
+		{
+			path:         "/api/v1/pipelinerun/:pipelineid/:runid/rerun-failed",
+			method:       http.MethodPost,
+			expectedPerm: "pipelines:runs/rerun",
+		},
+		{
+			path:         "/api/v1/pipelinerun/:pipelineid/:runid/job/:jobid/approve",
+			method:       http.MethodPost,
+			expectedPerm: "pipelines:runs/approve",
+		},
+		{
+			path:         "/api/v1/pipelinerun/:pipelineid/:runid/job/:jobid/reject",
+			method:       http.MethodPost,
+			expectedPerm: "pipelines:runs/approve",
+		},
 		{
 			path:         "/api/v1/pipelinerun/:pipelineid/:runid/latest",
 			method:       http.MethodGet,
@@ -95,11 +120,36 @@ func Test_RBACAPIMappings(t *testing.T) {
 			method:       http.MethodGet,
 			expectedPerm: "pipelines:runs/get-run",
 		},
+		{
+			path:         "/api/v1/pipelinerun/:pipelineid/:runid/queue",
+			method:       http.MethodGet,
+			expectedPerm: "pipelines:runs/get-run",
+		},
 		{
 			path:         "/api/v1/pipelinerun/:pipelineid/:runid/log",
 			method:       http.MethodGet,
 			expectedPerm: "pipelines:runs/get-run",
 		},
+		{
+			path:         "/api/v1/pipelinerun/:pipelineid/:runid/log/stream",
+			method:       http.MethodGet,
+			expectedPerm: "pipelines:runs/get-run",
+		},
+		{
+			path:         "/api/v1/pipelinerun/:pipelineid/:runid/job/:jobid/log",
+			method:       http.MethodGet,
+			expectedPerm: "pipelines:runs/get-run",
+		},
+		{
+			path:         "/api/v1/pipelinerun/:pipelineid/:runid/artifacts",
+			method:       http.MethodGet,
+			expectedPerm: "pipelines:runs/get-run",
+		},
+		{
+			path:         "/api/v1/pipelinerun/:pipelineid/:runid/artifacts/:jobid/*",
+			method:       http.MethodGet,
+			expectedPerm: "pipelines:runs/get-run",
+		},
 		{
 			path:         "/api/v1/pipelinerun/:pipelineid/latest",
 			method:       http.MethodGet,
@@ -210,6 +260,31 @@ func Test_RBACAPIMappings(t *testing.T) {
 			method:       http.MethodPut,
 			expectedPerm: "settings/update",
 		},
+		{
+			path:         "/api/v1/settings/retention",
+			method:       http.MethodGet,
+			expectedPerm: "settings/get",
+		},
+		{
+			path:         "/api/v1/settings/retention",
+			method:       http.MethodPut,
+			expectedPerm: "settings/update",
+		},
+		{
+			path:         "/api/v1/admin/drain",
+			method:       http.MethodPost,
+			expectedPerm: "admin/drain",
+		},
+		{
+			path:         "/api/v1/admin/drain",
+			method:       http.MethodDelete,
+			expectedPerm: "admin/resume",
+		},
+		{
+			path:         "/api/v1/events",
+			method:       http.MethodGet,
+			expectedPerm: "events/subscribe",
+		},
 		{
 			path:         "/api/v1/rbac/roles",
 			method:       http.MethodGet,
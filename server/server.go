@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/golang-jwt/jwt"
@@ -19,6 +21,7 @@ import (
 
 	"github.com/gaia-pipeline/gaia"
 	"github.com/gaia-pipeline/gaia/handlers"
+	"github.com/gaia-pipeline/gaia/metrics"
 	"github.com/gaia-pipeline/gaia/plugin"
 	"github.com/gaia-pipeline/gaia/providers/pipelines"
 	rbacProvider "github.com/gaia-pipeline/gaia/providers/rbac"
@@ -28,6 +31,7 @@ import (
 	"github.com/gaia-pipeline/gaia/security/rbac"
 	"github.com/gaia-pipeline/gaia/services"
 	"github.com/gaia-pipeline/gaia/store"
+	"github.com/gaia-pipeline/gaia/tracing"
 	"github.com/gaia-pipeline/gaia/workers/agent"
 	"github.com/gaia-pipeline/gaia/workers/pipeline"
 	"github.com/gaia-pipeline/gaia/workers/scheduler/gaiascheduler"
@@ -45,6 +49,9 @@ const (
 	dataFolder      = "data"
 	pipelinesFolder = "pipelines"
 	workspaceFolder = "workspace"
+
+	// tracingShutdownTimeout is the maximum time to export pending spans on exit.
+	tracingShutdownTimeout = 5 * time.Second
 )
 
 var fs *flag.FlagSet
@@ -85,6 +92,26 @@ func init() {
 	fs.StringVar(&gaia.Cfg.DockerWorkerGRPCHostURL, "docker-worker-grpc-host-url", "127.0.0.1:8989", "The host url of the primary/worker gRPC endpoint used for docker worker communication")
 	fs.BoolVar(&gaia.Cfg.RBACEnabled, "rbac-enabled", false, "Force RBAC to be enabled. Takes priority over value saved within the database")
 	fs.BoolVar(&gaia.Cfg.RBACDebug, "rbac-debug", false, "Enable RBAC debug logging.")
+	fs.DurationVar(&gaia.Cfg.JobTimeout, "job-timeout", 0, "Default maximum duration of a single job (e.g. 30m). Can be overwritten per pipeline. Zero means no timeout")
+	fs.DurationVar(&gaia.Cfg.RunTimeout, "run-timeout", 0, "Default maximum duration of a whole pipeline run (e.g. 2h). Can be overwritten per pipeline. Zero means no timeout")
+	fs.StringVar(&gaia.Cfg.RecoveryPolicyRaw, "recovery-policy", "fail", "What happens with pipeline runs which have been interrupted by a restart. Possible options are fail and reschedule")
+	fs.DurationVar(&gaia.Cfg.ShutdownGracePeriod, "shutdown-grace-period", 30*time.Second, "Maximum duration to wait on exit for running pipeline runs to finish before they are cancelled")
+	fs.StringVar(&gaia.Cfg.Store.Type, "store", "bolt", "The storage backend which is used to store all data. Possible options are bolt, sqlite and postgres")
+	fs.StringVar(&gaia.Cfg.Store.DSN, "store-dsn", "", "The data source name used to connect to the sqlite or postgres database. Defaults to a sqlite database file in the data folder")
+	fs.StringVar(&gaia.Cfg.Storage.Type, "storage", "local", "The storage backend which is used to store logs and artifacts of pipeline runs. Possible options are local and s3")
+	fs.StringVar(&gaia.Cfg.Storage.S3Endpoint, "storage-s3-endpoint", "", "Address (host:port) of the S3 compatible storage")
+	fs.StringVar(&gaia.Cfg.Storage.S3Bucket, "storage-s3-bucket", "", "Name of the existing bucket in the S3 compatible storage")
+	fs.StringVar(&gaia.Cfg.Storage.S3Region, "storage-s3-region", "", "Region of the bucket in the S3 compatible storage")
+	fs.StringVar(&gaia.Cfg.Storage.S3AccessKey, "storage-s3-access-key", "", "Access key used to authenticate at the S3 compatible storage")
+	fs.StringVar(&gaia.Cfg.Storage.S3SecretKey, "storage-s3-secret-key", "", "Secret key used to authenticate at the S3 compatible storage")
+	fs.BoolVar(&gaia.Cfg.Storage.S3Insecure, "storage-s3-insecure", false, "If true, the S3 compatible storage is accessed without TLS")
+	fs.StringVar(&gaia.Cfg.SMTP.Addr, "smtp-addr", "", "Address (host:port) of the SMTP server used to send email notifications")
+	fs.StringVar(&gaia.Cfg.SMTP.Username, "smtp-username", "", "Username used to authenticate at the SMTP server. Authentication is disabled if empty")
+	fs.StringVar(&gaia.Cfg.SMTP.Password, "smtp-password", "", "Password used to authenticate at the SMTP server")
+	fs.StringVar(&gaia.Cfg.SMTP.From, "smtp-from", "gaia@localhost", "Sender address of email notifications")
+	fs.BoolVar(&gaia.Cfg.MetricsEnabled, "metrics-enabled", false, "If true, exposes Prometheus metrics at /metrics without authentication. Worker agents additionally expose their slot usage and last contact with the primary instance")
+	fs.StringVar(&gaia.Cfg.TracingEndpoint, "tracing-endpoint", "", "Address (host:port) of the OpenTelemetry collector which receives traces via OTLP/gRPC. Tracing is disabled if empty")
+	fs.BoolVar(&gaia.Cfg.TracingInsecure, "tracing-insecure", false, "If true, traces are sent to the OpenTelemetry collector without TLS")
 
 	// Default values
 	gaia.Cfg.Bolt.Mode = 0600
@@ -123,6 +150,15 @@ func Start() (err error) {
 		return errors.New("unsupported mode used")
 	}
 
+	// Determine what happens with interrupted pipeline runs
+	switch gaia.RecoveryPolicy(gaia.Cfg.RecoveryPolicyRaw) {
+	case gaia.RecoveryFail, gaia.RecoveryReschedule:
+		gaia.Cfg.RecoveryPolicy = gaia.RecoveryPolicy(gaia.Cfg.RecoveryPolicyRaw)
+	default:
+		gaia.Cfg.Logger.Error("unsupported recovery policy used", "policy", gaia.Cfg.RecoveryPolicyRaw)
+		return errors.New("unsupported recovery policy used")
+	}
+
 	// Find path for gaia home folder if not given by parameter
 	if gaia.Cfg.HomePath == "" {
 		// Find executable path
@@ -169,12 +205,28 @@ func Start() (err error) {
 		return
 	}
 
+	// Initialize tracing
+	shutdownTracing := func(context.Context) error { return nil }
+	if gaia.Cfg.TracingEndpoint != "" {
+		shutdownTracing, err = tracing.Init(context.Background(), gaia.Cfg.TracingEndpoint, gaia.Cfg.TracingInsecure)
+		if err != nil {
+			gaia.Cfg.Logger.Error("cannot initialize tracing", "error", err.Error())
+			return
+		}
+	}
+
 	// Initialize store
 	store, err := services.StorageService()
 	if err != nil {
 		return
 	}
 
+	// Initialize run storage for logs and artifacts
+	runStorage, err := services.RunStorageService()
+	if err != nil {
+		return
+	}
+
 	// Initialize MemDB
 	db, err := services.MemDBService(store)
 	if err != nil {
@@ -242,12 +294,17 @@ func Start() (err error) {
 		}
 	}
 
+	// Initialize the event bus
+	eventBus := services.EventBus()
+
 	schedulerService, err := gaiascheduler.NewScheduler(gaiascheduler.Dependencies{
-		Store: store,
-		DB:    db,
-		CA:    ca,
-		PS:    &plugin.GoPlugin{},
-		Vault: v,
+		Store:   store,
+		DB:      db,
+		CA:      ca,
+		PS:      &plugin.GoPlugin{},
+		Vault:   v,
+		Events:  eventBus,
+		Storage: runStorage,
 	})
 	if err != nil {
 		gaia.Cfg.Logger.Error("cannot initialize scheduler", "error", err.Error())
@@ -255,6 +312,21 @@ func Start() (err error) {
 	}
 	// initiate the ticker
 	schedulerService.Init()
+
+	// Collect metrics
+	if gaia.Cfg.MetricsEnabled {
+		metricsCollector := metrics.NewCollector(metrics.Dependencies{
+			Scheduler:   schedulerService,
+			BufferLimit: gaiascheduler.SchedulerBufferLimit,
+			DB:          db,
+			Store:       store,
+		})
+		if err = metrics.Register(metricsCollector); err != nil {
+			gaia.Cfg.Logger.Error("cannot register metrics collector", "error", err.Error())
+			return err
+		}
+		metricsCollector.Start(eventBus)
+	}
 	pipelineService := pipeline.NewGaiaPipelineService(pipeline.Dependencies{
 		Scheduler: schedulerService,
 	})
@@ -288,6 +360,7 @@ func Start() (err error) {
 		Certificate:      ca,
 		RBACService:      rbacService,
 		Store:            store,
+		Events:           eventBus,
 	})
 
 	err = handlerService.InitHandlers(echoInstance)
@@ -306,6 +379,7 @@ func Start() (err error) {
 	// We need this in both modes (server and worker) for docker worker to run.
 	workerServer := server.InitWorkerServer(server.Dependencies{
 		Certificate: ca,
+		Scheduler:   schedulerService,
 	})
 	go func() {
 		if err := workerServer.Start(); err != nil {
@@ -320,6 +394,14 @@ func Start() (err error) {
 		// Start ticker. Periodic job to check for new plugins.
 		pipelineService.InitTicker()
 
+		// Send notifications for finished pipeline runs
+		notificationService, err := services.NotificationService()
+		if err != nil {
+			gaia.Cfg.Logger.Error("cannot initialize notification service", "error", err.Error())
+			return err
+		}
+		notificationService.Start(eventBus)
+
 		// Start API server
 		go func() {
 			err := echoInstance.Start(":" + gaia.Cfg.ListenPort)
@@ -329,6 +411,18 @@ func Start() (err error) {
 			}
 		}()
 	case gaia.ModeWorker:
+		// Collect agent metrics
+		if gaia.Cfg.MetricsEnabled {
+			if err = metrics.Register(metrics.NewAgentCollector(schedulerService)); err != nil {
+				gaia.Cfg.Logger.Error("cannot register agent metrics collector", "error", err.Error())
+				return err
+			}
+			if err = metrics.Register(metrics.AgentLastContact); err != nil {
+				gaia.Cfg.Logger.Error("cannot register agent last contact metric", "error", err.Error())
+				return err
+			}
+		}
+
 		// Start API server
 		go func() {
 			err := echoInstance.Start(":" + gaia.Cfg.ListenPort)
@@ -353,8 +447,27 @@ func Start() (err error) {
 	<-exitChan
 	gaia.Cfg.Logger.Info("exit signal received. Exiting...")
 
+	// Let the running pipeline runs finish. A second exit signal exits right away.
+	shutdownDone := make(chan struct{})
+	go func() {
+		schedulerService.Shutdown(gaia.Cfg.ShutdownGracePeriod)
+		close(shutdownDone)
+	}()
+	select {
+	case <-shutdownDone:
+	case <-exitChan:
+		gaia.Cfg.Logger.Warn("second exit signal received. Running pipeline runs are not finished")
+	}
+
 	// Run clean up func
 	cleanUpFunc()
+
+	// Export all pending spans
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		gaia.Cfg.Logger.Error("failed to export pending spans", "error", err.Error())
+	}
 	return
 }
 
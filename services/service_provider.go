@@ -4,6 +4,9 @@ import (
 	"reflect"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/event"
+	"github.com/gaia-pipeline/gaia/notification"
+	"github.com/gaia-pipeline/gaia/runstorage"
 	"github.com/gaia-pipeline/gaia/security"
 	"github.com/gaia-pipeline/gaia/store"
 	"github.com/gaia-pipeline/gaia/store/memdb"
@@ -19,6 +22,15 @@ var vaultService security.GaiaVault
 // memDBService is an instance of the internal memdb.
 var memDBService memdb.GaiaMemDB
 
+// notificationService is an instance of the notification dispatcher.
+var notificationService *notification.Dispatcher
+
+// eventBus is the instance of the internal event bus.
+var eventBus *event.Bus
+
+// runStorageService is an instance of the run storage.
+var runStorageService runstorage.GaiaRunStorage
+
 // StorageService initializes and keeps track of a storage service.
 // If the internal storage service is a singleton. This function retruns an error
 // but most of the times we don't care about it, because it's only ever
@@ -28,8 +40,13 @@ func StorageService() (store.GaiaStore, error) {
 	if storeService != nil && !reflect.ValueOf(storeService).IsNil() {
 		return storeService, nil
 	}
-	storeService = store.NewBoltStore()
-	err := storeService.Init(gaia.Cfg.DataPath)
+	s, err := store.New(gaia.Cfg.Store.Type, gaia.Cfg.Store.DSN)
+	if err != nil {
+		gaia.Cfg.Logger.Error("cannot create store", "error", err.Error())
+		return nil, err
+	}
+	storeService = s
+	err = storeService.Init(gaia.Cfg.DataPath)
 	if err != nil {
 		gaia.Cfg.Logger.Error("cannot initialize store", "error", err.Error())
 		return storeService, err
@@ -106,3 +123,61 @@ func MemDBService(store store.GaiaStore) (memdb.GaiaMemDB, error) {
 func MockMemDBService(db memdb.GaiaMemDB) {
 	memDBService = db
 }
+
+// NotificationService initializes and keeps track of the notification dispatcher.
+func NotificationService() (*notification.Dispatcher, error) {
+	if notificationService != nil {
+		return notificationService, nil
+	}
+
+	s, err := StorageService()
+	if err != nil {
+		return nil, err
+	}
+	notificationService = notification.NewDispatcher(s)
+	return notificationService, nil
+}
+
+// MockNotificationService sets the internal notification dispatcher singleton.
+func MockNotificationService(d *notification.Dispatcher) {
+	notificationService = d
+}
+
+// EventBus initializes and keeps track of the internal event bus.
+func EventBus() *event.Bus {
+	if eventBus == nil {
+		eventBus = event.NewBus()
+	}
+	return eventBus
+}
+
+// MockEventBus sets the internal event bus singleton.
+func MockEventBus(bus *event.Bus) {
+	eventBus = bus
+}
+
+// RunStorageService initializes and keeps track of the storage for logs and artifacts.
+// Workers always use the local storage because they ship all logs and artifacts
+// from their workspace to the primary instance. The local storage is stateless and
+// always follows the configured workspace path.
+func RunStorageService() (runstorage.GaiaRunStorage, error) {
+	if runStorageService != nil && !reflect.ValueOf(runStorageService).IsNil() {
+		return runStorageService, nil
+	}
+	if gaia.Cfg.Mode == gaia.ModeWorker || gaia.Cfg.Storage.Type == "" || gaia.Cfg.Storage.Type == runstorage.LocalType {
+		return runstorage.NewLocalStorage(gaia.Cfg.WorkspacePath), nil
+	}
+
+	s, err := runstorage.New(gaia.Cfg.Storage.Type)
+	if err != nil {
+		gaia.Cfg.Logger.Error("cannot initialize run storage", "error", err.Error())
+		return nil, err
+	}
+	runStorageService = s
+	return runStorageService, nil
+}
+
+// MockRunStorageService sets the internal run storage singleton.
+func MockRunStorageService(s runstorage.GaiaRunStorage) {
+	runStorageService = s
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// casbinRuleFields is the maximum number of values of a single casbin rule.
+const casbinRuleFields = 6
+
+// sqlCasbinAdapter is a casbin adapter which stores the policies in the
+// casbin_rules table of the SQL store. It only supports auto-save.
+type sqlCasbinAdapter struct {
+	store         *SQLStore
+	builtinPolicy string
+}
+
+// newSQLCasbinAdapter creates a new casbin adapter for the given SQL store. The builtin
+// policy is a casbin csv policy definition which is always loaded in addition.
+func newSQLCasbinAdapter(s *SQLStore, builtinPolicy string) *sqlCasbinAdapter {
+	return &sqlCasbinAdapter{
+		store:         s,
+		builtinPolicy: builtinPolicy,
+	}
+}
+
+// LoadPolicy loads the builtin policy and all stored rules into the casbin model.
+func (a *sqlCasbinAdapter) LoadPolicy(m model.Model) error {
+	for _, line := range strings.Split(a.builtinPolicy, "\n") {
+		persist.LoadPolicyLine(strings.TrimSpace(line), m)
+	}
+
+	rows, err := a.store.db.Query(`SELECT ptype, v0, v1, v2, v3, v4, v5 FROM casbin_rules`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rule := make([]string, casbinRuleFields+1)
+		if err := rows.Scan(&rule[0], &rule[1], &rule[2], &rule[3], &rule[4], &rule[5], &rule[6]); err != nil {
+			return err
+		}
+
+		// Strip the unused trailing values
+		for len(rule) > 1 && rule[len(rule)-1] == "" {
+			rule = rule[:len(rule)-1]
+		}
+		persist.LoadPolicyArray(rule, m)
+	}
+	return rows.Err()
+}
+
+// SavePolicy is not supported for this adapter. Auto-save should be used.
+func (a *sqlCasbinAdapter) SavePolicy(m model.Model) error {
+	return errors.New("not supported: must use auto-save with this adapter")
+}
+
+// AddPolicy inserts a rule. Already existing rules are ignored.
+func (a *sqlCasbinAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return a.AddPolicies(sec, ptype, [][]string{rule})
+}
+
+// AddPolicies inserts multiple rules in a single transaction.
+func (a *sqlCasbinAdapter) AddPolicies(sec string, ptype string, rules [][]string) error {
+	tx, err := a.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := a.store.rebind(`INSERT INTO casbin_rules (ptype, v0, v1, v2, v3, v4, v5)
+		VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`)
+	for _, rule := range rules {
+		values, err := casbinRuleValues(ptype, rule)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(query, values...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RemovePolicy removes the given rule.
+func (a *sqlCasbinAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return a.RemovePolicies(sec, ptype, [][]string{rule})
+}
+
+// RemovePolicies removes multiple rules in a single transaction.
+func (a *sqlCasbinAdapter) RemovePolicies(sec string, ptype string, rules [][]string) error {
+	tx, err := a.store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := a.store.rebind(`DELETE FROM casbin_rules
+		WHERE ptype = ? AND v0 = ? AND v1 = ? AND v2 = ? AND v3 = ? AND v4 = ? AND v5 = ?`)
+	for _, rule := range rules {
+		values, err := casbinRuleValues(ptype, rule)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(query, values...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RemoveFilteredPolicy removes all rules which match the given field values
+// starting at the given field index. Empty field values match every value.
+func (a *sqlCasbinAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	if fieldIndex < 0 || fieldIndex+len(fieldValues) > casbinRuleFields {
+		return fmt.Errorf("invalid policy filter: field index %d with %d values", fieldIndex, len(fieldValues))
+	}
+
+	query := "DELETE FROM casbin_rules WHERE ptype = ?"
+	args := []interface{}{ptype}
+	for i, v := range fieldValues {
+		if v == "" {
+			continue
+		}
+		query += fmt.Sprintf(" AND v%d = ?", fieldIndex+i)
+		args = append(args, v)
+	}
+	return a.store.exec(query, args...)
+}
+
+// casbinRuleValues converts the given rule into the column values of the casbin_rules table.
+func casbinRuleValues(ptype string, rule []string) ([]interface{}, error) {
+	if len(rule) > casbinRuleFields {
+		return nil, fmt.Errorf("policy rule has too many values: %d", len(rule))
+	}
+
+	values := make([]interface{}, casbinRuleFields+1)
+	values[0] = ptype
+	for i := 0; i < casbinRuleFields; i++ {
+		values[i+1] = ""
+		if i < len(rule) {
+			values[i+1] = rule[i]
+		}
+	}
+	return values, nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
 	"github.com/gaia-pipeline/gaia/helper/stringhelper"
 	"github.com/gaia-pipeline/gaia/store"
 	"github.com/gaia-pipeline/gaia/workers/docker"
@@ -52,13 +53,17 @@ type GaiaMemDB interface {
 	// InsertPipelineRun inserts a pipeline run in the memdb.
 	InsertPipelineRun(p *gaia.PipelineRun) error
 
-	// PopPipelineRun gets the oldest pipeline run by tags and removes it immediately
+	// PopPipelineRun gets the pipeline run with the highest priority by tags and removes it immediately
 	// from the memdb.
 	PopPipelineRun(tags []string) (*gaia.PipelineRun, error)
 
 	// DeletePipelineRun deletes the given pipeline run from the memdb.
 	DeletePipelineRun(runID string) error
 
+	// CountPipelineRunsByTag returns the number of queued pipeline runs
+	// per required tag. The pipeline type counts as a tag.
+	CountPipelineRunsByTag() map[string]int
+
 	// InsertDockerWorker inserts a docker worker into the memdb.
 	InsertDockerWorker(w *docker.Worker) error
 
@@ -265,8 +270,9 @@ func (m *MemDB) InsertPipelineRun(p *gaia.PipelineRun) error {
 	return nil
 }
 
-// PopPipelineRun gets the oldest pipeline run filtered by tags and removes it immediately
-// from the memdb.
+// PopPipelineRun gets the pipeline run with the highest effective priority filtered
+// by tags and removes it immediately from the memdb. Runs with the same priority
+// are returned from the oldest to the newest run.
 func (m *MemDB) PopPipelineRun(tags []string) (*gaia.PipelineRun, error) {
 	// Create a read transaction
 	txn := m.db.Txn(false)
@@ -279,8 +285,8 @@ func (m *MemDB) PopPipelineRun(tags []string) (*gaia.PipelineRun, error) {
 	}
 
 	// Iterate through all items
-	var oldestPipelineRunID string
-	var oldestPipelineRunDate time.Time
+	var nextPipelineRun *gaia.PipelineRun
+	now := time.Now()
 RunLoop:
 	for {
 		item := iter.Next()
@@ -308,10 +314,9 @@ RunLoop:
 			}
 		}
 
-		// Check if the current pipeline run is older than the previous one
-		if oldestPipelineRunID == "" || oldestPipelineRunDate.After(pipelineRun.ScheduleDate) {
-			oldestPipelineRunID = pipelineRun.UniqueID
-			oldestPipelineRunDate = pipelineRun.ScheduleDate
+		// Check if the current pipeline run has to be executed before the previous one
+		if nextPipelineRun == nil || pipelinehelper.RunBefore(pipelineRun, nextPipelineRun, now) {
+			nextPipelineRun = pipelineRun
 		}
 	}
 
@@ -319,20 +324,20 @@ RunLoop:
 	txn.Abort()
 
 	// Check if we found a valid pipeline run to pop
-	if oldestPipelineRunID != "" {
+	if nextPipelineRun != nil {
 		// Create a write transaction
 		txn := m.db.Txn(true)
 
 		// Get the pipeline run
-		pipelineRunRaw, err := txn.First(pipelineRunTable, "id", oldestPipelineRunID)
+		pipelineRunRaw, err := txn.First(pipelineRunTable, "id", nextPipelineRun.UniqueID)
 		if err != nil {
-			gaia.Cfg.Logger.Error("failed to get oldest pipeline run via poppipelinerun", "error", err.Error())
+			gaia.Cfg.Logger.Error("failed to get next pipeline run via poppipelinerun", "error", err.Error())
 			return nil, err
 		}
 
 		// Delete pipeline run from memdb
 		if err = txn.Delete(pipelineRunTable, pipelineRunRaw); err != nil {
-			gaia.Cfg.Logger.Error("failed to delete oldest pipeline run via poppipelinerun", "error", err.Error())
+			gaia.Cfg.Logger.Error("failed to delete next pipeline run via poppipelinerun", "error", err.Error())
 			return nil, err
 		}
 
@@ -380,6 +385,37 @@ func (m *MemDB) DeletePipelineRun(runID string) error {
 	return nil
 }
 
+// CountPipelineRunsByTag returns the number of queued pipeline runs
+// per required tag. The pipeline type counts as a tag.
+func (m *MemDB) CountPipelineRunsByTag() map[string]int {
+	counts := make(map[string]int)
+
+	// Create a read-only transaction
+	txn := m.db.Txn(false)
+	defer txn.Abort()
+
+	// Get all objects from the pipeline run table
+	iter, err := txn.Get(pipelineRunTable, "id_prefix")
+	if err != nil {
+		gaia.Cfg.Logger.Error("failed to get pipeline run objects from memdb via countpipelinerunsbytag", "error", err.Error())
+		return counts
+	}
+
+	for item := iter.Next(); item != nil; item = iter.Next() {
+		pipelineRun, ok := item.(*gaia.PipelineRun)
+		if !ok {
+			gaia.Cfg.Logger.Error("failed to convert pipeline run to data struct via countpipelinerunsbytag", "item", item)
+			continue
+		}
+
+		counts[pipelineRun.PipelineType.String()]++
+		for _, pipelineTag := range pipelineRun.PipelineTags {
+			counts[pipelineTag]++
+		}
+	}
+	return counts
+}
+
 // InsertDockerWorker inserts a docker worker into the memdb.
 func (m *MemDB) InsertDockerWorker(w *docker.Worker) error {
 	// Create a write transaction
@@ -1,7 +1,9 @@
 package memdb
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gaia-pipeline/gaia/workers/docker"
 
@@ -246,6 +248,68 @@ func TestPopPipelineRun(t *testing.T) {
 	}
 }
 
+func TestPopPipelineRunByPriority(t *testing.T) {
+	mockStore := mockStore{}
+	db, err := InitMemDB(mockStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	runs := []*gaia.PipelineRun{
+		{UniqueID: "nightly", PipelineType: gaia.PTypeGolang, ScheduleDate: now.Add(-time.Minute)},
+		{UniqueID: "hotfix", PipelineType: gaia.PTypeGolang, ScheduleDate: now, Priority: 10},
+		{UniqueID: "second-nightly", PipelineType: gaia.PTypeGolang, ScheduleDate: now},
+	}
+	for _, r := range runs {
+		if err := db.InsertPipelineRun(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The hotfix is executed first, the nightly runs in the order they have been scheduled
+	tags := []string{gaia.PTypeGolang.String()}
+	for _, expected := range []string{"hotfix", "nightly", "second-nightly"} {
+		pRun, err := db.PopPipelineRun(tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pRun == nil || pRun.UniqueID != expected {
+			t.Fatalf("expected pipeline run '%s' but got %#v", expected, pRun)
+		}
+	}
+}
+
+func TestCountPipelineRunsByTag(t *testing.T) {
+	mockStore := mockStore{}
+	db, err := InitMemDB(mockStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs := []*gaia.PipelineRun{
+		{UniqueID: "first-pipelinerun", PipelineType: gaia.PTypeGolang, PipelineTags: []string{"first-tag", "second-tag"}},
+		{UniqueID: "second-pipelinerun", PipelineType: gaia.PTypeGolang, PipelineTags: []string{"first-tag"}},
+		{UniqueID: "third-pipelinerun", PipelineType: gaia.PTypeCpp},
+	}
+	for _, r := range runs {
+		if err := db.InsertPipelineRun(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]int{
+		gaia.PTypeGolang.String(): 2,
+		gaia.PTypeCpp.String():    1,
+		"first-tag":               2,
+		"second-tag":              1,
+	}
+	counts := db.CountPipelineRunsByTag()
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("expected counts %v but got %v", expected, counts)
+	}
+}
+
 func TestDeletePipelineRun(t *testing.T) {
 	mockStore := mockStore{}
 	db, err := InitMemDB(mockStore)
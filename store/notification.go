@@ -0,0 +1,79 @@
+package store
+
+import (
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// maxNotificationDeliveries is the number of notification deliveries
+// which are kept per pipeline. Older deliveries are removed.
+const maxNotificationDeliveries = 100
+
+// NotificationDeliveryPut stores the given notification delivery.
+// On persist, the delivery will get a unique id.
+func (s *BoltStore) NotificationDeliveryPut(d *gaia.NotificationDelivery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		// Get the bucket of the pipeline
+		b, err := tx.Bucket(notificationDeliveryBucket).CreateBucketIfNotExists(itob(d.PipelineID))
+		if err != nil {
+			return err
+		}
+
+		// Generate ID for the delivery if its new
+		if d.ID == 0 {
+			id, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			d.ID = int(id)
+		}
+
+		m, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(itob(d.ID), m); err != nil {
+			return err
+		}
+
+		// Remove the oldest deliveries
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			keys = append(keys, k)
+		}
+		for i := 0; i < len(keys)-maxNotificationDeliveries; i++ {
+			if err := b.Delete(keys[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// NotificationDeliveryList returns the notification deliveries
+// of the given pipeline. The newest delivery comes first.
+func (s *BoltStore) NotificationDeliveryList(pipelineID int) ([]*gaia.NotificationDelivery, error) {
+	var deliveries []*gaia.NotificationDelivery
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(notificationDeliveryBucket).Bucket(itob(pipelineID))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			d := &gaia.NotificationDelivery{}
+			if err := json.Unmarshal(v, d); err != nil {
+				return err
+			}
+			deliveries = append(deliveries, d)
+		}
+		return nil
+	})
+	return deliveries, err
+}
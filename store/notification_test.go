@@ -0,0 +1,84 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestNotificationDelivery(t *testing.T) {
+	gaia.Cfg.Bolt.Mode = 0600
+	stores := map[string]GaiaStore{
+		TypeBolt:   NewBoltStore(),
+		TypeSQLite: NewSQLStore(sqliteDriver, ""),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			tmp, err := ioutil.TempDir("", "TestNotificationDelivery")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tmp)
+
+			if err := store.Init(tmp); err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+
+			deliveries, err := store.NotificationDeliveryList(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(deliveries) != 0 {
+				t.Fatalf("expected no deliveries but got %d", len(deliveries))
+			}
+
+			for i := 1; i <= maxNotificationDeliveries+5; i++ {
+				d := &gaia.NotificationDelivery{
+					PipelineID: 1,
+					RunID:      i,
+					Type:       gaia.NotificationWebhook,
+					Event:      gaia.NotificationEventFailed,
+					Status:     gaia.NotificationDelivered,
+					Attempts:   1,
+				}
+				if err := store.NotificationDeliveryPut(d); err != nil {
+					t.Fatal(err)
+				}
+				if d.ID != i {
+					t.Fatalf("expected delivery id %d but got %d", i, d.ID)
+				}
+			}
+
+			// Delivery of another pipeline
+			if err := store.NotificationDeliveryPut(&gaia.NotificationDelivery{PipelineID: 2, RunID: 1}); err != nil {
+				t.Fatal(err)
+			}
+
+			deliveries, err = store.NotificationDeliveryList(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(deliveries) != maxNotificationDeliveries {
+				t.Fatalf("expected %d deliveries but got %d", maxNotificationDeliveries, len(deliveries))
+			}
+			if deliveries[0].RunID != maxNotificationDeliveries+5 {
+				t.Fatalf("expected newest delivery first but got run %d", deliveries[0].RunID)
+			}
+			if last := deliveries[len(deliveries)-1]; last.RunID != 6 {
+				t.Fatalf("expected oldest retained delivery of run 6 but got run %d", last.RunID)
+			}
+
+			deliveries, err = store.NotificationDeliveryList(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(deliveries) != 1 {
+				t.Fatalf("expected one delivery of pipeline 2 but got %d", len(deliveries))
+			}
+		})
+	}
+}
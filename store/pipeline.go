@@ -1,13 +1,23 @@
 package store
 
 import (
+	"bytes"
 	"encoding/json"
+	"time"
 
 	bolt "go.etcd.io/bbolt"
 
 	"github.com/gaia-pipeline/gaia"
 )
 
+const (
+	// Number of pipeline runs per page if not specified otherwise
+	defaultRunsPerPage = 20
+
+	// Maximum number of pipeline runs per page
+	maxRunsPerPage = 100
+)
+
 // CreatePipelinePut adds a pipeline which
 // is not yet compiled but is about to.
 func (s *BoltStore) CreatePipelinePut(p *gaia.CreatePipeline) error {
@@ -144,30 +154,17 @@ func (s *BoltStore) PipelineGetRunHighestID(p *gaia.Pipeline) (int, error) {
 	var highestID int
 
 	return highestID, s.db.View(func(tx *bolt.Tx) error {
-		// Get Bucket
-		b := tx.Bucket(pipelineRunBucket)
-
-		// Iterate all pipeline runs.
-		return b.ForEach(func(k, v []byte) error {
-			// create single run object
-			r := &gaia.PipelineRun{}
-
-			// Unmarshal
-			err := json.Unmarshal(v, r)
-			if err != nil {
-				return err
-			}
-
-			// Is this a run from our pipeline?
-			if r.PipelineID == p.ID {
-				// Check if the id is higher than what we found before?
-				if r.ID > highestID {
-					highestID = r.ID
-				}
-			}
-
+		// Get the index of the pipeline
+		b := pipelineRunIndex(tx, p.ID)
+		if b == nil {
 			return nil
-		})
+		}
+
+		// The index is sorted by the run id
+		if k, _ := b.Cursor().Last(); k != nil {
+			highestID = btoi(k[:8])
+		}
+		return nil
 	})
 }
 
@@ -185,7 +182,12 @@ func (s *BoltStore) PipelinePutRun(r *gaia.PipelineRun) error {
 		}
 
 		// Persist bytes into bucket.
-		return b.Put([]byte(r.UniqueID), buf)
+		if err = b.Put([]byte(r.UniqueID), buf); err != nil {
+			return err
+		}
+
+		// Update the index
+		return putPipelineRunIndex(tx, r)
 	})
 }
 
@@ -223,35 +225,58 @@ func (s *BoltStore) PipelineGetScheduled(limit int) ([]*gaia.PipelineRun, error)
 	})
 }
 
-// PipelineGetRunByPipelineIDAndID looks for pipeline run by given pipeline id and run id.
-func (s *BoltStore) PipelineGetRunByPipelineIDAndID(pipelineid int, runid int) (*gaia.PipelineRun, error) {
-	var pipelineRun *gaia.PipelineRun
+// PipelineGetRunsByStatus returns all pipeline runs with the given status.
+func (s *BoltStore) PipelineGetRunsByStatus(status gaia.PipelineRunStatus) ([]*gaia.PipelineRun, error) {
+	var runList []*gaia.PipelineRun
 
-	return pipelineRun, s.db.View(func(tx *bolt.Tx) error {
-		// Get Bucket
+	return runList, s.db.View(func(tx *bolt.Tx) error {
+		// Get bucket
 		b := tx.Bucket(pipelineRunBucket)
 
 		// Iterate all pipeline runs.
 		return b.ForEach(func(k, v []byte) error {
-			// create single run object
 			r := &gaia.PipelineRun{}
-
-			// Unmarshal
-			err := json.Unmarshal(v, r)
-			if err != nil {
+			if err := json.Unmarshal(v, r); err != nil {
 				return err
 			}
-
-			// Is this a run from our pipeline?
-			if r.PipelineID == pipelineid && r.ID == runid {
-				pipelineRun = r
+			if r.Status == status {
+				runList = append(runList, r)
 			}
-
 			return nil
 		})
 	})
 }
 
+// PipelineGetRunByPipelineIDAndID looks for pipeline run by given pipeline id and run id.
+func (s *BoltStore) PipelineGetRunByPipelineIDAndID(pipelineid int, runid int) (*gaia.PipelineRun, error) {
+	var pipelineRun *gaia.PipelineRun
+
+	return pipelineRun, s.db.View(func(tx *bolt.Tx) error {
+		// Get the index of the pipeline
+		idx := pipelineRunIndex(tx, pipelineid)
+		if idx == nil {
+			return nil
+		}
+
+		// Look up the run in the index
+		prefix := itob(runid)
+		k, _ := idx.Cursor().Seek(prefix)
+		if k == nil || !bytes.HasPrefix(k, prefix) {
+			return nil
+		}
+
+		// Get pipeline run
+		v := tx.Bucket(pipelineRunBucket).Get(k[len(prefix):])
+		if v == nil {
+			return nil
+		}
+
+		// Unmarshal pipeline run object
+		pipelineRun = &gaia.PipelineRun{}
+		return json.Unmarshal(v, pipelineRun)
+	})
+}
+
 // PipelineGetRunByID returns the pipeline run by internal unique id.
 func (s *BoltStore) PipelineGetRunByID(runID string) (*gaia.PipelineRun, error) {
 	var pipelineRun *gaia.PipelineRun
@@ -284,31 +309,89 @@ func (s *BoltStore) PipelineGetAllRunsByPipelineID(pipelineID int) ([]gaia.Pipel
 	var runs []gaia.PipelineRun
 
 	return runs, s.db.View(func(tx *bolt.Tx) error {
-		// Get Bucket
+		// Get the index of the pipeline
+		idx := pipelineRunIndex(tx, pipelineID)
+		if idx == nil {
+			return nil
+		}
 		b := tx.Bucket(pipelineRunBucket)
 
-		// Iterate all pipeline runs.
-		return b.ForEach(func(k, v []byte) error {
-			// create single run object
-			r := &gaia.PipelineRun{}
-
-			// Unmarshal
-			err := json.Unmarshal(v, r)
-			if err != nil {
+		// Iterate all indexed runs of the pipeline.
+		return idx.ForEach(func(k, v []byte) error {
+			e := &pipelineRunIndexEntry{}
+			if err := json.Unmarshal(v, e); err != nil {
 				return err
 			}
 
-			// Is this a run from our pipeline?
-			if r.PipelineID == pipelineID {
-				// add this to our list
-				runs = append(runs, *r)
+			// Get pipeline run
+			raw := b.Get([]byte(e.UniqueID))
+			if raw == nil {
+				return nil
+			}
+
+			// Unmarshal
+			r := gaia.PipelineRun{}
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return err
 			}
 
+			// add this to our list
+			runs = append(runs, r)
 			return nil
 		})
 	})
 }
 
+// PipelineListRuns returns a single page of the runs of a pipeline which match the given filter.
+// The runs are ordered from the newest to the oldest run.
+func (s *BoltStore) PipelineListRuns(filter gaia.PipelineRunFilter, page gaia.Page) (*gaia.PipelineRunList, error) {
+	page = normalizePage(page)
+	offset := (page.Number - 1) * page.PerPage
+	list := &gaia.PipelineRunList{
+		Runs:    []gaia.PipelineRun{},
+		Page:    page.Number,
+		PerPage: page.PerPage,
+	}
+
+	return list, s.db.View(func(tx *bolt.Tx) error {
+		// Get the index of the pipeline
+		idx := pipelineRunIndex(tx, filter.PipelineID)
+		if idx == nil {
+			return nil
+		}
+		b := tx.Bucket(pipelineRunBucket)
+
+		// Iterate the index backwards so the newest runs come first.
+		// Only the runs of the requested page are loaded.
+		c := idx.Cursor()
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			e := &pipelineRunIndexEntry{}
+			if err := json.Unmarshal(v, e); err != nil {
+				return err
+			}
+			if !runFilterMatches(filter, e.Status, e.StartReason, e.StartDate) {
+				continue
+			}
+
+			list.Total++
+			if list.Total <= offset || len(list.Runs) >= page.PerPage {
+				continue
+			}
+
+			raw := b.Get([]byte(e.UniqueID))
+			if raw == nil {
+				continue
+			}
+			r := gaia.PipelineRun{}
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return err
+			}
+			list.Runs = append(list.Runs, r)
+		}
+		return nil
+	})
+}
+
 // PipelineGetAllRuns loads all existing pipeline runs.
 func (s *BoltStore) PipelineGetAllRuns() ([]gaia.PipelineRun, error) {
 	var runs []gaia.PipelineRun
@@ -386,7 +469,100 @@ func (s *BoltStore) PipelineRunDelete(uniqueID string) error {
 		// Get bucket
 		b := tx.Bucket(pipelineRunBucket)
 
+		// Remove the run from the index
+		if v := b.Get([]byte(uniqueID)); v != nil {
+			r := &gaia.PipelineRun{}
+			if err := json.Unmarshal(v, r); err != nil {
+				return err
+			}
+			if idx := pipelineRunIndex(tx, r.PipelineID); idx != nil {
+				if err := idx.Delete(pipelineRunIndexKey(r)); err != nil {
+					return err
+				}
+			}
+		}
+
 		// Delete pipeline
 		return b.Delete([]byte(uniqueID))
 	})
 }
+
+// pipelineRunIndexEntry is the value of a single pipeline run in the pipeline run index.
+// It holds all fields which are required to filter pipeline runs.
+type pipelineRunIndexEntry struct {
+	UniqueID    string                 `json:"uniqueid"`
+	Status      gaia.PipelineRunStatus `json:"status"`
+	StartReason string                 `json:"started_reason"`
+	StartDate   time.Time              `json:"startdate"`
+}
+
+// pipelineRunIndex returns the pipeline run index of the given pipeline.
+// Returns nil if the pipeline has no runs.
+func pipelineRunIndex(tx *bolt.Tx, pipelineID int) *bolt.Bucket {
+	return tx.Bucket(pipelineRunIndexBucket).Bucket(itob(pipelineID))
+}
+
+// pipelineRunIndexKey returns the key of the given run in the pipeline run index.
+// The key starts with the run id so that the index is sorted by run id.
+func pipelineRunIndexKey(r *gaia.PipelineRun) []byte {
+	return append(itob(r.ID), r.UniqueID...)
+}
+
+// putPipelineRunIndex adds or updates the given run in the pipeline run index.
+func putPipelineRunIndex(tx *bolt.Tx, r *gaia.PipelineRun) error {
+	idx, err := tx.Bucket(pipelineRunIndexBucket).CreateBucketIfNotExists(itob(r.PipelineID))
+	if err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(pipelineRunIndexEntry{
+		UniqueID:    r.UniqueID,
+		Status:      r.Status,
+		StartReason: r.StartReason,
+		StartDate:   r.StartDate,
+	})
+	if err != nil {
+		return err
+	}
+	return idx.Put(pipelineRunIndexKey(r), buf)
+}
+
+// normalizePage sets the defaults of the given page and limits the page size.
+func normalizePage(page gaia.Page) gaia.Page {
+	if page.Number < 1 {
+		page.Number = 1
+	}
+	if page.PerPage < 1 {
+		page.PerPage = defaultRunsPerPage
+	}
+	if page.PerPage > maxRunsPerPage {
+		page.PerPage = maxRunsPerPage
+	}
+	return page
+}
+
+// runFilterMatches returns true if a pipeline run with the given fields matches the given filter.
+func runFilterMatches(filter gaia.PipelineRunFilter, status gaia.PipelineRunStatus, startReason string, startDate time.Time) bool {
+	if len(filter.Status) > 0 {
+		found := false
+		for _, s := range filter.Status {
+			if s == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.StartReason != "" && filter.StartReason != startReason {
+		return false
+	}
+	if !filter.StartedAfter.IsZero() && startDate.Before(filter.StartedAfter) {
+		return false
+	}
+	if !filter.StartedBefore.IsZero() && !startDate.Before(filter.StartedBefore) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,362 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2/persist"
+
+	// Register the SQL drivers used by the SQL store
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/assethelper"
+)
+
+const (
+	// sqliteDriver is the name of the SQLite database driver. The driver
+	// is written in pure Go so that it works in builds without cgo.
+	sqliteDriver = "sqlite"
+
+	// postgresDriver is the name of the PostgreSQL database driver
+	postgresDriver = "postgres"
+
+	// SQLite database file name which is used if no data source name is given
+	sqliteDBFileName = "gaia.sqlite"
+
+	// pipelineSequence is the name of the sequence used to generate pipeline ids
+	pipelineSequence = "pipelines"
+
+	// migrationLockID is the key of the PostgreSQL advisory lock
+	// which is held while the migrations are running.
+	migrationLockID = 0x6761696d
+)
+
+// errMissingDSN is returned when the SQL store requires a data source name but none was given.
+var errMissingDSN = errors.New("a data source name is required for the postgres store")
+
+// SQLStore represents the access type for a SQL database backed store.
+// It supports SQLite and PostgreSQL.
+type SQLStore struct {
+	db            *sql.DB
+	driver        string
+	dsn           string
+	casbinAdapter persist.BatchAdapter
+}
+
+// Compile time interface compliance check for SQLStore.
+var _ GaiaStore = (*SQLStore)(nil)
+
+// NewSQLStore creates a new instance of the SQL store for the given
+// database driver and data source name.
+func NewSQLStore(driver, dsn string) *SQLStore {
+	return &SQLStore{
+		driver: driver,
+		dsn:    dsn,
+	}
+}
+
+// Init opens the database connection, runs all pending
+// migrations and makes sure that the default users exist.
+func (s *SQLStore) Init(dataPath string) error {
+	dsn := s.dsn
+	if dsn == "" {
+		if s.driver != sqliteDriver {
+			return errMissingDSN
+		}
+		dsn = "file:" + filepath.Join(dataPath, sqliteDBFileName) + "?_pragma=busy_timeout(5000)"
+	}
+
+	db, err := sql.Open(s.driver, dsn)
+	if err != nil {
+		return err
+	}
+
+	// SQLite only supports a single writer. Serialize all access
+	// to prevent locking errors.
+	if s.driver == sqliteDriver {
+		db.SetMaxOpenConns(1)
+	}
+
+	if err = db.Ping(); err != nil {
+		_ = db.Close()
+		return err
+	}
+	s.db = db
+
+	// Setup database
+	if err = s.migrate(); err != nil {
+		return err
+	}
+
+	builtinPolicy, _ := assethelper.LoadRBACBuiltinPolicy()
+	s.casbinAdapter = newSQLCasbinAdapter(s, builtinPolicy)
+
+	return createDefaultUsers(s)
+}
+
+// Close closes the active database connection.
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// CasbinStore is as a getter for the Casbin store adapter.
+func (s *SQLStore) CasbinStore() persist.BatchAdapter {
+	return s.casbinAdapter
+}
+
+// rebind replaces the '?' placeholders of the given query with
+// the placeholders of the used database driver.
+func (s *SQLStore) rebind(query string) string {
+	if s.driver != postgresDriver {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// exec executes the given query without returning any rows.
+func (s *SQLStore) exec(query string, args ...interface{}) error {
+	_, err := s.db.Exec(s.rebind(query), args...)
+	return err
+}
+
+// getData queries a single data column and returns it.
+// Returns nil if no row has been found.
+func (s *SQLStore) getData(query string, args ...interface{}) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRow(s.rebind(query), args...).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return data, err
+}
+
+// forEachData queries the data column of multiple rows
+// and calls the given function for every row.
+func (s *SQLStore) forEachData(fn func(data []byte) error, query string, args ...interface{}) error {
+	rows, err := s.db.Query(s.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// nextSequence increments the sequence with the given name and returns the new value.
+func (s *SQLStore) nextSequence(tx *sql.Tx, name string) (int, error) {
+	_, err := tx.Exec(s.rebind(`INSERT INTO sequences (name, value) VALUES (?, 1)
+		ON CONFLICT (name) DO UPDATE SET value = sequences.value + 1`), name)
+	if err != nil {
+		return 0, err
+	}
+
+	var value int
+	err = tx.QueryRow(s.rebind(`SELECT value FROM sequences WHERE name = ?`), name).Scan(&value)
+	return value, err
+}
+
+// unixNano converts the given time into unix nano seconds which are used
+// as sortable time columns. The zero time is converted to zero.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// sqlMigration represents a single version of the database schema.
+// The optional data migration runs after the statements.
+type sqlMigration struct {
+	version    int
+	statements []string
+	data       func(s *SQLStore, tx *sql.Tx) error
+}
+
+// sqlMigrations holds all migrations of the database schema in ascending order.
+// Already released migrations must never be changed. Add a new migration instead.
+var sqlMigrations = []sqlMigration{
+	{
+		version: 1,
+		statements: []string{
+			`CREATE TABLE sequences (name TEXT PRIMARY KEY, value BIGINT NOT NULL)`,
+			`CREATE TABLE users (username TEXT PRIMARY KEY, data TEXT NOT NULL)`,
+			`CREATE TABLE user_permissions (username TEXT PRIMARY KEY, data TEXT NOT NULL)`,
+			`CREATE TABLE pipelines (id BIGINT PRIMARY KEY, name TEXT NOT NULL, data TEXT NOT NULL)`,
+			`CREATE INDEX idx_pipelines_name ON pipelines (name)`,
+			`CREATE TABLE create_pipelines (id TEXT PRIMARY KEY, data TEXT NOT NULL)`,
+			`CREATE TABLE pipeline_runs (
+				unique_id TEXT PRIMARY KEY,
+				pipeline_id BIGINT NOT NULL,
+				id BIGINT NOT NULL,
+				status TEXT NOT NULL,
+				schedule_date BIGINT NOT NULL,
+				start_date BIGINT NOT NULL,
+				data TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_pipeline_runs_pipeline ON pipeline_runs (pipeline_id, id)`,
+			`CREATE INDEX idx_pipeline_runs_status ON pipeline_runs (status, schedule_date)`,
+			`CREATE TABLE settings (name TEXT PRIMARY KEY, data TEXT NOT NULL)`,
+			`CREATE TABLE workers (unique_id TEXT PRIMARY KEY, data TEXT NOT NULL)`,
+			`CREATE TABLE sha_pairs (pipeline_id BIGINT PRIMARY KEY, data TEXT NOT NULL)`,
+			`CREATE TABLE casbin_rules (
+				ptype TEXT NOT NULL,
+				v0 TEXT NOT NULL DEFAULT '',
+				v1 TEXT NOT NULL DEFAULT '',
+				v2 TEXT NOT NULL DEFAULT '',
+				v3 TEXT NOT NULL DEFAULT '',
+				v4 TEXT NOT NULL DEFAULT '',
+				v5 TEXT NOT NULL DEFAULT '',
+				UNIQUE (ptype, v0, v1, v2, v3, v4, v5)
+			)`,
+		},
+	},
+	{
+		version: 2,
+		statements: []string{
+			`ALTER TABLE pipeline_runs ADD COLUMN start_reason TEXT NOT NULL DEFAULT ''`,
+		},
+		data: migrateRunStartReason,
+	},
+	{
+		version: 3,
+		statements: []string{
+			`CREATE TABLE notification_deliveries (
+				id BIGINT PRIMARY KEY,
+				pipeline_id BIGINT NOT NULL,
+				data TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_notification_deliveries_pipeline ON notification_deliveries (pipeline_id, id)`,
+		},
+	},
+}
+
+// migrateRunStartReason fills the start_reason column of all existing pipeline runs.
+func migrateRunStartReason(s *SQLStore, tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT data FROM pipeline_runs`)
+	if err != nil {
+		return err
+	}
+
+	// Collect all runs first. Some drivers do not support
+	// further queries while rows are still open.
+	var runs []gaia.PipelineRun
+	for rows.Next() {
+		var data []byte
+		r := gaia.PipelineRun{}
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := json.Unmarshal(data, &r); err != nil {
+			rows.Close()
+			return err
+		}
+		runs = append(runs, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range runs {
+		if _, err := tx.Exec(s.rebind(`UPDATE pipeline_runs SET start_reason = ? WHERE unique_id = ?`), r.StartReason, r.UniqueID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrate brings the database schema up to date. Every migration
+// runs in its own transaction together with the update of the schema version.
+// All statements use a dedicated connection. For PostgreSQL the connection holds
+// an advisory lock so that instances which start at the same time cannot
+// run the migrations concurrently.
+func (s *SQLStore) migrate() (err error) {
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if s.driver == postgresDriver {
+		if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+			return fmt.Errorf("cannot acquire migration lock: %s", err)
+		}
+		defer func() {
+			if _, unlockErr := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockID); unlockErr != nil && err == nil {
+				err = fmt.Errorf("cannot release migration lock: %s", unlockErr)
+			}
+		}()
+	}
+
+	if _, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err = conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for _, m := range sqlMigrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, stmt := range m.statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("migration %d failed: %s", m.version, err)
+			}
+		}
+		if m.data != nil {
+			if err := m.data(s, tx); err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("migration %d failed: %s", m.version, err)
+			}
+		}
+		if _, err := tx.Exec(s.rebind(`INSERT INTO schema_migrations (version) VALUES (?)`), m.version); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// notificationDeliverySequence is the name of the sequence used to generate notification delivery ids
+const notificationDeliverySequence = "notification_deliveries"
+
+// NotificationDeliveryPut stores the given notification delivery.
+// On persist, the delivery will get a unique id.
+func (s *SQLStore) NotificationDeliveryPut(d *gaia.NotificationDelivery) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Generate ID for the delivery if its new
+	if d.ID == 0 {
+		id, err := s.nextSequence(tx, notificationDeliverySequence)
+		if err != nil {
+			return err
+		}
+		d.ID = id
+	}
+
+	m, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(s.rebind(`INSERT INTO notification_deliveries (id, pipeline_id, data) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data`), d.ID, d.PipelineID, string(m))
+	if err != nil {
+		return err
+	}
+
+	// Remove the oldest deliveries
+	_, err = tx.Exec(s.rebind(`DELETE FROM notification_deliveries WHERE pipeline_id = ? AND id NOT IN (
+		SELECT id FROM notification_deliveries WHERE pipeline_id = ? ORDER BY id DESC LIMIT ?)`),
+		d.PipelineID, d.PipelineID, maxNotificationDeliveries)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// NotificationDeliveryList returns the notification deliveries
+// of the given pipeline. The newest delivery comes first.
+func (s *SQLStore) NotificationDeliveryList(pipelineID int) ([]*gaia.NotificationDelivery, error) {
+	var deliveries []*gaia.NotificationDelivery
+
+	err := s.forEachData(func(data []byte) error {
+		d := &gaia.NotificationDelivery{}
+		if err := json.Unmarshal(data, d); err != nil {
+			return err
+		}
+		deliveries = append(deliveries, d)
+		return nil
+	}, `SELECT data FROM notification_deliveries WHERE pipeline_id = ? ORDER BY id DESC`, pipelineID)
+	return deliveries, err
+}
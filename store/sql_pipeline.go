@@ -0,0 +1,257 @@
+package store
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// CreatePipelinePut adds a pipeline which
+// is not yet compiled but is about to.
+func (s *SQLStore) CreatePipelinePut(p *gaia.CreatePipeline) error {
+	m, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(`INSERT INTO create_pipelines (id, data) VALUES (?, ?)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data`, p.ID, string(m))
+}
+
+// CreatePipelineGet returns all available create pipeline
+// objects in the store.
+func (s *SQLStore) CreatePipelineGet() ([]gaia.CreatePipeline, error) {
+	var pipelineList []gaia.CreatePipeline
+
+	err := s.forEachData(func(data []byte) error {
+		p := gaia.CreatePipeline{}
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		pipelineList = append(pipelineList, p)
+		return nil
+	}, `SELECT data FROM create_pipelines ORDER BY id`)
+	return pipelineList, err
+}
+
+// PipelinePut puts a pipeline into the store.
+// On persist, the pipeline will get a unique id.
+func (s *SQLStore) PipelinePut(p *gaia.Pipeline) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Generate ID for the pipeline if its new.
+	if p.ID == 0 {
+		id, err := s.nextSequence(tx, pipelineSequence)
+		if err != nil {
+			return err
+		}
+		p.ID = id
+	}
+
+	buf, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(s.rebind(`INSERT INTO pipelines (id, name, data) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, data = excluded.data`), p.ID, p.Name, string(buf))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PipelineGet gets a pipeline by given id.
+func (s *SQLStore) PipelineGet(id int) (*gaia.Pipeline, error) {
+	data, err := s.getData(`SELECT data FROM pipelines WHERE id = ?`, id)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	pipeline := &gaia.Pipeline{}
+	return pipeline, json.Unmarshal(data, pipeline)
+}
+
+// PipelineGetByName looks up a pipeline by the given name.
+// Returns nil if pipeline was not found.
+func (s *SQLStore) PipelineGetByName(n string) (*gaia.Pipeline, error) {
+	data, err := s.getData(`SELECT data FROM pipelines WHERE name = ? ORDER BY id DESC LIMIT 1`, n)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	pipeline := &gaia.Pipeline{}
+	return pipeline, json.Unmarshal(data, pipeline)
+}
+
+// PipelineGetRunHighestID looks for the highest public id for the given pipeline.
+func (s *SQLStore) PipelineGetRunHighestID(p *gaia.Pipeline) (int, error) {
+	var highestID int
+	err := s.db.QueryRow(s.rebind(`SELECT COALESCE(MAX(id), 0) FROM pipeline_runs WHERE pipeline_id = ?`), p.ID).Scan(&highestID)
+	return highestID, err
+}
+
+// PipelinePutRun takes the given pipeline run and puts it into the store.
+// If a pipeline run already exists in the store it will be overwritten.
+func (s *SQLStore) PipelinePutRun(r *gaia.PipelineRun) error {
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(`INSERT INTO pipeline_runs (unique_id, pipeline_id, id, status, start_reason, schedule_date, start_date, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (unique_id) DO UPDATE SET
+			pipeline_id = excluded.pipeline_id,
+			id = excluded.id,
+			status = excluded.status,
+			start_reason = excluded.start_reason,
+			schedule_date = excluded.schedule_date,
+			start_date = excluded.start_date,
+			data = excluded.data`,
+		r.UniqueID, r.PipelineID, r.ID, string(r.Status), r.StartReason, unixNano(r.ScheduleDate), unixNano(r.StartDate), string(buf))
+}
+
+// PipelineGetScheduled returns the scheduled pipelines with a return limit.
+func (s *SQLStore) PipelineGetScheduled(limit int) ([]*gaia.PipelineRun, error) {
+	var runList []*gaia.PipelineRun
+
+	err := s.forEachData(func(data []byte) error {
+		r := &gaia.PipelineRun{}
+		if err := json.Unmarshal(data, r); err != nil {
+			return err
+		}
+		runList = append(runList, r)
+		return nil
+	}, `SELECT data FROM pipeline_runs WHERE status = ? ORDER BY schedule_date LIMIT ?`, string(gaia.RunNotScheduled), limit)
+	return runList, err
+}
+
+// PipelineGetRunsByStatus returns all pipeline runs with the given status.
+func (s *SQLStore) PipelineGetRunsByStatus(status gaia.PipelineRunStatus) ([]*gaia.PipelineRun, error) {
+	var runList []*gaia.PipelineRun
+
+	err := s.forEachData(func(data []byte) error {
+		r := &gaia.PipelineRun{}
+		if err := json.Unmarshal(data, r); err != nil {
+			return err
+		}
+		runList = append(runList, r)
+		return nil
+	}, `SELECT data FROM pipeline_runs WHERE status = ? ORDER BY schedule_date`, string(status))
+	return runList, err
+}
+
+// PipelineGetRunByPipelineIDAndID looks for pipeline run by given pipeline id and run id.
+func (s *SQLStore) PipelineGetRunByPipelineIDAndID(pipelineid int, runid int) (*gaia.PipelineRun, error) {
+	data, err := s.getData(`SELECT data FROM pipeline_runs WHERE pipeline_id = ? AND id = ? LIMIT 1`, pipelineid, runid)
+	return unmarshalRun(data, err)
+}
+
+// PipelineGetRunByID returns the pipeline run by internal unique id.
+func (s *SQLStore) PipelineGetRunByID(runID string) (*gaia.PipelineRun, error) {
+	data, err := s.getData(`SELECT data FROM pipeline_runs WHERE unique_id = ?`, runID)
+	return unmarshalRun(data, err)
+}
+
+// PipelineGetAllRunsByPipelineID looks for all pipeline runs by the given pipeline id.
+func (s *SQLStore) PipelineGetAllRunsByPipelineID(pipelineID int) ([]gaia.PipelineRun, error) {
+	return s.getRuns(`SELECT data FROM pipeline_runs WHERE pipeline_id = ? ORDER BY id`, pipelineID)
+}
+
+// PipelineListRuns returns a single page of the runs of a pipeline which match the given filter.
+// The runs are ordered from the newest to the oldest run.
+func (s *SQLStore) PipelineListRuns(filter gaia.PipelineRunFilter, page gaia.Page) (*gaia.PipelineRunList, error) {
+	page = normalizePage(page)
+	list := &gaia.PipelineRunList{
+		Page:    page.Number,
+		PerPage: page.PerPage,
+	}
+
+	// Build the filter
+	where := ` WHERE pipeline_id = ?`
+	args := []interface{}{filter.PipelineID}
+	if len(filter.Status) > 0 {
+		where += ` AND status IN (?` + strings.Repeat(`, ?`, len(filter.Status)-1) + `)`
+		for _, status := range filter.Status {
+			args = append(args, string(status))
+		}
+	}
+	if filter.StartReason != "" {
+		where += ` AND start_reason = ?`
+		args = append(args, filter.StartReason)
+	}
+	if !filter.StartedAfter.IsZero() {
+		where += ` AND start_date >= ?`
+		args = append(args, filter.StartedAfter.UnixNano())
+	}
+	if !filter.StartedBefore.IsZero() {
+		where += ` AND start_date < ?`
+		args = append(args, filter.StartedBefore.UnixNano())
+	}
+
+	err := s.db.QueryRow(s.rebind(`SELECT COUNT(*) FROM pipeline_runs`+where), args...).Scan(&list.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, page.PerPage, (page.Number-1)*page.PerPage)
+	list.Runs, err = s.getRuns(`SELECT data FROM pipeline_runs`+where+` ORDER BY id DESC, unique_id DESC LIMIT ? OFFSET ?`, args...)
+	if list.Runs == nil {
+		list.Runs = []gaia.PipelineRun{}
+	}
+	return list, err
+}
+
+// PipelineGetAllRuns loads all existing pipeline runs.
+func (s *SQLStore) PipelineGetAllRuns() ([]gaia.PipelineRun, error) {
+	return s.getRuns(`SELECT data FROM pipeline_runs ORDER BY pipeline_id, id`)
+}
+
+// PipelineGetLatestRun returns the latest run by the given pipeline id.
+func (s *SQLStore) PipelineGetLatestRun(pipelineID int) (*gaia.PipelineRun, error) {
+	data, err := s.getData(`SELECT data FROM pipeline_runs WHERE pipeline_id = ?
+		ORDER BY start_date DESC, id DESC LIMIT 1`, pipelineID)
+	return unmarshalRun(data, err)
+}
+
+// PipelineDelete deletes the pipeline with the given id.
+func (s *SQLStore) PipelineDelete(id int) error {
+	return s.exec(`DELETE FROM pipelines WHERE id = ?`, id)
+}
+
+// PipelineRunDelete deletes the pipeline run with the given id.
+func (s *SQLStore) PipelineRunDelete(uniqueID string) error {
+	return s.exec(`DELETE FROM pipeline_runs WHERE unique_id = ?`, uniqueID)
+}
+
+// getRuns returns all pipeline runs selected by the given query.
+func (s *SQLStore) getRuns(query string, args ...interface{}) ([]gaia.PipelineRun, error) {
+	var runs []gaia.PipelineRun
+
+	err := s.forEachData(func(data []byte) error {
+		r := gaia.PipelineRun{}
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+		runs = append(runs, r)
+		return nil
+	}, query, args...)
+	return runs, err
+}
+
+// unmarshalRun unmarshals a single pipeline run.
+// Returns nil if no data has been found.
+func unmarshalRun(data []byte, err error) (*gaia.PipelineRun, error) {
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	r := &gaia.PipelineRun{}
+	return r, json.Unmarshal(data, r)
+}
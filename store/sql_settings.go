@@ -0,0 +1,29 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// SettingsPut puts settings into the store.
+func (s *SQLStore) SettingsPut(c *gaia.StoreConfig) error {
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(`INSERT INTO settings (name, data) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET data = excluded.data`, configSettings, string(buf))
+}
+
+// SettingsGet gets the settings from the store.
+func (s *SQLStore) SettingsGet() (*gaia.StoreConfig, error) {
+	config := &gaia.StoreConfig{}
+
+	data, err := s.getData(`SELECT data FROM settings WHERE name = ?`, configSettings)
+	if err != nil || data == nil {
+		return config, err
+	}
+	return config, json.Unmarshal(data, config)
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// UpsertSHAPair creates or updates a record for a SHA pair of the original SHA and the
+// rebuilt Worker SHA for a pipeline.
+func (s *SQLStore) UpsertSHAPair(pair gaia.SHAPair) error {
+	m, err := json.Marshal(pair)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(`INSERT INTO sha_pairs (pipeline_id, data) VALUES (?, ?)
+		ON CONFLICT (pipeline_id) DO UPDATE SET data = excluded.data`, pair.PipelineID, string(m))
+}
+
+// GetSHAPair returns a pair of shas for this pipeline run.
+func (s *SQLStore) GetSHAPair(pipelineID int) (ok bool, pair gaia.SHAPair, err error) {
+	data, err := s.getData(`SELECT data FROM sha_pairs WHERE pipeline_id = ?`, pipelineID)
+	if err != nil || data == nil {
+		return false, pair, err
+	}
+
+	err = json.Unmarshal(data, &pair)
+	return err == nil, pair, err
+}
@@ -0,0 +1,390 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/assethelper"
+)
+
+func newTestSQLStore(t *testing.T, tmp string) *SQLStore {
+	t.Helper()
+	s := NewSQLStore(sqliteDriver, "")
+	if err := s.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSQLStoreInit(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestSQLStoreInit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	s := newTestSQLStore(t, tmp)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening an existing database must not fail
+	s = newTestSQLStore(t, tmp)
+	defer s.Close()
+
+	for _, username := range []string{adminUsername, autoUsername} {
+		u, err := s.UserGet(username)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u == nil {
+			t.Fatalf("expected default user %s to exist", username)
+		}
+	}
+
+	perms, err := s.UserPermissionsGet(adminUsername)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perms == nil {
+		t.Fatal("expected admin permissions to exist")
+	}
+
+	if _, err := New(TypePostgres, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewSQLStore(postgresDriver, "").Init(tmp); err != errMissingDSN {
+		t.Fatalf("expected error %v but got %v", errMissingDSN, err)
+	}
+	if _, err := New("unknown", ""); err == nil {
+		t.Fatal("expected error for unknown store type")
+	}
+}
+
+func TestSQLStoreUser(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestSQLStoreUser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	s := newTestSQLStore(t, tmp)
+	defer s.Close()
+
+	u := &gaia.User{Username: "testuser", Password: "12345!#+21+", DisplayName: "Test"}
+	if err := s.UserPut(u, true); err != nil {
+		t.Fatal(err)
+	}
+	if u.Password != "" {
+		t.Fatal("expected password to be cleared")
+	}
+
+	u.Password = "12345!#+21+"
+	user, err := s.UserAuth(u, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user == nil || user.LastLogin.IsZero() {
+		t.Fatalf("expected authenticated user with last login but got %v", user)
+	}
+
+	u.Password = "wrong"
+	if user, _ = s.UserAuth(u, false); user != nil {
+		t.Fatal("expected authentication to fail")
+	}
+
+	users, err := s.UserGetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 3 {
+		t.Fatalf("expected 3 users but got %d", len(users))
+	}
+	for _, u := range users {
+		if u.Password != "" {
+			t.Fatalf("expected password of user %s to be cleared", u.Username)
+		}
+	}
+
+	if err := s.UserDelete(u.Username); err != nil {
+		t.Fatal(err)
+	}
+	if user, err = s.UserGet(u.Username); err != nil || user != nil {
+		t.Fatalf("expected user to be deleted but got %v, %v", user, err)
+	}
+}
+
+func TestSQLStorePipeline(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestSQLStorePipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	s := newTestSQLStore(t, tmp)
+	defer s.Close()
+
+	p1 := &gaia.Pipeline{Name: "pipeline1", Type: gaia.PTypeGolang}
+	p2 := &gaia.Pipeline{Name: "pipeline2", Type: gaia.PTypeGolang}
+	for _, p := range []*gaia.Pipeline{p1, p2} {
+		if err := s.PipelinePut(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p1.ID != 1 || p2.ID != 2 {
+		t.Fatalf("expected pipeline ids 1 and 2 but got %d and %d", p1.ID, p2.ID)
+	}
+
+	p1.Name = "renamed"
+	if err := s.PipelinePut(p1); err != nil {
+		t.Fatal(err)
+	}
+	p, err := s.PipelineGetByName("renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || p.ID != p1.ID {
+		t.Fatalf("expected pipeline %d but got %v", p1.ID, p)
+	}
+
+	if err := s.PipelineDelete(p2.ID); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = s.PipelineGet(p2.ID); err != nil || p != nil {
+		t.Fatalf("expected pipeline to be deleted but got %v, %v", p, err)
+	}
+
+	cp := &gaia.CreatePipeline{ID: "create-id", Pipeline: *p1}
+	if err := s.CreatePipelinePut(cp); err != nil {
+		t.Fatal(err)
+	}
+	cps, err := s.CreatePipelineGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cps) != 1 || cps[0].ID != cp.ID {
+		t.Fatalf("expected create pipeline %s but got %v", cp.ID, cps)
+	}
+}
+
+func TestSQLStorePipelineRun(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestSQLStorePipelineRun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	s := newTestSQLStore(t, tmp)
+	defer s.Close()
+
+	now := time.Now()
+	runs := []*gaia.PipelineRun{
+		{UniqueID: "a", ID: 1, PipelineID: 1, Status: gaia.RunSuccess, StartDate: now.Add(-time.Hour)},
+		{UniqueID: "b", ID: 2, PipelineID: 1, Status: gaia.RunSuccess, StartDate: now},
+		{UniqueID: "c", ID: 3, PipelineID: 1, Status: gaia.RunNotScheduled, ScheduleDate: now},
+		{UniqueID: "d", ID: 1, PipelineID: 2, Status: gaia.RunNotScheduled, ScheduleDate: now.Add(-time.Minute)},
+	}
+	for _, r := range runs {
+		if err := s.PipelinePutRun(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	highestID, err := s.PipelineGetRunHighestID(&gaia.Pipeline{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if highestID != 3 {
+		t.Fatalf("expected highest id 3 but got %d", highestID)
+	}
+
+	all, err := s.PipelineGetAllRunsByPipelineID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].UniqueID != "a" || all[2].UniqueID != "c" {
+		t.Fatalf("expected runs of pipeline 1 ordered by id but got %v", all)
+	}
+
+	scheduled, err := s.PipelineGetScheduled(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scheduled) != 1 || scheduled[0].UniqueID != "d" {
+		t.Fatalf("expected the oldest scheduled run but got %v", scheduled)
+	}
+
+	latest, err := s.PipelineGetLatestRun(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest == nil || latest.UniqueID != "b" {
+		t.Fatalf("expected latest run b but got %v", latest)
+	}
+
+	// Updating a run must update the indexed columns too
+	runs[2].Status = gaia.RunRunning
+	if err := s.PipelinePutRun(runs[2]); err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.PipelineGetRunByPipelineIDAndID(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil || r.Status != gaia.RunRunning {
+		t.Fatalf("expected running run but got %v", r)
+	}
+	if scheduled, err = s.PipelineGetScheduled(10); err != nil || len(scheduled) != 1 {
+		t.Fatalf("expected one scheduled run but got %v, %v", scheduled, err)
+	}
+	if running, err := s.PipelineGetRunsByStatus(gaia.RunRunning); err != nil || len(running) != 1 || running[0].UniqueID != "c" {
+		t.Fatalf("expected running run c but got %v, %v", running, err)
+	}
+
+	if err := s.PipelineRunDelete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err = s.PipelineGetRunByID("a"); err != nil || r != nil {
+		t.Fatalf("expected run to be deleted but got %v, %v", r, err)
+	}
+	if all, err = s.PipelineGetAllRuns(); err != nil || len(all) != 3 {
+		t.Fatalf("expected 3 runs but got %v, %v", all, err)
+	}
+}
+
+func TestSQLStoreWorkerSettingsSHAPair(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestSQLStoreWorkerSettingsSHAPair")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	s := newTestSQLStore(t, tmp)
+	defer s.Close()
+
+	for _, id := range []string{"worker1", "worker2"} {
+		if err := s.WorkerPut(&gaia.Worker{UniqueID: id, Name: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w, err := s.WorkerGet("worker1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w == nil || w.Name != "worker1" {
+		t.Fatalf("expected worker1 but got %v", w)
+	}
+	if err := s.WorkerDelete("worker1"); err != nil {
+		t.Fatal(err)
+	}
+	workers, err := s.WorkerGetAll()
+	if err != nil || len(workers) != 1 {
+		t.Fatalf("expected one worker but got %v, %v", workers, err)
+	}
+	if err := s.WorkerDeleteAll(); err != nil {
+		t.Fatal(err)
+	}
+	if workers, err = s.WorkerGetAll(); err != nil || len(workers) != 0 {
+		t.Fatalf("expected no worker but got %v, %v", workers, err)
+	}
+
+	if err := s.SettingsPut(&gaia.StoreConfig{Poll: true}); err != nil {
+		t.Fatal(err)
+	}
+	config, err := s.SettingsGet()
+	if err != nil || !config.Poll {
+		t.Fatalf("expected poll setting to be stored but got %v, %v", config, err)
+	}
+
+	ok, _, err := s.GetSHAPair(1)
+	if err != nil || ok {
+		t.Fatalf("expected no sha pair but got %v, %v", ok, err)
+	}
+	if err := s.UpsertSHAPair(gaia.SHAPair{PipelineID: 1, Original: []byte("original"), Worker: []byte("worker")}); err != nil {
+		t.Fatal(err)
+	}
+	ok, pair, err := s.GetSHAPair(1)
+	if err != nil || !ok || string(pair.Worker) != "worker" {
+		t.Fatalf("expected sha pair but got %v, %v, %v", ok, pair, err)
+	}
+}
+
+func TestSQLCasbinAdapter(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestSQLCasbinAdapter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	s := newTestSQLStore(t, tmp)
+	defer s.Close()
+
+	modelStr, err := assethelper.LoadRBACModel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := model.NewModelFromString(modelStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e, err := casbin.NewEnforcer(m, s.CasbinStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddPolicy("role:test", "pipelines", "get", "*", "allow"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddRoleForUser("user1", "role:test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.AddRoleForUser("user2", "role:test"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reload the policies from the database
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := e.Enforce("user1", "pipelines", "get", "*"); err != nil || !ok {
+		t.Fatalf("expected user1 to be allowed but got %v, %v", ok, err)
+	}
+	if ok, err := e.Enforce("admin", "pipelines", "delete", "*"); err != nil || !ok {
+		t.Fatalf("expected builtin admin policy to be loaded but got %v, %v", ok, err)
+	}
+
+	if _, err := e.DeleteRolesForUser("user1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.RemoveFilteredPolicy(1, "pipelines", "get"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if users, _ := e.GetUsersForRole("role:test"); len(users) != 1 || users[0] != "user2" {
+		t.Fatalf("expected only user2 to have role:test but got %v", users)
+	}
+	if policies := e.GetFilteredPolicy(0, "role:test"); len(policies) != 0 {
+		t.Fatalf("expected role:test policies to be removed but got %v", policies)
+	}
+}
+
+func TestSQLStoreRebind(t *testing.T) {
+	query := `SELECT data FROM pipeline_runs WHERE pipeline_id = ? AND id = ?`
+
+	if got := NewSQLStore(sqliteDriver, "").rebind(query); got != query {
+		t.Fatalf("expected query to be unchanged but got %s", got)
+	}
+
+	expected := `SELECT data FROM pipeline_runs WHERE pipeline_id = $1 AND id = $2`
+	if got := NewSQLStore(postgresDriver, "").rebind(query); got != expected {
+		t.Fatalf("expected %s but got %s", expected, got)
+	}
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// UserPut takes the given user and saves it
+// to the database. User will be overwritten
+// if it already exists.
+// It also clears the password field afterwards.
+func (s *SQLStore) UserPut(u *gaia.User, encryptPassword bool) error {
+	// Encrypt password before we save it
+	if encryptPassword {
+		if err := encryptUserPassword(u); err != nil {
+			return err
+		}
+	}
+
+	m, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+
+	// Clear password from origin object
+	u.Password = ""
+
+	return s.exec(`INSERT INTO users (username, data) VALUES (?, ?)
+		ON CONFLICT (username) DO UPDATE SET data = excluded.data`, u.Username, string(m))
+}
+
+// UserAuth looks up a user by given username.
+// Then it compares passwords and returns user obj if
+// given password is valid. Returns nil if password was
+// wrong or user not found.
+func (s *SQLStore) UserAuth(u *gaia.User, updateLastLogin bool) (*gaia.User, error) {
+	return userAuth(s, u, updateLastLogin)
+}
+
+// UserGet looks up a user by given username.
+// Returns nil if user was not found.
+func (s *SQLStore) UserGet(username string) (*gaia.User, error) {
+	data, err := s.getData(`SELECT data FROM users WHERE username = ?`, username)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	user := &gaia.User{}
+	return user, json.Unmarshal(data, user)
+}
+
+// UserGetAll returns all stored users.
+func (s *SQLStore) UserGetAll() ([]gaia.User, error) {
+	var users []gaia.User
+
+	err := s.forEachData(func(data []byte) error {
+		u := gaia.User{}
+		if err := json.Unmarshal(data, &u); err != nil {
+			return err
+		}
+
+		// Remove password for security reasons
+		u.Password = ""
+		users = append(users, u)
+		return nil
+	}, `SELECT data FROM users ORDER BY username`)
+	return users, err
+}
+
+// UserDelete deletes the given user.
+func (s *SQLStore) UserDelete(u string) error {
+	return s.exec(`DELETE FROM users WHERE username = ?`, u)
+}
+
+// UserPermissionsGet gets the permission data for a given username.
+func (s *SQLStore) UserPermissionsGet(username string) (*gaia.UserPermission, error) {
+	data, err := s.getData(`SELECT data FROM user_permissions WHERE username = ?`, username)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	perms := &gaia.UserPermission{}
+	return perms, json.Unmarshal(data, perms)
+}
+
+// UserPermissionsPut adds or updates user permissions.
+func (s *SQLStore) UserPermissionsPut(perms *gaia.UserPermission) error {
+	m, err := json.Marshal(perms)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(`INSERT INTO user_permissions (username, data) VALUES (?, ?)
+		ON CONFLICT (username) DO UPDATE SET data = excluded.data`, perms.Username, string(m))
+}
+
+// UserPermissionsDelete deletes permission data for a given username.
+func (s *SQLStore) UserPermissionsDelete(username string) error {
+	return s.exec(`DELETE FROM user_permissions WHERE username = ?`, username)
+}
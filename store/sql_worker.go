@@ -0,0 +1,56 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// WorkerPut stores the given worker in the database.
+// Worker object will be overwritten in case it already exist.
+func (s *SQLStore) WorkerPut(w *gaia.Worker) error {
+	m, err := json.Marshal(*w)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(`INSERT INTO workers (unique_id, data) VALUES (?, ?)
+		ON CONFLICT (unique_id) DO UPDATE SET data = excluded.data`, w.UniqueID, string(m))
+}
+
+// WorkerGetAll returns all existing worker objects from the store.
+// It returns an error when the action failed.
+func (s *SQLStore) WorkerGetAll() ([]*gaia.Worker, error) {
+	var worker []*gaia.Worker
+
+	err := s.forEachData(func(data []byte) error {
+		w := &gaia.Worker{}
+		if err := json.Unmarshal(data, w); err != nil {
+			return err
+		}
+		worker = append(worker, w)
+		return nil
+	}, `SELECT data FROM workers ORDER BY unique_id`)
+	return worker, err
+}
+
+// WorkerDeleteAll deletes all worker objects.
+func (s *SQLStore) WorkerDeleteAll() error {
+	return s.exec(`DELETE FROM workers`)
+}
+
+// WorkerDelete deletes a worker by the given identifier.
+func (s *SQLStore) WorkerDelete(id string) error {
+	return s.exec(`DELETE FROM workers WHERE unique_id = ?`, id)
+}
+
+// WorkerGet gets a worker by the given identifier.
+func (s *SQLStore) WorkerGet(id string) (*gaia.Worker, error) {
+	data, err := s.getData(`SELECT data FROM workers WHERE unique_id = ?`, id)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	worker := &gaia.Worker{}
+	return worker, json.Unmarshal(data, worker)
+}
@@ -32,6 +32,10 @@ var (
 	// Name of the bucket where we store all pipeline runs.
 	pipelineRunBucket = []byte("PipelineRun")
 
+	// Name of the bucket where we index all pipeline runs.
+	// It contains a nested bucket for every pipeline.
+	pipelineRunIndexBucket = []byte("PipelineRunIndex")
+
 	// Name of the bucket where we store information about settings
 	settingsBucket = []byte("Settings")
 
@@ -40,6 +44,10 @@ var (
 
 	// SHA pair bucket.
 	shaPairBucket = []byte("SHAPair")
+
+	// Name of the bucket where we store the notification delivery history.
+	// It contains a nested bucket for every pipeline.
+	notificationDeliveryBucket = []byte("NotificationDelivery")
 )
 
 const (
@@ -51,6 +59,15 @@ const (
 
 	// Bolt database file name
 	boltDBFileName = "gaia.db"
+
+	// TypeBolt is the store type of the default bolt store
+	TypeBolt = "bolt"
+
+	// TypeSQLite is the store type of the SQL store backed by SQLite
+	TypeSQLite = "sqlite"
+
+	// TypePostgres is the store type of the SQL store backed by PostgreSQL
+	TypePostgres = "postgres"
 )
 
 // BoltStore represents the access type for store
@@ -79,9 +96,11 @@ type GaiaStore interface {
 	PipelineGetRunHighestID(pipeline *gaia.Pipeline) (id int, err error)
 	PipelinePutRun(r *gaia.PipelineRun) error
 	PipelineGetScheduled(limit int) ([]*gaia.PipelineRun, error)
+	PipelineGetRunsByStatus(status gaia.PipelineRunStatus) ([]*gaia.PipelineRun, error)
 	PipelineGetRunByPipelineIDAndID(pipelineid int, runid int) (*gaia.PipelineRun, error)
 	PipelineGetAllRuns() ([]gaia.PipelineRun, error)
 	PipelineGetAllRunsByPipelineID(pipelineID int) ([]gaia.PipelineRun, error)
+	PipelineListRuns(filter gaia.PipelineRunFilter, page gaia.Page) (*gaia.PipelineRunList, error)
 	PipelineGetLatestRun(pipelineID int) (*gaia.PipelineRun, error)
 	PipelineGetRunByID(runID string) (*gaia.PipelineRun, error)
 	PipelineDelete(id int) error
@@ -101,6 +120,8 @@ type GaiaStore interface {
 	WorkerGet(id string) (*gaia.Worker, error)
 	UpsertSHAPair(pair gaia.SHAPair) error
 	GetSHAPair(pipelineID int) (bool, gaia.SHAPair, error)
+	NotificationDeliveryPut(d *gaia.NotificationDelivery) error
+	NotificationDeliveryList(pipelineID int) ([]*gaia.NotificationDelivery, error)
 	CasbinStore() persist.BatchAdapter
 }
 
@@ -108,6 +129,21 @@ type GaiaStore interface {
 // wouldn't implement GaiaStore this line wouldn't compile.
 var _ GaiaStore = (*BoltStore)(nil)
 
+// New creates a new store instance of the given store type.
+// The data source name is only used by the SQL store types.
+func New(storeType, dsn string) (GaiaStore, error) {
+	switch storeType {
+	case "", TypeBolt:
+		return NewBoltStore(), nil
+	case TypeSQLite:
+		return NewSQLStore(sqliteDriver, dsn), nil
+	case TypePostgres:
+		return NewSQLStore(postgresDriver, dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported store type: %s", storeType)
+	}
+}
+
 // NewBoltStore creates a new instance of Store.
 func NewBoltStore() *BoltStore {
 	s := &BoltStore{}
@@ -187,11 +223,23 @@ func (s *BoltStore) setupDatabase() error {
 	setP.update(settingsBucket)
 	setP.update(workerBucket)
 	setP.update(shaPairBucket)
+	setP.update(notificationDeliveryBucket)
 
 	if setP.err != nil {
 		return setP.err
 	}
 
+	// Index the runs of databases created before the run index existed
+	if err := s.createPipelineRunIndexIfNotExisting(); err != nil {
+		return err
+	}
+
+	return createDefaultUsers(s)
+}
+
+// createDefaultUsers makes sure that the admin and the auto user exist
+// and that all users have permissions.
+func createDefaultUsers(s GaiaStore) error {
 	// Make sure that the user "admin" does exist
 	admin, err := s.UserGet(adminUsername)
 	if err != nil {
@@ -211,7 +259,7 @@ func (s *BoltStore) setupDatabase() error {
 		}
 	}
 
-	err = s.CreatePermissionsIfNotExisting()
+	err = createPermissionsIfNotExisting(s)
 	if err != nil {
 		return err
 	}
@@ -248,6 +296,11 @@ func itob(v int) []byte {
 	return b
 }
 
+// btoi returns the int representation of the given 8-byte big endian value.
+func btoi(b []byte) int {
+	return int(binary.BigEndian.Uint64(b))
+}
+
 // CasbinStore is as a getter for the Casbin store adapter.
 func (s *BoltStore) CasbinStore() persist.BatchAdapter {
 	return s.casbinAdapter
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gofrs/uuid"
+	bolt "go.etcd.io/bbolt"
 
 	"github.com/gaia-pipeline/gaia"
 )
@@ -521,6 +522,20 @@ func TestPipelineGetScheduled(t *testing.T) {
 		t.Fatalf("expected %d runs, got %d", 1, len(runs))
 	}
 
+	pipelineRun2.Status = gaia.RunWaitingApproval
+	err = store.PipelinePutRun(pipelineRun2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err = store.PipelineGetRunsByStatus(gaia.RunWaitingApproval)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(runs) != 1 || runs[0].ID != 2 {
+		t.Fatalf("expected run 2 waiting for approval, got %v", runs)
+	}
 }
 
 func TestPipelineGetRunByPipelineIDAndID(t *testing.T) {
@@ -762,3 +777,141 @@ func TestUserPermissionsPutGetDelete(t *testing.T) {
 	}
 
 }
+
+func TestPipelineListRuns(t *testing.T) {
+	gaia.Cfg.Bolt.Mode = 0600
+	stores := map[string]GaiaStore{
+		TypeBolt:   NewBoltStore(),
+		TypeSQLite: NewSQLStore(sqliteDriver, ""),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			tmp, err := ioutil.TempDir("", "TestPipelineListRuns")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(tmp)
+
+			if err := store.Init(tmp); err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+
+			start := time.Now()
+			for i := 1; i <= 25; i++ {
+				r := &gaia.PipelineRun{
+					UniqueID:    uuid.Must(uuid.NewV4()).String(),
+					ID:          i,
+					PipelineID:  1,
+					Status:      gaia.RunSuccess,
+					StartReason: gaia.StartReasonManual,
+					StartDate:   start.Add(time.Duration(i) * time.Minute),
+				}
+				if i%5 == 0 {
+					r.Status = gaia.RunFailed
+					r.StartReason = gaia.StartReasonScheduled
+				}
+				if err := store.PipelinePutRun(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Run of another pipeline
+			if err := store.PipelinePutRun(&gaia.PipelineRun{UniqueID: "other", ID: 26, PipelineID: 2}); err != nil {
+				t.Fatal(err)
+			}
+
+			list, err := store.PipelineListRuns(gaia.PipelineRunFilter{PipelineID: 1}, gaia.Page{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if list.Total != 25 || list.Page != 1 || list.PerPage != defaultRunsPerPage || len(list.Runs) != defaultRunsPerPage {
+				t.Fatalf("unexpected first page: total %d, page %d, per page %d, runs %d", list.Total, list.Page, list.PerPage, len(list.Runs))
+			}
+			if list.Runs[0].ID != 25 || list.Runs[19].ID != 6 {
+				t.Fatalf("expected runs 25 to 6 but got %d to %d", list.Runs[0].ID, list.Runs[19].ID)
+			}
+
+			list, err = store.PipelineListRuns(gaia.PipelineRunFilter{PipelineID: 1}, gaia.Page{Number: 2, PerPage: 20})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(list.Runs) != 5 || list.Runs[4].ID != 1 {
+				t.Fatalf("expected last 5 runs but got %d", len(list.Runs))
+			}
+
+			filter := gaia.PipelineRunFilter{
+				PipelineID:    1,
+				Status:        []gaia.PipelineRunStatus{gaia.RunFailed},
+				StartReason:   gaia.StartReasonScheduled,
+				StartedAfter:  start.Add(11 * time.Minute),
+				StartedBefore: start.Add(20 * time.Minute),
+			}
+			list, err = store.PipelineListRuns(filter, gaia.Page{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if list.Total != 1 || len(list.Runs) != 1 || list.Runs[0].ID != 15 {
+				t.Fatalf("expected only run 15 but got %d runs", list.Total)
+			}
+
+			list, err = store.PipelineListRuns(gaia.PipelineRunFilter{PipelineID: 3}, gaia.Page{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if list.Total != 0 || list.Runs == nil || len(list.Runs) != 0 {
+				t.Fatalf("expected empty list but got %v", list)
+			}
+		})
+	}
+}
+
+func TestCreatePipelineRunIndexIfNotExisting(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestCreatePipelineRunIndexIfNotExisting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	store := NewBoltStore()
+	gaia.Cfg.Bolt.Mode = 0600
+	if err := store.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		r := &gaia.PipelineRun{UniqueID: uuid.Must(uuid.NewV4()).String(), ID: i, PipelineID: 1}
+		if err := store.PipelinePutRun(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Simulate a database without the run index
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(pipelineRunIndexBucket)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	if err := store.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	runs, err := store.PipelineGetAllRunsByPipelineID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 3 {
+		t.Fatalf("expected 3 indexed runs but got %d", len(runs))
+	}
+	highestID, err := store.PipelineGetRunHighestID(&gaia.Pipeline{ID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if highestID != 3 {
+		t.Fatalf("expected highest id 3 but got %d", highestID)
+	}
+}
@@ -1,6 +1,10 @@
 package store
 
 import (
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+
 	"github.com/gaia-pipeline/gaia"
 	"github.com/gaia-pipeline/gaia/helper/rolehelper"
 )
@@ -8,6 +12,11 @@ import (
 // CreatePermissionsIfNotExisting iterates any existing users and creates default permissions if they don't exist.
 // This is most probably when they have upgraded to the Gaia version where permissions was added.
 func (s *BoltStore) CreatePermissionsIfNotExisting() error {
+	return createPermissionsIfNotExisting(s)
+}
+
+// createPermissionsIfNotExisting creates default permissions for all users of the given store without permissions.
+func createPermissionsIfNotExisting(s GaiaStore) error {
 	users, _ := s.UserGetAll()
 	for _, user := range users {
 		perms, err := s.UserPermissionsGet(user.Username)
@@ -28,3 +37,25 @@ func (s *BoltStore) CreatePermissionsIfNotExisting() error {
 	}
 	return nil
 }
+
+// createPipelineRunIndexIfNotExisting creates the pipeline run index and adds all existing runs to it.
+// This is required when they have upgraded from a Gaia version without the pipeline run index.
+func (s *BoltStore) createPipelineRunIndexIfNotExisting() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(pipelineRunIndexBucket) != nil {
+			return nil
+		}
+		if _, err := tx.CreateBucket(pipelineRunIndexBucket); err != nil {
+			return err
+		}
+
+		// Index all existing pipeline runs
+		return tx.Bucket(pipelineRunBucket).ForEach(func(k, v []byte) error {
+			r := &gaia.PipelineRun{}
+			if err := json.Unmarshal(v, r); err != nil {
+				return err
+			}
+			return putPipelineRunIndex(tx, r)
+		})
+	})
+}
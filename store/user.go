@@ -17,11 +17,9 @@ import (
 func (s *BoltStore) UserPut(u *gaia.User, encryptPassword bool) error {
 	// Encrypt password before we save it
 	if encryptPassword {
-		hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.MinCost)
-		if err != nil {
+		if err := encryptUserPassword(u); err != nil {
 			return err
 		}
-		u.Password = string(hash)
 	}
 
 	return s.db.Update(func(tx *bolt.Tx) error {
@@ -47,6 +45,11 @@ func (s *BoltStore) UserPut(u *gaia.User, encryptPassword bool) error {
 // given password is valid. Returns nil if password was
 // wrong or user not found.
 func (s *BoltStore) UserAuth(u *gaia.User, updateLastLogin bool) (*gaia.User, error) {
+	return userAuth(s, u, updateLastLogin)
+}
+
+// userAuth authenticates the given user against the users of the given store.
+func userAuth(s GaiaStore, u *gaia.User, updateLastLogin bool) (*gaia.User, error) {
 	// Look up user
 	user, err := s.UserGet(u.Username)
 
@@ -140,3 +143,13 @@ func (s *BoltStore) UserDelete(u string) error {
 		return b.Delete([]byte(u))
 	})
 }
+
+// encryptUserPassword replaces the password of the given user with its bcrypt hash.
+func encryptUserPassword(u *gaia.User) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.MinCost)
+	if err != nil {
+		return err
+	}
+	u.Password = string(hash)
+	return nil
+}
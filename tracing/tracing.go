@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+const (
+	// tracerName is the name of the tracer which creates all Gaia spans.
+	tracerName = "github.com/gaia-pipeline/gaia"
+
+	// serviceName is the service name of all exported spans.
+	serviceName = "gaia"
+)
+
+// Init configures the global tracer provider which exports all spans via
+// OTLP to the collector listening at the given gRPC endpoint.
+// The returned function flushes all pending spans and stops the exporter.
+func Init(ctx context.Context, endpoint string, insecure bool) (func(context.Context) error, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := []attribute.KeyValue{
+		semconv.ServiceNameKey.String(serviceName),
+		attribute.String("gaia.mode", gaia.Cfg.ModeRaw),
+	}
+	if gaia.Cfg.WorkerName != "" {
+		attrs = append(attrs, semconv.ServiceInstanceIDKey.String(gaia.Cfg.WorkerName))
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, attrs...)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer used to create all Gaia spans.
+// Spans are not recorded if tracing has not been initialized.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// SetRunTrace stores the trace of the span in the given context at the
+// pipeline run. All further spans of the run become part of this trace.
+func SetRunTrace(ctx context.Context, r *gaia.PipelineRun) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	r.TraceID = sc.TraceID().String()
+	r.TraceSpanID = sc.SpanID().String()
+}
+
+// RunContext returns a copy of the given context which carries the trace
+// of the given pipeline run. The context is returned unchanged if the run
+// has no trace.
+func RunContext(ctx context.Context, r *gaia.PipelineRun) context.Context {
+	traceID, err := trace.TraceIDFromHex(r.TraceID)
+	if err != nil {
+		return ctx
+	}
+	spanID, err := trace.SpanIDFromHex(r.TraceSpanID)
+	if err != nil {
+		return ctx
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	return trace.ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// ServerOptions returns the options for gRPC servers which continue
+// the traces propagated by the clients.
+func ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor()),
+	}
+}
+
+// DialOptions returns the options for gRPC clients which propagate
+// the trace of the call context to the server.
+func DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
+	}
+}
+
+// EndSpan records the given error at the span, if any, and ends the span.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
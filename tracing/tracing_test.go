@@ -0,0 +1,60 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestRunTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	// Spans of runs without a trace start a new trace
+	r := &gaia.PipelineRun{}
+	if ctx := RunContext(context.Background(), r); trace.SpanContextFromContext(ctx).IsValid() {
+		t.Fatal("expected no span context for a run without trace")
+	}
+
+	ctx, root := Tracer().Start(context.Background(), "root")
+	SetRunTrace(ctx, r)
+	root.End()
+	if r.TraceID != root.SpanContext().TraceID().String() || r.TraceSpanID != root.SpanContext().SpanID().String() {
+		t.Fatalf("expected run trace %s/%s but got %s/%s", root.SpanContext().TraceID(), root.SpanContext().SpanID(), r.TraceID, r.TraceSpanID)
+	}
+
+	// Spans created later with the run context are children of the root span
+	_, child := Tracer().Start(RunContext(context.Background(), r), "child")
+	EndSpan(child, errors.New("failed"))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans but got %d", len(spans))
+	}
+	if spans[1].Parent().SpanID() != root.SpanContext().SpanID() || spans[1].SpanContext().TraceID() != root.SpanContext().TraceID() {
+		t.Fatal("expected child span to be part of the run trace")
+	}
+	if spans[1].Status().Code != codes.Error || len(spans[1].Events()) != 1 {
+		t.Fatalf("expected child span to record the error but got status %v", spans[1].Status())
+	}
+}
+
+func TestSetRunTraceWithoutTracing(t *testing.T) {
+	r := &gaia.PipelineRun{}
+	ctx, span := Tracer().Start(context.Background(), "noop")
+	defer span.End()
+	SetRunTrace(ctx, r)
+	if r.TraceID != "" || r.TraceSpanID != "" {
+		t.Fatalf("expected no run trace without tracing but got %s/%s", r.TraceID, r.TraceSpanID)
+	}
+}
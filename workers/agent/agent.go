@@ -11,6 +11,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -20,8 +21,11 @@ import (
 	"github.com/gaia-pipeline/gaia"
 	"github.com/gaia-pipeline/gaia/helper/filehelper"
 	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
+	"github.com/gaia-pipeline/gaia/metrics"
 	"github.com/gaia-pipeline/gaia/security"
+	"github.com/gaia-pipeline/gaia/services"
 	"github.com/gaia-pipeline/gaia/store"
+	"github.com/gaia-pipeline/gaia/tracing"
 	"github.com/gaia-pipeline/gaia/workers/agent/api"
 	gp "github.com/gaia-pipeline/gaia/workers/pipeline"
 	pb "github.com/gaia-pipeline/gaia/workers/proto"
@@ -77,15 +81,26 @@ type Agent struct {
 
 	// Signal channel for this agent
 	exitChan chan os.Signal
+
+	// logOffsets holds the number of log bytes which have been already
+	// shipped to the primary instance per pipeline run and job.
+	// The job id zero represents the combined log file of the pipeline run.
+	logOffsets map[string]map[uint32]int64
+
+	// shippedArtifacts holds the checksums of the artifacts which have been
+	// already shipped to the primary instance per pipeline run and artifact path.
+	shippedArtifacts map[string]map[string]string
 }
 
 // InitAgent initiates the agent instance
 func InitAgent(exitChan chan os.Signal, scheduler service.GaiaScheduler, pipelineService gp.Servicer, store store.GaiaStore, certPath string) *Agent {
 	ag := &Agent{
-		exitChan:        exitChan,
-		scheduler:       scheduler,
-		store:           store,
-		pipelineService: pipelineService,
+		exitChan:         exitChan,
+		scheduler:        scheduler,
+		store:            store,
+		pipelineService:  pipelineService,
+		logOffsets:       make(map[string]map[uint32]int64),
+		shippedArtifacts: make(map[string]map[string]string),
 	}
 
 	// Set path to local certificates
@@ -105,9 +120,9 @@ func (a *Agent) StartAgent() (func(), error) {
 		return nil, err
 	}
 
-	// Setup gRPC connection
-	dialOption := grpc.WithTransportCredentials(clientTLS)
-	conn, err := grpc.Dial(gaia.Cfg.WorkerGRPCHostURL, dialOption)
+	// Setup gRPC connection which propagates the traces of the pipeline runs
+	dialOptions := append(tracing.DialOptions(), grpc.WithTransportCredentials(clientTLS))
+	conn, err := grpc.Dial(gaia.Cfg.WorkerGRPCHostURL, dialOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to remote host: %s", err.Error())
 	}
@@ -270,6 +285,11 @@ func (a *Agent) scheduleWork() {
 	// Print info output
 	gaia.Cfg.Logger.Trace("try to pull work from Gaia primary instance...")
 
+	// A draining worker does not pull new work
+	if a.scheduler.Draining() {
+		return
+	}
+
 	// Set available worker slots. Primary instance decides if worker needs work.
 	a.self.WorkerSlots = a.scheduler.GetFreeWorkers()
 
@@ -292,6 +312,7 @@ func (a *Agent) scheduleWork() {
 
 		// Stream was closed
 		if err == io.EOF {
+			metrics.AgentLastContact.SetToCurrentTime()
 			break
 		}
 		if err != nil {
@@ -330,16 +351,27 @@ func (a *Agent) scheduleWork() {
 			ScheduleDate: time.Unix(pipelineRunPB.ScheduleDate, 0),
 			PipelineType: gaia.PipelineType(pipelineRunPB.PipelineType),
 			Docker:       pipelineRunPB.Docker,
+			JobTimeout:   int(pipelineRunPB.JobTimeout),
+			RunTimeout:   int(pipelineRunPB.RunTimeout),
+			TraceID:      pipelineRunPB.TraceId,
+			TraceSpanID:  pipelineRunPB.TraceSpanId,
+		}
+		if pipelineRunPB.StartDate > 0 {
+			pipelineRun.StartDate = time.Unix(pipelineRunPB.StartDate, 0)
 		}
 
 		// Convert jobs
 		jobsMap := make(map[uint32]*gaia.Job)
 		for _, job := range pipelineRunPB.Jobs {
 			j := &gaia.Job{
-				ID:          job.UniqueId,
-				Title:       job.Title,
-				Status:      gaia.JobStatus(job.Status),
-				Description: job.Description,
+				ID:           job.UniqueId,
+				Title:        job.Title,
+				Status:       gaia.JobStatus(job.Status),
+				Description:  job.Description,
+				RunCondition: gaia.JobRunCondition(job.RunCondition),
+			}
+			if job.Approval {
+				j.Approval = &gaia.ApprovalPolicy{}
 			}
 			jobsMap[j.ID] = j
 			pipelineRun.Jobs = append(pipelineRun.Jobs, j)
@@ -355,6 +387,35 @@ func (a *Agent) scheduleWork() {
 				}
 				j.Args = append(j.Args, a)
 			}
+
+			// Outputs of jobs which have been taken over from a previous run
+			for _, output := range job.Outputs {
+				j.Outputs = append(j.Outputs, &gaia.Argument{
+					Description: output.Description,
+					Type:        output.Type,
+					Key:         output.Key,
+					Value:       output.Value,
+				})
+			}
+
+			// Convert retry policy and attempts
+			if job.Retry != nil {
+				j.Retry = &gaia.RetryPolicy{
+					MaxAttempts:     int(job.Retry.MaxAttempts),
+					Backoff:         int(job.Retry.Backoff),
+					InfraErrorsOnly: job.Retry.InfraErrorsOnly,
+				}
+			}
+			for _, attempt := range job.Attempts {
+				j.Attempts = append(j.Attempts, &gaia.JobAttempt{
+					Attempt:    int(attempt.Attempt),
+					StartDate:  time.Unix(attempt.StartDate, 0),
+					FinishDate: time.Unix(attempt.FinishDate, 0),
+					Status:     gaia.JobStatus(attempt.Status),
+					Error:      attempt.Error,
+					InfraError: attempt.InfraError,
+				})
+			}
 		}
 
 		// Convert dependencies
@@ -389,7 +450,7 @@ func (a *Agent) scheduleWork() {
 		pipelineFullPath := filepath.Join(gaia.Cfg.PipelinePath, pipelineName)
 		if _, err := os.Stat(pipelineFullPath); err != nil {
 			// Download binary from remote gaia instance
-			if err = a.streamBinary(pipelineRunPB, pipelineFullPath); err != nil {
+			if err = a.streamBinary(tracing.RunContext(ctx, pipelineRun), pipelineRunPB, pipelineFullPath); err != nil {
 				gaia.Cfg.Logger.Error("failed to download pipeline binary from remote instance", "error", err.Error(), "pipelinerun", pipelineRunPB)
 				reschedulePipeline()
 				return
@@ -414,7 +475,7 @@ func (a *Agent) scheduleWork() {
 					reschedulePipeline()
 					return
 				}
-				if err := a.streamBinary(pipelineRunPB, pipelineFullPath); err != nil {
+				if err := a.streamBinary(tracing.RunContext(ctx, pipelineRun), pipelineRunPB, pipelineFullPath); err != nil {
 					gaia.Cfg.Logger.Error("failed to download pipeline binary from remote instance", "error", err.Error(), "pipelinerun", pipelineRunPB)
 					reschedulePipeline()
 					return
@@ -508,6 +569,44 @@ func (a *Agent) scheduleWork() {
 			}
 		}
 		pipelineRun.Jobs = pipeline.Jobs
+
+		// The retry policies, run conditions, approvals and arguments are defined at the primary instance.
+		// Jobs which have been taken over from a previous run or which have been
+		// finished before the run has been paused are already done.
+		for _, job := range pipelineRun.Jobs {
+			job.Retry = nil
+			job.RunCondition = ""
+			job.Approval = nil
+			job.ApprovalInfo = nil
+			job.Status = gaia.JobWaitingExec
+			job.FailPipeline = false
+			job.Outputs = nil
+			if j, ok := jobsMap[job.ID]; ok {
+				job.Retry = j.Retry
+				job.RunCondition = j.RunCondition
+				job.Approval = j.Approval
+				if len(j.Args) > 0 {
+					job.Args = j.Args
+				}
+				switch j.Status {
+				case gaia.JobSuccess, gaia.JobSkipped:
+					job.Status = j.Status
+					job.Outputs = j.Outputs
+				case gaia.JobFailed, gaia.JobTimedOut:
+					job.Status = j.Status
+					job.FailPipeline = true
+				}
+			}
+		}
+
+		// The values of secret parameters are kept in the vault of this worker and
+		// are resolved when the job is executed. They are never stored together with the run.
+		if err = storeSecretParameters(pipelineRun); err != nil {
+			gaia.Cfg.Logger.Error("failed to store secret parameters in vault", "error", err.Error(), "pipelinerun", pipelineRun.UniqueID)
+			reschedulePipeline()
+			return
+		}
+
 		// Store pipeline
 		if err = a.store.PipelinePut(pipeline); err != nil {
 			gaia.Cfg.Logger.Error("failed to store pipeline in store", "error", err.Error(), "pipelinerun", pipelineRunPB)
@@ -534,6 +633,50 @@ func (a *Agent) scheduleWork() {
 	}
 }
 
+// hasSecretParameters returns true if a job of the given pipeline run has secret parameters.
+// If withValue is true, only secret parameters which carry a value are considered.
+func hasSecretParameters(r *gaia.PipelineRun, withValue bool) bool {
+	for _, job := range r.Jobs {
+		for _, arg := range job.Args {
+			if arg.Type == string(gaia.ParameterSecret) && (!withValue || arg.Value != "") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// storeSecretParameters moves the values of the secret parameters
+// of the given pipeline run into the vault of this worker.
+func storeSecretParameters(r *gaia.PipelineRun) error {
+	if !hasSecretParameters(r, true) {
+		return nil
+	}
+	v, err := services.DefaultVaultService()
+	if err != nil {
+		return err
+	}
+	for _, job := range r.Jobs {
+		if err := pipelinehelper.StoreSecretParameters(v, r.UniqueID, job.Args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeSecretParameters removes the values of the secret parameters
+// of the given pipeline run from the vault of this worker.
+func removeSecretParameters(r *gaia.PipelineRun) error {
+	if !hasSecretParameters(r, false) {
+		return nil
+	}
+	v, err := services.DefaultVaultService()
+	if err != nil {
+		return err
+	}
+	return pipelinehelper.RemoveSecretParameters(v, r.UniqueID)
+}
+
 // compareSHAs compares shas of the binaries with possibly stored sha pairs. First it compares the original if they match
 // second it compares the local sha with the new one that the worker possibly rebuilt. If there is no entry,
 // we return false, because we don't know anything about the sha.
@@ -595,9 +738,13 @@ func (a *Agent) rebuildWorkerBinary(ctx context.Context, pipeline *gaia.Pipeline
 }
 
 // streamBinary streams the binary in chunks from the remote instance to the given path.
-func (a *Agent) streamBinary(pipelineRunPB *pb.PipelineRun, pipelinePath string) error {
+// The download is recorded as a span of the trace carried by the given context.
+func (a *Agent) streamBinary(ctx context.Context, pipelineRunPB *pb.PipelineRun, pipelinePath string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "stream pipeline binary")
+	defer func() { tracing.EndSpan(span, err) }()
+
 	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	ctx = metadata.AppendToOutgoingContext(ctx, idMDKey, a.self.UniqueId)
 	defer cancel()
 
@@ -640,6 +787,42 @@ func (a *Agent) streamBinary(pipelineRunPB *pb.PipelineRun, pipelinePath string)
 	return os.Chmod(pipelinePath, gaia.ExecutablePermission)
 }
 
+// cancelWork asks the Gaia primary instance which of the pipeline runs executed
+// by this agent have been cancelled and stops them. The cancelled status is
+// reported to the primary instance with the next update.
+func (a *Agent) cancelWork(ctx context.Context, runs []gaia.PipelineRun) {
+	ids := &pb.PipelineRunIDs{}
+	runsMap := make(map[string]gaia.PipelineRun)
+	for _, run := range runs {
+		if run.Status == gaia.RunFailed || run.Status == gaia.RunSuccess || run.Status == gaia.RunCancelled || run.Status == gaia.RunReschedule {
+			continue
+		}
+		ids.UniqueIds = append(ids.UniqueIds, run.UniqueID)
+		runsMap[run.UniqueID] = run
+	}
+	if len(ids.UniqueIds) == 0 {
+		return
+	}
+
+	cancelled, err := a.client.GetCancelledWork(ctx, ids)
+	if err != nil {
+		gaia.Cfg.Logger.Error("failed to get cancelled work from remote instance", "error", err.Error())
+		return
+	}
+
+	for _, id := range cancelled.UniqueIds {
+		run, ok := runsMap[id]
+		if !ok {
+			continue
+		}
+		if err := a.scheduler.StopPipelineRun(&gaia.Pipeline{ID: run.PipelineID}, run.ID); err != nil {
+			gaia.Cfg.Logger.Error("failed to stop cancelled pipeline run", "error", err.Error(), "pipelinerun", run)
+			continue
+		}
+		gaia.Cfg.Logger.Info("pipeline run has been cancelled by the primary instance", "pipelineid", run.PipelineID, "runid", run.ID)
+	}
+}
+
 // updateWork is periodically called and it is used to
 // send new information about a pipeline run to the Gaia primary instance.
 func (a *Agent) updateWork() {
@@ -657,6 +840,9 @@ func (a *Agent) updateWork() {
 	ctx = metadata.AppendToOutgoingContext(ctx, idMDKey, a.self.UniqueId)
 	defer cancel()
 
+	// Stop the pipeline runs which have been cancelled at the primary instance
+	a.cancelWork(ctx, runs)
+
 	// Send all pipeline runs to the remote primary instance
 	for _, run := range runs {
 		// Transform to protobuf struct
@@ -669,16 +855,19 @@ func (a *Agent) updateWork() {
 			StartDate:    run.StartDate.Unix(),
 			FinishDate:   run.FinishDate.Unix(),
 			Docker:       run.Docker,
+			TraceId:      run.TraceID,
+			TraceSpanId:  run.TraceSpanID,
 		}
 
 		// Transform pipeline run jobs
 		jobsMap := make(map[uint32]*pb.Job)
 		for _, job := range run.Jobs {
 			j := &pb.Job{
-				UniqueId:    job.ID,
-				Title:       job.Title,
-				Status:      string(job.Status),
-				Description: job.Description,
+				UniqueId:     job.ID,
+				Title:        job.Title,
+				Status:       string(job.Status),
+				Description:  job.Description,
+				RunCondition: string(job.RunCondition),
 			}
 			runPB.Jobs = append(runPB.Jobs, j)
 
@@ -696,6 +885,46 @@ func (a *Agent) updateWork() {
 				}
 				j.Args = append(j.Args, a)
 			}
+
+			// Convert retry policy and attempts
+			if job.Retry != nil {
+				j.Retry = &pb.RetryPolicy{
+					MaxAttempts:     int64(job.Retry.MaxAttempts),
+					Backoff:         int64(job.Retry.Backoff),
+					InfraErrorsOnly: job.Retry.InfraErrorsOnly,
+				}
+			}
+			for _, attempt := range job.Attempts {
+				j.Attempts = append(j.Attempts, &pb.JobAttempt{
+					Attempt:    int64(attempt.Attempt),
+					StartDate:  attempt.StartDate.Unix(),
+					FinishDate: attempt.FinishDate.Unix(),
+					Status:     string(attempt.Status),
+					Error:      attempt.Error,
+					InfraError: attempt.InfraError,
+				})
+			}
+
+			// Convert artifacts
+			for _, artifact := range job.Artifacts {
+				j.Artifacts = append(j.Artifacts, &pb.Artifact{
+					JobId:  artifact.JobID,
+					Name:   artifact.Name,
+					Size:   artifact.Size,
+					Sha256: artifact.SHA256,
+					Date:   artifact.Date.Unix(),
+				})
+			}
+
+			// Convert outputs
+			for _, output := range job.Outputs {
+				j.Outputs = append(j.Outputs, &pb.Argument{
+					Description: output.Description,
+					Type:        output.Type,
+					Key:         output.Key,
+					Value:       output.Value,
+				})
+			}
 		}
 
 		// Convert dependencies
@@ -719,6 +948,11 @@ func (a *Agent) updateWork() {
 			return
 		}
 
+		// Ship job artifacts before their metadata reaches the primary instance
+		if err := a.shipArtifacts(ctx, &run); err != nil {
+			return
+		}
+
 		// Send to remote instance
 		if _, err := a.client.UpdateWork(ctx, runPB); err != nil {
 			gaia.Cfg.Logger.Error("failed to send update information to remote instance", "error", err.Error())
@@ -726,22 +960,61 @@ func (a *Agent) updateWork() {
 		}
 
 		// Remove pipeline run from store when the state is finalized
-		if run.Status == gaia.RunFailed || run.Status == gaia.RunSuccess || run.Status == gaia.RunCancelled || run.Status == gaia.RunReschedule {
+		if run.Status == gaia.RunFailed || run.Status == gaia.RunSuccess || run.Status == gaia.RunCancelled || run.Status == gaia.RunReschedule || run.Status == gaia.RunWaitingApproval {
 			if err = a.store.PipelineRunDelete(run.UniqueID); err != nil {
 				gaia.Cfg.Logger.Error("failed to remove pipeline run from store", "error", err.Error(), "pipelinerun", run)
 			}
+			delete(a.logOffsets, run.UniqueID)
+			delete(a.shippedArtifacts, run.UniqueID)
+			if err = removeSecretParameters(&run); err != nil {
+				gaia.Cfg.Logger.Error("failed to remove secret parameters from vault", "error", err.Error(), "pipelinerun", run.UniqueID)
+			}
 		}
 	}
 }
 
-// shipPipelineLogs ships pipeline from the given pipeline run to the primary instance.
+// shipPipelineLogs ships pipeline logs from the given pipeline run to the primary instance.
+// The combined log file and the log files of all jobs are shipped separately.
 // It will only return an error when an error occurred during transmission, not when
 // no logs for a pipeline are not existent.
 func (a *Agent) shipPipelineLogs(ctx context.Context, run *gaia.PipelineRun) error {
-	// Check if log file exists for pipeline run.
-	// If the file does not exist, we simply skip the shipping.
-	logFilePath := filepath.Join(gaia.Cfg.WorkspacePath, strconv.Itoa(run.PipelineID), strconv.Itoa(run.ID), gaia.LogsFolderName, gaia.LogsFileName)
-	if _, err := os.Stat(logFilePath); err != nil {
+	logFolderPath := filepath.Join(gaia.Cfg.WorkspacePath, strconv.Itoa(run.PipelineID), strconv.Itoa(run.ID), gaia.LogsFolderName)
+	if _, ok := a.logOffsets[run.UniqueID]; !ok {
+		a.logOffsets[run.UniqueID] = make(map[uint32]int64)
+	}
+
+	// Ship combined logs
+	if err := a.shipLogFile(ctx, run, 0, filepath.Join(logFolderPath, gaia.LogsFileName)); err != nil {
+		return err
+	}
+
+	// Ship job logs
+	for _, job := range run.Jobs {
+		if err := a.shipLogFile(ctx, run, job.ID, filepath.Join(logFolderPath, gaia.JobLogsFileName(job.ID))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shipLogFile ships the given log file to the primary instance.
+// Only the part of the log file which has not been shipped yet is transferred.
+// If the file does not exist, we simply skip the shipping.
+func (a *Agent) shipLogFile(ctx context.Context, run *gaia.PipelineRun, jobID uint32, logFilePath string) error {
+	fileInfo, err := os.Stat(logFilePath)
+	if err != nil {
+		return nil
+	}
+
+	// The log file might have been recreated, e.g. the run was rescheduled.
+	// In this case we start shipping from the beginning again.
+	offset := a.logOffsets[run.UniqueID][jobID]
+	if fileInfo.Size() < offset {
+		offset = 0
+	}
+
+	// Nothing new to ship
+	if fileInfo.Size() == offset {
 		return nil
 	}
 
@@ -753,6 +1026,12 @@ func (a *Agent) shipPipelineLogs(ctx context.Context, run *gaia.PipelineRun) err
 	}
 	defer file.Close()
 
+	// Skip the part which has been already shipped
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		gaia.Cfg.Logger.Warn("failed to seek in pipeline run log file via shipPipelineLogs", "error", err.Error(), "pipelinerun", run)
+		return err
+	}
+
 	// Open streaming session to primary instance
 	stream, err := a.client.StreamLogs(ctx)
 	if err != nil {
@@ -763,6 +1042,7 @@ func (a *Agent) shipPipelineLogs(ctx context.Context, run *gaia.PipelineRun) err
 	chunk := &pb.LogChunk{
 		PipelineId: int64(run.PipelineID),
 		RunId:      int64(run.ID),
+		JobId:      jobID,
 	}
 	buffer := make([]byte, chunkSize)
 	for {
@@ -777,19 +1057,108 @@ func (a *Agent) shipPipelineLogs(ctx context.Context, run *gaia.PipelineRun) err
 			break
 		}
 
-		// Set bytes
+		// Set bytes and position
 		chunk.Chunk = buffer[:bytesread]
+		chunk.Offset = offset
 
 		// Stream it to primary instance
 		if err = stream.Send(chunk); err != nil {
 			gaia.Cfg.Logger.Error("failed to stream log chunk to primary instance", "error", err.Error(), "pipelinerun", run)
 			return err
 		}
+		offset += int64(bytesread)
 	}
-	if err = stream.CloseSend(); err != nil {
+	if _, err = stream.CloseAndRecv(); err != nil && err != io.EOF {
 		gaia.Cfg.Logger.Warn("failed to safely close gRPC connection via updatework", "error", err.Error())
 		return err
 	}
+	a.logOffsets[run.UniqueID][jobID] = offset
+	return nil
+}
+
+// shipArtifacts ships all artifacts of the given pipeline run to the primary
+// instance which have not been shipped yet or have changed since, e.g. because
+// the job has been retried. Artifacts which do not exist anymore are skipped.
+func (a *Agent) shipArtifacts(ctx context.Context, run *gaia.PipelineRun) error {
+	if _, ok := a.shippedArtifacts[run.UniqueID]; !ok {
+		a.shippedArtifacts[run.UniqueID] = make(map[string]string)
+	}
+
+	for _, job := range run.Jobs {
+		for _, artifact := range job.Artifacts {
+			key := path.Join(strconv.FormatUint(uint64(job.ID), 10), artifact.Name)
+			if a.shippedArtifacts[run.UniqueID][key] == artifact.SHA256 {
+				continue
+			}
+			if err := a.shipArtifact(ctx, run, artifact); err != nil {
+				return err
+			}
+			a.shippedArtifacts[run.UniqueID][key] = artifact.SHA256
+		}
+	}
+	return nil
+}
+
+// shipArtifact streams the given artifact to the primary instance.
+// If the file does not exist, we simply skip the shipping.
+func (a *Agent) shipArtifact(ctx context.Context, run *gaia.PipelineRun, artifact *gaia.Artifact) error {
+	artifactPath := filepath.Join(gaia.JobArtifactsPath(run.PipelineID, run.ID, artifact.JobID), filepath.FromSlash(artifact.Name))
+	file, err := os.Open(artifactPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	// Open streaming session to primary instance
+	stream, err := a.client.StreamArtifacts(ctx)
+	if err != nil {
+		gaia.Cfg.Logger.Warn("failed to open stream session to primary instance to ship artifacts via shipArtifacts", "error", err.Error(), "pipelinerun", run)
+		return err
+	}
+
+	chunk := &pb.ArtifactChunk{
+		PipelineId: int64(run.PipelineID),
+		RunId:      int64(run.ID),
+		JobId:      artifact.JobID,
+		Name:       artifact.Name,
+	}
+	buffer := make([]byte, chunkSize)
+	var offset int64
+	for {
+		bytesread, err := file.Read(buffer)
+
+		// Check for errors
+		if err != nil {
+			if err != io.EOF {
+				gaia.Cfg.Logger.Warn("error occurred during artifact disk read", "error", err.Error(), "artifact", artifact.Name)
+				return err
+			}
+			break
+		}
+
+		// Set bytes and position
+		chunk.Chunk = buffer[:bytesread]
+		chunk.Offset = offset
+
+		// Stream it to primary instance
+		if err = stream.Send(chunk); err != nil {
+			gaia.Cfg.Logger.Error("failed to stream artifact chunk to primary instance", "error", err.Error(), "artifact", artifact.Name)
+			return err
+		}
+		offset += int64(bytesread)
+	}
+
+	// Empty artifacts are created by a single empty chunk
+	if offset == 0 {
+		if err = stream.Send(chunk); err != nil {
+			gaia.Cfg.Logger.Error("failed to stream artifact chunk to primary instance", "error", err.Error(), "artifact", artifact.Name)
+			return err
+		}
+	}
+	if _, err = stream.CloseAndRecv(); err != nil && err != io.EOF {
+		gaia.Cfg.Logger.Warn("failed to safely close gRPC connection via shipArtifacts", "error", err.Error())
+		return err
+	}
 	return nil
 }
 
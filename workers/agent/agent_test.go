@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net"
@@ -28,6 +29,7 @@ import (
 
 	"github.com/gaia-pipeline/gaia"
 	"github.com/gaia-pipeline/gaia/helper/filehelper"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
 	"github.com/gaia-pipeline/gaia/security"
 	"github.com/gaia-pipeline/gaia/services"
 	"github.com/gaia-pipeline/gaia/store"
@@ -39,11 +41,18 @@ import (
 
 type mockScheduler struct {
 	service.GaiaScheduler
-	err error
+	stopped  []int
+	draining bool
+	err      error
 }
 
 func (ms *mockScheduler) SetPipelineJobs(p *gaia.Pipeline) error { return ms.err }
 func (ms *mockScheduler) GetFreeWorkers() int32                  { return int32(0) }
+func (ms *mockScheduler) Draining() bool                         { return ms.draining }
+func (ms *mockScheduler) StopPipelineRun(p *gaia.Pipeline, runID int) error {
+	ms.stopped = append(ms.stopped, runID)
+	return ms.err
+}
 func (ms *mockScheduler) SchedulePipeline(p *gaia.Pipeline, startedBy string, args []*gaia.Argument) (*gaia.PipelineRun, error) {
 	return nil, ms.err
 }
@@ -127,8 +136,10 @@ var tmpFolder string
 const bufsize = 1024 * 1024
 
 type mockWorkerInterface struct {
-	pbRuns  []*pb.PipelineRun
-	gitRepo *pb.GitRepo
+	pbRuns    []*pb.PipelineRun
+	gitRepo   *pb.GitRepo
+	artifacts map[string][]byte
+	cancelled map[string]bool
 }
 
 func (mw *mockWorkerInterface) GetGitRepo(context.Context, *pb.PipelineID) (*pb.GitRepo, error) {
@@ -225,6 +236,16 @@ func (mw *mockWorkerInterface) UpdateWork(ctx context.Context, pipelineRun *pb.P
 	return &empty.Empty{}, nil
 }
 
+func (mw *mockWorkerInterface) GetCancelledWork(ctx context.Context, ids *pb.PipelineRunIDs) (*pb.PipelineRunIDs, error) {
+	cancelled := &pb.PipelineRunIDs{}
+	for _, id := range ids.UniqueIds {
+		if mw.cancelled[id] {
+			cancelled.UniqueIds = append(cancelled.UniqueIds, id)
+		}
+	}
+	return cancelled, nil
+}
+
 func (mw *mockWorkerInterface) StreamBinary(pipelineRun *pb.PipelineRun, serv pb.Worker_StreamBinaryServer) error {
 	if pipelineRun.PipelineName == "my-cpp-pipeline-broken_cpp" {
 		content, err := ioutil.ReadFile(filepath.Join(tmpFolder, "my-cpp-pipeline_cpp"))
@@ -265,6 +286,19 @@ func (mw *mockWorkerInterface) StreamLogs(stream pb.Worker_StreamLogsServer) err
 	return nil
 }
 
+func (mw *mockWorkerInterface) StreamArtifacts(stream pb.Worker_StreamArtifactsServer) error {
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&empty.Empty{})
+		}
+		if err != nil {
+			return err
+		}
+		mw.artifacts[chunk.Name] = append(mw.artifacts[chunk.Name], chunk.Chunk...)
+	}
+}
+
 func (mw *mockWorkerInterface) Deregister(ctx context.Context, workInst *pb.WorkerInstance) (*empty.Empty, error) {
 	return &empty.Empty{}, nil
 }
@@ -299,6 +333,14 @@ func TestInitAgent(t *testing.T) {
 	}
 }
 
+func TestScheduleWorkDraining(t *testing.T) {
+	gaia.Cfg = &gaia.Config{Logger: hclog.NewNullLogger()}
+
+	// The agent has no connection and must not pull work
+	ag := InitAgent(nil, &mockScheduler{draining: true}, nil, &mockStore{}, "")
+	ag.scheduleWork()
+}
+
 func TestSetupConnectionInfo(t *testing.T) {
 	tmp, err := ioutil.TempDir("", "TestSetupConnectionInfo")
 	if err != nil {
@@ -656,7 +698,7 @@ func TestStreamBinary(t *testing.T) {
 	}
 	pipelinePath := filepath.Join(tmpFolder, "test-pipeline")
 
-	if err := ag.streamBinary(run, pipelinePath); err != nil {
+	if err := ag.streamBinary(context.Background(), run, pipelinePath); err != nil {
 		t.Fatal(err)
 	}
 
@@ -733,6 +775,159 @@ func TestUpdateWork(t *testing.T) {
 	}
 }
 
+func TestCancelWork(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := pb.NewWorkerClient(conn)
+
+	// Init agent
+	mStore := &mockStore{}
+	mScheduler := &mockScheduler{}
+	ag := InitAgent(nil, mScheduler, nil, mStore, "")
+	ag.client = client
+	ag.self = &pb.WorkerInstance{UniqueId: "my-worker"}
+	gaia.Cfg = &gaia.Config{}
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	mW.cancelled = map[string]bool{"second-pipeline-run": true}
+	defer func() { mW.cancelled = nil }()
+
+	runs, _ := mStore.PipelineGetAllRuns()
+	ag.cancelWork(ctx, runs)
+
+	if len(mScheduler.stopped) != 1 || mScheduler.stopped[0] != 2 {
+		t.Fatalf("expected run 2 to be stopped but got %v", mScheduler.stopped)
+	}
+}
+
+func TestShipArtifacts(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ag := InitAgent(nil, &mockScheduler{}, nil, &mockStore{}, "")
+	ag.client = pb.NewWorkerClient(conn)
+	gaia.Cfg = &gaia.Config{
+		WorkspacePath: tmpFolder,
+		Logger:        hclog.NewNullLogger(),
+	}
+
+	// Create test artifacts
+	artifactsFolder := gaia.JobArtifactsPath(1, 3, 7)
+	if err := os.MkdirAll(filepath.Join(artifactsFolder, "reports"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(artifactsFolder, "reports", "coverage.out"), []byte("coverage"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(artifactsFolder, "empty"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	run := &gaia.PipelineRun{
+		UniqueID:   "artifacts-run",
+		ID:         3,
+		PipelineID: 1,
+		Jobs: []*gaia.Job{{ID: 7, Artifacts: []*gaia.Artifact{
+			{JobID: 7, Name: "reports/coverage.out", SHA256: "a"},
+			{JobID: 7, Name: "empty", SHA256: "b"},
+			{JobID: 7, Name: "missing", SHA256: "c"},
+		}}},
+	}
+
+	mW.artifacts = make(map[string][]byte)
+	if err := ag.shipArtifacts(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+	if len(mW.artifacts) != 2 {
+		t.Fatalf("expected 2 shipped artifacts but got %d", len(mW.artifacts))
+	}
+	if string(mW.artifacts["reports/coverage.out"]) != "coverage" {
+		t.Fatalf("expected artifact content 'coverage' but got %q", mW.artifacts["reports/coverage.out"])
+	}
+	if _, ok := mW.artifacts["empty"]; !ok {
+		t.Fatal("expected empty artifact to be shipped")
+	}
+
+	// Unchanged artifacts are not shipped again
+	mW.artifacts = make(map[string][]byte)
+	run.Jobs[0].Artifacts[1].SHA256 = "d"
+	if err := ag.shipArtifacts(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+	if len(mW.artifacts) != 1 {
+		t.Fatalf("expected only the changed artifact to be shipped but got %d", len(mW.artifacts))
+	}
+}
+
+type mockVault struct {
+	data map[string][]byte
+}
+
+func (v *mockVault) LoadSecrets() error { return nil }
+func (v *mockVault) GetAll() []string {
+	keys := make([]string, 0, len(v.data))
+	for k := range v.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+func (v *mockVault) SaveSecrets() error           { return nil }
+func (v *mockVault) Add(key string, value []byte) { v.data[key] = value }
+func (v *mockVault) Remove(key string)            { delete(v.data, key) }
+func (v *mockVault) Get(key string) ([]byte, error) {
+	value, ok := v.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	return value, nil
+}
+
+func TestSecretParameters(t *testing.T) {
+	gaia.Cfg = &gaia.Config{Logger: hclog.NewNullLogger()}
+	v := &mockVault{data: make(map[string][]byte)}
+	services.MockVaultService(v)
+	defer services.MockVaultService(nil)
+
+	run := &gaia.PipelineRun{
+		UniqueID: "first-pipeline-run",
+		Jobs: []*gaia.Job{{
+			Args: []*gaia.Argument{
+				{Type: string(gaia.ParameterSecret), Key: "token", Value: "secret"},
+				{Type: string(gaia.ParameterString), Key: "version", Value: "1.2.3"},
+			},
+		}},
+	}
+
+	// The values are moved into the vault of the worker
+	if err := storeSecretParameters(run); err != nil {
+		t.Fatal(err)
+	}
+	if run.Jobs[0].Args[0].Value != "" || run.Jobs[0].Args[1].Value != "1.2.3" {
+		t.Fatalf("expected only the secret value to be removed but got %v", run.Jobs[0].Args)
+	}
+	if err := pipelinehelper.ResolveSecretParameters(v, run.UniqueID, run.Jobs[0].Args); err != nil {
+		t.Fatal(err)
+	}
+	if run.Jobs[0].Args[0].Value != "secret" {
+		t.Fatalf("expected secret value to be resolved but got '%s'", run.Jobs[0].Args[0].Value)
+	}
+
+	// The values are removed when the run has been handed back
+	if err := removeSecretParameters(run); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.data) != 0 {
+		t.Fatalf("expected vault to be empty but got %v", v.GetAll())
+	}
+}
+
 func TestScheduleWorkExecFormatError(t *testing.T) {
 	ctx := context.Background()
 	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithDialer(bufDialer), grpc.WithInsecure())
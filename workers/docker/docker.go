@@ -8,12 +8,17 @@ import (
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
 
 	"github.com/gaia-pipeline/gaia"
 	"github.com/gaia-pipeline/gaia/security"
 )
 
+// workerIDLabel is the label which marks the containers of docker workers.
+// Its value is the id of the worker.
+const workerIDLabel = "io.gaia-pipeline.worker-id"
+
 // Worker represents the data structure of a docker worker.
 type Worker struct {
 	Host          string `json:"host"`
@@ -54,6 +59,9 @@ func (w *Worker) SetupDockerWorker(workerImage string, workerSecret string) erro
 				"GAIA_WORKER_TAGS=" + fmt.Sprintf("%s,dockerworker", w.WorkerID),
 				"GAIA_WORKER_SECRET=" + workerSecret,
 			},
+			Labels: map[string]string{
+				workerIDLabel: w.WorkerID,
+			},
 		}, &container.HostConfig{}, nil, nil, "")
 	}
 
@@ -152,3 +160,39 @@ func (w *Worker) KillDockerWorker() error {
 	}
 	return nil
 }
+
+// RemoveAllDockerWorkers removes all docker worker containers from the given docker host.
+// It is used to clean up the containers which have been orphaned by a restart.
+func RemoveAllDockerWorkers(host string) error {
+	// Setup docker client
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+	cli, err := client.NewClientWithOpts(client.WithHost(host))
+	if err != nil {
+		gaia.Cfg.Logger.Error("failed to setup docker client", "error", err)
+		return err
+	}
+	cli.NegotiateAPIVersion(ctx)
+
+	// Find all docker worker containers
+	containers, err := cli.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", workerIDLabel)),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Kill containers
+	for _, c := range containers {
+		if err := cli.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{
+			RemoveVolumes: true,
+			Force:         true,
+		}); err != nil {
+			gaia.Cfg.Logger.Error("failed to remove orphaned docker worker", "error", err, "containerid", c.ID)
+			return err
+		}
+		gaia.Cfg.Logger.Info("removed orphaned docker worker", "workerid", c.Labels[workerIDLabel], "containerid", c.ID)
+	}
+	return nil
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/gaia-pipeline/gaia/security"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/metrics"
 	"github.com/gaia-pipeline/gaia/services"
 )
 
@@ -86,7 +88,9 @@ func (s *GaiaPipelineService) CreatePipeline(p *gaia.CreatePipeline) {
 	}
 
 	// Run compile process
+	buildStart := time.Now()
 	err = bP.ExecuteBuild(p)
+	metrics.ObserveBuild(p.Pipeline.Type, buildStart, err)
 	if err != nil {
 		p.StatusType = gaia.CreatePipelineFailed
 		_ = storeService.CreatePipelinePut(p)
@@ -7,6 +7,7 @@ import (
 	"log"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gaia-pipeline/gaia"
 	"github.com/gaia-pipeline/gaia/services"
@@ -36,6 +37,17 @@ func (ms *mockScheduler) Init() {}
 func (ms *mockScheduler) SchedulePipeline(p *gaia.Pipeline, startedBy string, args []*gaia.Argument) (*gaia.PipelineRun, error) {
 	return nil, nil
 }
+func (ms *mockScheduler) RerunPipeline(p *gaia.Pipeline, run *gaia.PipelineRun, failedOnly bool) (*gaia.PipelineRun, error) {
+	return nil, ms.Error
+}
+func (ms *mockScheduler) DecideApproval(p *gaia.Pipeline, runID int, jobID uint32, username string, approved bool) error {
+	return ms.Error
+}
+func (ms *mockScheduler) RecoverWorkerRuns(workerID string) error           { return ms.Error }
+func (ms *mockScheduler) Drain()                                            {}
+func (ms *mockScheduler) Resume()                                           {}
+func (ms *mockScheduler) Draining() bool                                    { return false }
+func (ms *mockScheduler) Shutdown(gracePeriod time.Duration)                {}
 func (ms *mockScheduler) SetPipelineJobs(p *gaia.Pipeline) error            { return ms.Error }
 func (ms *mockScheduler) StopPipelineRun(p *gaia.Pipeline, runid int) error { return ms.Error }
 func (ms *mockScheduler) GetFreeWorkers() int32                             { return int32(0) }
@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/go-github/github"
 	gossh "golang.org/x/crypto/ssh"
@@ -24,6 +25,7 @@ import (
 	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/metrics"
 	"github.com/gaia-pipeline/gaia/services"
 )
 
@@ -160,7 +162,10 @@ func (s *GaiaPipelineService) UpdateRepository(pipe *gaia.Pipeline) error {
 	gaia.Cfg.Logger.Debug("updating pipeline: ", "message", pipe.Name)
 	b := newBuildPipeline(pipe.Type)
 	createPipeline := &gaia.CreatePipeline{Pipeline: *pipe}
-	if err := b.ExecuteBuild(createPipeline); err != nil {
+	buildStart := time.Now()
+	err = b.ExecuteBuild(createPipeline)
+	metrics.ObserveBuild(pipe.Type, buildStart, err)
+	if err != nil {
 		gaia.Cfg.Logger.Error("error while executing the build", "error", err.Error())
 		return err
 	}
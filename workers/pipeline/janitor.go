@@ -0,0 +1,134 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
+	"github.com/gaia-pipeline/gaia/runstorage"
+	"github.com/gaia-pipeline/gaia/services"
+)
+
+// janitorInterval defines how often the janitor prunes old pipeline runs.
+var janitorInterval = time.Hour
+
+// startJanitor starts the janitor which periodically deletes all pipeline
+// runs and their workspace folders which are not retained anymore.
+func (s *GaiaPipelineService) startJanitor() {
+	ticker := time.NewTicker(janitorInterval)
+	go func() {
+		defer ticker.Stop()
+		s.PruneRuns()
+		for range ticker.C {
+			s.PruneRuns()
+		}
+	}()
+}
+
+// PruneRuns deletes the pipeline runs of all active pipelines which are not
+// retained by the pipeline or global retention policy. The workspace folders,
+// logs and artifacts of the deleted runs are removed as well.
+func (s *GaiaPipelineService) PruneRuns() {
+	storeService, _ := services.StorageService()
+	runStorage, _ := services.RunStorageService()
+	settings, err := storeService.SettingsGet()
+	if err != nil {
+		gaia.Cfg.Logger.Error("cannot get settings for pruning pipeline runs", "error", err.Error())
+		return
+	}
+
+	for _, p := range GlobalActivePipelines.GetAll() {
+		policy := settings.Retention
+		if p.Retention != nil {
+			policy = *p.Retention
+		}
+		if !policy.Enabled() {
+			continue
+		}
+
+		runs, err := storeService.PipelineGetAllRunsByPipelineID(p.ID)
+		if err != nil {
+			gaia.Cfg.Logger.Error("cannot get pipeline runs for pruning", "error", err.Error(), "pipeline", p.Name)
+			continue
+		}
+
+		for _, r := range expiredRuns(runs, policy, time.Now()) {
+			if err := storeService.PipelineRunDelete(r.UniqueID); err != nil {
+				gaia.Cfg.Logger.Error("cannot delete pipeline run", "error", err.Error(), "pipeline", p.Name, "run", r.ID)
+				continue
+			}
+
+			runPath := filepath.Join(gaia.Cfg.WorkspacePath, strconv.Itoa(r.PipelineID), strconv.Itoa(r.ID))
+			if err := os.RemoveAll(runPath); err != nil {
+				gaia.Cfg.Logger.Error("cannot remove pipeline run workspace", "error", err.Error(), "path", runPath)
+			}
+			if err := runStorage.Delete(runstorage.RunPrefix(r.PipelineID, r.ID)); err != nil {
+				gaia.Cfg.Logger.Error("cannot remove pipeline run logs and artifacts", "error", err.Error(), "pipeline", p.Name, "run", r.ID)
+			}
+			if err := removeSecretParameters(r); err != nil {
+				gaia.Cfg.Logger.Error("cannot remove secret parameters of pipeline run", "error", err.Error(), "pipeline", p.Name, "run", r.ID)
+			}
+		}
+	}
+}
+
+// removeSecretParameters removes the values of the secret parameters of the given run from the vault.
+func removeSecretParameters(r gaia.PipelineRun) error {
+	for _, param := range r.Parameters {
+		if param.Type != string(gaia.ParameterSecret) {
+			continue
+		}
+		v, err := services.DefaultVaultService()
+		if err != nil {
+			return err
+		}
+		return pipelinehelper.RemoveSecretParameters(v, r.UniqueID)
+	}
+	return nil
+}
+
+// expiredRuns returns all finished runs which are not retained by the given policy.
+// The last successful run is always retained.
+func expiredRuns(runs []gaia.PipelineRun, policy gaia.RetentionPolicy, now time.Time) []gaia.PipelineRun {
+	// Newest runs first
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].ID > runs[j].ID
+	})
+
+	var expired []gaia.PipelineRun
+	lastSuccessFound := false
+	for i, r := range runs {
+		switch r.Status {
+		case gaia.RunSuccess:
+			if !lastSuccessFound {
+				lastSuccessFound = true
+				continue
+			}
+		case gaia.RunFailed, gaia.RunCancelled:
+		default:
+			// Unfinished runs are never deleted
+			continue
+		}
+
+		if policy.KeepRuns > 0 && i < policy.KeepRuns {
+			continue
+		}
+		if policy.KeepDays > 0 && now.Sub(runDate(r)) < time.Duration(policy.KeepDays)*24*time.Hour {
+			continue
+		}
+		expired = append(expired, r)
+	}
+	return expired
+}
+
+// runDate returns the date which is used to determine the age of a run.
+func runDate(r gaia.PipelineRun) time.Time {
+	if !r.StartDate.IsZero() {
+		return r.StartDate
+	}
+	return r.ScheduleDate
+}
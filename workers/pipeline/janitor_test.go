@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/services"
+)
+
+func TestExpiredRuns(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	newRuns := func() []gaia.PipelineRun {
+		// Oldest run first. Run 6 is still running and run 3 is the last successful run.
+		return []gaia.PipelineRun{
+			{ID: 1, Status: gaia.RunSuccess, StartDate: now.Add(-10 * day)},
+			{ID: 2, Status: gaia.RunFailed, StartDate: now.Add(-9 * day)},
+			{ID: 3, Status: gaia.RunSuccess, StartDate: now.Add(-8 * day)},
+			{ID: 4, Status: gaia.RunCancelled, StartDate: now.Add(-2 * day)},
+			{ID: 5, Status: gaia.RunFailed, StartDate: now.Add(-1 * day)},
+			{ID: 6, Status: gaia.RunRunning, StartDate: now.Add(-20 * day)},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		policy   gaia.RetentionPolicy
+		expected []int
+	}{
+		{name: "keep runs", policy: gaia.RetentionPolicy{KeepRuns: 2}, expected: []int{4, 2, 1}},
+		{name: "keep days", policy: gaia.RetentionPolicy{KeepDays: 3}, expected: []int{2, 1}},
+		{name: "keep runs or days", policy: gaia.RetentionPolicy{KeepRuns: 4, KeepDays: 3}, expected: []int{2, 1}},
+		{name: "keep all", policy: gaia.RetentionPolicy{KeepRuns: 10}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []int
+			for _, r := range expiredRuns(newRuns(), tt.policy, now) {
+				ids = append(ids, r.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Fatalf("expected expired runs %v but got %v", tt.expected, ids)
+			}
+		})
+	}
+}
+
+func TestPruneRuns(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPruneRuns")
+	defer os.RemoveAll(tmp)
+
+	gaia.Cfg = &gaia.Config{
+		Logger:        hclog.NewNullLogger(),
+		DataPath:      tmp,
+		HomePath:      tmp,
+		WorkspacePath: tmp,
+	}
+	gaia.Cfg.Bolt.Mode = 0600
+
+	// Initialize store
+	dataStore, err := services.StorageService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { services.MockStorageService(nil) }()
+
+	// The first pipeline uses the global policy, the second one overrides it
+	GlobalActivePipelines = NewActivePipelines()
+	GlobalActivePipelines.Append(gaia.Pipeline{ID: 1, Name: "global"})
+	GlobalActivePipelines.Append(gaia.Pipeline{ID: 2, Name: "override", Retention: &gaia.RetentionPolicy{KeepRuns: 3}})
+	if err := dataStore.SettingsPut(&gaia.StoreConfig{Retention: gaia.RetentionPolicy{KeepRuns: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	runPath := func(pipelineID, runID int) string {
+		return filepath.Join(tmp, strconv.Itoa(pipelineID), strconv.Itoa(runID))
+	}
+	for pipelineID := 1; pipelineID <= 2; pipelineID++ {
+		for runID := 1; runID <= 4; runID++ {
+			r := &gaia.PipelineRun{
+				UniqueID:   fmt.Sprintf("%d-%d", pipelineID, runID),
+				ID:         runID,
+				PipelineID: pipelineID,
+				Status:     gaia.RunFailed,
+			}
+			if err := dataStore.PipelinePutRun(r); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Join(runPath(pipelineID, runID), gaia.LogsFolderName), 0700); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	s := NewGaiaPipelineService(Dependencies{})
+	s.PruneRuns()
+
+	for pipelineID, retained := range map[int][]int{1: {4}, 2: {2, 3, 4}} {
+		runs, err := dataStore.PipelineGetAllRunsByPipelineID(pipelineID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, r := range runs {
+			ids = append(ids, r.ID)
+		}
+		if !reflect.DeepEqual(ids, retained) {
+			t.Fatalf("expected retained runs %v of pipeline %d but got %v", retained, pipelineID, ids)
+		}
+
+		for runID := 1; runID <= 4; runID++ {
+			_, err := os.Stat(runPath(pipelineID, runID))
+			kept := runID >= retained[0]
+			if kept && err != nil {
+				t.Fatalf("expected workspace of run %d of pipeline %d to exist: %s", runID, pipelineID, err)
+			}
+			if !kept && !os.IsNotExist(err) {
+				t.Fatalf("expected workspace of run %d of pipeline %d to be removed", runID, pipelineID)
+			}
+		}
+	}
+}
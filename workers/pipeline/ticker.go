@@ -12,6 +12,7 @@ import (
 	"github.com/robfig/cron"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/event"
 	"github.com/gaia-pipeline/gaia/helper/filehelper"
 	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
 	"github.com/gaia-pipeline/gaia/services"
@@ -85,12 +86,15 @@ func (s *GaiaPipelineService) InitTicker() {
 			select {
 			case <-ticker.C:
 				s.CheckActivePipelines()
-				updateWorker()
+				s.updateWorker()
 			}
 		}
 	}()
 
 	_ = s.StartPoller()
+
+	// Periodically prune old pipeline runs
+	s.startJanitor()
 }
 
 // CheckActivePipelines looks up all files in the pipeline folder.
@@ -257,8 +261,8 @@ func (s *GaiaPipelineService) CheckActivePipelines() {
 }
 
 // updateWorker checks the latest worker information and determines the status
-// of the worker.
-func updateWorker() {
+// of the worker. The pipeline runs of workers which became inactive are recovered.
+func (s *GaiaPipelineService) updateWorker() {
 	// Get memdb service
 	db, err := services.DefaultMemDBService()
 	if err != nil {
@@ -284,6 +288,12 @@ func updateWorker() {
 				if err := db.UpsertWorker(worker, true); err != nil {
 					gaia.Cfg.Logger.Error("failed to store update to worker via updateWorker", "error", err)
 				}
+				services.EventBus().Publish(event.NewWorkerEvent(event.WorkerInactive, worker))
+
+				// The worker might never come back, therefore its runs are recovered
+				if err := s.deps.Scheduler.RecoverWorkerRuns(worker.UniqueID); err != nil {
+					gaia.Cfg.Logger.Error("failed to recover pipeline runs of inactive worker", "error", err, "worker", worker.UniqueID)
+				}
 			}
 		} else if worker.Status == gaia.WorkerInactive {
 			// Worker is marked inactive but we got contact.
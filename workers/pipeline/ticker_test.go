@@ -17,13 +17,19 @@ import (
 
 type mockScheduleService struct {
 	service.GaiaScheduler
-	err error
+	recovered []string
+	err       error
 }
 
 func (ms *mockScheduleService) SetPipelineJobs(p *gaia.Pipeline) error {
 	return ms.err
 }
 
+func (ms *mockScheduleService) RecoverWorkerRuns(workerID string) error {
+	ms.recovered = append(ms.recovered, workerID)
+	return ms.err
+}
+
 type mockMemDBService struct {
 	worker    *gaia.Worker
 	setWorker *gaia.Worker
@@ -236,12 +242,17 @@ func TestUpdateWorker(t *testing.T) {
 	services.MockMemDBService(db)
 	defer func() { services.MockMemDBService(nil) }()
 	db.setWorker = &gaia.Worker{
+		UniqueID:    "my-worker",
 		Status:      gaia.WorkerActive,
 		LastContact: time.Now().Add(-6 * time.Minute),
 	}
+	ms := &mockScheduleService{}
+	pipelineService := NewGaiaPipelineService(Dependencies{
+		Scheduler: ms,
+	})
 
 	// Run update worker
-	updateWorker()
+	pipelineService.updateWorker()
 
 	// Validate
 	if db.worker == nil {
@@ -250,6 +261,9 @@ func TestUpdateWorker(t *testing.T) {
 	if db.worker.Status != gaia.WorkerInactive {
 		t.Fatalf("expected '%s' but got '%s'", string(gaia.WorkerInactive), string(db.worker.Status))
 	}
+	if len(ms.recovered) != 1 || ms.recovered[0] != "my-worker" {
+		t.Fatalf("expected runs of inactive worker to be recovered but got %v", ms.recovered)
+	}
 	db.worker = nil
 
 	// Set new test data
@@ -259,7 +273,7 @@ func TestUpdateWorker(t *testing.T) {
 	}
 
 	// Run update worker
-	updateWorker()
+	pipelineService.updateWorker()
 
 	// Validate
 	if db.worker == nil {
@@ -94,6 +94,10 @@ type PipelineRun struct {
 	ShaSum               []byte   `protobuf:"bytes,10,opt,name=sha_sum,json=shaSum,proto3" json:"sha_sum,omitempty"`
 	Jobs                 []*Job   `protobuf:"bytes,11,rep,name=jobs,proto3" json:"jobs,omitempty"`
 	Docker               bool     `protobuf:"varint,12,opt,name=docker,proto3" json:"docker,omitempty"`
+	JobTimeout           int64    `protobuf:"varint,13,opt,name=job_timeout,json=jobTimeout,proto3" json:"job_timeout,omitempty"`
+	RunTimeout           int64    `protobuf:"varint,14,opt,name=run_timeout,json=runTimeout,proto3" json:"run_timeout,omitempty"`
+	TraceId              string   `protobuf:"bytes,15,opt,name=trace_id,json=traceId,proto3" json:"trace_id,omitempty"`
+	TraceSpanId          string   `protobuf:"bytes,16,opt,name=trace_span_id,json=traceSpanId,proto3" json:"trace_span_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -208,6 +212,34 @@ func (m *PipelineRun) GetDocker() bool {
 	return false
 }
 
+func (m *PipelineRun) GetJobTimeout() int64 {
+	if m != nil {
+		return m.JobTimeout
+	}
+	return 0
+}
+
+func (m *PipelineRun) GetRunTimeout() int64 {
+	if m != nil {
+		return m.RunTimeout
+	}
+	return 0
+}
+
+func (m *PipelineRun) GetTraceId() string {
+	if m != nil {
+		return m.TraceId
+	}
+	return ""
+}
+
+func (m *PipelineRun) GetTraceSpanId() string {
+	if m != nil {
+		return m.TraceSpanId
+	}
+	return ""
+}
+
 // PrivateKey represents a key.
 type PrivateKey struct {
 	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
@@ -391,24 +423,70 @@ func (m *PipelineID) GetId() int64 {
 	return 0
 }
 
+// PipelineRunIDs represents a list of pipeline runs by their unique ids.
+type PipelineRunIDs struct {
+	UniqueIds            []string `protobuf:"bytes,1,rep,name=unique_ids,json=uniqueIds,proto3" json:"unique_ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PipelineRunIDs) Reset()         { *m = PipelineRunIDs{} }
+func (m *PipelineRunIDs) String() string { return proto.CompactTextString(m) }
+func (*PipelineRunIDs) ProtoMessage()    {}
+func (*PipelineRunIDs) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e4ff6184b07e587a, []int{5}
+}
+
+func (m *PipelineRunIDs) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PipelineRunIDs.Unmarshal(m, b)
+}
+func (m *PipelineRunIDs) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PipelineRunIDs.Marshal(b, m, deterministic)
+}
+func (m *PipelineRunIDs) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PipelineRunIDs.Merge(m, src)
+}
+func (m *PipelineRunIDs) XXX_Size() int {
+	return xxx_messageInfo_PipelineRunIDs.Size(m)
+}
+func (m *PipelineRunIDs) XXX_DiscardUnknown() {
+	xxx_messageInfo_PipelineRunIDs.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PipelineRunIDs proto.InternalMessageInfo
+
+func (m *PipelineRunIDs) GetUniqueIds() []string {
+	if m != nil {
+		return m.UniqueIds
+	}
+	return nil
+}
+
 // Job represents one job from a pipeline run.
 type Job struct {
-	UniqueId             uint32      `protobuf:"varint,1,opt,name=unique_id,json=uniqueId,proto3" json:"unique_id,omitempty"`
-	Title                string      `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
-	Description          string      `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
-	DependsOn            []*Job      `protobuf:"bytes,4,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
-	Status               string      `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
-	Args                 []*Argument `protobuf:"bytes,6,rep,name=args,proto3" json:"args,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+	UniqueId             uint32        `protobuf:"varint,1,opt,name=unique_id,json=uniqueId,proto3" json:"unique_id,omitempty"`
+	Title                string        `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Description          string        `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	DependsOn            []*Job        `protobuf:"bytes,4,rep,name=depends_on,json=dependsOn,proto3" json:"depends_on,omitempty"`
+	Status               string        `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	Args                 []*Argument   `protobuf:"bytes,6,rep,name=args,proto3" json:"args,omitempty"`
+	Retry                *RetryPolicy  `protobuf:"bytes,7,opt,name=retry,proto3" json:"retry,omitempty"`
+	Attempts             []*JobAttempt `protobuf:"bytes,8,rep,name=attempts,proto3" json:"attempts,omitempty"`
+	RunCondition         string        `protobuf:"bytes,9,opt,name=run_condition,json=runCondition,proto3" json:"run_condition,omitempty"`
+	Artifacts            []*Artifact   `protobuf:"bytes,10,rep,name=artifacts,proto3" json:"artifacts,omitempty"`
+	Outputs              []*Argument   `protobuf:"bytes,11,rep,name=outputs,proto3" json:"outputs,omitempty"`
+	Approval             bool          `protobuf:"varint,12,opt,name=approval,proto3" json:"approval,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
 }
 
 func (m *Job) Reset()         { *m = Job{} }
 func (m *Job) String() string { return proto.CompactTextString(m) }
 func (*Job) ProtoMessage()    {}
 func (*Job) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e4ff6184b07e587a, []int{5}
+	return fileDescriptor_e4ff6184b07e587a, []int{6}
 }
 
 func (m *Job) XXX_Unmarshal(b []byte) error {
@@ -471,6 +549,256 @@ func (m *Job) GetArgs() []*Argument {
 	return nil
 }
 
+func (m *Job) GetRetry() *RetryPolicy {
+	if m != nil {
+		return m.Retry
+	}
+	return nil
+}
+
+func (m *Job) GetAttempts() []*JobAttempt {
+	if m != nil {
+		return m.Attempts
+	}
+	return nil
+}
+
+func (m *Job) GetRunCondition() string {
+	if m != nil {
+		return m.RunCondition
+	}
+	return ""
+}
+
+func (m *Job) GetArtifacts() []*Artifact {
+	if m != nil {
+		return m.Artifacts
+	}
+	return nil
+}
+
+func (m *Job) GetOutputs() []*Argument {
+	if m != nil {
+		return m.Outputs
+	}
+	return nil
+}
+
+func (m *Job) GetApproval() bool {
+	if m != nil {
+		return m.Approval
+	}
+	return false
+}
+
+// RetryPolicy represents the retry policy of a job.
+type RetryPolicy struct {
+	MaxAttempts          int64    `protobuf:"varint,1,opt,name=max_attempts,json=maxAttempts,proto3" json:"max_attempts,omitempty"`
+	Backoff              int64    `protobuf:"varint,2,opt,name=backoff,proto3" json:"backoff,omitempty"`
+	InfraErrorsOnly      bool     `protobuf:"varint,3,opt,name=infra_errors_only,json=infraErrorsOnly,proto3" json:"infra_errors_only,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RetryPolicy) Reset()         { *m = RetryPolicy{} }
+func (m *RetryPolicy) String() string { return proto.CompactTextString(m) }
+func (*RetryPolicy) ProtoMessage()    {}
+func (*RetryPolicy) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e4ff6184b07e587a, []int{7}
+}
+
+func (m *RetryPolicy) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RetryPolicy.Unmarshal(m, b)
+}
+func (m *RetryPolicy) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RetryPolicy.Marshal(b, m, deterministic)
+}
+func (m *RetryPolicy) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RetryPolicy.Merge(m, src)
+}
+func (m *RetryPolicy) XXX_Size() int {
+	return xxx_messageInfo_RetryPolicy.Size(m)
+}
+func (m *RetryPolicy) XXX_DiscardUnknown() {
+	xxx_messageInfo_RetryPolicy.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RetryPolicy proto.InternalMessageInfo
+
+func (m *RetryPolicy) GetMaxAttempts() int64 {
+	if m != nil {
+		return m.MaxAttempts
+	}
+	return 0
+}
+
+func (m *RetryPolicy) GetBackoff() int64 {
+	if m != nil {
+		return m.Backoff
+	}
+	return 0
+}
+
+func (m *RetryPolicy) GetInfraErrorsOnly() bool {
+	if m != nil {
+		return m.InfraErrorsOnly
+	}
+	return false
+}
+
+// JobAttempt represents one execution attempt of a job.
+type JobAttempt struct {
+	Attempt              int64    `protobuf:"varint,1,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	StartDate            int64    `protobuf:"varint,2,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	FinishDate           int64    `protobuf:"varint,3,opt,name=finish_date,json=finishDate,proto3" json:"finish_date,omitempty"`
+	Status               string   `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	Error                string   `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	InfraError           bool     `protobuf:"varint,6,opt,name=infra_error,json=infraError,proto3" json:"infra_error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *JobAttempt) Reset()         { *m = JobAttempt{} }
+func (m *JobAttempt) String() string { return proto.CompactTextString(m) }
+func (*JobAttempt) ProtoMessage()    {}
+func (*JobAttempt) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e4ff6184b07e587a, []int{8}
+}
+
+func (m *JobAttempt) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_JobAttempt.Unmarshal(m, b)
+}
+func (m *JobAttempt) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_JobAttempt.Marshal(b, m, deterministic)
+}
+func (m *JobAttempt) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_JobAttempt.Merge(m, src)
+}
+func (m *JobAttempt) XXX_Size() int {
+	return xxx_messageInfo_JobAttempt.Size(m)
+}
+func (m *JobAttempt) XXX_DiscardUnknown() {
+	xxx_messageInfo_JobAttempt.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_JobAttempt proto.InternalMessageInfo
+
+func (m *JobAttempt) GetAttempt() int64 {
+	if m != nil {
+		return m.Attempt
+	}
+	return 0
+}
+
+func (m *JobAttempt) GetStartDate() int64 {
+	if m != nil {
+		return m.StartDate
+	}
+	return 0
+}
+
+func (m *JobAttempt) GetFinishDate() int64 {
+	if m != nil {
+		return m.FinishDate
+	}
+	return 0
+}
+
+func (m *JobAttempt) GetStatus() string {
+	if m != nil {
+		return m.Status
+	}
+	return ""
+}
+
+func (m *JobAttempt) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *JobAttempt) GetInfraError() bool {
+	if m != nil {
+		return m.InfraError
+	}
+	return false
+}
+
+// Artifact represents one file published by a job.
+type Artifact struct {
+	JobId                uint32   `protobuf:"varint,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Size                 int64    `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256               string   `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	Date                 int64    `protobuf:"varint,5,opt,name=date,proto3" json:"date,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Artifact) Reset()         { *m = Artifact{} }
+func (m *Artifact) String() string { return proto.CompactTextString(m) }
+func (*Artifact) ProtoMessage()    {}
+func (*Artifact) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e4ff6184b07e587a, []int{9}
+}
+
+func (m *Artifact) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Artifact.Unmarshal(m, b)
+}
+func (m *Artifact) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Artifact.Marshal(b, m, deterministic)
+}
+func (m *Artifact) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Artifact.Merge(m, src)
+}
+func (m *Artifact) XXX_Size() int {
+	return xxx_messageInfo_Artifact.Size(m)
+}
+func (m *Artifact) XXX_DiscardUnknown() {
+	xxx_messageInfo_Artifact.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Artifact proto.InternalMessageInfo
+
+func (m *Artifact) GetJobId() uint32 {
+	if m != nil {
+		return m.JobId
+	}
+	return 0
+}
+
+func (m *Artifact) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Artifact) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Artifact) GetSha256() string {
+	if m != nil {
+		return m.Sha256
+	}
+	return ""
+}
+
+func (m *Artifact) GetDate() int64 {
+	if m != nil {
+		return m.Date
+	}
+	return 0
+}
+
 // Argument represents one argument from a job.
 type Argument struct {
 	Description          string   `protobuf:"bytes,1,opt,name=description,proto3" json:"description,omitempty"`
@@ -486,7 +814,7 @@ func (m *Argument) Reset()         { *m = Argument{} }
 func (m *Argument) String() string { return proto.CompactTextString(m) }
 func (*Argument) ProtoMessage()    {}
 func (*Argument) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e4ff6184b07e587a, []int{6}
+	return fileDescriptor_e4ff6184b07e587a, []int{10}
 }
 
 func (m *Argument) XXX_Unmarshal(b []byte) error {
@@ -536,10 +864,15 @@ func (m *Argument) GetValue() string {
 }
 
 // LogChunk represents one chunk of a log file.
+// The offset is the position of the chunk in the log file.
+// The job id is the id of the job which wrote the log file.
+// A job id of zero represents the combined log file of the pipeline run.
 type LogChunk struct {
 	RunId                int64    `protobuf:"varint,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
 	PipelineId           int64    `protobuf:"varint,2,opt,name=pipeline_id,json=pipelineId,proto3" json:"pipeline_id,omitempty"`
 	Chunk                []byte   `protobuf:"bytes,3,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	Offset               int64    `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	JobId                uint32   `protobuf:"varint,5,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -549,7 +882,7 @@ func (m *LogChunk) Reset()         { *m = LogChunk{} }
 func (m *LogChunk) String() string { return proto.CompactTextString(m) }
 func (*LogChunk) ProtoMessage()    {}
 func (*LogChunk) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e4ff6184b07e587a, []int{7}
+	return fileDescriptor_e4ff6184b07e587a, []int{11}
 }
 
 func (m *LogChunk) XXX_Unmarshal(b []byte) error {
@@ -591,6 +924,103 @@ func (m *LogChunk) GetChunk() []byte {
 	return nil
 }
 
+func (m *LogChunk) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *LogChunk) GetJobId() uint32 {
+	if m != nil {
+		return m.JobId
+	}
+	return 0
+}
+
+// ArtifactChunk represents one chunk of an artifact.
+// The offset is the position of the chunk in the artifact.
+// The name is the path of the artifact relative to the
+// artifacts folder of the job which published it.
+type ArtifactChunk struct {
+	RunId                int64    `protobuf:"varint,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	PipelineId           int64    `protobuf:"varint,2,opt,name=pipeline_id,json=pipelineId,proto3" json:"pipeline_id,omitempty"`
+	JobId                uint32   `protobuf:"varint,3,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Name                 string   `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	Chunk                []byte   `protobuf:"bytes,5,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	Offset               int64    `protobuf:"varint,6,opt,name=offset,proto3" json:"offset,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ArtifactChunk) Reset()         { *m = ArtifactChunk{} }
+func (m *ArtifactChunk) String() string { return proto.CompactTextString(m) }
+func (*ArtifactChunk) ProtoMessage()    {}
+func (*ArtifactChunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e4ff6184b07e587a, []int{12}
+}
+
+func (m *ArtifactChunk) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ArtifactChunk.Unmarshal(m, b)
+}
+func (m *ArtifactChunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ArtifactChunk.Marshal(b, m, deterministic)
+}
+func (m *ArtifactChunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ArtifactChunk.Merge(m, src)
+}
+func (m *ArtifactChunk) XXX_Size() int {
+	return xxx_messageInfo_ArtifactChunk.Size(m)
+}
+func (m *ArtifactChunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_ArtifactChunk.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ArtifactChunk proto.InternalMessageInfo
+
+func (m *ArtifactChunk) GetRunId() int64 {
+	if m != nil {
+		return m.RunId
+	}
+	return 0
+}
+
+func (m *ArtifactChunk) GetPipelineId() int64 {
+	if m != nil {
+		return m.PipelineId
+	}
+	return 0
+}
+
+func (m *ArtifactChunk) GetJobId() uint32 {
+	if m != nil {
+		return m.JobId
+	}
+	return 0
+}
+
+func (m *ArtifactChunk) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ArtifactChunk) GetChunk() []byte {
+	if m != nil {
+		return m.Chunk
+	}
+	return nil
+}
+
+func (m *ArtifactChunk) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
 // FileChunk represents one chunk of a file.
 type FileChunk struct {
 	Chunk                []byte   `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"`
@@ -603,7 +1033,7 @@ func (m *FileChunk) Reset()         { *m = FileChunk{} }
 func (m *FileChunk) String() string { return proto.CompactTextString(m) }
 func (*FileChunk) ProtoMessage()    {}
 func (*FileChunk) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e4ff6184b07e587a, []int{8}
+	return fileDescriptor_e4ff6184b07e587a, []int{13}
 }
 
 func (m *FileChunk) XXX_Unmarshal(b []byte) error {
@@ -637,66 +1067,97 @@ func init() {
 	proto.RegisterType((*PrivateKey)(nil), "protobuf.PrivateKey")
 	proto.RegisterType((*GitRepo)(nil), "protobuf.GitRepo")
 	proto.RegisterType((*PipelineID)(nil), "protobuf.PipelineID")
+	proto.RegisterType((*PipelineRunIDs)(nil), "protobuf.PipelineRunIDs")
 	proto.RegisterType((*Job)(nil), "protobuf.Job")
+	proto.RegisterType((*RetryPolicy)(nil), "protobuf.RetryPolicy")
+	proto.RegisterType((*JobAttempt)(nil), "protobuf.JobAttempt")
+	proto.RegisterType((*Artifact)(nil), "protobuf.Artifact")
 	proto.RegisterType((*Argument)(nil), "protobuf.Argument")
 	proto.RegisterType((*LogChunk)(nil), "protobuf.LogChunk")
+	proto.RegisterType((*ArtifactChunk)(nil), "protobuf.ArtifactChunk")
 	proto.RegisterType((*FileChunk)(nil), "protobuf.FileChunk")
 }
 
 func init() { proto.RegisterFile("worker.proto", fileDescriptor_e4ff6184b07e587a) }
 
 var fileDescriptor_e4ff6184b07e587a = []byte{
-	// 789 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x54, 0x5b, 0x8f, 0x1b, 0x35,
-	0x14, 0xd6, 0xe4, 0xb6, 0x99, 0x33, 0xd9, 0x05, 0x4c, 0x5a, 0x46, 0x69, 0x11, 0xd9, 0x41, 0x82,
-	0x3c, 0xa0, 0x6d, 0xb5, 0xa8, 0x2f, 0x50, 0x21, 0x51, 0x16, 0x56, 0x29, 0x15, 0x54, 0xb3, 0xdc,
-	0xde, 0x22, 0x27, 0x3e, 0x9d, 0x98, 0x9d, 0xd8, 0x83, 0xed, 0x69, 0x95, 0x7f, 0xc8, 0x9f, 0xe1,
-	0x0d, 0xf1, 0x8c, 0x6c, 0x8f, 0x33, 0xd9, 0xa6, 0xdb, 0x87, 0x3e, 0xcd, 0x9c, 0xef, 0x7c, 0x3e,
-	0x3e, 0x97, 0xcf, 0x07, 0x46, 0xaf, 0xa4, 0xba, 0x46, 0x75, 0x56, 0x29, 0x69, 0x24, 0x19, 0xba,
-	0xcf, 0xb2, 0x7e, 0x31, 0xb9, 0x57, 0x48, 0x59, 0x94, 0xf8, 0x20, 0x00, 0x0f, 0x70, 0x53, 0x99,
-	0xad, 0xa7, 0x65, 0x0c, 0x4e, 0x7e, 0x77, 0xc7, 0xe6, 0x42, 0x1b, 0x2a, 0x56, 0x48, 0xee, 0x41,
-	0x5c, 0x0b, 0xfe, 0x57, 0x8d, 0x0b, 0xce, 0xd2, 0x68, 0x1a, 0xcd, 0xe2, 0x7c, 0xe8, 0x81, 0x39,
-	0x23, 0xa7, 0xe1, 0x96, 0x85, 0x2e, 0xa5, 0xd1, 0x69, 0x67, 0x1a, 0xcd, 0xfa, 0x79, 0xe2, 0xb1,
-	0x2b, 0x0b, 0x11, 0x02, 0x3d, 0x43, 0x0b, 0x9d, 0x76, 0xa7, 0xdd, 0x59, 0x9c, 0xbb, 0xff, 0xec,
-	0xbf, 0x0e, 0x24, 0xcf, 0x79, 0x85, 0x25, 0x17, 0x98, 0xd7, 0xe2, 0xed, 0x77, 0x9c, 0x40, 0x87,
-	0x33, 0x17, 0xb9, 0x9b, 0x77, 0x38, 0x23, 0x77, 0x61, 0xa0, 0x0d, 0x35, 0xb5, 0x0d, 0x69, 0x99,
-	0x8d, 0x45, 0x3e, 0x06, 0xd0, 0x86, 0x2a, 0xb3, 0x60, 0xd4, 0x60, 0xda, 0x73, 0xfc, 0xd8, 0x21,
-	0x17, 0xd4, 0x20, 0xf9, 0x04, 0x92, 0x17, 0x5c, 0x70, 0xbd, 0xf6, 0xfe, 0xbe, 0xf3, 0x83, 0x87,
-	0x1c, 0xe1, 0x53, 0x38, 0xd6, 0xab, 0x35, 0xb2, 0xba, 0x44, 0x4f, 0x19, 0x38, 0xca, 0x28, 0x80,
-	0x21, 0x4a, 0xd5, 0x24, 0x6e, 0x73, 0x3d, 0xf2, 0x51, 0x02, 0x34, 0x67, 0x36, 0xca, 0x8e, 0x20,
-	0xe8, 0x06, 0xd3, 0xa1, 0x4b, 0x72, 0x14, 0xc0, 0x9f, 0xe8, 0x06, 0x6f, 0x90, 0xcc, 0xb6, 0xc2,
-	0x34, 0xbe, 0x49, 0xfa, 0x65, 0x5b, 0x21, 0xf9, 0x08, 0x8e, 0xf4, 0x9a, 0x2e, 0x74, 0xbd, 0x49,
-	0x61, 0x1a, 0xcd, 0x46, 0xf9, 0x40, 0xaf, 0xe9, 0x55, 0xbd, 0x21, 0xa7, 0xd0, 0xfb, 0x53, 0x2e,
-	0x75, 0x9a, 0x4c, 0xbb, 0xb3, 0xe4, 0xfc, 0xf8, 0x2c, 0x0c, 0xf2, 0xec, 0xa9, 0x5c, 0xe6, 0xce,
-	0x65, 0x7b, 0xc4, 0xe4, 0xea, 0x1a, 0x55, 0x3a, 0x9a, 0x46, 0xb3, 0x61, 0xde, 0x58, 0xd9, 0x6f,
-	0x00, 0xcf, 0x15, 0x7f, 0x49, 0x0d, 0xfe, 0x88, 0x5b, 0xf2, 0x3e, 0x74, 0xaf, 0x71, 0xdb, 0x34,
-	0xdc, 0xfe, 0x92, 0x09, 0x0c, 0x6b, 0x8d, 0xca, 0x25, 0xde, 0x69, 0xe6, 0xd0, 0xd8, 0xd6, 0x57,
-	0x51, 0xad, 0x5f, 0x49, 0xc5, 0x9a, 0xce, 0xef, 0xec, 0xec, 0x9f, 0x08, 0x8e, 0x2e, 0xb9, 0xc9,
-	0xb1, 0x92, 0xe4, 0x11, 0x24, 0x95, 0xbf, 0x63, 0x11, 0xa2, 0x27, 0xe7, 0xe3, 0x36, 0xcb, 0x36,
-	0x81, 0x1c, 0xaa, 0x36, 0x99, 0x77, 0xbc, 0xda, 0x16, 0x51, 0xab, 0xd2, 0xcd, 0x3b, 0xce, 0xed,
-	0x2f, 0xf9, 0x1c, 0xde, 0xd3, 0x58, 0xe2, 0xca, 0x20, 0x5b, 0x2c, 0x15, 0x15, 0xab, 0xb5, 0x9b,
-	0x76, 0x9c, 0x9f, 0x04, 0xf8, 0x89, 0x43, 0x6d, 0x58, 0xef, 0x47, 0x9d, 0x0e, 0x9c, 0x3c, 0x77,
-	0x36, 0xb9, 0x0f, 0x71, 0x29, 0x57, 0xb4, 0x64, 0xa8, 0x8d, 0x1b, 0x73, 0x9c, 0xb7, 0x40, 0x76,
-	0x1f, 0x20, 0xe8, 0x77, 0x7e, 0xd1, 0x28, 0x34, 0x0a, 0x0a, 0xcd, 0xfe, 0x8e, 0xa0, 0xfb, 0x54,
-	0x2e, 0x0f, 0x65, 0x7d, 0xbc, 0x27, 0xeb, 0x31, 0xf4, 0x0d, 0x37, 0x65, 0x28, 0xd6, 0x1b, 0x64,
-	0x0a, 0x09, 0x43, 0xbd, 0x52, 0xbc, 0x32, 0x5c, 0x8a, 0xa6, 0xd8, 0x7d, 0x88, 0x7c, 0x01, 0xc0,
-	0xb0, 0x42, 0xc1, 0xf4, 0x42, 0x8a, 0xb4, 0xf7, 0x26, 0x0d, 0xc4, 0x0d, 0xe1, 0x67, 0xb1, 0xf7,
-	0x58, 0xfa, 0x37, 0x1e, 0xcb, 0x67, 0xd0, 0xa3, 0xaa, 0xf0, 0x65, 0x27, 0xe7, 0xa4, 0x3d, 0xff,
-	0xad, 0x2a, 0xea, 0x0d, 0x0a, 0x93, 0x3b, 0x7f, 0xb6, 0x86, 0x61, 0x40, 0x5e, 0xcf, 0x2d, 0x3a,
-	0xcc, 0xcd, 0xbe, 0x75, 0x2b, 0x67, 0x5f, 0x92, 0xfb, 0x0f, 0x22, 0xeb, 0xb6, 0x22, 0x1b, 0x43,
-	0xff, 0x25, 0x2d, 0x6b, 0x6c, 0x66, 0xe6, 0x8d, 0xec, 0x0f, 0x18, 0x3e, 0x93, 0xc5, 0x77, 0xeb,
-	0x5a, 0x5c, 0x93, 0x3b, 0x30, 0x50, 0xb5, 0x58, 0xec, 0x9a, 0xda, 0x57, 0xb5, 0x98, 0xb3, 0xd7,
-	0x1f, 0x5f, 0xe7, 0xe0, 0xf1, 0x8d, 0xa1, 0xbf, 0xb2, 0x01, 0xdc, 0x6d, 0xa3, 0xdc, 0x1b, 0xd9,
-	0x29, 0xc4, 0x3f, 0xf0, 0x12, 0x7d, 0xe8, 0x1d, 0x25, 0xda, 0xa3, 0x9c, 0xff, 0xdb, 0x81, 0x81,
-	0xdf, 0x7b, 0xe4, 0x31, 0x1c, 0x5d, 0xa2, 0xb1, 0x06, 0x49, 0xdb, 0xb6, 0xdc, 0x5c, 0x8a, 0x93,
-	0x3b, 0x7b, 0x72, 0x6e, 0xf7, 0xd8, 0xc3, 0x88, 0x7c, 0x0d, 0xf0, 0x6b, 0x65, 0xb7, 0x87, 0x0b,
-	0xf0, 0x66, 0xda, 0xe4, 0xee, 0x99, 0x5f, 0xc1, 0xad, 0xf7, 0x7b, 0xbb, 0x82, 0xc9, 0x63, 0x18,
-	0x5d, 0x19, 0x85, 0x74, 0xf3, 0x84, 0x0b, 0xaa, 0xb6, 0xb7, 0x1d, 0xff, 0xb0, 0x85, 0x77, 0x75,
-	0x3d, 0x8c, 0xc8, 0x57, 0x00, 0xfe, 0xf4, 0x33, 0x59, 0x68, 0xb2, 0x37, 0xd2, 0xd0, 0xd6, 0xdb,
-	0xee, 0x9d, 0x45, 0xe4, 0x1b, 0x80, 0x0b, 0x54, 0x58, 0x70, 0x6d, 0x50, 0xbd, 0xa5, 0xee, 0xdb,
-	0x32, 0x7f, 0x04, 0x70, 0x89, 0x26, 0x6c, 0x80, 0xf1, 0x61, 0xde, 0xf3, 0x8b, 0xc9, 0x07, 0x2d,
-	0xda, 0x10, 0x97, 0x03, 0x87, 0x7c, 0xf9, 0x7f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x4e, 0x36, 0xeb,
-	0x40, 0xab, 0x06, 0x00, 0x00,
+	// 1214 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x56, 0xcf, 0x6e, 0xdc, 0x36,
+	0x13, 0x87, 0xbc, 0xff, 0xa4, 0xd1, 0xda, 0x4e, 0xf8, 0x39, 0x89, 0x3e, 0x27, 0x45, 0x37, 0x2a,
+	0xd0, 0x2e, 0xda, 0xc0, 0x31, 0x5c, 0xa4, 0x87, 0x36, 0x28, 0xe0, 0x64, 0x13, 0x63, 0xd3, 0xa0,
+	0x09, 0xe4, 0xb4, 0x3d, 0x2e, 0xb8, 0x12, 0x77, 0x57, 0xb6, 0x96, 0x54, 0x49, 0xca, 0xc9, 0xf6,
+	0xd8, 0x4b, 0x5f, 0xa3, 0x2f, 0xd1, 0x77, 0xe8, 0xcb, 0xf4, 0xdc, 0x6b, 0x41, 0x52, 0x94, 0xb4,
+	0x5e, 0x3b, 0x01, 0xda, 0x93, 0x38, 0xbf, 0xf9, 0x71, 0x38, 0x33, 0x9c, 0x19, 0x0a, 0xfa, 0x6f,
+	0x19, 0x3f, 0x27, 0xfc, 0x20, 0xe7, 0x4c, 0x32, 0xe4, 0xea, 0xcf, 0xb4, 0x98, 0xed, 0xdf, 0x9d,
+	0x33, 0x36, 0xcf, 0xc8, 0x43, 0x0b, 0x3c, 0x24, 0xcb, 0x5c, 0xae, 0x0c, 0x2d, 0x4c, 0x60, 0xe7,
+	0x27, 0xbd, 0x6d, 0x4c, 0x85, 0xc4, 0x34, 0x26, 0xe8, 0x2e, 0x78, 0x05, 0x4d, 0x7f, 0x2e, 0xc8,
+	0x24, 0x4d, 0x02, 0x67, 0xe0, 0x0c, 0xbd, 0xc8, 0x35, 0xc0, 0x38, 0x41, 0xf7, 0xed, 0x29, 0x13,
+	0x91, 0x31, 0x29, 0x82, 0xad, 0x81, 0x33, 0xec, 0x44, 0xbe, 0xc1, 0x4e, 0x15, 0x84, 0x10, 0xb4,
+	0x25, 0x9e, 0x8b, 0xa0, 0x35, 0x68, 0x0d, 0xbd, 0x48, 0xaf, 0xc3, 0xbf, 0x5b, 0xe0, 0xbf, 0x4e,
+	0x73, 0x92, 0xa5, 0x94, 0x44, 0x05, 0x7d, 0xff, 0x19, 0x3b, 0xb0, 0x95, 0x26, 0xda, 0x72, 0x2b,
+	0xda, 0x4a, 0x13, 0x74, 0x1b, 0xba, 0x42, 0x62, 0x59, 0x28, 0x93, 0x8a, 0x59, 0x4a, 0xe8, 0x23,
+	0x00, 0x21, 0x31, 0x97, 0x93, 0x04, 0x4b, 0x12, 0xb4, 0x35, 0xdf, 0xd3, 0xc8, 0x08, 0x4b, 0x82,
+	0x3e, 0x06, 0x7f, 0x96, 0xd2, 0x54, 0x2c, 0x8c, 0xbe, 0xa3, 0xf5, 0x60, 0x20, 0x4d, 0xf8, 0x04,
+	0xb6, 0x45, 0xbc, 0x20, 0x49, 0x91, 0x11, 0x43, 0xe9, 0x6a, 0x4a, 0xdf, 0x82, 0xd6, 0x4a, 0x5e,
+	0x3a, 0xae, 0x7c, 0xed, 0x19, 0x2b, 0x16, 0x1a, 0x27, 0xca, 0x4a, 0x45, 0xa0, 0x78, 0x49, 0x02,
+	0x57, 0x3b, 0xd9, 0xb7, 0xe0, 0xf7, 0x78, 0x49, 0xd6, 0x48, 0x72, 0x95, 0x93, 0xc0, 0x5b, 0x27,
+	0xbd, 0x59, 0xe5, 0x04, 0xdd, 0x81, 0x9e, 0x58, 0xe0, 0x89, 0x28, 0x96, 0x01, 0x0c, 0x9c, 0x61,
+	0x3f, 0xea, 0x8a, 0x05, 0x3e, 0x2d, 0x96, 0xe8, 0x3e, 0xb4, 0xcf, 0xd8, 0x54, 0x04, 0xfe, 0xa0,
+	0x35, 0xf4, 0x8f, 0xb6, 0x0f, 0xec, 0x45, 0x1e, 0xbc, 0x60, 0xd3, 0x48, 0xab, 0x54, 0x8e, 0x12,
+	0x16, 0x9f, 0x13, 0x1e, 0xf4, 0x07, 0xce, 0xd0, 0x8d, 0x4a, 0x49, 0xb9, 0x7f, 0xc6, 0xa6, 0x13,
+	0x99, 0x2e, 0x09, 0x2b, 0x64, 0xb0, 0x6d, 0xdc, 0x3f, 0x63, 0xd3, 0x37, 0x06, 0x51, 0x04, 0x5e,
+	0xd0, 0x8a, 0xb0, 0x63, 0x08, 0xbc, 0xa0, 0x96, 0xf0, 0x7f, 0x70, 0x25, 0xc7, 0xb1, 0x8e, 0x7e,
+	0x57, 0x7b, 0xdd, 0xd3, 0xf2, 0x38, 0x41, 0x21, 0x6c, 0x1b, 0x95, 0xc8, 0x31, 0x55, 0xfa, 0x1b,
+	0x5a, 0xef, 0x6b, 0xf0, 0x34, 0xc7, 0x74, 0x9c, 0x84, 0x3f, 0x02, 0xbc, 0xe6, 0xe9, 0x05, 0x96,
+	0xe4, 0x3b, 0xb2, 0x42, 0x37, 0xa0, 0x75, 0x4e, 0x56, 0xe5, 0x8d, 0xab, 0x25, 0xda, 0x07, 0xb7,
+	0x10, 0x84, 0xeb, 0xcc, 0x6d, 0x95, 0x85, 0x50, 0xca, 0x4a, 0x97, 0x63, 0x21, 0xde, 0x32, 0x9e,
+	0x94, 0x57, 0x5f, 0xc9, 0xe1, 0x5f, 0x0e, 0xf4, 0x4e, 0x52, 0x19, 0x91, 0x9c, 0xa1, 0x47, 0xe0,
+	0xe7, 0xe6, 0x8c, 0x89, 0xb5, 0xee, 0x1f, 0xed, 0xd5, 0x69, 0xaa, 0x1d, 0x88, 0x20, 0xaf, 0x9d,
+	0xf9, 0x97, 0x47, 0xab, 0x20, 0x0a, 0x9e, 0xe9, 0x82, 0xf3, 0x22, 0xb5, 0x44, 0x9f, 0xc1, 0xae,
+	0x20, 0x19, 0x89, 0x25, 0x49, 0x26, 0x53, 0x8e, 0x69, 0xbc, 0xd0, 0xe5, 0xe6, 0x45, 0x3b, 0x16,
+	0x7e, 0xa2, 0x51, 0x65, 0xd6, 0xe8, 0x89, 0x08, 0xba, 0xba, 0x3f, 0x2a, 0x19, 0xdd, 0x03, 0x2f,
+	0x63, 0x31, 0xce, 0x12, 0x22, 0xa4, 0xae, 0x33, 0x2f, 0xaa, 0x81, 0xf0, 0x1e, 0x80, 0x6d, 0xa0,
+	0xf1, 0xa8, 0x6c, 0x11, 0xc7, 0xb6, 0x48, 0xf8, 0x10, 0x76, 0x1a, 0xed, 0x35, 0x1e, 0xe9, 0xe6,
+	0xa8, 0x3a, 0x4c, 0x04, 0x8e, 0x3e, 0xcb, 0xb3, 0x2d, 0x26, 0xc2, 0x3f, 0x5b, 0xd0, 0x7a, 0xc1,
+	0xa6, 0x9b, 0x8d, 0xb8, 0xdd, 0x68, 0xc4, 0x3d, 0xe8, 0xc8, 0x54, 0x66, 0x36, 0x3b, 0x46, 0x40,
+	0x03, 0xf0, 0x13, 0x22, 0x62, 0x9e, 0xe6, 0x32, 0x65, 0xb4, 0xcc, 0x4e, 0x13, 0x42, 0x0f, 0x00,
+	0x12, 0x92, 0x13, 0x9a, 0x88, 0x09, 0xa3, 0x41, 0xfb, 0xaa, 0xaa, 0xf5, 0x4a, 0xc2, 0x2b, 0xda,
+	0x68, 0xef, 0xce, 0x5a, 0x7b, 0x7f, 0x0a, 0x6d, 0xcc, 0xe7, 0x26, 0x4f, 0xfe, 0x11, 0xaa, 0xf7,
+	0x1f, 0xf3, 0x79, 0xb1, 0x24, 0x54, 0x46, 0x5a, 0x8f, 0xbe, 0x80, 0x0e, 0x27, 0x92, 0xaf, 0x74,
+	0xce, 0xfc, 0xa3, 0x5b, 0x35, 0x31, 0x52, 0xf0, 0x6b, 0x96, 0xa5, 0xf1, 0x2a, 0x32, 0x1c, 0x74,
+	0x08, 0x2e, 0x96, 0x52, 0x0d, 0x40, 0x11, 0xb8, 0x83, 0xd6, 0x7a, 0x9d, 0xbc, 0x60, 0xd3, 0x63,
+	0xa3, 0x8c, 0x2a, 0x96, 0x6a, 0x5d, 0xd5, 0x20, 0x31, 0xa3, 0x49, 0xaa, 0x03, 0x2e, 0x5b, 0x97,
+	0x17, 0xf4, 0xa9, 0xc5, 0xd0, 0x21, 0x78, 0x98, 0xcb, 0x74, 0x86, 0x63, 0x29, 0x02, 0xd8, 0x74,
+	0xd8, 0xa8, 0xa2, 0x9a, 0x84, 0x1e, 0x40, 0x8f, 0x15, 0x32, 0x2f, 0xa4, 0x6d, 0xeb, 0xab, 0x02,
+	0xb4, 0x14, 0x55, 0x37, 0x38, 0xcf, 0x39, 0xbb, 0xc0, 0x59, 0xd9, 0xe0, 0x95, 0x1c, 0x5e, 0x80,
+	0xdf, 0x08, 0x54, 0x4d, 0xe8, 0x25, 0x7e, 0x37, 0xa9, 0xa2, 0x34, 0x45, 0xe2, 0x2f, 0xf1, 0xbb,
+	0x63, 0x1b, 0x52, 0x00, 0xbd, 0x29, 0x8e, 0xcf, 0xd9, 0x6c, 0x56, 0x4e, 0x59, 0x2b, 0xa2, 0xcf,
+	0xe1, 0x66, 0x4a, 0x67, 0x1c, 0x4f, 0x08, 0xe7, 0x8c, 0xab, 0xeb, 0xcb, 0x56, 0xfa, 0x86, 0xdd,
+	0x68, 0x57, 0x2b, 0x9e, 0x69, 0xfc, 0x15, 0xcd, 0x56, 0xe1, 0x1f, 0x0e, 0x40, 0x9d, 0x31, 0x65,
+	0xb4, 0x3c, 0xb3, 0x3c, 0xd2, 0x8a, 0x97, 0xe6, 0xf4, 0xd6, 0x07, 0xe6, 0x74, 0x6b, 0x63, 0x4e,
+	0xd7, 0x05, 0xd2, 0x5e, 0x2b, 0x90, 0x3d, 0xe8, 0x68, 0x37, 0xcb, 0xba, 0x31, 0x82, 0x32, 0xd7,
+	0x08, 0x41, 0xcf, 0x74, 0x37, 0x82, 0xda, 0xf9, 0xb0, 0x00, 0xd7, 0x5e, 0x08, 0xba, 0x05, 0x5d,
+	0x35, 0x1e, 0xab, 0xda, 0xef, 0x9c, 0xb1, 0xe9, 0x38, 0x51, 0x4f, 0x58, 0x63, 0x2a, 0xe8, 0xb5,
+	0xc2, 0x44, 0xfa, 0x8b, 0xf5, 0x4f, 0xaf, 0xb5, 0x67, 0x0b, 0x7c, 0xf4, 0xe8, 0xab, 0xca, 0x33,
+	0x2d, 0x29, 0x6e, 0xe3, 0xcd, 0xd1, 0xeb, 0x70, 0x01, 0xae, 0xbd, 0xd7, 0xcb, 0x2d, 0xe4, 0x6c,
+	0xb6, 0x90, 0x7a, 0x44, 0x57, 0x79, 0xe5, 0x81, 0x5a, 0xdb, 0xe1, 0xd9, 0xaa, 0x87, 0xe7, 0x1e,
+	0x74, 0x2e, 0x70, 0x56, 0x90, 0xf2, 0x78, 0x23, 0x84, 0xbf, 0x39, 0xe0, 0xbe, 0x64, 0xf3, 0xa7,
+	0x8b, 0x82, 0x9e, 0xab, 0x08, 0x55, 0xf9, 0x56, 0xd3, 0xa2, 0xc3, 0x0b, 0x3a, 0x4e, 0x2e, 0x3f,
+	0x6b, 0x5b, 0x1b, 0xcf, 0xda, 0x1e, 0x74, 0x62, 0x65, 0x40, 0x1f, 0xd7, 0x8f, 0x8c, 0xa0, 0x02,
+	0x66, 0xb3, 0x99, 0x20, 0xb2, 0x7c, 0x6e, 0x4b, 0xa9, 0x91, 0xc7, 0x4e, 0x23, 0x8f, 0xe1, 0xef,
+	0x0e, 0x6c, 0xdb, 0x5c, 0xff, 0x37, 0x77, 0xea, 0x03, 0x5a, 0x57, 0x5d, 0x54, 0xbb, 0x71, 0x51,
+	0x95, 0xe7, 0x9d, 0xab, 0x3d, 0xef, 0x36, 0x3d, 0x0f, 0xef, 0x83, 0xf7, 0x3c, 0xcd, 0x88, 0xf1,
+	0xae, 0xda, 0xea, 0x34, 0xb6, 0x1e, 0xfd, 0xda, 0x86, 0xae, 0xf9, 0x47, 0x42, 0x8f, 0xa1, 0x77,
+	0x42, 0xa4, 0x12, 0x50, 0x50, 0xf7, 0xeb, 0xfa, 0x0f, 0xd4, 0x7e, 0x63, 0x02, 0x35, 0x86, 0xf2,
+	0xa1, 0x83, 0xbe, 0x01, 0xf8, 0x21, 0x57, 0xc5, 0xa0, 0x0d, 0x5c, 0x4d, 0xdb, 0xbf, 0x7d, 0x60,
+	0x7e, 0xd7, 0x6a, 0xed, 0x33, 0xf5, 0xbb, 0x86, 0x1e, 0x43, 0xff, 0x54, 0x72, 0x82, 0x97, 0x4f,
+	0x52, 0x8a, 0xf9, 0xea, 0xba, 0xed, 0xff, 0xab, 0xe1, 0x2a, 0xae, 0x43, 0x07, 0x7d, 0x0d, 0x60,
+	0x76, 0xbf, 0x64, 0x73, 0x81, 0x1a, 0xb3, 0xc6, 0x16, 0xca, 0x75, 0xe7, 0x0e, 0x1d, 0x34, 0x82,
+	0x5d, 0xb3, 0xf7, 0xb8, 0x9a, 0x5e, 0x77, 0x36, 0x87, 0xdb, 0x87, 0xac, 0x7c, 0x0b, 0x30, 0x22,
+	0x9c, 0xcc, 0x53, 0x21, 0x09, 0x7f, 0x4f, 0xf6, 0xae, 0x8b, 0xff, 0x11, 0xc0, 0x09, 0x91, 0xf6,
+	0xc9, 0xdf, 0xdb, 0x8c, 0x7e, 0x3c, 0xda, 0xbf, 0x59, 0xa3, 0x96, 0xf8, 0x1c, 0x6e, 0x9c, 0x10,
+	0xf9, 0x54, 0x99, 0xce, 0x32, 0x92, 0x5c, 0xbe, 0xba, 0xf5, 0x57, 0x73, 0xff, 0x5a, 0xcd, 0xb4,
+	0xab, 0x15, 0x5f, 0xfe, 0x33, 0x00, 0x6f, 0x02, 0x1e, 0x94, 0x65, 0x0b, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -719,10 +1180,14 @@ type WorkerClient interface {
 	StreamBinary(ctx context.Context, in *PipelineRun, opts ...grpc.CallOption) (Worker_StreamBinaryClient, error)
 	// StreamLogs streams pipeline run logs to the primary instance.
 	StreamLogs(ctx context.Context, opts ...grpc.CallOption) (Worker_StreamLogsClient, error)
+	// StreamArtifacts streams job artifacts to the primary instance.
+	StreamArtifacts(ctx context.Context, opts ...grpc.CallOption) (Worker_StreamArtifactsClient, error)
 	// Deregister deregister a registered worker from the primary instance.
 	Deregister(ctx context.Context, in *WorkerInstance, opts ...grpc.CallOption) (*empty.Empty, error)
 	// GetGitRepo returns git repo information to the worker based on a pipeline name.
 	GetGitRepo(ctx context.Context, in *PipelineID, opts ...grpc.CallOption) (*GitRepo, error)
+	// GetCancelledWork returns the given pipeline runs which have been cancelled at the primary instance.
+	GetCancelledWork(ctx context.Context, in *PipelineRunIDs, opts ...grpc.CallOption) (*PipelineRunIDs, error)
 }
 
 type workerClient struct {
@@ -840,6 +1305,40 @@ func (x *workerStreamLogsClient) CloseAndRecv() (*empty.Empty, error) {
 	return m, nil
 }
 
+func (c *workerClient) StreamArtifacts(ctx context.Context, opts ...grpc.CallOption) (Worker_StreamArtifactsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Worker_serviceDesc.Streams[3], "/protobuf.Worker/StreamArtifacts", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &workerStreamArtifactsClient{stream}
+	return x, nil
+}
+
+type Worker_StreamArtifactsClient interface {
+	Send(*ArtifactChunk) error
+	CloseAndRecv() (*empty.Empty, error)
+	grpc.ClientStream
+}
+
+type workerStreamArtifactsClient struct {
+	grpc.ClientStream
+}
+
+func (x *workerStreamArtifactsClient) Send(m *ArtifactChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *workerStreamArtifactsClient) CloseAndRecv() (*empty.Empty, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(empty.Empty)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *workerClient) Deregister(ctx context.Context, in *WorkerInstance, opts ...grpc.CallOption) (*empty.Empty, error) {
 	out := new(empty.Empty)
 	err := c.cc.Invoke(ctx, "/protobuf.Worker/Deregister", in, out, opts...)
@@ -858,6 +1357,15 @@ func (c *workerClient) GetGitRepo(ctx context.Context, in *PipelineID, opts ...g
 	return out, nil
 }
 
+func (c *workerClient) GetCancelledWork(ctx context.Context, in *PipelineRunIDs, opts ...grpc.CallOption) (*PipelineRunIDs, error) {
+	out := new(PipelineRunIDs)
+	err := c.cc.Invoke(ctx, "/protobuf.Worker/GetCancelledWork", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // WorkerServer is the server API for Worker service.
 type WorkerServer interface {
 	// GetWork pulls work from the primary instance.
@@ -868,10 +1376,14 @@ type WorkerServer interface {
 	StreamBinary(*PipelineRun, Worker_StreamBinaryServer) error
 	// StreamLogs streams pipeline run logs to the primary instance.
 	StreamLogs(Worker_StreamLogsServer) error
+	// StreamArtifacts streams job artifacts to the primary instance.
+	StreamArtifacts(Worker_StreamArtifactsServer) error
 	// Deregister deregister a registered worker from the primary instance.
 	Deregister(context.Context, *WorkerInstance) (*empty.Empty, error)
 	// GetGitRepo returns git repo information to the worker based on a pipeline name.
 	GetGitRepo(context.Context, *PipelineID) (*GitRepo, error)
+	// GetCancelledWork returns the given pipeline runs which have been cancelled at the primary instance.
+	GetCancelledWork(context.Context, *PipelineRunIDs) (*PipelineRunIDs, error)
 }
 
 func RegisterWorkerServer(s *grpc.Server, srv WorkerServer) {
@@ -964,6 +1476,32 @@ func (x *workerStreamLogsServer) Recv() (*LogChunk, error) {
 	return m, nil
 }
 
+func _Worker_StreamArtifacts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(WorkerServer).StreamArtifacts(&workerStreamArtifactsServer{stream})
+}
+
+type Worker_StreamArtifactsServer interface {
+	SendAndClose(*empty.Empty) error
+	Recv() (*ArtifactChunk, error)
+	grpc.ServerStream
+}
+
+type workerStreamArtifactsServer struct {
+	grpc.ServerStream
+}
+
+func (x *workerStreamArtifactsServer) SendAndClose(m *empty.Empty) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *workerStreamArtifactsServer) Recv() (*ArtifactChunk, error) {
+	m := new(ArtifactChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _Worker_Deregister_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(WorkerInstance)
 	if err := dec(in); err != nil {
@@ -1000,6 +1538,24 @@ func _Worker_GetGitRepo_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Worker_GetCancelledWork_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PipelineRunIDs)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkerServer).GetCancelledWork(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protobuf.Worker/GetCancelledWork",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkerServer).GetCancelledWork(ctx, req.(*PipelineRunIDs))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Worker_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protobuf.Worker",
 	HandlerType: (*WorkerServer)(nil),
@@ -1016,6 +1572,10 @@ var _Worker_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetGitRepo",
 			Handler:    _Worker_GetGitRepo_Handler,
 		},
+		{
+			MethodName: "GetCancelledWork",
+			Handler:    _Worker_GetCancelledWork_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -1033,6 +1593,11 @@ var _Worker_serviceDesc = grpc.ServiceDesc{
 			Handler:       _Worker_StreamLogs_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "StreamArtifacts",
+			Handler:       _Worker_StreamArtifacts_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "worker.proto",
 }
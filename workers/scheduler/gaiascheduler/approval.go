@@ -0,0 +1,226 @@
+package gaiascheduler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+var (
+	// errRunNotFound is returned when the pipeline run of an approval does not exist.
+	errRunNotFound = errors.New("pipeline run not found")
+
+	// errJobNotFound is returned when the job of an approval does not exist.
+	errJobNotFound = errors.New("job not found in pipeline run")
+
+	// errJobNotWaitingApproval is returned when a job is approved which is not waiting for an approval.
+	errJobNotWaitingApproval = errors.New("job is not waiting for approval")
+
+	// errRunNotPaused is returned when a job is approved whose run is still executed by a worker.
+	errRunNotPaused = errors.New("pipeline run is still executed by a worker, the job can be approved once the run has been paused")
+
+	// errTooManyDecisions is returned when more decisions are queued than the run has jobs.
+	errTooManyDecisions = errors.New("too many pending approval decisions for the pipeline run")
+)
+
+// approvalDecision is the approval or rejection of a job waiting for approval.
+type approvalDecision struct {
+	jobID    uint32
+	approved bool
+	user     string
+	date     time.Time
+}
+
+// needsApproval returns true if the given job has to be approved before it is executed.
+func needsApproval(j *gaia.Job) bool {
+	return j.Approval != nil && !j.ApprovalInfo.Decided()
+}
+
+// requestApproval pauses the given job until it has been approved.
+func requestApproval(j *gaia.Job) {
+	j.Status = gaia.JobWaitingApproval
+	if j.ApprovalInfo == nil {
+		j.ApprovalInfo = &gaia.JobApproval{RequestDate: time.Now()}
+	}
+}
+
+// applyApproval records the given decision on the given job. Approved jobs
+// wait for their execution while rejected jobs fail the pipeline run.
+func applyApproval(j *gaia.Job, d approvalDecision) {
+	requestApproval(j)
+	j.ApprovalInfo.DecisionDate = d.date
+	j.ApprovalInfo.Approved = d.approved
+	j.ApprovalInfo.User = d.user
+	if d.approved {
+		j.Status = gaia.JobWaitingExec
+	} else {
+		j.Status = gaia.JobFailed
+		j.FailPipeline = true
+	}
+}
+
+// DecideApproval approves or rejects the job with the given id which waits for
+// approval in the given pipeline run. The decision is recorded with the given username.
+// Runs which have been paused are scheduled again to continue the execution.
+func (s *Scheduler) DecideApproval(p *gaia.Pipeline, runID int, jobID uint32, username string, approved bool) error {
+	run, err := s.storeService.PipelineGetRunByPipelineIDAndID(p.ID, runID)
+	if err != nil {
+		return err
+	}
+	if run == nil {
+		return errRunNotFound
+	}
+	return s.decideApproval(run.UniqueID, jobID, username, approved)
+}
+
+// decideApproval approves or rejects the given job of the run with the given unique id.
+// The decision is passed to the execution if the run is executed by this instance.
+func (s *Scheduler) decideApproval(uniqueID string, jobID uint32, username string, approved bool) error {
+	// The lock guarantees that a run is not paused while a decision is passed to it
+	s.approvalsLock.Lock()
+	defer s.approvalsLock.Unlock()
+
+	run, err := s.storeService.PipelineGetRunByID(uniqueID)
+	if err != nil {
+		return err
+	}
+	if run == nil {
+		return errRunNotFound
+	}
+	var job *gaia.Job
+	for _, j := range run.Jobs {
+		if j.ID == jobID {
+			job = j
+			break
+		}
+	}
+	if job == nil {
+		return errJobNotFound
+	}
+	if job.Status != gaia.JobWaitingApproval || job.ApprovalInfo.Decided() {
+		return errJobNotWaitingApproval
+	}
+
+	d := approvalDecision{
+		jobID:    jobID,
+		approved: approved,
+		user:     username,
+		date:     time.Now(),
+	}
+
+	// The run is still executed by this instance
+	if decisions, ok := s.approvals[uniqueID]; ok {
+		select {
+		case decisions <- d:
+			return nil
+		default:
+			return errTooManyDecisions
+		}
+	}
+
+	if run.Status != gaia.RunWaitingApproval {
+		return errRunNotPaused
+	}
+
+	// Schedule the paused run again. The docker worker of a paused
+	// docker run has been removed and a new one is started.
+	applyApproval(job, d)
+	s.detachDockerWorker(run)
+	run.Status = gaia.RunNotScheduled
+	return s.storeService.PipelinePutRun(run)
+}
+
+// registerApprovals creates the queue of approval decisions for the given run.
+func (s *Scheduler) registerApprovals(r *gaia.PipelineRun) chan approvalDecision {
+	s.approvalsLock.Lock()
+	defer s.approvalsLock.Unlock()
+
+	decisions := make(chan approvalDecision, len(r.Jobs))
+	s.approvals[r.UniqueID] = decisions
+	return decisions
+}
+
+// unregisterApprovals removes the given queue of approval decisions of the given run.
+// A resumed run might have registered a new queue in the meantime which is kept.
+func (s *Scheduler) unregisterApprovals(r *gaia.PipelineRun, decisions chan approvalDecision) {
+	s.approvalsLock.Lock()
+	defer s.approvalsLock.Unlock()
+
+	if s.approvals[r.UniqueID] == decisions {
+		delete(s.approvals, r.UniqueID)
+	}
+}
+
+// pauseForApproval marks the given run as waiting for approval and stores it.
+// It returns false if decisions are pending which have to be applied first.
+// The queue of approval decisions of the run is removed so that further
+// decisions schedule the paused run again.
+func (s *Scheduler) pauseForApproval(r *gaia.PipelineRun, decisions chan approvalDecision) bool {
+	s.approvalsLock.Lock()
+	defer s.approvalsLock.Unlock()
+
+	if len(decisions) > 0 {
+		return false
+	}
+	delete(s.approvals, r.UniqueID)
+
+	r.Status = gaia.RunWaitingApproval
+	if err := s.storeService.PipelinePutRun(r); err != nil {
+		gaia.Cfg.Logger.Error("cannot store pipeline run waiting for approval", "error", err.Error())
+	}
+	return true
+}
+
+// cancelPausedRun cancels the paused run with the given unique id.
+func (s *Scheduler) cancelPausedRun(uniqueID string) error {
+	s.approvalsLock.Lock()
+	defer s.approvalsLock.Unlock()
+
+	// The run might have been scheduled again in the meantime
+	run, err := s.storeService.PipelineGetRunByID(uniqueID)
+	if err != nil {
+		return err
+	}
+	if run == nil {
+		return errRunNotFound
+	}
+	if run.Status != gaia.RunWaitingApproval {
+		return errors.New("pipeline is not in a cancellable state")
+	}
+
+	for _, job := range run.Jobs {
+		if job.Status == gaia.JobWaitingApproval || job.Status == gaia.JobWaitingExec {
+			job.Status = gaia.JobFailed
+			job.FailPipeline = true
+		}
+	}
+	s.finishPipelineRun(run, gaia.RunCancelled)
+	return nil
+}
+
+// rejectExpiredApprovals rejects all jobs which have not been approved
+// within the timeout of their approval policy.
+func (s *Scheduler) rejectExpiredApprovals() {
+	for _, status := range []gaia.PipelineRunStatus{gaia.RunRunning, gaia.RunWaitingApproval} {
+		runs, err := s.storeService.PipelineGetRunsByStatus(status)
+		if err != nil {
+			gaia.Cfg.Logger.Debug("cannot get pipeline runs waiting for approval", "error", err.Error())
+			return
+		}
+
+		for _, run := range runs {
+			for _, job := range run.Jobs {
+				if job.Status != gaia.JobWaitingApproval || job.Approval == nil || job.Approval.Timeout <= 0 || job.ApprovalInfo == nil {
+					continue
+				}
+				if time.Since(job.ApprovalInfo.RequestDate) < time.Duration(job.Approval.Timeout)*time.Second {
+					continue
+				}
+				if err := s.decideApproval(run.UniqueID, job.ID, "", false); err != nil {
+					gaia.Cfg.Logger.Debug("cannot reject job after approval timeout", "error", err.Error(), "job", job.Title)
+				}
+			}
+		}
+	}
+}
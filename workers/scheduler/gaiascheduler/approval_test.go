@@ -0,0 +1,289 @@
+package gaiascheduler
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/plugin"
+	"github.com/gaia-pipeline/gaia/security"
+	"github.com/gaia-pipeline/gaia/store"
+	"github.com/hashicorp/go-hclog"
+)
+
+type PluginFakeApproval struct {
+	sync.Mutex
+	executed map[string]int
+	block    chan struct{}
+}
+
+func (p *PluginFakeApproval) NewPlugin(ca security.CAAPI) plugin.Plugin { return p }
+func (p *PluginFakeApproval) Init(cmd *exec.Cmd, log io.WriteCloser, combinedLog io.Writer) error {
+	return nil
+}
+func (p *PluginFakeApproval) Validate() error { return nil }
+func (p *PluginFakeApproval) Execute(ctx context.Context, j *gaia.Job) error {
+	if j.Title == "Job1" && p.block != nil {
+		<-p.block
+	}
+	p.Lock()
+	defer p.Unlock()
+	p.executed[j.Title]++
+	j.Outputs = []*gaia.Argument{{Key: "producer", Value: j.Title}}
+	j.Status = gaia.JobSuccess
+	return nil
+}
+func (p *PluginFakeApproval) GetJobs() ([]*gaia.Job, error) { return prepareJobs(), nil }
+func (p *PluginFakeApproval) FlushLogs() error              { return nil }
+func (p *PluginFakeApproval) Close()                        {}
+
+func TestPrepareAndExecApproval(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestPrepareAndExecApproval")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := prepareTestData()
+	_ = storeInstance.PipelinePut(&p)
+
+	tests := []struct {
+		name           string
+		approved       bool
+		expectedStatus gaia.PipelineRunStatus
+		expectedJobs   map[string]gaia.JobStatus
+		expectedExecs  map[string]int
+	}{
+		{
+			name:           "approved",
+			approved:       true,
+			expectedStatus: gaia.RunSuccess,
+			expectedJobs:   map[string]gaia.JobStatus{"Job1": gaia.JobSuccess, "Job2": gaia.JobSuccess, "Job3": gaia.JobSuccess, "Job4": gaia.JobSuccess},
+			expectedExecs:  map[string]int{"Job1": 1, "Job2": 1, "Job3": 1, "Job4": 1},
+		},
+		{
+			name:           "rejected",
+			approved:       false,
+			expectedStatus: gaia.RunFailed,
+			expectedJobs:   map[string]gaia.JobStatus{"Job1": gaia.JobSuccess, "Job2": gaia.JobSuccess, "Job3": gaia.JobFailed, "Job4": gaia.JobSkipped},
+			expectedExecs:  map[string]int{"Job1": 1, "Job2": 1},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pS := &PluginFakeApproval{executed: make(map[string]int)}
+			s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, pS, &CAFake{}, &VaultFake{}, nil, nil})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, r := prepareTestData()
+			r.ID = i + 1
+			r.Jobs = prepareJobs()
+			r.Jobs[2].Approval = &gaia.ApprovalPolicy{}
+
+			// The run is paused before Job3
+			s.prepareAndExec(r)
+			run, err := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.Status != gaia.RunWaitingApproval {
+				t.Fatalf("expected run to wait for approval but got %s", run.Status)
+			}
+			if run.Jobs[2].Status != gaia.JobWaitingApproval || run.Jobs[2].ApprovalInfo == nil || run.Jobs[2].ApprovalInfo.RequestDate.IsZero() {
+				t.Fatalf("expected Job3 to wait for approval but got %s, %v", run.Jobs[2].Status, run.Jobs[2].ApprovalInfo)
+			}
+			if run.Jobs[3].Status != gaia.JobWaitingExec {
+				t.Fatalf("expected Job4 to wait for execution but got %s", run.Jobs[3].Status)
+			}
+
+			// Only jobs waiting for approval can be decided
+			if err := s.DecideApproval(&p, r.ID, run.Jobs[1].ID, "admin", tt.approved); err != errJobNotWaitingApproval {
+				t.Fatalf("expected %v but got %v", errJobNotWaitingApproval, err)
+			}
+
+			startDate := run.StartDate
+
+			// The decision schedules the run again
+			if err := s.DecideApproval(&p, r.ID, run.Jobs[2].ID, "admin", tt.approved); err != nil {
+				t.Fatal(err)
+			}
+			run, _ = storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+			if run.Status != gaia.RunNotScheduled {
+				t.Fatalf("expected run to be scheduled again but got %s", run.Status)
+			}
+			info := run.Jobs[2].ApprovalInfo
+			if !info.Decided() || info.Approved != tt.approved || info.User != "admin" {
+				t.Fatalf("unexpected approval %#v", info)
+			}
+
+			// The run continues where it has been paused
+			s.prepareAndExec(*run)
+			run, _ = storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+			if run.Status != tt.expectedStatus {
+				t.Fatalf("expected run status %s but got %s", tt.expectedStatus, run.Status)
+			}
+			if !run.StartDate.Equal(startDate) {
+				t.Fatalf("expected resumed run to keep its start date %v but got %v", startDate, run.StartDate)
+			}
+			for _, job := range run.Jobs {
+				if job.Status != tt.expectedJobs[job.Title] {
+					t.Fatalf("expected %s to be %s but got %s", job.Title, tt.expectedJobs[job.Title], job.Status)
+				}
+				if pS.executed[job.Title] != tt.expectedExecs[job.Title] {
+					t.Fatalf("expected %s to be executed %d times but got %d", job.Title, tt.expectedExecs[job.Title], pS.executed[job.Title])
+				}
+			}
+		})
+	}
+
+	t.Run("approved while running", func(t *testing.T) {
+		pS := &PluginFakeApproval{executed: make(map[string]int), block: make(chan struct{})}
+		s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, pS, &CAFake{}, &VaultFake{}, nil, nil})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Job2 does not depend on the still running Job1
+		_, r := prepareTestData()
+		r.ID = 10
+		r.Jobs = prepareJobs()
+		r.Jobs[1].DependsOn = nil
+		r.Jobs[1].Approval = &gaia.ApprovalPolicy{}
+
+		finished := make(chan bool)
+		go func() {
+			s.prepareAndExec(r)
+			close(finished)
+		}()
+		for i := 0; ; i++ {
+			run, _ := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+			if run != nil && run.Jobs[1].Status == gaia.JobWaitingApproval {
+				break
+			}
+			if i > 100 {
+				t.Fatal("expected Job2 to wait for approval")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err := s.DecideApproval(&p, r.ID, r.Jobs[1].ID, "admin", true); err != nil {
+			t.Fatal(err)
+		}
+		close(pS.block)
+		<-finished
+
+		run, _ := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+		if run.Status != gaia.RunSuccess {
+			t.Fatalf("expected run to succeed without pause but got %s", run.Status)
+		}
+		if !run.Jobs[1].ApprovalInfo.Decided() || run.Jobs[1].ApprovalInfo.User != "admin" {
+			t.Fatalf("expected approval to be recorded but got %#v", run.Jobs[1].ApprovalInfo)
+		}
+	})
+
+	t.Run("approved docker run", func(t *testing.T) {
+		s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeApproval{}, &CAFake{}, &VaultFake{}, nil, nil})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The docker worker of the paused run has been removed
+		_, r := prepareTestData()
+		r.ID = 11
+		r.Jobs = prepareJobs()
+		r.Jobs[2].Status = gaia.JobWaitingApproval
+		r.Jobs[2].ApprovalInfo = &gaia.JobApproval{RequestDate: time.Now()}
+		r.Status = gaia.RunWaitingApproval
+		r.Docker = true
+		r.DockerWorkerID = "docker-worker"
+		r.PipelineTags = []string{"linux", "docker-worker", dockerWorkerTag}
+		_ = storeInstance.PipelinePutRun(&r)
+
+		if err := s.DecideApproval(&p, r.ID, r.Jobs[2].ID, "admin", true); err != nil {
+			t.Fatal(err)
+		}
+
+		// The run is picked up by a new docker worker
+		run, _ := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+		if run.Status != gaia.RunNotScheduled {
+			t.Fatalf("expected run to be scheduled again but got %s", run.Status)
+		}
+		if !run.Docker || run.DockerWorkerID != "" {
+			t.Fatalf("expected docker run without docker worker but got %v, '%s'", run.Docker, run.DockerWorkerID)
+		}
+		if len(run.PipelineTags) != 1 || run.PipelineTags[0] != "linux" {
+			t.Fatalf("expected the docker worker tags to be removed but got %v", run.PipelineTags)
+		}
+	})
+}
+
+func TestRejectExpiredApprovals(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestRejectExpiredApprovals")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := prepareTestData()
+	_ = storeInstance.PipelinePut(&p)
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newRun := func(uniqueID string, id int, requested time.Time) *gaia.PipelineRun {
+		_, r := prepareTestData()
+		r.UniqueID = uniqueID
+		r.ID = id
+		r.Status = gaia.RunWaitingApproval
+		r.Jobs = prepareJobs()
+		r.Jobs[0].Status = gaia.JobWaitingApproval
+		r.Jobs[0].Approval = &gaia.ApprovalPolicy{Timeout: 60}
+		r.Jobs[0].ApprovalInfo = &gaia.JobApproval{RequestDate: requested}
+		if err := storeInstance.PipelinePutRun(&r); err != nil {
+			t.Fatal(err)
+		}
+		return &r
+	}
+	expired := newRun("expired", 1, time.Now().Add(-2*time.Minute))
+	pending := newRun("pending", 2, time.Now())
+	cancelled := newRun("cancelled", 3, time.Now())
+
+	s.rejectExpiredApprovals()
+
+	run, _ := storeInstance.PipelineGetRunByID(expired.UniqueID)
+	if run.Status != gaia.RunNotScheduled || run.Jobs[0].Status != gaia.JobFailed {
+		t.Fatalf("expected expired approval to be rejected but got %s, %s", run.Status, run.Jobs[0].Status)
+	}
+	if info := run.Jobs[0].ApprovalInfo; !info.Decided() || info.Approved || info.User != "" {
+		t.Fatalf("expected automatic rejection but got %#v", info)
+	}
+	run, _ = storeInstance.PipelineGetRunByID(pending.UniqueID)
+	if run.Status != gaia.RunWaitingApproval || run.Jobs[0].Status != gaia.JobWaitingApproval {
+		t.Fatalf("expected pending approval to be kept but got %s, %s", run.Status, run.Jobs[0].Status)
+	}
+
+	// Paused runs are cancelled without being executed
+	if err := s.StopPipelineRun(&p, cancelled.ID); err != nil {
+		t.Fatal(err)
+	}
+	run, _ = storeInstance.PipelineGetRunByID(cancelled.UniqueID)
+	if run.Status != gaia.RunCancelled || run.Jobs[0].Status != gaia.JobFailed {
+		t.Fatalf("expected paused run to be cancelled but got %s, %s", run.Status, run.Jobs[0].Status)
+	}
+}
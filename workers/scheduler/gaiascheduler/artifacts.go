@@ -0,0 +1,70 @@
+package gaiascheduler
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/filehelper"
+	"github.com/gaia-pipeline/gaia/runstorage"
+)
+
+// prepareArtifacts creates an empty artifacts folder for the next attempt of a job.
+// Artifacts published by earlier attempts are removed.
+func prepareArtifacts(folder string) error {
+	if err := os.RemoveAll(folder); err != nil {
+		return err
+	}
+	return os.MkdirAll(folder, 0700)
+}
+
+// collectArtifacts returns the metadata of all files which have
+// been published by the given job into the given artifacts folder.
+func collectArtifacts(jobID uint32, folder string) ([]*gaia.Artifact, error) {
+	var artifacts []*gaia.Artifact
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		name, err := filepath.Rel(folder, path)
+		if err != nil {
+			return err
+		}
+		sum, err := filehelper.GetSHA256Sum(path)
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, &gaia.Artifact{
+			JobID:  jobID,
+			Name:   filepath.ToSlash(name),
+			Size:   info.Size(),
+			SHA256: hex.EncodeToString(sum),
+			Date:   info.ModTime(),
+		})
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return artifacts, err
+}
+
+// storeArtifacts stores the given artifacts which have been published into the
+// given artifacts folder in the storage of the pipeline run.
+func storeArtifacts(logs *runLogs, folder string, artifacts []*gaia.Artifact) error {
+	for _, a := range artifacts {
+		key, err := runstorage.ArtifactKey(logs.pipelineID, logs.runID, a.JobID, a.Name)
+		if err != nil {
+			return err
+		}
+		if err := logs.storage.Put(key, filepath.Join(folder, filepath.FromSlash(a.Name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
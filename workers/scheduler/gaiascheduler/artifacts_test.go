@@ -0,0 +1,52 @@
+package gaiascheduler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCollectArtifacts(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestCollectArtifacts")
+	defer os.RemoveAll(tmp)
+	folder := filepath.Join(tmp, "1")
+
+	// Artifacts of earlier attempts are removed
+	if err := os.MkdirAll(folder, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(folder, "old.txt"), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := prepareArtifacts(folder); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(folder, "bin"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(folder, "bin", "app"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts, err := collectArtifacts(1, folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != 1 {
+		t.Fatalf("expected 1 artifact but got %d", len(artifacts))
+	}
+	a := artifacts[0]
+	if a.JobID != 1 || a.Name != "bin/app" || a.Size != 5 {
+		t.Fatalf("unexpected artifact %+v", a)
+	}
+	if a.SHA256 != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Fatalf("unexpected artifact checksum %s", a.SHA256)
+	}
+
+	// Jobs without artifacts folder have no artifacts
+	artifacts, err = collectArtifacts(2, filepath.Join(tmp, "2"))
+	if err != nil || artifacts != nil {
+		t.Fatalf("expected no artifacts but got %v, %v", artifacts, err)
+	}
+}
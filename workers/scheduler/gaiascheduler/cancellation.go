@@ -0,0 +1,59 @@
+package gaiascheduler
+
+import (
+	"context"
+)
+
+// runCancellation is the cancellation of a run executed by this instance.
+type runCancellation struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// registerCancellation registers the cancellation of the run with the given unique id.
+// The returned context is cancelled when the run has been cancelled. The returned
+// function removes the cancellation again once the execution has been finished.
+func (s *Scheduler) registerCancellation(uniqueID string) (context.Context, func()) {
+	s.cancellationsLock.Lock()
+	defer s.cancellationsLock.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &runCancellation{ctx: ctx, cancel: cancel}
+	s.cancellations[uniqueID] = c
+
+	return ctx, func() {
+		s.cancellationsLock.Lock()
+		defer s.cancellationsLock.Unlock()
+
+		// A resumed run might have registered a new cancellation in the meantime which is kept
+		if s.cancellations[uniqueID] == c {
+			delete(s.cancellations, uniqueID)
+		}
+		cancel()
+	}
+}
+
+// cancelRun cancels the execution of the run with the given unique id.
+// It returns false if the run is not executed by this instance.
+func (s *Scheduler) cancelRun(uniqueID string) bool {
+	s.cancellationsLock.Lock()
+	defer s.cancellationsLock.Unlock()
+
+	c, ok := s.cancellations[uniqueID]
+	if ok {
+		c.cancel()
+	}
+	return ok
+}
+
+// executedRuns returns the unique ids of the runs which are executed by this instance.
+func (s *Scheduler) executedRuns() []string {
+	s.cancellationsLock.Lock()
+	defer s.cancellationsLock.Unlock()
+
+	ids := make([]string, 0, len(s.cancellations))
+	for uniqueID := range s.cancellations {
+		ids = append(ids, uniqueID)
+	}
+	return ids
+}
@@ -0,0 +1,171 @@
+package gaiascheduler
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/store"
+	"github.com/gofrs/uuid"
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestStopPipelineRunConcurrently(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestStopPipelineRunConcurrently")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeTimeout{hangingJob: "Job1"}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Runs of different pipelines share the same run ids
+	pipelines := make([]gaia.Pipeline, 2)
+	for i := range pipelines {
+		pipelines[i], _ = prepareTestData()
+		pipelines[i].ID = i + 1
+		_ = storeInstance.PipelinePut(&pipelines[i])
+	}
+	var runs []gaia.PipelineRun
+	for id := 1; id <= 3; id++ {
+		for _, p := range pipelines {
+			v4, _ := uuid.NewV4()
+			runs = append(runs, gaia.PipelineRun{
+				ID:         id,
+				PipelineID: p.ID,
+				UniqueID:   uuid.Must(v4, nil).String(),
+				Status:     gaia.RunNotScheduled,
+				Jobs:       prepareJobs(),
+			})
+		}
+	}
+
+	finished := make(map[string]chan bool)
+	for _, r := range runs {
+		done := make(chan bool)
+		finished[r.UniqueID] = done
+		go func(r gaia.PipelineRun) {
+			s.prepareAndExec(r)
+			close(done)
+		}(r)
+	}
+	waitForStatus := func(r gaia.PipelineRun, status gaia.PipelineRunStatus) {
+		for i := 0; i < 100; i++ {
+			run, _ := storeInstance.PipelineGetRunByID(r.UniqueID)
+			if run != nil && run.Status == status {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatalf("run %d of pipeline %d did not become %s", r.ID, r.PipelineID, status)
+	}
+	for _, r := range runs {
+		waitForStatus(r, gaia.RunRunning)
+	}
+
+	// Cancel all runs of the first pipeline at the same time
+	var wg sync.WaitGroup
+	for _, r := range runs {
+		if r.PipelineID != 1 {
+			continue
+		}
+		wg.Add(1)
+		go func(r gaia.PipelineRun) {
+			defer wg.Done()
+			if err := s.StopPipelineRun(&pipelines[0], r.ID); err != nil {
+				t.Error(err)
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	for _, r := range runs {
+		if r.PipelineID != 1 {
+			continue
+		}
+		select {
+		case <-finished[r.UniqueID]:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("run %d of pipeline 1 has not been stopped", r.ID)
+		}
+		waitForStatus(r, gaia.RunCancelled)
+	}
+
+	// The runs of the second pipeline are not affected
+	for _, r := range runs {
+		if r.PipelineID != 2 {
+			continue
+		}
+		select {
+		case <-finished[r.UniqueID]:
+			t.Fatalf("run %d of pipeline 2 should still be running", r.ID)
+		default:
+		}
+		if !s.cancelRun(r.UniqueID) {
+			t.Fatalf("run %d of pipeline 2 should be cancellable", r.ID)
+		}
+		<-finished[r.UniqueID]
+	}
+	if len(s.cancellations) != 0 {
+		t.Fatalf("expected all cancellations to be removed but got %d", len(s.cancellations))
+	}
+}
+
+func TestCancelRunStopsGoroutines(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestCancelRunStopsGoroutines")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeTimeout{hangingJob: "Job1"}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, r := prepareTestData()
+	_ = storeInstance.PipelinePut(&p)
+	before := runtime.NumGoroutine()
+
+	finished := make(chan bool)
+	go func() {
+		s.prepareAndExec(r)
+		close(finished)
+	}()
+	for i := 0; i < 100 && len(s.executedRuns()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The jobs which depend on the hanging job wait for it when the run is cancelled
+	if !s.cancelRun(r.UniqueID) {
+		t.Fatal("run should be cancellable")
+	}
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run has not been stopped")
+	}
+
+	// All goroutines of the run have been stopped
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		buf := make([]byte, 1<<16)
+		t.Fatalf("expected %d goroutines but got %d:\n%s", before, n, buf[:runtime.Stack(buf, true)])
+	}
+}
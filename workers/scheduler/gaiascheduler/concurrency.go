@@ -0,0 +1,124 @@
+package gaiascheduler
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// activeRunStatus are the statuses of runs which occupy their concurrency group.
+var activeRunStatus = []gaia.PipelineRunStatus{gaia.RunScheduled, gaia.RunRunning, gaia.RunWaitingApproval}
+
+// concurrencyGroup returns the concurrency group of the given run and the
+// number of runs of the group which may be executed at the same time.
+func concurrencyGroup(r *gaia.PipelineRun) (string, int) {
+	limit := r.Concurrency.Limit
+	if limit <= 0 {
+		limit = 1
+	}
+	if r.Concurrency.Group != "" {
+		return "group:" + r.Concurrency.Group, limit
+	}
+	return "pipeline:" + strconv.Itoa(r.PipelineID), limit
+}
+
+// sortBySchedule sorts the given runs from the oldest to the newest scheduled run.
+// The oldest runs of a concurrency group are cancelled first.
+func sortBySchedule(runs []*gaia.PipelineRun) {
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].ScheduleDate.Before(runs[j].ScheduleDate)
+	})
+}
+
+// applyConcurrency returns up to max runs of the given not scheduled runs which can
+// be executed without exceeding the limits of their concurrency groups. The given
+// runs have to be sorted in the order they have to be executed. If the group of
+// a run is busy, the policy of the run decides if the run is kept queued, cancelled
+// or if the oldest runs of the group are cancelled to make room for it.
+func (s *Scheduler) applyConcurrency(runs []*gaia.PipelineRun, max int) []*gaia.PipelineRun {
+	var limited bool
+	for _, r := range runs {
+		limited = limited || r.Concurrency != nil
+	}
+	if !limited {
+		if len(runs) > max {
+			return runs[:max]
+		}
+		return runs
+	}
+
+	// Collect the runs which occupy a concurrency group
+	active := make(map[string][]*gaia.PipelineRun)
+	for _, status := range activeRunStatus {
+		activeRuns, err := s.storeService.PipelineGetRunsByStatus(status)
+		if err != nil {
+			gaia.Cfg.Logger.Debug("cannot get active pipeline runs", "error", err.Error())
+			return nil
+		}
+		for _, r := range activeRuns {
+			if r.Concurrency != nil {
+				group, _ := concurrencyGroup(r)
+				active[group] = append(active[group], r)
+			}
+		}
+	}
+	for _, groupRuns := range active {
+		sortBySchedule(groupRuns)
+	}
+
+	var ready []*gaia.PipelineRun
+	cancelled := make(map[string]bool)
+	for _, r := range runs {
+		if len(ready) >= max {
+			break
+		}
+		if r.Concurrency == nil {
+			ready = append(ready, r)
+			continue
+		}
+
+		group, limit := concurrencyGroup(r)
+		if len(active[group]) >= limit {
+			switch r.Concurrency.Policy {
+			case gaia.ConcurrencySkipNew:
+				gaia.Cfg.Logger.Info("pipeline run skipped since its concurrency group is busy", "pipelineid", r.PipelineID, "runid", r.ID)
+				s.finishPipelineRun(r, gaia.RunCancelled)
+				continue
+			case gaia.ConcurrencyCancelOlder:
+				for len(active[group]) >= limit {
+					older := active[group][0]
+					active[group] = active[group][1:]
+					gaia.Cfg.Logger.Info("pipeline run cancelled by a newer run of its concurrency group", "pipelineid", older.PipelineID, "runid", older.ID)
+
+					switch older.Status {
+					case gaia.RunNotScheduled:
+						// The run has been accepted in this iteration but has not been started yet
+						cancelled[older.UniqueID] = true
+						s.finishPipelineRun(older, gaia.RunCancelled)
+					default:
+						if err := s.stopRun(older); err != nil {
+							gaia.Cfg.Logger.Error("cannot cancel older pipeline run", "error", err.Error(), "runid", older.ID)
+						}
+					}
+				}
+			default:
+				// Keep the run queued until the group has room for it
+				continue
+			}
+		}
+
+		active[group] = append(active[group], r)
+		sortBySchedule(active[group])
+		ready = append(ready, r)
+	}
+
+	// Remove the runs which have been cancelled by newer runs
+	readyRuns := ready[:0]
+	for _, r := range ready {
+		if !cancelled[r.UniqueID] {
+			readyRuns = append(readyRuns, r)
+		}
+	}
+	return readyRuns
+}
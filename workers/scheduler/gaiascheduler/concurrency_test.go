@@ -0,0 +1,105 @@
+package gaiascheduler
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/store"
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestScheduleConcurrency(t *testing.T) {
+	type run struct {
+		pipelineID int
+		status     gaia.PipelineRunStatus
+		group      string
+		expected   gaia.PipelineRunStatus
+	}
+	tests := []struct {
+		name   string
+		policy gaia.ConcurrencyPolicy
+		runs   []run
+	}{
+		{
+			name:   "queue",
+			policy: gaia.ConcurrencyQueue,
+			runs: []run{
+				{pipelineID: 1, status: gaia.RunRunning, expected: gaia.RunRunning},
+				{pipelineID: 1, status: gaia.RunNotScheduled, expected: gaia.RunNotScheduled},
+				{pipelineID: 2, status: gaia.RunNotScheduled, expected: gaia.RunScheduled},
+			},
+		},
+		{
+			name:   "skip new",
+			policy: gaia.ConcurrencySkipNew,
+			runs: []run{
+				{pipelineID: 1, status: gaia.RunWaitingApproval, expected: gaia.RunWaitingApproval},
+				{pipelineID: 1, status: gaia.RunNotScheduled, expected: gaia.RunCancelled},
+			},
+		},
+		{
+			name:   "cancel older",
+			policy: gaia.ConcurrencyCancelOlder,
+			runs: []run{
+				{pipelineID: 1, status: gaia.RunScheduled, expected: gaia.RunCancelled},
+				{pipelineID: 1, status: gaia.RunNotScheduled, expected: gaia.RunCancelled},
+				{pipelineID: 1, status: gaia.RunNotScheduled, expected: gaia.RunScheduled},
+			},
+		},
+		{
+			name:   "shared group",
+			policy: gaia.ConcurrencyQueue,
+			runs: []run{
+				{pipelineID: 1, status: gaia.RunRunning, group: "deploy", expected: gaia.RunRunning},
+				{pipelineID: 2, status: gaia.RunNotScheduled, group: "deploy", expected: gaia.RunNotScheduled},
+				{pipelineID: 2, status: gaia.RunNotScheduled, expected: gaia.RunScheduled},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gaia.Cfg = &gaia.Config{}
+			storeInstance := store.NewBoltStore()
+			tmp, _ := ioutil.TempDir("", "TestScheduleConcurrency")
+			gaia.Cfg.DataPath = tmp
+			gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+			gaia.Cfg.Bolt.Mode = 0600
+			gaia.Cfg.Logger = hclog.NewNullLogger()
+			if err := storeInstance.Init(tmp); err != nil {
+				t.Fatal(err)
+			}
+			s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			runs := make([]*gaia.PipelineRun, len(tt.runs))
+			for i, r := range tt.runs {
+				runs[i] = &gaia.PipelineRun{
+					UniqueID:     string(rune('a' + i)),
+					ID:           i + 1,
+					PipelineID:   r.pipelineID,
+					Status:       r.status,
+					ScheduleDate: time.Now().Add(time.Duration(i) * time.Second),
+					Concurrency:  &gaia.Concurrency{Group: r.group, Policy: tt.policy},
+				}
+				if err := storeInstance.PipelinePutRun(runs[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s.schedule()
+
+			for i, r := range tt.runs {
+				run, _ := storeInstance.PipelineGetRunByID(runs[i].UniqueID)
+				if run.Status != r.expected {
+					t.Fatalf("expected run %d to be %s but got %s", i+1, r.expected, run.Status)
+				}
+			}
+		})
+	}
+}
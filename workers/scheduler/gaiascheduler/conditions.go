@@ -0,0 +1,18 @@
+package gaiascheduler
+
+import (
+	"github.com/gaia-pipeline/gaia"
+)
+
+// runConditionMet decides if the given job should be executed based on
+// its run condition and whether another job of the run has already failed.
+func runConditionMet(j *gaia.Job, runFailed bool) bool {
+	switch j.RunCondition {
+	case gaia.RunConditionAlways:
+		return true
+	case gaia.RunConditionOnFailure:
+		return runFailed
+	default:
+		return !runFailed
+	}
+}
@@ -0,0 +1,30 @@
+package gaiascheduler
+
+import (
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestRunConditionMet(t *testing.T) {
+	tests := []struct {
+		condition gaia.JobRunCondition
+		runFailed bool
+		expected  bool
+	}{
+		{"", false, true},
+		{"", true, false},
+		{gaia.RunConditionOnSuccess, false, true},
+		{gaia.RunConditionOnSuccess, true, false},
+		{gaia.RunConditionOnFailure, false, false},
+		{gaia.RunConditionOnFailure, true, true},
+		{gaia.RunConditionAlways, false, true},
+		{gaia.RunConditionAlways, true, true},
+	}
+	for _, tt := range tests {
+		j := &gaia.Job{RunCondition: tt.condition}
+		if got := runConditionMet(j, tt.runFailed); got != tt.expected {
+			t.Fatalf("expected %v for condition %q and failed run %v but got %v", tt.expected, tt.condition, tt.runFailed, got)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package gaiascheduler
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+const (
+	// shutdownPollInterval is the interval the shutdown checks
+	// if runs are still executed by this instance.
+	shutdownPollInterval = 500 * time.Millisecond
+
+	// shutdownCancelTimeout is the maximum duration the shutdown waits
+	// for cancelled runs to be stopped.
+	shutdownCancelTimeout = 10 * time.Second
+)
+
+const (
+	// drainingDrained is the draining state of a drained scheduler.
+	drainingDrained int32 = 1
+
+	// drainingShutdown is the draining state of a scheduler which is shut down.
+	// It cannot be resumed anymore.
+	drainingShutdown int32 = 2
+)
+
+// Drain stops this instance from starting new pipeline runs. Runs which are
+// already executed are finished as usual. Runs which have not been started yet
+// are kept in the store and are scheduled again once the instance has been restarted.
+func (s *Scheduler) Drain() {
+	if atomic.CompareAndSwapInt32(s.draining, 0, drainingDrained) {
+		gaia.Cfg.Logger.Info("scheduler is draining. No new pipeline runs are started")
+	}
+}
+
+// Resume lets this instance start new pipeline runs again after it has been drained.
+// A scheduler which is shut down is not resumed.
+func (s *Scheduler) Resume() {
+	if atomic.CompareAndSwapInt32(s.draining, drainingDrained, 0) {
+		gaia.Cfg.Logger.Info("scheduler has been resumed. New pipeline runs are started again")
+	}
+}
+
+// Draining returns true if this instance does not start new pipeline runs.
+func (s *Scheduler) Draining() bool {
+	return atomic.LoadInt32(s.draining) != 0
+}
+
+// Shutdown drains the scheduler and waits up to the given grace period for the
+// runs executed by this instance to finish. Runs which are still executed
+// afterwards are cancelled.
+func (s *Scheduler) Shutdown(gracePeriod time.Duration) {
+	s.Drain()
+	atomic.StoreInt32(s.draining, drainingShutdown)
+	if s.waitForRuns(gracePeriod) {
+		return
+	}
+
+	for _, uniqueID := range s.executedRuns() {
+		gaia.Cfg.Logger.Info("pipeline run is cancelled by shutdown", "uniqueid", uniqueID)
+		s.cancelRun(uniqueID)
+	}
+	if !s.waitForRuns(shutdownCancelTimeout) {
+		gaia.Cfg.Logger.Warn("pipeline runs have not been stopped in time", "uniqueids", s.executedRuns())
+	}
+}
+
+// waitForRuns waits up to the given timeout until this instance does not execute
+// runs anymore. It returns false if runs are still executed after the timeout.
+func (s *Scheduler) waitForRuns(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for len(s.executedRuns()) > 0 {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(shutdownPollInterval)
+	}
+	return true
+}
+
+// unscheduleRun hands the given run, which has been taken from the queue of
+// this instance, back to the store without starting it.
+func (s *Scheduler) unscheduleRun(r gaia.PipelineRun) {
+	// The run might have been cancelled while waiting for a free worker
+	run, err := s.storeService.PipelineGetRunByID(r.UniqueID)
+	if err != nil || run == nil || run.Status != gaia.RunScheduled {
+		return
+	}
+
+	run.Status = gaia.RunNotScheduled
+	if err := s.storeService.PipelinePutRun(run); err != nil {
+		gaia.Cfg.Logger.Error("cannot put unscheduled pipeline run into store", "error", err.Error(), "runid", run.ID)
+	}
+}
@@ -0,0 +1,122 @@
+package gaiascheduler
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/store"
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestDrain(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestDrain")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := prepareTestData()
+	_ = storeInstance.PipelinePut(&p)
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.SchedulePipeline(&p, gaia.StartReasonManual, prepareArgs())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Drain()
+	if !s.Draining() {
+		t.Fatal("expected scheduler to be draining")
+	}
+
+	// No new work is picked up
+	s.schedule()
+	run, _ := storeInstance.PipelineGetRunByID(r.UniqueID)
+	if run.Status != gaia.RunNotScheduled {
+		t.Fatalf("expected run to stay %s but got %s", gaia.RunNotScheduled, run.Status)
+	}
+	if s.CountScheduledRuns() != 0 {
+		t.Fatalf("expected no queued runs but got %d", s.CountScheduledRuns())
+	}
+
+	// Queued runs are handed back to the store instead of being started
+	run.Status = gaia.RunScheduled
+	_ = storeInstance.PipelinePutRun(run)
+	s.prepareAndExec(*run)
+	run, _ = storeInstance.PipelineGetRunByID(r.UniqueID)
+	if run.Status != gaia.RunNotScheduled {
+		t.Fatalf("expected run to be %s but got %s", gaia.RunNotScheduled, run.Status)
+	}
+
+	// New work is picked up again once resumed
+	s.Resume()
+	if s.Draining() {
+		t.Fatal("expected scheduler not to be draining")
+	}
+	s.schedule()
+	run, _ = storeInstance.PipelineGetRunByID(r.UniqueID)
+	if run.Status != gaia.RunScheduled {
+		t.Fatalf("expected run to be %s but got %s", gaia.RunScheduled, run.Status)
+	}
+	if s.CountScheduledRuns() != 1 {
+		t.Fatalf("expected one queued run but got %d", s.CountScheduledRuns())
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestShutdown")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, r := prepareTestData()
+	_ = storeInstance.PipelinePut(&p)
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeTimeout{hangingJob: "Job1"}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	finished := make(chan bool)
+	go func() {
+		s.prepareAndExec(r)
+		close(finished)
+	}()
+	for i := 0; i < 100 && len(s.executedRuns()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The hanging run is cancelled after the grace period
+	s.Shutdown(100 * time.Millisecond)
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run has not been stopped")
+	}
+	run, _ := storeInstance.PipelineGetRunByID(r.UniqueID)
+	if run.Status != gaia.RunCancelled {
+		t.Fatalf("expected run to be %s but got %s", gaia.RunCancelled, run.Status)
+	}
+	if !s.Draining() {
+		t.Fatal("expected scheduler to be draining")
+	}
+
+	// A scheduler which is shut down cannot be resumed
+	s.Resume()
+	if !s.Draining() {
+		t.Fatal("expected scheduler to stay draining")
+	}
+}
@@ -0,0 +1,46 @@
+package gaiascheduler
+
+import (
+	"github.com/gaia-pipeline/gaia"
+)
+
+// jobArgsWithOutputs returns the arguments of the given job extended by the
+// outputs of the jobs it directly depends on. The outputs are looked up in the
+// jobs of the given run because only these carry the results of this run.
+// An output replaces an argument with the same key.
+// The arguments of the given job are not modified.
+func jobArgsWithOutputs(r *gaia.PipelineRun, j *gaia.Job) []*gaia.Argument {
+	args := make([]*gaia.Argument, 0, len(j.Args))
+	for _, arg := range j.Args {
+		a := *arg
+		args = append(args, &a)
+	}
+
+	for _, depJob := range j.DependsOn {
+		for _, job := range r.Jobs {
+			if job.ID != depJob.ID {
+				continue
+			}
+			for _, output := range job.Outputs {
+				args = setArgument(args, &gaia.Argument{
+					Description: output.Description,
+					Type:        argTypeOutput,
+					Key:         output.Key,
+					Value:       output.Value,
+				})
+			}
+		}
+	}
+	return args
+}
+
+// setArgument replaces the argument with the same key or appends the given argument.
+func setArgument(args []*gaia.Argument, arg *gaia.Argument) []*gaia.Argument {
+	for i, a := range args {
+		if a.Key == arg.Key {
+			args[i] = arg
+			return args
+		}
+	}
+	return append(args, arg)
+}
@@ -0,0 +1,34 @@
+package gaiascheduler
+
+import (
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestJobArgsWithOutputs(t *testing.T) {
+	build := &gaia.Job{ID: 1, Outputs: []*gaia.Argument{{Key: "imagetag", Value: "v1.2.3"}}}
+	test := &gaia.Job{ID: 2, Outputs: []*gaia.Argument{{Key: "coverage", Value: "80"}}}
+	deploy := &gaia.Job{
+		ID:        3,
+		DependsOn: []*gaia.Job{{ID: 1}},
+		Args:      []*gaia.Argument{{Key: "imagetag", Value: "latest"}, {Key: "env", Value: "prod"}},
+	}
+	r := &gaia.PipelineRun{Jobs: []*gaia.Job{build, test, deploy}}
+
+	args := jobArgsWithOutputs(r, deploy)
+	if len(args) != 2 {
+		t.Fatalf("expected 2 arguments but got %d", len(args))
+	}
+	if args[0].Key != "imagetag" || args[0].Value != "v1.2.3" || args[0].Type != argTypeOutput {
+		t.Fatalf("expected argument to be replaced by output but got %#v", args[0])
+	}
+	if args[1].Key != "env" || args[1].Value != "prod" {
+		t.Fatalf("expected argument to be kept but got %#v", args[1])
+	}
+
+	// The arguments of the job itself are not modified
+	if deploy.Args[0].Value != "latest" {
+		t.Fatalf("expected job arguments to be unchanged but got %#v", deploy.Args[0])
+	}
+}
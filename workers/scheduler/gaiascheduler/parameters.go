@@ -0,0 +1,45 @@
+package gaiascheduler
+
+import (
+	"fmt"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
+)
+
+// argKeyDocker is the key of the argument which overwrites the docker setting of a pipeline.
+const argKeyDocker = "docker"
+
+// runParameters validates the given arguments against the declared parameters
+// of the given pipeline and the arguments of the given jobs. Unknown arguments
+// are rejected. It returns the effective values of all declared parameters
+// followed by the given job arguments.
+func runParameters(p *gaia.Pipeline, jobs []*gaia.Job, args []*gaia.Argument) ([]*gaia.Argument, error) {
+	declared := make(map[string]bool, len(p.Parameters))
+	for _, param := range p.Parameters {
+		declared[param.Name] = true
+	}
+	// Values of vault arguments are loaded from the vault and never taken from the given arguments
+	jobArgs := make(map[string]bool)
+	for _, job := range jobs {
+		for _, arg := range job.Args {
+			jobArgs[arg.Key] = jobArgs[arg.Key] || arg.Type != argTypeVault
+		}
+	}
+	for _, arg := range args {
+		if _, ok := jobArgs[arg.Key]; !ok && !declared[arg.Key] && arg.Key != argKeyDocker {
+			return nil, fmt.Errorf("unknown parameter %q", arg.Key)
+		}
+	}
+
+	params, err := pipelinehelper.ResolveParameters(p.Parameters, args)
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		if !declared[arg.Key] && jobArgs[arg.Key] {
+			params = append(params, arg)
+		}
+	}
+	return params, nil
+}
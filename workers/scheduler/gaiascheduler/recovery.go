@@ -0,0 +1,136 @@
+package gaiascheduler
+
+import (
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/stringhelper"
+	"github.com/gaia-pipeline/gaia/workers/docker"
+)
+
+const (
+	// dockerWorkerTag is the tag of the workers which run inside a docker container.
+	dockerWorkerTag = "dockerworker"
+
+	// defaultMaxReschedules is the number of times an interrupted run is
+	// rescheduled if the pipeline does not define a limit.
+	defaultMaxReschedules = 3
+)
+
+// recoverRuns reconciles the pipeline runs which were in flight when this instance
+// has been stopped. The queues of this instance and the queue of the workers are
+// not persisted, therefore runs which have not been started yet are scheduled again.
+// Runs which have been interrupted are failed or scheduled again depending on the
+// recovery policy. Runs of active workers are kept since the workers still execute them.
+func (s *Scheduler) recoverRuns() {
+	// Docker workers are not persisted and their containers are orphaned
+	if gaia.Cfg.Mode == gaia.ModeServer {
+		s.removeDockerWorkers()
+	}
+
+	for _, status := range []gaia.PipelineRunStatus{gaia.RunScheduled, gaia.RunRunning} {
+		runs, err := s.storeService.PipelineGetRunsByStatus(status)
+		if err != nil {
+			gaia.Cfg.Logger.Error("cannot get pipeline runs to recover", "error", err.Error(), "status", status)
+			continue
+		}
+		for _, r := range runs {
+			if r.WorkerID != "" {
+				if w, err := s.memDBService.GetWorker(r.WorkerID); err == nil && w != nil && w.Status == gaia.WorkerActive {
+					continue
+				}
+			}
+			s.recoverRun(r)
+		}
+	}
+}
+
+// RecoverWorkerRuns recovers the pipeline runs which have been handed out to the
+// worker with the given id. It is called when the worker has been lost.
+func (s *Scheduler) RecoverWorkerRuns(workerID string) error {
+	for _, status := range []gaia.PipelineRunStatus{gaia.RunScheduled, gaia.RunRunning} {
+		runs, err := s.storeService.PipelineGetRunsByStatus(status)
+		if err != nil {
+			return err
+		}
+		for _, r := range runs {
+			if r.WorkerID == workerID {
+				s.recoverRun(r)
+			}
+		}
+	}
+	return nil
+}
+
+// recoverRun recovers the given pipeline run which has not been finished.
+func (s *Scheduler) recoverRun(r *gaia.PipelineRun) {
+	// The worker of the run does not execute it anymore
+	r.WorkerID = ""
+	s.detachDockerWorker(r)
+
+	// Runs which have not been started yet are scheduled again
+	if r.Status == gaia.RunScheduled {
+		gaia.Cfg.Logger.Info("pipeline run is scheduled again", "pipelineid", r.PipelineID, "runid", r.ID)
+		r.Status = gaia.RunNotScheduled
+		if err := s.storeService.PipelinePutRun(r); err != nil {
+			gaia.Cfg.Logger.Error("cannot store recovered pipeline run", "error", err.Error(), "runid", r.ID)
+		}
+		return
+	}
+
+	policy, maxReschedules := gaia.Cfg.RecoveryPolicy, defaultMaxReschedules
+	if r.Recovery != nil {
+		if r.Recovery.Policy != "" {
+			policy = r.Recovery.Policy
+		}
+		if r.Recovery.MaxReschedules > 0 {
+			maxReschedules = r.Recovery.MaxReschedules
+		}
+	}
+
+	if policy == gaia.RecoveryReschedule && r.Reschedules < maxReschedules {
+		gaia.Cfg.Logger.Info("interrupted pipeline run is scheduled again", "pipelineid", r.PipelineID, "runid", r.ID)
+		r.Reschedules++
+
+		// Only the interrupted jobs are executed again
+		for _, job := range r.Jobs {
+			if job.Status == gaia.JobRunning {
+				job.Status = gaia.JobWaitingExec
+			}
+		}
+
+		// A worker hands the run back to the primary instance which schedules it again
+		r.Status = gaia.RunNotScheduled
+		if gaia.Cfg.Mode == gaia.ModeWorker {
+			r.Status = gaia.RunReschedule
+		}
+		if err := s.storeService.PipelinePutRun(r); err != nil {
+			gaia.Cfg.Logger.Error("cannot store recovered pipeline run", "error", err.Error(), "runid", r.ID)
+		}
+		return
+	}
+
+	gaia.Cfg.Logger.Info("interrupted pipeline run is marked as failed", "pipelineid", r.PipelineID, "runid", r.ID, "reschedules", r.Reschedules)
+	for _, job := range r.Jobs {
+		if job.Status == gaia.JobRunning || job.Status == gaia.JobWaitingExec {
+			job.Status = gaia.JobFailed
+			job.FailPipeline = true
+		}
+	}
+	s.finishPipelineRun(r, gaia.RunFailed)
+}
+
+// removeDockerWorkers removes all docker workers including their containers.
+func (s *Scheduler) removeDockerWorkers() {
+	if err := docker.RemoveAllDockerWorkers(gaia.Cfg.DockerHostURL); err != nil {
+		gaia.Cfg.Logger.Debug("cannot remove orphaned docker workers", "error", err.Error())
+	}
+
+	// Deregister the docker workers from this instance
+	for _, w := range s.memDBService.GetAllWorker() {
+		if !stringhelper.IsContainedInSlice(w.Tags, dockerWorkerTag, true) {
+			continue
+		}
+		if err := s.memDBService.DeleteWorker(w.UniqueID, true); err != nil {
+			gaia.Cfg.Logger.Error("failed to remove orphaned docker worker", "error", err.Error(), "worker", w)
+		}
+	}
+}
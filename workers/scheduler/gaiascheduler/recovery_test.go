@@ -0,0 +1,202 @@
+package gaiascheduler
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/store"
+	"github.com/gaia-pipeline/gaia/workers/docker"
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestRecoverRuns(t *testing.T) {
+	tests := []struct {
+		name           string
+		mode           gaia.Mode
+		policy         gaia.RecoveryPolicy
+		status         gaia.PipelineRunStatus
+		expected       gaia.PipelineRunStatus
+		expectedJob    gaia.JobStatus
+		dockerWorkerID string
+	}{
+		{name: "scheduled", mode: gaia.ModeServer, policy: gaia.RecoveryFail, status: gaia.RunScheduled, expected: gaia.RunNotScheduled, expectedJob: gaia.JobRunning},
+		{name: "scheduled docker", policy: gaia.RecoveryFail, status: gaia.RunScheduled, expected: gaia.RunNotScheduled, expectedJob: gaia.JobRunning, dockerWorkerID: "docker-worker"},
+		{name: "running fail", policy: gaia.RecoveryFail, status: gaia.RunRunning, expected: gaia.RunFailed, expectedJob: gaia.JobFailed},
+		{name: "running reschedule", policy: gaia.RecoveryReschedule, status: gaia.RunRunning, expected: gaia.RunNotScheduled, expectedJob: gaia.JobWaitingExec},
+		{name: "running reschedule worker", mode: gaia.ModeWorker, policy: gaia.RecoveryReschedule, status: gaia.RunRunning, expected: gaia.RunReschedule, expectedJob: gaia.JobWaitingExec},
+		{name: "waiting approval", policy: gaia.RecoveryFail, status: gaia.RunWaitingApproval, expected: gaia.RunWaitingApproval, expectedJob: gaia.JobRunning},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gaia.Cfg = &gaia.Config{Mode: tt.mode, RecoveryPolicy: tt.policy}
+			storeInstance := store.NewBoltStore()
+			tmp, _ := ioutil.TempDir("", "TestRecoverRuns")
+			gaia.Cfg.DataPath = tmp
+			gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+			gaia.Cfg.Bolt.Mode = 0600
+			gaia.Cfg.Logger = hclog.NewNullLogger()
+			if err := storeInstance.Init(tmp); err != nil {
+				t.Fatal(err)
+			}
+			s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The server mode must not depend on a docker host
+			if tt.mode == gaia.ModeServer {
+				gaia.Cfg.DockerHostURL = "unix://" + filepath.Join(tmp, "docker.sock")
+			}
+
+			_, r := prepareTestData()
+			r.Status = tt.status
+			r.Jobs = prepareJobs()
+			r.Jobs[0].Status = gaia.JobSuccess
+			r.Jobs[1].Status = gaia.JobRunning
+			r.PipelineTags = []string{"linux"}
+			if tt.dockerWorkerID != "" {
+				r.Docker = true
+				r.DockerWorkerID = tt.dockerWorkerID
+				r.PipelineTags = append(r.PipelineTags, tt.dockerWorkerID, dockerWorkerTag)
+			}
+			if err := storeInstance.PipelinePutRun(&r); err != nil {
+				t.Fatal(err)
+			}
+
+			s.recoverRuns()
+
+			run, _ := storeInstance.PipelineGetRunByID(r.UniqueID)
+			if run.Status != tt.expected {
+				t.Fatalf("expected run to be %s but got %s", tt.expected, run.Status)
+			}
+			if run.Jobs[0].Status != gaia.JobSuccess {
+				t.Fatalf("expected finished job to stay %s but got %s", gaia.JobSuccess, run.Jobs[0].Status)
+			}
+			if run.Jobs[1].Status != tt.expectedJob {
+				t.Fatalf("expected interrupted job to be %s but got %s", tt.expectedJob, run.Jobs[1].Status)
+			}
+			if run.DockerWorkerID != "" || len(run.PipelineTags) != 1 {
+				t.Fatalf("expected docker worker to be removed but got %q with tags %v", run.DockerWorkerID, run.PipelineTags)
+			}
+		})
+	}
+}
+
+func TestRecoverWorkerRuns(t *testing.T) {
+	gaia.Cfg = &gaia.Config{Mode: gaia.ModeServer, RecoveryPolicy: gaia.RecoveryFail}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestRecoverWorkerRuns")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, lost := prepareTestData()
+	lost.Status = gaia.RunRunning
+	lost.WorkerID = "lost-worker"
+	lost.Recovery = &gaia.Recovery{Policy: gaia.RecoveryReschedule, MaxReschedules: 1}
+	_, other := prepareTestData()
+	other.ID = 2
+	other.Status = gaia.RunRunning
+	other.WorkerID = "other-worker"
+	for _, r := range []*gaia.PipelineRun{&lost, &other} {
+		if err := storeInstance.PipelinePutRun(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The pipeline policy reschedules the run instead of the default policy
+	if err := s.RecoverWorkerRuns("lost-worker"); err != nil {
+		t.Fatal(err)
+	}
+	run, _ := storeInstance.PipelineGetRunByID(lost.UniqueID)
+	if run.Status != gaia.RunNotScheduled || run.Reschedules != 1 || run.WorkerID != "" {
+		t.Fatalf("expected run to be rescheduled once but got %s with %d reschedules at %q", run.Status, run.Reschedules, run.WorkerID)
+	}
+
+	// The run is failed once the limit of reschedules has been reached
+	run.Status = gaia.RunRunning
+	run.WorkerID = "lost-worker"
+	if err := storeInstance.PipelinePutRun(run); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RecoverWorkerRuns("lost-worker"); err != nil {
+		t.Fatal(err)
+	}
+	run, _ = storeInstance.PipelineGetRunByID(lost.UniqueID)
+	if run.Status != gaia.RunFailed {
+		t.Fatalf("expected run to be failed but got %s", run.Status)
+	}
+
+	// Runs of other workers are not affected
+	run, _ = storeInstance.PipelineGetRunByID(other.UniqueID)
+	if run.Status != gaia.RunRunning || run.WorkerID != "other-worker" {
+		t.Fatalf("expected run of other worker to be unchanged but got %s at %q", run.Status, run.WorkerID)
+	}
+}
+
+type MemDBFakeNoDockerWorker struct {
+	MemDBFakeDockerWorker
+}
+
+func (m *MemDBFakeNoDockerWorker) GetDockerWorker(workerID string) (*docker.Worker, error) {
+	return nil, nil
+}
+
+func TestRecoverDockerRun(t *testing.T) {
+	gaia.Cfg = &gaia.Config{Mode: gaia.ModeServer, RecoveryPolicy: gaia.RecoveryFail}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestRecoverDockerRun")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+
+	// The docker worker of the run is still known
+	db := &MemDBFakeDockerWorker{}
+	s, err := NewScheduler(Dependencies{storeInstance, db, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, r := prepareTestData()
+	r.Status = gaia.RunScheduled
+	r.Docker = true
+	r.DockerWorkerID = "dockerworker"
+	r.PipelineTags = []string{"dockerworker", dockerWorkerTag}
+	s.recoverRun(&r)
+	if !reflect.DeepEqual(db.deletedDockerWorkers, []string{"dockerworker"}) || !reflect.DeepEqual(db.deletedWorkers, []string{"worker"}) {
+		t.Fatalf("expected docker worker to be removed but got %v and %v", db.deletedDockerWorkers, db.deletedWorkers)
+	}
+
+	// The docker worker of the run has been already removed
+	noWorkerDB := &MemDBFakeNoDockerWorker{}
+	s, err = NewScheduler(Dependencies{storeInstance, noWorkerDB, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, r = prepareTestData()
+	r.Status = gaia.RunScheduled
+	r.Docker = true
+	r.DockerWorkerID = "dockerworker"
+	s.recoverRun(&r)
+	if len(noWorkerDB.deletedDockerWorkers) != 0 || len(noWorkerDB.deletedWorkers) != 0 {
+		t.Fatalf("expected no docker worker to be removed but got %v and %v", noWorkerDB.deletedDockerWorkers, noWorkerDB.deletedWorkers)
+	}
+	if r.DockerWorkerID != "" {
+		t.Fatalf("expected docker worker to be detached but got %q", r.DockerWorkerID)
+	}
+}
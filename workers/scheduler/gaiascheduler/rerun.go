@@ -0,0 +1,113 @@
+package gaiascheduler
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
+)
+
+var (
+	// errRunNotFinished is returned when a pipeline run is rerun which has not been finished yet.
+	errRunNotFinished = errors.New("pipeline run has not been finished yet")
+
+	// errPipelineChanged is returned when the pipeline has been changed since the pipeline run.
+	errPipelineChanged = errors.New("pipeline has been changed since the pipeline run and cannot be rerun")
+
+	// errNoFailedJobs is returned when the failed jobs of a pipeline run without failed jobs are rerun.
+	errNoFailedJobs = errors.New("pipeline run has no failed jobs")
+)
+
+// RerunPipeline schedules a new run of the given pipeline which replays the given
+// finished run with the same arguments, pipeline binary and docker setting.
+// If failedOnly is set, only the failed jobs and the jobs which depend on them
+// are executed. All other jobs are taken over as successful from the given run.
+func (s *Scheduler) RerunPipeline(p *gaia.Pipeline, run *gaia.PipelineRun, failedOnly bool) (*gaia.PipelineRun, error) {
+	switch run.Status {
+	case gaia.RunSuccess, gaia.RunFailed, gaia.RunCancelled:
+	default:
+		return nil, errRunNotFinished
+	}
+
+	// Runs which have been created before the checksum was recorded cannot be verified
+	if len(run.SHA256Sum) > 0 && !bytes.Equal(run.SHA256Sum, p.SHA256Sum) {
+		return nil, errPipelineChanged
+	}
+
+	// Do not change the docker setting of the active pipeline
+	pipeline := *p
+	pipeline.Docker = run.Docker
+
+	// The values of secret parameters are only kept in the vault
+	params := make([]*gaia.Argument, 0, len(run.Parameters))
+	for _, param := range run.Parameters {
+		a := *param
+		params = append(params, &a)
+	}
+	if err := pipelinehelper.ResolveSecretParameters(s.vault, run.UniqueID, params); err != nil {
+		gaia.Cfg.Logger.Error("cannot resolve secret parameters of pipeline run", "error", err.Error(), "runid", run.ID)
+		return nil, err
+	}
+
+	return s.schedulePipeline(&pipeline, gaia.StartReasonRerun, params, func(r *gaia.PipelineRun) error {
+		r.RerunOf = run.ID
+		if failedOnly {
+			return keepSucceededJobs(r, run)
+		}
+		return nil
+	})
+}
+
+// keepSucceededJobs takes over the jobs of the given original run which succeeded
+// and do not depend on a job which has to be executed again. These jobs are marked
+// as successful in the given run together with their outputs so that they are treated
+// as done when the dependencies of the remaining jobs are resolved.
+func keepSucceededJobs(r, original *gaia.PipelineRun) error {
+	originalJobs := make(map[uint32]*gaia.Job, len(original.Jobs))
+	var failed bool
+	for _, job := range original.Jobs {
+		originalJobs[job.ID] = job
+		failed = failed || job.Status == gaia.JobFailed || job.Status == gaia.JobTimedOut
+	}
+	if !failed {
+		return errNoFailedJobs
+	}
+
+	runJobs := make(map[uint32]*gaia.Job, len(r.Jobs))
+	for _, job := range r.Jobs {
+		runJobs[job.ID] = job
+	}
+
+	// A job is kept if it succeeded and all jobs it depends on are kept
+	kept := make(map[uint32]bool, len(r.Jobs))
+	var keep func(id uint32) bool
+	keep = func(id uint32) bool {
+		if k, ok := kept[id]; ok {
+			return k
+		}
+		kept[id] = false
+		job, ok := runJobs[id]
+		if !ok {
+			return false
+		}
+		if originalJob, ok := originalJobs[id]; !ok || originalJob.Status != gaia.JobSuccess {
+			return false
+		}
+		for _, depJob := range job.DependsOn {
+			if !keep(depJob.ID) {
+				return false
+			}
+		}
+		kept[id] = true
+		return true
+	}
+
+	for _, job := range r.Jobs {
+		if keep(job.ID) {
+			job.Status = gaia.JobSuccess
+			job.Outputs = originalJobs[job.ID].Outputs
+		}
+	}
+	return nil
+}
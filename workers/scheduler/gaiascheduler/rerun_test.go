@@ -0,0 +1,116 @@
+package gaiascheduler
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/store"
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestRerunPipeline(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestRerunPipeline")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := prepareTestData()
+	p.SHA256Sum = []byte("sha")
+	p.Docker = true
+	_ = storeInstance.PipelinePut(&p)
+	pS := &PluginFakeOutputs{args: make(map[string][]*gaia.Argument)}
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, pS, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original, err := s.SchedulePipeline(&p, gaia.StartReasonManual, []*gaia.Argument{{Key: "firstarg", Value: "first"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(original.SHA256Sum, p.SHA256Sum) {
+		t.Fatalf("expected checksum %s to be recorded but got %s", p.SHA256Sum, original.SHA256Sum)
+	}
+
+	// Runs which have not been finished cannot be rerun
+	if _, err := s.RerunPipeline(&p, original, false); err != errRunNotFinished {
+		t.Fatalf("expected %v but got %v", errRunNotFinished, err)
+	}
+
+	// Job3 failed and Job4 has been skipped
+	original.Status = gaia.RunFailed
+	statuses := map[string]gaia.JobStatus{"Job1": gaia.JobSuccess, "Job2": gaia.JobSuccess, "Job3": gaia.JobFailed, "Job4": gaia.JobSkipped}
+	for _, job := range original.Jobs {
+		job.Status = statuses[job.Title]
+		job.Outputs = []*gaia.Argument{{Key: "producer", Value: job.Title}}
+	}
+
+	// The docker setting of the run is replayed
+	active := p
+	active.Docker = false
+	r, err := s.RerunPipeline(&active, original, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RerunOf != original.ID || r.ID != original.ID+1 || r.StartReason != gaia.StartReasonRerun || !r.Docker {
+		t.Fatalf("unexpected rerun %#v", r)
+	}
+	if !reflect.DeepEqual(r.Parameters, original.Parameters) {
+		t.Fatalf("expected parameters %v but got %v", original.Parameters, r.Parameters)
+	}
+	for _, job := range r.Jobs {
+		if job.Status != gaia.JobWaitingExec || job.Outputs != nil {
+			t.Fatalf("expected %s to be executed again but got %s", job.Title, job.Status)
+		}
+	}
+
+	// Only the failed job and its dependents are executed again
+	r, err = s.RerunPipeline(&p, original, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]gaia.JobStatus{"Job1": gaia.JobSuccess, "Job2": gaia.JobSuccess, "Job3": gaia.JobWaitingExec, "Job4": gaia.JobWaitingExec}
+	for _, job := range r.Jobs {
+		if job.Status != expected[job.Title] {
+			t.Fatalf("expected %s to be %s but got %s", job.Title, expected[job.Title], job.Status)
+		}
+	}
+
+	s.prepareAndExec(*r)
+	if _, ok := pS.args["Job2"]; ok || len(pS.args) != 2 {
+		t.Fatalf("expected only Job3 and Job4 to be executed but got %v", pS.args)
+	}
+	var found bool
+	for _, arg := range pS.args["Job3"] {
+		found = found || (arg.Key == "producer" && arg.Value == "Job2")
+	}
+	if !found {
+		t.Fatalf("expected Job3 to receive the output of Job2 but got %v", pS.args["Job3"])
+	}
+	run, err := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != gaia.RunSuccess {
+		t.Fatalf("expected rerun to succeed but got %s", run.Status)
+	}
+
+	// Runs without failed jobs cannot be rerun partially
+	if _, err := s.RerunPipeline(&p, run, true); err != errNoFailedJobs {
+		t.Fatalf("expected %v but got %v", errNoFailedJobs, err)
+	}
+
+	// The pipeline must not have been changed since the run
+	p.SHA256Sum = []byte("changed")
+	if _, err := s.RerunPipeline(&p, original, false); err != errPipelineChanged {
+		t.Fatalf("expected %v but got %v", errPipelineChanged, err)
+	}
+}
@@ -0,0 +1,69 @@
+package gaiascheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+// shouldRetry decides if the given finished job should be executed again
+// based on its retry policy and the already recorded attempts.
+// Jobs are never retried when the given run context is done.
+func shouldRetry(ctx context.Context, j *gaia.Job) bool {
+	if j.Retry == nil || ctx.Err() != nil || len(j.Attempts) == 0 {
+		return false
+	}
+	if j.Status != gaia.JobFailed && j.Status != gaia.JobTimedOut {
+		return false
+	}
+	if len(j.Attempts) >= j.Retry.MaxAttempts || len(j.Attempts) >= gaia.RetryMaxAttempts {
+		return false
+	}
+
+	// Jobs which failed by themselves are only retried if allowed.
+	lastAttempt := j.Attempts[len(j.Attempts)-1]
+	return !j.Retry.InfraErrorsOnly || lastAttempt.InfraError
+}
+
+// retryBackoff returns the delay before the next attempt of the given job.
+// The backoff of the retry policy is doubled with every further attempt
+// but never exceeds gaia.RetryMaxBackoff.
+func retryBackoff(j *gaia.Job) time.Duration {
+	if j.Retry == nil || j.Retry.Backoff <= 0 || len(j.Attempts) == 0 {
+		return 0
+	}
+	maxBackoff := gaia.RetryMaxBackoff * time.Second
+	backoff := time.Duration(j.Retry.Backoff) * time.Second
+	for i := 1; i < len(j.Attempts) && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// retryJob waits for the given backoff and executes the given job again.
+// If the run context is done or the abort channel is closed in the meantime,
+// the job is not executed again and reported with the status of its last attempt.
+func (s *Scheduler) retryJob(ctx context.Context, j gaia.Job, backoff, timeout time.Duration, rp *runPlugin, triggerSave chan gaia.Job, abort chan bool) {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		s.executeJob(ctx, j, timeout, rp, triggerSave)
+	case <-ctx.Done():
+		s.abortRetry(j, triggerSave)
+	case <-abort:
+		s.abortRetry(j, triggerSave)
+	}
+}
+
+// abortRetry reports the given job as finished with the status of its last attempt.
+func (s *Scheduler) abortRetry(j gaia.Job, triggerSave chan gaia.Job) {
+	j.Status = j.Attempts[len(j.Attempts)-1].Status
+	j.FailPipeline = true
+	triggerSave <- j
+}
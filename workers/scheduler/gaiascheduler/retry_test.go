@@ -0,0 +1,60 @@
+package gaiascheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
+
+func TestShouldRetry(t *testing.T) {
+	failed := []*gaia.JobAttempt{{Attempt: 1, Status: gaia.JobFailed}}
+	infraFailed := []*gaia.JobAttempt{{Attempt: 1, Status: gaia.JobFailed, InfraError: true}}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		job      gaia.Job
+		expected bool
+	}{
+		{"no policy", context.Background(), gaia.Job{Status: gaia.JobFailed, Attempts: failed}, false},
+		{"successful job", context.Background(), gaia.Job{Status: gaia.JobSuccess, Attempts: failed, Retry: &gaia.RetryPolicy{MaxAttempts: 2}}, false},
+		{"failed job", context.Background(), gaia.Job{Status: gaia.JobFailed, Attempts: failed, Retry: &gaia.RetryPolicy{MaxAttempts: 2}}, true},
+		{"timed out job", context.Background(), gaia.Job{Status: gaia.JobTimedOut, Attempts: failed, Retry: &gaia.RetryPolicy{MaxAttempts: 2}}, true},
+		{"max attempts reached", context.Background(), gaia.Job{Status: gaia.JobFailed, Attempts: failed, Retry: &gaia.RetryPolicy{MaxAttempts: 1}}, false},
+		{"job error with infra errors only", context.Background(), gaia.Job{Status: gaia.JobFailed, Attempts: failed, Retry: &gaia.RetryPolicy{MaxAttempts: 2, InfraErrorsOnly: true}}, false},
+		{"infra error with infra errors only", context.Background(), gaia.Job{Status: gaia.JobFailed, Attempts: infraFailed, Retry: &gaia.RetryPolicy{MaxAttempts: 2, InfraErrorsOnly: true}}, true},
+		{"run context done", cancelled, gaia.Job{Status: gaia.JobFailed, Attempts: failed, Retry: &gaia.RetryPolicy{MaxAttempts: 2}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldRetry(tt.ctx, &tt.job); got != tt.expected {
+				t.Fatalf("expected %v but got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	j := &gaia.Job{Retry: &gaia.RetryPolicy{MaxAttempts: 4, Backoff: 5}}
+	expected := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second}
+	for _, e := range expected {
+		j.Attempts = append(j.Attempts, &gaia.JobAttempt{Attempt: len(j.Attempts) + 1})
+		if got := retryBackoff(j); got != e {
+			t.Fatalf("expected backoff %s after %d attempts but got %s", e, len(j.Attempts), got)
+		}
+	}
+
+	// The backoff is capped
+	j = &gaia.Job{Retry: &gaia.RetryPolicy{MaxAttempts: 100, Backoff: gaia.RetryMaxBackoff / 2}}
+	expected = []time.Duration{gaia.RetryMaxBackoff / 2 * time.Second, gaia.RetryMaxBackoff * time.Second, gaia.RetryMaxBackoff * time.Second}
+	for _, e := range expected {
+		j.Attempts = append(j.Attempts, &gaia.JobAttempt{Attempt: len(j.Attempts) + 1})
+		if got := retryBackoff(j); got != e {
+			t.Fatalf("expected backoff %s after %d attempts but got %s", e, len(j.Attempts), got)
+		}
+	}
+}
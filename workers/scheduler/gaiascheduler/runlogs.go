@@ -0,0 +1,151 @@
+package gaiascheduler
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/plugin"
+	"github.com/gaia-pipeline/gaia/runstorage"
+)
+
+// logTimeFormat is the time format used for log entries written by the scheduler.
+const logTimeFormat = "2006/01/02 15:04:05"
+
+// runLogs holds the log writers of a single pipeline run.
+// The output of the pipeline process is written into the logs of all jobs
+// which are running at that time and additionally into the combined log of
+// the pipeline run. Output written while several jobs run in parallel
+// therefore ends up in the logs of all of them.
+// runLogs can be safely shared between goroutines.
+type runLogs struct {
+	sync.Mutex
+
+	// storage stores the logs of the pipeline run.
+	storage runstorage.GaiaRunStorage
+
+	// pipelineID, runID and uniqueID identify the pipeline run.
+	pipelineID int
+	runID      int
+	uniqueID   string
+
+	// artifactsFolder is the artifacts folder of the pipeline run.
+	artifactsFolder string
+
+	// combinedLog is the combined log of the pipeline run.
+	combinedLog runstorage.Writer
+
+	// combined is the concurrent safe writer for the combined log.
+	combined *plugin.GaiaLogWriter
+
+	// jobLogs holds the logs of all running jobs.
+	jobLogs map[uint32]runstorage.Writer
+}
+
+// newRunLogs opens the combined log of the given pipeline run in the
+// given storage and returns a new instance of runLogs. The combined log of
+// a resumed run is continued. Jobs publish their artifacts into the given
+// artifacts folder.
+func newRunLogs(storage runstorage.GaiaRunStorage, r *gaia.PipelineRun, artifactsFolder string) (*runLogs, error) {
+	w, err := runstorage.Append(storage, runstorage.LogKey(r.PipelineID, r.ID, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	return &runLogs{
+		storage:         storage,
+		pipelineID:      r.PipelineID,
+		runID:           r.ID,
+		uniqueID:        r.UniqueID,
+		artifactsFolder: artifactsFolder,
+		combinedLog:     w,
+		combined:        plugin.NewGaiaLogWriter(w),
+		jobLogs:         make(map[uint32]runstorage.Writer),
+	}, nil
+}
+
+// jobLog opens the log of the job with the given id for appending.
+// Retries of a job append to the log of the previous attempts.
+func (l *runLogs) jobLog(jobID uint32) (runstorage.Writer, error) {
+	return runstorage.Append(l.storage, runstorage.LogKey(l.pipelineID, l.runID, jobID))
+}
+
+// jobArtifactsPath returns the artifacts folder of the job with the given id.
+func (l *runLogs) jobArtifactsPath(jobID uint32) string {
+	return filepath.Join(l.artifactsFolder, strconv.FormatUint(uint64(jobID), 10))
+}
+
+// openJobLog opens the log of the given job. The output of the pipeline
+// process is written into it until the log is closed again.
+func (l *runLogs) openJobLog(jobID uint32) error {
+	w, err := l.jobLog(jobID)
+	if err != nil {
+		return err
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	l.jobLogs[jobID] = w
+	return nil
+}
+
+// closeJobLog closes the log of the given job.
+func (l *runLogs) closeJobLog(jobID uint32) {
+	l.Lock()
+	defer l.Unlock()
+	if w, ok := l.jobLogs[jobID]; ok {
+		_ = w.Close()
+		delete(l.jobLogs, jobID)
+	}
+	_ = l.combined.Flush()
+}
+
+// Write writes the given output of the pipeline process into the logs of all running jobs.
+func (l *runLogs) Write(p []byte) (n int, err error) {
+	l.Lock()
+	defer l.Unlock()
+	for _, w := range l.jobLogs {
+		if _, wErr := w.Write(p); wErr != nil {
+			err = wErr
+		}
+	}
+	return len(p), err
+}
+
+// Close implements io.Closer. The job logs are closed by closeJobLog.
+func (l *runLogs) Close() error {
+	return nil
+}
+
+// writeJobError writes the given error message to the logs of the given job.
+// This is used when the job could not be started at all.
+func (l *runLogs) writeJobError(j *gaia.Job, err error) {
+	msg := fmt.Sprintf("%s Job '%s' could not be started: %s\n", time.Now().Format(logTimeFormat), j.Title, err.Error())
+
+	// Append to the job log
+	if w, wErr := l.jobLog(j.ID); wErr == nil {
+		_, _ = w.Write([]byte(msg))
+		_ = w.Close()
+	}
+
+	_, _ = l.combined.WriteString(msg)
+}
+
+// flush flushes the logs of all running jobs and the combined logs.
+func (l *runLogs) flush() {
+	l.Lock()
+	defer l.Unlock()
+	for _, w := range l.jobLogs {
+		_ = w.Flush()
+	}
+	_ = l.combined.Flush()
+}
+
+// close flushes and closes the combined log.
+func (l *runLogs) close() {
+	l.flush()
+	_ = l.combinedLog.Close()
+}
@@ -0,0 +1,104 @@
+package gaiascheduler
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/plugin"
+	"github.com/gaia-pipeline/gaia/tracing"
+)
+
+// runPlugin holds the plugin instance which executes the jobs of a single pipeline run.
+// All jobs of a run are executed by the same pipeline process so that they share its memory.
+// The process is started with the first job of the run and its output is written into
+// the logs of the jobs which are running at that time.
+// runPlugin can be safely shared between goroutines.
+type runPlugin struct {
+	sync.Mutex
+
+	s        *Scheduler
+	pipeline *gaia.Pipeline
+	logs     *runLogs
+
+	// pS is the running plugin instance or nil if none has been started yet.
+	pS plugin.Plugin
+}
+
+// newRunPlugin returns a new runPlugin for the given pipeline.
+// The output of the plugin is written to the given run logs.
+func (s *Scheduler) newRunPlugin(p *gaia.Pipeline, logs *runLogs) *runPlugin {
+	return &runPlugin{
+		s:        s,
+		pipeline: p,
+		logs:     logs,
+	}
+}
+
+// get returns the plugin instance of the run.
+// A new instance is created, initialized and validated if none is running.
+func (rp *runPlugin) get(ctx context.Context) (pS plugin.Plugin, err error) {
+	rp.Lock()
+	defer rp.Unlock()
+	if rp.pS != nil {
+		return rp.pS, nil
+	}
+
+	_, span := tracing.Tracer().Start(ctx, "start plugin")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	// Create the start command for the pipeline
+	c := createPipelineCmd(rp.pipeline)
+	if c == nil {
+		return nil, errCreateCMDForPipeline
+	}
+
+	// Create new plugin instance
+	pS = rp.s.pluginSystem.NewPlugin(rp.s.ca)
+
+	// Init the plugin
+	if err := pS.Init(c, rp.logs, rp.logs.combined); err != nil {
+		return nil, err
+	}
+
+	// Validate the plugin(pipeline)
+	if err := pS.Validate(); err != nil {
+		pS.Close()
+		return nil, err
+	}
+	rp.pS = pS
+
+	return pS, nil
+}
+
+// reset closes the given plugin instance if it is still the instance of the run.
+// This is used when the plugin is broken. The next job starts a new instance.
+func (rp *runPlugin) reset(pS plugin.Plugin) {
+	rp.Lock()
+	defer rp.Unlock()
+	if rp.pS == pS {
+		rp.pS = nil
+		pS.Close()
+	}
+}
+
+// flush flushes the logs of the plugin and the run logs.
+func (rp *runPlugin) flush() {
+	rp.Lock()
+	if rp.pS != nil {
+		_ = rp.pS.FlushLogs()
+	}
+	rp.Unlock()
+	rp.logs.flush()
+}
+
+// close flushes the logs of the plugin and kills the plugin process.
+func (rp *runPlugin) close() {
+	rp.Lock()
+	defer rp.Unlock()
+	if rp.pS != nil {
+		_ = rp.pS.FlushLogs()
+		rp.pS.Close()
+		rp.pS = nil
+	}
+}
@@ -0,0 +1,64 @@
+package gaiascheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
+)
+
+// runQueue holds the pipeline runs which wait for a free worker of this instance.
+// Runs are taken in the order of their effective priority.
+type runQueue struct {
+	lock sync.Mutex
+	cond *sync.Cond
+	runs []*gaia.PipelineRun
+}
+
+// newRunQueue creates a new empty run queue.
+func newRunQueue() *runQueue {
+	q := &runQueue{}
+	q.cond = sync.NewCond(&q.lock)
+	return q
+}
+
+// push adds the given run to the queue.
+func (q *runQueue) push(r gaia.PipelineRun) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.runs = append(q.runs, &r)
+	q.cond.Signal()
+}
+
+// pop removes the run which has to be executed next from the queue.
+// It blocks until a run is available.
+func (q *runQueue) pop() gaia.PipelineRun {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for len(q.runs) == 0 {
+		q.cond.Wait()
+	}
+
+	// The effective priority changes over time, therefore the next run is determined on every pop
+	now := time.Now()
+	next := 0
+	for i, r := range q.runs {
+		if pipelinehelper.RunBefore(r, q.runs[next], now) {
+			next = i
+		}
+	}
+	r := q.runs[next]
+	q.runs = append(q.runs[:next], q.runs[next+1:]...)
+	return *r
+}
+
+// len returns the number of runs in the queue.
+func (q *runQueue) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	return len(q.runs)
+}
@@ -0,0 +1,44 @@
+package gaiascheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
+)
+
+func TestRunQueue(t *testing.T) {
+	q := newRunQueue()
+	now := time.Now()
+	q.push(gaia.PipelineRun{ID: 1, ScheduleDate: now.Add(-time.Minute)})
+	q.push(gaia.PipelineRun{ID: 2, ScheduleDate: now, Priority: 3})
+	q.push(gaia.PipelineRun{ID: 3, ScheduleDate: now.Add(-5 * pipelinehelper.PriorityAgingInterval), Priority: -1})
+	q.push(gaia.PipelineRun{ID: 4, ScheduleDate: now})
+
+	if q.len() != 4 {
+		t.Fatalf("expected 4 runs but got %d", q.len())
+	}
+
+	// Run 3 has been raised to a priority of 4 while waiting
+	for _, expected := range []int{3, 2, 1, 4} {
+		if r := q.pop(); r.ID != expected {
+			t.Fatalf("expected run %d but got %d", expected, r.ID)
+		}
+	}
+
+	// Pop blocks until a run has been pushed
+	popped := make(chan gaia.PipelineRun)
+	go func() {
+		popped <- q.pop()
+	}()
+	q.push(gaia.PipelineRun{ID: 5})
+	select {
+	case r := <-popped:
+		if r.ID != 5 {
+			t.Fatalf("expected run 5 but got %d", r.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected pushed run to be popped")
+	}
+}
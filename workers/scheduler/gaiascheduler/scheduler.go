@@ -1,9 +1,9 @@
 package gaiascheduler
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strconv"
 	"sync"
@@ -11,18 +11,25 @@ import (
 	"time"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/event"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
 	"github.com/gaia-pipeline/gaia/helper/stringhelper"
 	"github.com/gaia-pipeline/gaia/plugin"
+	"github.com/gaia-pipeline/gaia/runstorage"
 	"github.com/gaia-pipeline/gaia/security"
 	"github.com/gaia-pipeline/gaia/store"
 	"github.com/gaia-pipeline/gaia/store/memdb"
+	"github.com/gaia-pipeline/gaia/tracing"
 	"github.com/gaia-pipeline/gaia/workers/docker"
 	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	// Maximum buffer limit for scheduler
-	schedulerBufferLimit = 50
+	// SchedulerBufferLimit is the maximum number of runs buffered by the scheduler.
+	SchedulerBufferLimit = 50
 
 	// schedulerIntervalSeconds defines the interval the scheduler will look
 	// for new work to schedule. Definition in seconds.
@@ -34,6 +41,9 @@ const (
 	// argTypeVault is the argument type vault.
 	argTypeVault = "vault"
 
+	// argTypeOutput is the argument type of outputs passed from upstream jobs.
+	argTypeOutput = "output"
+
 	// logFlushInterval defines the interval where logs will be flushed to disk.
 	logFlushInterval = 1
 )
@@ -65,6 +75,12 @@ type GaiaScheduler interface {
 	SchedulePipeline(p *gaia.Pipeline, startedBy string, args []*gaia.Argument) (*gaia.PipelineRun, error)
 	SetPipelineJobs(p *gaia.Pipeline) error
 	StopPipelineRun(p *gaia.Pipeline, runID int) error
+	RerunPipeline(p *gaia.Pipeline, run *gaia.PipelineRun, failedOnly bool) (*gaia.PipelineRun, error)
+	DecideApproval(p *gaia.Pipeline, runID int, jobID uint32, username string, approved bool) error
+	RecoverWorkerRuns(workerID string) error
+	Drain()
+	Draining() bool
+	Shutdown(gracePeriod time.Duration)
 	GetFreeWorkers() int32
 	CountScheduledRuns() int
 }
@@ -73,8 +89,8 @@ var _ GaiaScheduler = (*Scheduler)(nil)
 
 // Scheduler represents the schuler object
 type Scheduler struct {
-	// buffered channel which is used as queue
-	scheduledRuns chan gaia.PipelineRun
+	// scheduledRuns is the queue of runs which wait for a free worker of this instance
+	scheduledRuns *runQueue
 
 	// storeService is an instance of store.
 	storeService store.GaiaStore
@@ -91,17 +107,32 @@ type Scheduler struct {
 	// vault is the instance of the vault.
 	vault security.GaiaVault
 
+	// events is the bus which receives the run and job lifecycle events.
+	events *event.Bus
+
+	// storage stores the logs and artifacts of pipeline runs.
+	storage runstorage.GaiaRunStorage
+
 	// Atomic Counter that represents the current free workers
 	freeWorkers *int32
 
+	// Atomic flag which is set when no new runs are started anymore
+	draining *int32
+
 	// Lock for scheduling
 	schedulePipelineLock sync.RWMutex
 	// Lock for scheduling
 	schedulerLock sync.RWMutex
 
-	// killedPipelineRun is used to signal the scheduler to abort a pipeline run.
-	// This has the size one for delayed guarantee of signal delivery.
-	killedPipelineRun chan *gaia.PipelineRun
+	// cancellations holds the cancellations of the runs which are
+	// executed by this instance, keyed by the unique run id.
+	cancellations     map[string]*runCancellation
+	cancellationsLock sync.Mutex
+
+	// approvals holds the queues of approval decisions of the runs
+	// which are executed by this instance, keyed by the unique run id.
+	approvals     map[string]chan approvalDecision
+	approvalsLock sync.Mutex
 }
 
 // Dependencies defines the dependencies of the scheduler service.
@@ -111,26 +142,48 @@ type Dependencies struct {
 	PS    plugin.Plugin
 	CA    security.CAAPI
 	Vault security.GaiaVault
+
+	// Events is optional. If not set, no lifecycle events are published.
+	Events *event.Bus
+
+	// Storage is optional. If not set, logs and artifacts
+	// are stored in the workspace folder.
+	Storage runstorage.GaiaRunStorage
 }
 
 // NewScheduler creates a new Scheduler service.
 func NewScheduler(deps Dependencies) (*Scheduler, error) {
 	// Create new scheduler
 	s := &Scheduler{
-		scheduledRuns:     make(chan gaia.PipelineRun, schedulerBufferLimit),
-		storeService:      deps.Store,
-		memDBService:      deps.DB,
-		pluginSystem:      deps.PS,
-		ca:                deps.CA,
-		vault:             deps.Vault,
-		freeWorkers:       new(int32),
-		killedPipelineRun: make(chan *gaia.PipelineRun, 1),
+		scheduledRuns: newRunQueue(),
+		storeService:  deps.Store,
+		memDBService:  deps.DB,
+		pluginSystem:  deps.PS,
+		ca:            deps.CA,
+		vault:         deps.Vault,
+		events:        deps.Events,
+		storage:       deps.Storage,
+		freeWorkers:   new(int32),
+		draining:      new(int32),
+		cancellations: make(map[string]*runCancellation),
+		approvals:     make(map[string]chan approvalDecision),
 	}
 	return s, nil
 }
 
+// runStorage returns the storage for logs and artifacts of pipeline runs.
+func (s *Scheduler) runStorage() runstorage.GaiaRunStorage {
+	if s.storage == nil {
+		return runstorage.NewLocalStorage(gaia.Cfg.WorkspacePath)
+	}
+	return s.storage
+}
+
 // Init initializes the scheduler.
 func (s *Scheduler) Init() {
+	// Recover the runs which have been interrupted by a restart
+	s.recoverRuns()
+
 	// Setup worker
 	for i := 0; i < gaia.Cfg.Worker; i++ {
 		go s.work()
@@ -144,6 +197,9 @@ func (s *Scheduler) Init() {
 			case <-schedulerJob.C:
 				// Do the scheduling
 				s.schedule()
+
+				// Reject jobs which have not been approved in time
+				s.rejectExpiredApprovals()
 			}
 		}
 	}()
@@ -158,7 +214,7 @@ func (s *Scheduler) work() {
 		atomic.AddInt32(s.freeWorkers, 1)
 
 		// Take one scheduled run, block if there are no scheduled pipelines
-		r := <-s.scheduledRuns
+		r := s.scheduledRuns.pop()
 
 		// We picked up work and are from now on busy
 		atomic.AddInt32(s.freeWorkers, -1)
@@ -170,9 +226,38 @@ func (s *Scheduler) work() {
 
 // prepareAndExec does the preparation and starts the execution.
 func (s *Scheduler) prepareAndExec(r gaia.PipelineRun) {
-	// Mark the scheduled run as running
+	// Register the cancellation first so that no cancellation of the run gets lost
+	killed, unregister := s.registerCancellation(r.UniqueID)
+	defer unregister()
+
+	// Skip runs which have been cancelled while waiting for a free worker
+	if run, err := s.storeService.PipelineGetRunByID(r.UniqueID); err == nil && run != nil && run.Status == gaia.RunCancelled {
+		return
+	}
+
+	// A draining instance does not start new runs. The cancellation has been registered
+	// first so that a shutdown either waits for the run or the run is not started at all.
+	if s.Draining() {
+		s.unscheduleRun(r)
+		return
+	}
+
+	// Mark the scheduled run as running. A run which has been resumed after
+	// an approval keeps its first start date which its deadline is based on.
 	r.Status = gaia.RunRunning
-	r.StartDate = time.Now()
+	if r.StartDate.IsZero() {
+		r.StartDate = time.Now()
+	}
+
+	// Record the time the run waited for execution and the execution itself
+	ctx := tracing.RunContext(context.Background(), &r)
+	_, wait := tracing.Tracer().Start(ctx, "wait for execution", trace.WithTimestamp(r.ScheduleDate))
+	wait.End(trace.WithTimestamp(r.StartDate))
+	ctx, span := tracing.Tracer().Start(ctx, "execute pipeline run", trace.WithTimestamp(r.StartDate), trace.WithAttributes(
+		attribute.Int("pipeline.id", r.PipelineID),
+		attribute.Int("pipelinerun.id", r.ID),
+	))
+	defer span.End()
 
 	// Update entry in store
 	err := s.storeService.PipelinePutRun(&r)
@@ -180,6 +265,7 @@ func (s *Scheduler) prepareAndExec(r gaia.PipelineRun) {
 		gaia.Cfg.Logger.Debug("could not put pipeline run into store during executing work", "error", err.Error())
 		return
 	}
+	s.events.Publish(event.NewRunEvent(event.RunStarted, &r))
 
 	// Get related pipeline from pipeline run
 	pipeline, _ := s.storeService.PipelineGet(r.PipelineID)
@@ -203,43 +289,30 @@ func (s *Scheduler) prepareAndExec(r gaia.PipelineRun) {
 		}
 	}
 
-	// Create logs folder for this run
-	path := filepath.Join(gaia.Cfg.WorkspacePath, strconv.Itoa(r.PipelineID), strconv.Itoa(r.ID), gaia.LogsFolderName)
-	err = os.MkdirAll(path, 0700)
-	if err != nil {
-		gaia.Cfg.Logger.Error("cannot create pipeline run folder", "error", err.Error(), "path", path)
-	}
-
-	// Create the start command for the pipeline
-	c := createPipelineCmd(pipeline)
-	if c == nil {
+	// Check if we are able to create the start command for the pipeline
+	if c := createPipelineCmd(pipeline); c == nil {
 		gaia.Cfg.Logger.Debug("cannot create pipeline start command", "error", errCreateCMDForPipeline.Error())
 		s.finishPipelineRun(&r, gaia.RunFailed)
 		return
 	}
 
-	// Create new plugin instance
-	pS := s.pluginSystem.NewPlugin(s.ca)
-
-	// Init the plugin
-	path = filepath.Join(path, gaia.LogsFileName)
-	if err := pS.Init(c, &path); err != nil {
-		gaia.Cfg.Logger.Debug("cannot initialize the plugin", "error", err.Error(), "pipeline", pipeline)
+	// Create the combined logs of this run
+	artifactsPath := filepath.Join(gaia.Cfg.WorkspacePath, strconv.Itoa(r.PipelineID), strconv.Itoa(r.ID), gaia.ArtifactsFolderName)
+	logs, err := newRunLogs(s.runStorage(), &r, artifactsPath)
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot create pipeline run logs", "error", err.Error(), "pipeline", pipeline)
 		s.finishPipelineRun(&r, gaia.RunFailed)
 		return
 	}
+	defer logs.close()
 
-	// Validate the plugin(pipeline)
-	if err := pS.Validate(); err != nil {
-		gaia.Cfg.Logger.Debug("cannot validate pipeline", "error", err.Error(), "pipeline", pipeline)
-		s.finishPipelineRun(&r, gaia.RunFailed)
-		return
-	}
-	defer pS.Close()
+	// All jobs of this run are executed by the same pipeline process
+	rp := s.newRunPlugin(pipeline, logs)
+	defer rp.close()
 
 	// Schedule jobs and execute them.
 	// Also update the run in the store.
-	s.executeScheduledJobs(r, pS)
+	s.executeScheduledJobs(ctx, killed, r, rp)
 }
 
 // schedule looks in the store for new work and schedules it.
@@ -247,25 +320,36 @@ func (s *Scheduler) schedule() {
 	s.schedulerLock.Lock()
 	defer s.schedulerLock.Unlock()
 
+	// A draining instance does not pick up new work
+	if s.Draining() {
+		return
+	}
+
 	// Do we have space left in our buffer?
-	if s.CountScheduledRuns() >= schedulerBufferLimit {
+	if s.CountScheduledRuns() >= SchedulerBufferLimit {
 		// No space left. Exit.
 		return
 	}
 
-	// Get scheduled pipelines but limit the returning number of elements.
-	scheduled, err := s.storeService.PipelineGetScheduled(schedulerBufferLimit)
+	// Get all scheduled pipelines. Runs which wait for their concurrency
+	// group must not prevent runs of other pipelines from being scheduled.
+	scheduled, err := s.storeService.PipelineGetRunsByStatus(gaia.RunNotScheduled)
 	if err != nil {
 		gaia.Cfg.Logger.Debug("cannot get scheduled pipelines", "error", err.Error())
 		return
 	}
 
+	// Take the runs with the highest priority which do not exceed the limit of their concurrency group.
+	pipelinehelper.SortRunsByPriority(scheduled, time.Now())
+	scheduled = s.applyConcurrency(scheduled, SchedulerBufferLimit)
+
 	// Iterate scheduled runs
 	for id := range scheduled {
 		// Small helper function to update the pipeline run status in the store
 		storeUpdate := func(run *gaia.PipelineRun, status gaia.PipelineRunStatus) {
-			// Update entry in store
+			// Update entry in store. The run has not been handed out to a worker yet.
 			run.Status = status
+			run.WorkerID = ""
 			if err := s.storeService.PipelinePutRun(run); err != nil {
 				gaia.Cfg.Logger.Debug("could not put pipeline run into store via schedule", "error", err.Error(), "run", run)
 			}
@@ -283,7 +367,7 @@ func (s *Scheduler) schedule() {
 					invalidWorkers++
 				case w.Status != gaia.WorkerActive:
 					invalidWorkers++
-				case stringhelper.IsContainedInSlice(w.Tags, "dockerworker", true):
+				case stringhelper.IsContainedInSlice(w.Tags, dockerWorkerTag, true):
 					invalidWorkers++
 				}
 			}
@@ -339,7 +423,7 @@ func (s *Scheduler) schedule() {
 
 			// If it is a docker run, pipeline will be executed by a worker inside a container
 			scheduled[id].DockerWorkerID = worker.WorkerID
-			scheduled[id].PipelineTags = append(scheduled[id].PipelineTags, []string{worker.WorkerID, "dockerworker"}...)
+			scheduled[id].PipelineTags = append(scheduled[id].PipelineTags, []string{worker.WorkerID, dockerWorkerTag}...)
 			if err := s.memDBService.InsertPipelineRun(scheduled[id]); err != nil {
 				gaia.Cfg.Logger.Error("failed to insert pipeline run into memdb via schedule", "error", err.Error())
 				continue
@@ -351,8 +435,13 @@ func (s *Scheduler) schedule() {
 			continue
 		}
 
-		// push scheduled run into our channel
-		s.scheduledRuns <- *scheduled[id]
+		// Keep the run in the store until there is space left in our queue
+		if s.CountScheduledRuns() >= SchedulerBufferLimit {
+			continue
+		}
+
+		// push scheduled run into our queue
+		s.scheduledRuns.push(*scheduled[id])
 
 		// Run is now scheduled
 		storeUpdate(scheduled[id], gaia.RunScheduled)
@@ -366,24 +455,105 @@ func (s *Scheduler) StopPipelineRun(p *gaia.Pipeline, runID int) error {
 	if err != nil {
 		return err
 	}
+	return s.stopRun(pr)
+}
+
+// stopRun cancels the given pipeline run.
+func (s *Scheduler) stopRun(pr *gaia.PipelineRun) error {
 	if pr.Status == gaia.RunFailed || pr.Status == gaia.RunCancelled || pr.Status == gaia.RunSuccess {
 		return errors.New("pipeline is not in a cancellable state")
 	}
 
+	// Paused runs are not executed and are cancelled right away
+	if pr.Status == gaia.RunWaitingApproval {
+		return s.cancelPausedRun(pr.UniqueID)
+	}
+
+	// Runs which have not been started yet are removed from the queue of the workers
+	if pr.Status == gaia.RunNotScheduled || pr.Status == gaia.RunScheduled {
+		if err := s.memDBService.DeletePipelineRun(pr.UniqueID); err != nil {
+			return err
+		}
+		s.finishPipelineRun(pr, gaia.RunCancelled)
+
+		// The run might have just been picked up by a worker of this instance
+		s.cancelRun(pr.UniqueID)
+
+		// The container of a docker run has been already started by the schedule
+		if pr.DockerWorkerID != "" {
+			s.removeDockerWorker(pr.DockerWorkerID)
+		}
+		return nil
+	}
+
 	pr.Status = gaia.RunCancelled
-	err = s.storeService.PipelinePutRun(pr)
+	err := s.storeService.PipelinePutRun(pr)
 	if err != nil {
 		return err
 	}
-	s.killedPipelineRun <- pr
+
+	// Runs executed by a remote worker are not known here. The worker
+	// asks for cancelled runs and stops them itself.
+	s.cancelRun(pr.UniqueID)
 
 	return nil
 }
 
+// removeDockerWorker deregisters the docker worker with the given id
+// from this instance and kills its container.
+func (s *Scheduler) removeDockerWorker(workerID string) {
+	worker, err := s.memDBService.GetDockerWorker(workerID)
+	if err != nil || worker == nil {
+		gaia.Cfg.Logger.Error("failed to find docker worker of pipeline run in memdb", "workerid", workerID)
+		return
+	}
+
+	// The docker worker might have already registered itself
+	for _, w := range s.memDBService.GetAllWorker() {
+		if stringhelper.IsContainedInSlice(w.Tags, workerID, false) {
+			if err := s.memDBService.DeleteWorker(w.UniqueID, true); err != nil {
+				gaia.Cfg.Logger.Error("failed to remove docker worker", "error", err.Error(), "worker", w)
+			}
+			break
+		}
+	}
+
+	_ = s.memDBService.DeleteDockerWorker(workerID)
+	if err := worker.KillDockerWorker(); err != nil {
+		gaia.Cfg.Logger.Error("failed to kill docker worker", "error", err.Error(), "workerid", workerID)
+	}
+}
+
+// detachDockerWorker removes the docker worker from the given pipeline run
+// so that the run is picked up by a new docker worker when it is scheduled again.
+// The docker worker is removed and killed if it is still known to this instance.
+func (s *Scheduler) detachDockerWorker(r *gaia.PipelineRun) {
+	if r.DockerWorkerID == "" {
+		return
+	}
+	if worker, err := s.memDBService.GetDockerWorker(r.DockerWorkerID); err == nil && worker != nil {
+		s.removeDockerWorker(r.DockerWorkerID)
+	}
+
+	tags := r.PipelineTags[:0]
+	for _, tag := range r.PipelineTags {
+		if tag != r.DockerWorkerID && tag != dockerWorkerTag {
+			tags = append(tags, tag)
+		}
+	}
+	r.PipelineTags = tags
+	r.DockerWorkerID = ""
+}
+
 // SchedulePipeline schedules a pipeline. We create a new schedule object
 // and save it in our store. The scheduler will later pick this up and will continue the work.
 func (s *Scheduler) SchedulePipeline(p *gaia.Pipeline, startedReason string, args []*gaia.Argument) (*gaia.PipelineRun, error) {
+	return s.schedulePipeline(p, startedReason, args, nil)
+}
 
+// schedulePipeline creates a new pipeline run for the given pipeline.
+// The optional prepare function is called with the new run before it is stored.
+func (s *Scheduler) schedulePipeline(p *gaia.Pipeline, startedReason string, args []*gaia.Argument, prepare func(run *gaia.PipelineRun) error) (*gaia.PipelineRun, error) {
 	// Introduce a semaphore locking here because this function can be called
 	// in parallel if multiple users happen to trigger a pipeline run at the same time.
 	// (or someone is just simply eager and presses (Start Pipeline) in quick successions).
@@ -393,6 +563,13 @@ func (s *Scheduler) SchedulePipeline(p *gaia.Pipeline, startedReason string, arg
 	s.schedulePipelineLock.Lock()
 	defer s.schedulePipelineLock.Unlock()
 
+	// The span of the schedule is the root of the run trace
+	ctx, span := tracing.Tracer().Start(context.Background(), "schedule pipeline run", trace.WithAttributes(
+		attribute.Int("pipeline.id", p.ID),
+		attribute.String("pipeline.name", p.Name),
+	))
+	defer span.End()
+
 	// Get highest public id used for this pipeline
 	highestID, err := s.storeService.PipelineGetRunHighestID(p)
 	if err != nil {
@@ -409,6 +586,26 @@ func (s *Scheduler) SchedulePipeline(p *gaia.Pipeline, startedReason string, arg
 		gaia.Cfg.Logger.Error("cannot get pipeline jobs during schedule", "error", err.Error(), "pipeline", p)
 		return nil, err
 	}
+	applyJobSettings(jobs, p.Jobs)
+
+	// Validate the given arguments and determine the effective parameters
+	params, err := runParameters(p, jobs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create new not scheduled pipeline run
+	v4, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+	uniqueID := uuid.Must(v4, nil).String()
+
+	// The values of secret parameters are kept in the vault instead of the run
+	if err := pipelinehelper.StoreSecretParameters(s.vault, uniqueID, params); err != nil {
+		gaia.Cfg.Logger.Error("cannot store secret parameters in vault during schedule pipeline", "error", err.Error())
+		return nil, err
+	}
 
 	// Load secret from vault and set it
 	err = s.vault.LoadSecrets()
@@ -418,6 +615,7 @@ func (s *Scheduler) SchedulePipeline(p *gaia.Pipeline, startedReason string, arg
 	}
 	// We have to go through all jobs to find the related arguments.
 	// We will only pass related arguments to the specific job.
+	// The declared parameters of the pipeline are passed to all jobs.
 	for jobID, job := range jobs {
 		if job.Args != nil {
 			for argID, arg := range job.Args {
@@ -432,23 +630,23 @@ func (s *Scheduler) SchedulePipeline(p *gaia.Pipeline, startedReason string, arg
 					jobs[jobID].Args[argID].Value = string(s)
 				} else {
 					// Find related argument in given arguments
-					for _, givenArg := range args {
+					for _, givenArg := range params {
 						if arg.Key == givenArg.Key {
-							jobs[jobID].Args[argID] = givenArg
+							jobs[jobID].Args[argID].Value = givenArg.Value
 						}
 					}
 				}
 			}
 		}
+		// The effective parameters start with the declared parameters
+		for _, param := range params[:len(p.Parameters)] {
+			a := *param
+			jobs[jobID].Args = setArgument(jobs[jobID].Args, &a)
+		}
 	}
 
-	// Create new not scheduled pipeline run
-	v4, err := uuid.NewV4()
-	if err != nil {
-		return nil, err
-	}
 	run := gaia.PipelineRun{
-		UniqueID:     uuid.Must(v4, nil).String(),
+		UniqueID:     uniqueID,
 		ID:           highestID,
 		PipelineID:   p.ID,
 		ScheduleDate: time.Now(),
@@ -458,24 +656,129 @@ func (s *Scheduler) SchedulePipeline(p *gaia.Pipeline, startedReason string, arg
 		PipelineTags: p.Tags,
 		Docker:       p.Docker,
 		StartReason:  startedReason,
+		JobTimeout:   timeoutSeconds(p.JobTimeout, gaia.Cfg.JobTimeout),
+		RunTimeout:   timeoutSeconds(p.RunTimeout, gaia.Cfg.RunTimeout),
+		Parameters:   params,
+		SHA256Sum:    p.SHA256Sum,
+		Priority:     p.Priority,
+	}
+	if p.Concurrency != nil {
+		concurrency := *p.Concurrency
+		run.Concurrency = &concurrency
+	}
+	if p.Recovery != nil {
+		recovery := *p.Recovery
+		run.Recovery = &recovery
+	}
+	if prepare != nil {
+		if err := prepare(&run); err != nil {
+			if errtwo := pipelinehelper.RemoveSecretParameters(s.vault, uniqueID); errtwo != nil {
+				gaia.Cfg.Logger.Error("cannot remove secret parameters from vault", "error", errtwo.Error())
+			}
+			return nil, err
+		}
 	}
+	tracing.SetRunTrace(ctx, &run)
 
 	// Put run into store
-	return &run, s.storeService.PipelinePutRun(&run)
+	if err := s.storeService.PipelinePutRun(&run); err != nil {
+		return &run, err
+	}
+	s.events.Publish(event.NewRunEvent(event.RunScheduled, &run))
+	return &run, nil
 }
 
 // executeJob executes a job and informs via triggerSave that the job can be saved to the store.
+// The job is executed by the pipeline process of the run. The output of the process is
+// written to the job log while the job is executed. The artifacts folder of the job is
+// passed as argument.
+// The job is aborted when the given context is done or the given timeout has been exceeded.
 // This method is blocking.
-func executeJob(j gaia.Job, pS plugin.Plugin, triggerSave chan gaia.Job) {
+func (s *Scheduler) executeJob(ctx context.Context, j gaia.Job, timeout time.Duration, rp *runPlugin, triggerSave chan gaia.Job) {
+	logs := rp.logs
+
 	// Set Job to running and trigger save
 	j.Status = gaia.JobRunning
 	triggerSave <- j
 
-	// Execute job
-	if err := pS.Execute(&j); err != nil {
-		gaia.Cfg.Logger.Debug("error during job execution", "error", err.Error(), "job", j)
+	ctx, span := tracing.Tracer().Start(ctx, "execute job", trace.WithAttributes(
+		attribute.String("job.title", j.Title),
+		attribute.Int("job.attempt", len(j.Attempts)+1),
+	))
+
+	// Apply the job timeout
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Record this execution as a new attempt of the job
+	attempt := &gaia.JobAttempt{
+		Attempt:   len(j.Attempts) + 1,
+		StartDate: time.Now(),
+	}
+
+	// Prepare an empty artifacts folder for this job and
+	// resolve the values of the secret parameters from the vault
+	artifactsPath := logs.jobArtifactsPath(j.ID)
+	err := prepareArtifacts(artifactsPath)
+	if err == nil {
+		err = pipelinehelper.ResolveSecretParameters(s.vault, logs.uniqueID, j.Args)
 	}
 
+	// Tell the job where to publish its artifacts
+	j.Args = setArgument(j.Args, &gaia.Argument{Key: gaia.ArtifactsDirArg, Value: artifactsPath})
+	var pS plugin.Plugin
+	if err == nil {
+		pS, err = rp.get(ctx)
+	}
+	if err == nil {
+		// Output written before belongs to the jobs which ran before
+		_ = pS.FlushLogs()
+		err = logs.openJobLog(j.ID)
+	}
+	if err != nil {
+		gaia.Cfg.Logger.Debug("cannot start plugin for job", "error", err.Error(), "job", j)
+		logs.writeJobError(&j, err)
+		j.Status = gaia.JobFailed
+		j.FailPipeline = true
+		attempt.Error = err.Error()
+		attempt.InfraError = true
+	} else {
+		// Execute job
+		if err := pS.Execute(ctx, &j); err != nil {
+			gaia.Cfg.Logger.Debug("error during job execution", "error", err.Error(), "job", j)
+			attempt.Error = err.Error()
+			attempt.InfraError = true
+
+			// The plugin broke on its own. Following jobs start a new one.
+			if ctx.Err() == nil {
+				rp.reset(pS)
+			}
+		}
+
+		// Write the buffered output into the job log before closing it
+		_ = pS.FlushLogs()
+		logs.closeJobLog(j.ID)
+	}
+	attempt.FinishDate = time.Now()
+	attempt.Status = j.Status
+	j.Attempts = append(j.Attempts, attempt)
+
+	// Record the artifacts published by this attempt and store them
+	j.Artifacts, err = collectArtifacts(j.ID, artifactsPath)
+	if err == nil {
+		err = storeArtifacts(logs, artifactsPath, j.Artifacts)
+	}
+	if err != nil {
+		gaia.Cfg.Logger.Error("cannot store job artifacts", "error", err.Error(), "job", j.Title)
+	}
+	if j.Status != gaia.JobSuccess {
+		span.SetStatus(codes.Error, "job "+string(j.Status))
+	}
+	span.End()
+
 	// Trigger another save to store the result of the execute
 	triggerSave <- j
 }
@@ -551,11 +854,17 @@ func (s *Scheduler) resolveDependencies(j *gaia.Job, mw *managedWorkloads, execu
 	// If we are here, then the job is resolved.
 	// We have to check if the job still needs to be run
 	// or if another goroutine has already started the execution.
+	// The execute scheduler decides based on the run condition
+	// of the job if the job is executed or skipped.
 	relatedWL := mw.GetByID(j.ID)
 	if !relatedWL.started {
 		// Job has not been executed yet.
 		// Send workload to execute scheduler.
-		executeScheduler <- j
+		select {
+		case executeScheduler <- j:
+		case <-done:
+			return
+		}
 
 		// Wait until finished
 		<-relatedWL.finishedSig
@@ -568,14 +877,20 @@ func (s *Scheduler) resolveDependencies(j *gaia.Job, mw *managedWorkloads, execu
 
 // executeScheduledJobs is a small wrapper around executeScheduler which
 // is responsible for finalizing the pipeline run.
-func (s *Scheduler) executeScheduledJobs(r gaia.PipelineRun, pS plugin.Plugin) {
+func (s *Scheduler) executeScheduledJobs(ctx, killed context.Context, r gaia.PipelineRun, rp *runPlugin) {
 	// Start the main execute process and wait until finished.
-	s.executeScheduler(&r, pS)
+	s.executeScheduler(ctx, killed, &r, rp)
+
+	// The run has been paused until the approval of a job has been decided
+	if r.Status == gaia.RunWaitingApproval {
+		return
+	}
 
 	// Run finished. Set pipeline status.
 	var runFail bool
+	// Skipped jobs and jobs which succeeded do not fail the run.
 	for _, job := range r.Jobs {
-		if job.Status != gaia.JobSuccess && job.FailPipeline {
+		if job.Status != gaia.JobSuccess && job.Status != gaia.JobSkipped && job.FailPipeline {
 			runFail = true
 		}
 	}
@@ -591,7 +906,20 @@ func (s *Scheduler) executeScheduledJobs(r gaia.PipelineRun, pS plugin.Plugin) {
 
 // executeScheduler is our main function which coordinates the
 // whole execution process and dependency resolve algorithm.
-func (s *Scheduler) executeScheduler(r *gaia.PipelineRun, pS plugin.Plugin) {
+// The given context carries the trace of the run. The killed
+// context is cancelled when the run has been cancelled.
+func (s *Scheduler) executeScheduler(ctx, killed context.Context, r *gaia.PipelineRun, rp *runPlugin) {
+	// Create the context for all jobs of this run. It is cancelled when the run
+	// deadline has been reached or the run has been finished or killed.
+	// This also kills all still running plugins.
+	var cancel context.CancelFunc
+	if r.RunTimeout > 0 {
+		ctx, cancel = context.WithDeadline(ctx, r.StartDate.Add(time.Duration(r.RunTimeout)*time.Second))
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
 	// Create a queue which is used to execute the resolved workloads.
 	executeScheduler := make(chan *gaia.Job)
 
@@ -602,14 +930,30 @@ func (s *Scheduler) executeScheduler(r *gaia.PipelineRun, pS plugin.Plugin) {
 
 	// Iterate all jobs from this run
 	mw := newManagedWorkloads()
+	var pending, failedBefore bool
 	for id := range r.Jobs {
+		// Jobs which have been taken over from a previous run or
+		// which have been finished before the run has been paused are already done
+		finished := jobFinished(r.Jobs[id])
+		pending = pending || !finished
+		failedBefore = failedBefore || r.Jobs[id].Status == gaia.JobFailed || r.Jobs[id].Status == gaia.JobTimedOut
+
 		// Create new workload object
 		mw.Append(workload{
 			job:         r.Jobs[id],
 			finishedSig: make(chan bool),
+			started:     finished,
+			done:        finished,
 		})
+	}
+
+	// Nothing to do
+	if !pending {
+		return
+	}
 
-		// Start resolving go routine for this job
+	// Start resolving go routine for every job
+	for id := range r.Jobs {
 		go s.resolveDependencies(r.Jobs[id], mw, executeScheduler, done)
 	}
 
@@ -622,7 +966,7 @@ func (s *Scheduler) executeScheduler(r *gaia.PipelineRun, pS plugin.Plugin) {
 		for {
 			select {
 			case <-ticker.C:
-				_ = pS.FlushLogs()
+				rp.flush()
 			case _, ok := <-pipelineFinished:
 				if !ok {
 					return
@@ -634,29 +978,128 @@ func (s *Scheduler) executeScheduler(r *gaia.PipelineRun, pS plugin.Plugin) {
 	// Separate channel to save updates about the status of job executions.
 	triggerSave := make(chan gaia.Job)
 
+	// executions tracks the started job executions and retries
+	// which report their status back via triggerSave.
+	var executions sync.WaitGroup
+	startExecution := func(execute func()) {
+		executions.Add(1)
+		go func() {
+			defer executions.Done()
+			execute()
+		}()
+	}
+
 	// Let's loop until we are done
-	var finalize bool
+	var finalize, runFailed bool
 	finished := make(chan bool, 1)
+
+	// runFailedSig is closed as soon as a job of this run has failed.
+	runFailedSig := make(chan bool)
+	if failedBefore {
+		runFailed = true
+		close(runFailedSig)
+	}
+
+	// Decisions about jobs waiting for approval are received via this queue.
+	decisions := s.registerApprovals(r)
+	defer s.unregisterApprovals(r, decisions)
+
+	// pauseRun pauses the run when all remaining jobs wait for an approval.
+	// The run is scheduled again once the approval has been decided.
+	pauseRun := func() {
+		if finalize || !mw.Blocked() || !s.pauseForApproval(r, decisions) {
+			return
+		}
+
+		// Resolvers of the parked jobs and their dependents exit via done.
+		close(done)
+		for wl := range mw.Iter() {
+			if wl.parked {
+				close(wl.finishedSig)
+			}
+		}
+		close(triggerSave)
+		finished <- true
+		finalize = true
+	}
+
+	// finishWorkload marks the workload of the given job as done and
+	// finishes the run when all workloads are done.
+	finishWorkload := func(id uint32) {
+		wl := mw.GetByID(id)
+		wl.done = true
+		mw.Replace(*wl)
+
+		// Let's check if we are done.
+		var allWLDone = true
+		for wl := range mw.Iter() {
+			if !wl.done {
+				allWLDone = false
+			}
+		}
+
+		if allWLDone && !finalize {
+			// Resolvers which still wait to send a workload exit via done.
+			close(done)
+			close(triggerSave)
+			finished <- true
+			finalize = true
+		} else {
+			pauseRun()
+		}
+
+		// Close go-routine which was waiting for this job.
+		close(wl.finishedSig)
+	}
+
+	// abortRun stops dispatching further jobs and finishes the run. Running jobs
+	// get the given status and jobs which have not been finished yet fail.
+	// The running plugins are killed and abortRun waits until all started
+	// executions have reported back.
+	abortRun := func(running gaia.JobStatus) {
+		finalize = true
+		for _, job := range r.Jobs {
+			switch job.Status {
+			case gaia.JobRunning:
+				job.Status = running
+				job.FailPipeline = true
+			case gaia.JobWaitingExec, gaia.JobWaitingApproval:
+				job.Status = gaia.JobFailed
+				job.FailPipeline = true
+			}
+		}
+		_ = s.storeService.PipelinePutRun(r)
+
+		// Resolvers which wait to send a workload or wait for a workload exit via done.
+		close(done)
+		for wl := range mw.Iter() {
+			if !wl.done {
+				close(wl.finishedSig)
+			}
+		}
+
+		cancel()
+		awaitExecutions(&executions, triggerSave)
+		close(pipelineFinished)
+	}
 	for {
 		select {
-		case pr, ok := <-s.killedPipelineRun:
-			if ok {
-				if pr.ID == r.ID {
-					for _, job := range r.Jobs {
-						if job.Status == gaia.JobRunning || job.Status == gaia.JobWaitingExec {
-							job.Status = gaia.JobFailed
-							job.FailPipeline = true
-						}
-					}
-					r.Status = gaia.RunCancelled
-					_ = s.storeService.PipelinePutRun(r)
-					close(done)
-					close(executeScheduler)
-					finished <- true
-					finalize = true
-					return
-				}
+		case <-killed.Done():
+			// The run has been finished already
+			if finalize {
+				break
+			}
+			r.Status = gaia.RunCancelled
+			abortRun(gaia.JobFailed)
+			return
+		case <-ctx.Done():
+			// The run has been finished already
+			if finalize {
+				break
 			}
+			gaia.Cfg.Logger.Info("pipeline run exceeded its timeout and will be aborted", "pipelineid", r.PipelineID, "runid", r.ID)
+			abortRun(gaia.JobTimedOut)
+			return
 		case <-finished:
 			close(pipelineFinished)
 			return
@@ -665,90 +1108,97 @@ func (s *Scheduler) executeScheduler(r *gaia.PipelineRun, pS plugin.Plugin) {
 				break
 			}
 
+			// Retry the job if it failed and the retry policy allows it.
+			// The job waits for the next attempt, therefore the workload is not done yet.
+			retry := !finalize && shouldRetry(ctx, &j) && runConditionMet(&j, runFailed)
+			if retry {
+				j.Status = gaia.JobWaitingExec
+				j.FailPipeline = false
+			}
+
 			// Filter out the job and update the real job
 			for id, job := range r.Jobs {
 				if job.ID == j.ID {
 					r.Jobs[id].Status = j.Status
 					r.Jobs[id].FailPipeline = j.FailPipeline
+					r.Jobs[id].Attempts = j.Attempts
+					r.Jobs[id].Artifacts = j.Artifacts
+					r.Jobs[id].Outputs = j.Outputs
 					break
 				}
 			}
 
 			// Store status update
 			_ = s.storeService.PipelinePutRun(r)
+			switch j.Status {
+			case gaia.JobRunning:
+				s.events.Publish(event.NewJobEvent(event.JobStarted, r, &j))
+			case gaia.JobSuccess, gaia.JobFailed, gaia.JobTimedOut:
+				s.events.Publish(event.NewJobEvent(event.JobFinished, r, &j))
+			}
 
-			// Send signal to resolver that this job is finished.
-			if j.Status == gaia.JobSuccess || j.Status == gaia.JobFailed {
-				// Job is done
-				wl := mw.GetByID(j.ID)
-				wl.done = true
-				mw.Replace(*wl)
-
-				// Let's check if we are done and if all jobs ran successful.
-				var allWLDone = true
-				for wl := range mw.Iter() {
-					if !wl.done {
-						allWLDone = false
-					}
-				}
-
-				if allWLDone && !finalize {
-					close(done)
-					close(executeScheduler)
-					close(triggerSave)
-					finished <- true
-					finalize = true
+			if retry {
+				// Jobs which only run on success stop retrying when the run fails in the meantime.
+				var abort chan bool
+				if !runConditionMet(&j, true) {
+					abort = runFailedSig
 				}
 
-				// Close go-routine which was waiting for this job.
-				close(wl.finishedSig)
+				backoff := retryBackoff(&j)
+				gaia.Cfg.Logger.Info("job failed and will be retried", "job", j.Title, "attempt", len(j.Attempts)+1, "backoff", backoff)
+				startExecution(func() {
+					s.retryJob(ctx, j, backoff, time.Duration(r.JobTimeout)*time.Second, rp, triggerSave, abort)
+				})
+				break
 			}
 
-			// Dependent of the status output, decide what should happen next.
-			if !finalize && j.Status == gaia.JobFailed {
-				// we are entering the finalize phase
-				finalize = true
+			// A failed job fails the whole run. Jobs which have not been started
+			// yet will only be executed if their run condition allows it.
+			if !runFailed && (j.Status == gaia.JobFailed || j.Status == gaia.JobTimedOut) {
+				runFailed = true
+				close(runFailedSig)
+			}
 
-				// Send done signal to all resolvers
-				close(done)
+			// Send signal to resolver that this job is finished.
+			if j.Status == gaia.JobSuccess || j.Status == gaia.JobFailed || j.Status == gaia.JobTimedOut {
+				finishWorkload(j.ID)
+			}
+		case d := <-decisions:
+			// Ignore decisions about jobs which do not wait for approval anymore
+			wl := mw.GetByID(d.jobID)
+			if finalize || wl == nil || !wl.parked {
+				break
+			}
+			wl.parked = false
+			mw.Replace(*wl)
 
-				// read all jobs which are waiting to be executed to free the channel
-				var channelClean = false
-				for !channelClean {
-					select {
-					case <-executeScheduler:
-						// just read from the channel
-					default:
-						channelClean = true
-					}
+			var job *gaia.Job
+			for id := range r.Jobs {
+				if r.Jobs[id].ID == d.jobID {
+					job = r.Jobs[id]
+					break
 				}
+			}
+			applyApproval(job, d)
+			_ = s.storeService.PipelinePutRun(r)
 
-				// Close executeScheduler. No new jobs should be scheduled.
-				close(executeScheduler)
-
-				// A job failed. Finish all currently running jobs.
-				go func() {
-					// We might have still running jobs. Wait until all jobs are finished.
-					for {
-						var notFinishedWorkloadChan chan bool
-						for singleWL := range mw.Iter() {
-							if singleWL.started && !singleWL.done {
-								notFinishedWorkloadChan = singleWL.finishedSig
-							}
-						}
-
-						if notFinishedWorkloadChan == nil {
-							break
-						}
-
-						// wait until finished
-						<-notFinishedWorkloadChan
-					}
+			// Start execution of the approved job with the outputs of the upstream jobs
+			if d.approved {
+				approved := *job
+				approved.Args = jobArgsWithOutputs(r, job)
+				startExecution(func() {
+					s.executeJob(ctx, approved, time.Duration(r.JobTimeout)*time.Second, rp, triggerSave)
+				})
+				break
+			}
 
-					finished <- true
-					close(triggerSave)
-				}()
+			// A rejected job fails the run
+			s.events.Publish(event.NewJobEvent(event.JobFinished, r, job))
+			if !runFailed {
+				runFailed = true
+				close(runFailedSig)
 			}
+			finishWorkload(d.jobID)
 		case j, ok := <-executeScheduler:
 			if !ok {
 				break
@@ -763,13 +1213,67 @@ func (s *Scheduler) executeScheduler(r *gaia.PipelineRun, pS plugin.Plugin) {
 				wl.started = true
 				mw.Replace(*wl)
 
-				// Start execution
-				go executeJob(*j, pS, triggerSave)
+				// Skip the job if its run condition is not met.
+				if !runConditionMet(j, runFailed) {
+					for id, job := range r.Jobs {
+						if job.ID == j.ID {
+							r.Jobs[id].Status = gaia.JobSkipped
+							break
+						}
+					}
+					_ = s.storeService.PipelinePutRun(r)
+					skipped := *j
+					skipped.Status = gaia.JobSkipped
+					s.events.Publish(event.NewJobEvent(event.JobFinished, r, &skipped))
+					finishWorkload(j.ID)
+					break
+				}
+
+				// Pause the job until it has been approved
+				var runJob *gaia.Job
+				for id := range r.Jobs {
+					if r.Jobs[id].ID == j.ID {
+						runJob = r.Jobs[id]
+						break
+					}
+				}
+				if runJob != nil && needsApproval(runJob) {
+					requestApproval(runJob)
+					_ = s.storeService.PipelinePutRun(r)
+					wl.parked = true
+					mw.Replace(*wl)
+					pauseRun()
+					break
+				}
+
+				// Start execution with the outputs of the upstream jobs
+				job := *j
+				job.Args = jobArgsWithOutputs(r, j)
+				startExecution(func() {
+					s.executeJob(ctx, job, time.Duration(r.JobTimeout)*time.Second, rp, triggerSave)
+				})
 			}
 		}
 	}
 }
 
+// awaitExecutions waits until the given executions have been finished. The status
+// updates which are still reported by the executions are discarded.
+func awaitExecutions(executions *sync.WaitGroup, triggerSave chan gaia.Job) {
+	finished := make(chan struct{})
+	go func() {
+		executions.Wait()
+		close(finished)
+	}()
+	for {
+		select {
+		case <-triggerSave:
+		case <-finished:
+			return
+		}
+	}
+}
+
 // getPipelineJobs uses the plugin system to get all jobs from the given pipeline.
 func (s *Scheduler) getPipelineJobs(p *gaia.Pipeline) ([]*gaia.Job, error) {
 	// Create the start command for the pipeline
@@ -783,7 +1287,7 @@ func (s *Scheduler) getPipelineJobs(p *gaia.Pipeline) ([]*gaia.Job, error) {
 	pS := s.pluginSystem.NewPlugin(s.ca)
 
 	// Init the go-plugin
-	if err := pS.Init(c, nil); err != nil {
+	if err := pS.Init(c, nil, nil); err != nil {
 		gaia.Cfg.Logger.Debug("cannot initialize the pipeline", "error", err.Error(), "pipeline", p)
 		return nil, err
 	}
@@ -806,6 +1310,9 @@ func (s *Scheduler) SetPipelineJobs(p *gaia.Pipeline) error {
 	if err != nil {
 		return err
 	}
+
+	// Keep the settings of already known jobs
+	applyJobSettings(jobs, p.Jobs)
 	p.Jobs = jobs
 
 	return nil
@@ -818,7 +1325,16 @@ func (s *Scheduler) GetFreeWorkers() int32 {
 
 // CountScheduledRuns returns the number of scheduled runs.
 func (s *Scheduler) CountScheduledRuns() int {
-	return len(s.scheduledRuns)
+	return s.scheduledRuns.len()
+}
+
+// timeoutSeconds returns the given pipeline timeout in seconds or the
+// given default timeout if the pipeline does not define a timeout.
+func timeoutSeconds(pipelineTimeout int, defaultTimeout time.Duration) int {
+	if pipelineTimeout > 0 {
+		return pipelineTimeout
+	}
+	return int(defaultTimeout / time.Second)
 }
 
 // finishPipelineRun finishes the pipeline run and stores the results.
@@ -834,4 +1350,39 @@ func (s *Scheduler) finishPipelineRun(r *gaia.PipelineRun, status gaia.PipelineR
 	if err != nil {
 		gaia.Cfg.Logger.Error("cannot store finished pipeline", "error", err.Error())
 	}
+
+	s.events.Publish(event.NewRunEvent(event.RunFinished, r))
+}
+
+// jobFinished returns true if the given job has been finished.
+func jobFinished(j *gaia.Job) bool {
+	switch j.Status {
+	case gaia.JobSuccess, gaia.JobFailed, gaia.JobTimedOut, gaia.JobSkipped:
+		return true
+	}
+	return false
+}
+
+// applyJobSettings copies the retry policies, run conditions and approval
+// policies of the given pipeline jobs to the jobs with the same id.
+func applyJobSettings(jobs []*gaia.Job, pipelineJobs []*gaia.Job) {
+	pipelineJobsMap := make(map[uint32]*gaia.Job, len(pipelineJobs))
+	for _, job := range pipelineJobs {
+		pipelineJobsMap[job.ID] = job
+	}
+	for _, job := range jobs {
+		pipelineJob, ok := pipelineJobsMap[job.ID]
+		if !ok {
+			continue
+		}
+		if pipelineJob.Retry != nil {
+			retry := *pipelineJob.Retry
+			job.Retry = &retry
+		}
+		job.RunCondition = pipelineJob.RunCondition
+		if pipelineJob.Approval != nil {
+			approval := *pipelineJob.Approval
+			job.Approval = &approval
+		}
+	}
 }
@@ -1,12 +1,16 @@
 package gaiascheduler
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"hash/fnv"
+	"io"
 	"io/ioutil"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -14,19 +18,28 @@ import (
 	"github.com/gaia-pipeline/gaia/workers/docker"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/event"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
 	"github.com/gaia-pipeline/gaia/plugin"
 	"github.com/gaia-pipeline/gaia/security"
 	"github.com/gaia-pipeline/gaia/store"
+	"github.com/gaia-pipeline/gaia/tracing"
 	"github.com/gofrs/uuid"
 	"github.com/hashicorp/go-hclog"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type PluginFake struct{}
 
 func (p *PluginFake) NewPlugin(ca security.CAAPI) plugin.Plugin { return &PluginFake{} }
-func (p *PluginFake) Init(cmd *exec.Cmd, logPath *string) error { return nil }
-func (p *PluginFake) Validate() error                           { return nil }
-func (p *PluginFake) Execute(j *gaia.Job) error {
+func (p *PluginFake) Init(cmd *exec.Cmd, log io.WriteCloser, combinedLog io.Writer) error {
+	return nil
+}
+func (p *PluginFake) Validate() error { return nil }
+func (p *PluginFake) Execute(ctx context.Context, j *gaia.Job) error {
 	j.Status = gaia.JobSuccess
 	return nil
 }
@@ -53,6 +66,42 @@ func (v *VaultFake) Add(key string, value []byte)   {}
 func (v *VaultFake) Remove(key string)              {}
 func (v *VaultFake) Get(key string) ([]byte, error) { return []byte{}, nil }
 
+type VaultFakeMemory struct {
+	sync.Mutex
+	data map[string][]byte
+}
+
+func (v *VaultFakeMemory) LoadSecrets() error { return nil }
+func (v *VaultFakeMemory) GetAll() []string {
+	v.Lock()
+	defer v.Unlock()
+	keys := make([]string, 0, len(v.data))
+	for k := range v.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+func (v *VaultFakeMemory) SaveSecrets() error { return nil }
+func (v *VaultFakeMemory) Add(key string, value []byte) {
+	v.Lock()
+	defer v.Unlock()
+	v.data[key] = value
+}
+func (v *VaultFakeMemory) Remove(key string) {
+	v.Lock()
+	defer v.Unlock()
+	delete(v.data, key)
+}
+func (v *VaultFakeMemory) Get(key string) ([]byte, error) {
+	v.Lock()
+	defer v.Unlock()
+	value, ok := v.data[key]
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	return value, nil
+}
+
 type MemDBFake struct{}
 
 func (m *MemDBFake) SyncStore() error                                { return nil }
@@ -65,6 +114,9 @@ func (m *MemDBFake) PopPipelineRun(tags []string) (*gaia.PipelineRun, error) {
 	return &gaia.PipelineRun{}, nil
 }
 func (m *MemDBFake) DeletePipelineRun(runID string) error { return nil }
+func (m *MemDBFake) CountPipelineRunsByTag() map[string]int {
+	return map[string]int{}
+}
 func (m *MemDBFake) UpsertSHAPair(pair gaia.SHAPair) error {
 	return nil
 }
@@ -96,7 +148,7 @@ func TestInit(t *testing.T) {
 	if err := storeInstance.Init(tmp); err != nil {
 		t.Fatal(err)
 	}
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -106,9 +158,11 @@ func TestInit(t *testing.T) {
 type PluginFakeFailed struct{}
 
 func (p *PluginFakeFailed) NewPlugin(ca security.CAAPI) plugin.Plugin { return &PluginFakeFailed{} }
-func (p *PluginFakeFailed) Init(cmd *exec.Cmd, logPath *string) error { return nil }
-func (p *PluginFakeFailed) Validate() error                           { return nil }
-func (p *PluginFakeFailed) Execute(j *gaia.Job) error {
+func (p *PluginFakeFailed) Init(cmd *exec.Cmd, log io.WriteCloser, combinedLog io.Writer) error {
+	return nil
+}
+func (p *PluginFakeFailed) Validate() error { return nil }
+func (p *PluginFakeFailed) Execute(ctx context.Context, j *gaia.Job) error {
 	j.Status = gaia.JobFailed
 	j.FailPipeline = true
 	return errors.New("job failed")
@@ -135,7 +189,7 @@ func TestPrepareAndExecFail(t *testing.T) {
 	}
 	p, r := prepareTestData()
 	_ = storeInstance.PipelinePut(&p)
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -158,6 +212,607 @@ func TestPrepareAndExecFail(t *testing.T) {
 	}
 }
 
+func TestPrepareAndExecEvents(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPrepareAndExecEvents")
+	gaia.Cfg = &gaia.Config{
+		Logger:        hclog.NewNullLogger(),
+		DataPath:      tmp,
+		WorkspacePath: filepath.Join(tmp, "tmp"),
+	}
+	gaia.Cfg.Bolt.Mode = 0600
+	storeInstance := store.NewBoltStore()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, r := prepareTestData()
+	_ = storeInstance.PipelinePut(&p)
+
+	bus := event.NewBus()
+	sub := bus.Subscribe()
+	defer sub.Close()
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, bus, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.prepareAndExec(r)
+
+	counts := make(map[event.Type]int)
+	for len(sub.Events()) > 0 {
+		e := <-sub.Events()
+		if e.PipelineID != p.ID || e.RunUniqueID != r.UniqueID {
+			t.Fatalf("event %s belongs to the wrong run: %+v", e.Type, e)
+		}
+		counts[e.Type]++
+	}
+	expected := map[event.Type]int{
+		event.RunStarted:  1,
+		event.JobStarted:  len(p.Jobs),
+		event.JobFinished: len(p.Jobs),
+		event.RunFinished: 1,
+	}
+	for typ, count := range expected {
+		if counts[typ] != count {
+			t.Fatalf("expected %d events of type %s but got %d", count, typ, counts[typ])
+		}
+	}
+}
+
+func TestPrepareAndExecTracing(t *testing.T) {
+	tmp, _ := ioutil.TempDir("", "TestPrepareAndExecTracing")
+	gaia.Cfg = &gaia.Config{
+		Logger:        hclog.NewNullLogger(),
+		DataPath:      tmp,
+		WorkspacePath: filepath.Join(tmp, "tmp"),
+	}
+	gaia.Cfg.Bolt.Mode = 0600
+	storeInstance := store.NewBoltStore()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, r := prepareTestData()
+	_ = storeInstance.PipelinePut(&p)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	ctx, root := tracing.Tracer().Start(context.Background(), "root")
+	tracing.SetRunTrace(ctx, &r)
+	root.End()
+
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.prepareAndExec(r)
+
+	counts := make(map[string]int)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID().String() != r.TraceID {
+			t.Fatalf("span %s is not part of the run trace", span.Name())
+		}
+		counts[span.Name()]++
+	}
+	expected := map[string]int{
+		"root":                 1,
+		"wait for execution":   1,
+		"execute pipeline run": 1,
+		"execute job":          len(p.Jobs),
+		"start plugin":         1,
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("expected spans %v but got %v", expected, counts)
+	}
+
+	// The run trace is kept when the run is stored
+	run, err := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.TraceID != r.TraceID {
+		t.Fatalf("expected stored run trace %s but got %s", r.TraceID, run.TraceID)
+	}
+}
+
+// PluginFakeLogs writes the job log name into the job log and the combined log.
+type PluginFakeLogs struct {
+	log         io.WriteCloser
+	combinedLog io.Writer
+}
+
+func (p *PluginFakeLogs) NewPlugin(ca security.CAAPI) plugin.Plugin { return &PluginFakeLogs{} }
+func (p *PluginFakeLogs) Init(cmd *exec.Cmd, log io.WriteCloser, combinedLog io.Writer) error {
+	p.log = log
+	p.combinedLog = combinedLog
+	return nil
+}
+func (p *PluginFakeLogs) Validate() error { return nil }
+func (p *PluginFakeLogs) Execute(ctx context.Context, j *gaia.Job) error {
+	line := gaia.JobLogsFileName(j.ID) + "\n"
+	if _, err := p.combinedLog.Write([]byte(line)); err != nil {
+		return err
+	}
+	if _, err := p.log.Write([]byte(line)); err != nil {
+		return err
+	}
+	j.Status = gaia.JobSuccess
+	return nil
+}
+func (p *PluginFakeLogs) GetJobs() ([]*gaia.Job, error) { return prepareJobs(), nil }
+func (p *PluginFakeLogs) FlushLogs() error              { return nil }
+func (p *PluginFakeLogs) Close()                        { _ = p.log.Close() }
+
+func TestPrepareAndExecJobLogs(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestPrepareAndExecJobLogs")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.New(&hclog.LoggerOptions{
+		Level:  hclog.Trace,
+		Output: hclog.DefaultOutput,
+		Name:   "Gaia",
+	})
+
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, r := prepareTestData()
+	_ = storeInstance.PipelinePut(&p)
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeLogs{}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.prepareAndExec(r)
+
+	// Every job should have its own log file
+	logsFolder := filepath.Join(gaia.Cfg.WorkspacePath, "1", "1", gaia.LogsFolderName)
+	combined, err := ioutil.ReadFile(filepath.Join(logsFolder, gaia.LogsFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range r.Jobs {
+		jobLogName := gaia.JobLogsFileName(job.ID)
+		content, err := ioutil.ReadFile(filepath.Join(logsFolder, jobLogName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != jobLogName+"\n" {
+			t.Fatalf("expected job log '%s' but got '%s'", jobLogName, string(content))
+		}
+		if !strings.Contains(string(combined), jobLogName+"\n") {
+			t.Fatalf("expected combined log to contain '%s' but got '%s'", jobLogName, string(combined))
+		}
+	}
+}
+
+// PluginFakeTimeout blocks the execution of the given job until the context is done.
+type PluginFakeTimeout struct {
+	hangingJob string
+}
+
+func (p *PluginFakeTimeout) NewPlugin(ca security.CAAPI) plugin.Plugin {
+	return &PluginFakeTimeout{hangingJob: p.hangingJob}
+}
+func (p *PluginFakeTimeout) Init(cmd *exec.Cmd, log io.WriteCloser, combinedLog io.Writer) error {
+	return nil
+}
+func (p *PluginFakeTimeout) Validate() error { return nil }
+func (p *PluginFakeTimeout) Execute(ctx context.Context, j *gaia.Job) error {
+	if j.Title == p.hangingJob {
+		<-ctx.Done()
+		j.Status = gaia.JobTimedOut
+		j.FailPipeline = true
+		return nil
+	}
+	j.Status = gaia.JobSuccess
+	return nil
+}
+func (p *PluginFakeTimeout) GetJobs() ([]*gaia.Job, error) { return prepareJobs(), nil }
+func (p *PluginFakeTimeout) FlushLogs() error              { return nil }
+func (p *PluginFakeTimeout) Close()                        {}
+
+func TestPrepareAndExecTimeout(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestPrepareAndExecTimeout")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.New(&hclog.LoggerOptions{
+		Level:  hclog.Trace,
+		Output: hclog.DefaultOutput,
+		Name:   "Gaia",
+	})
+
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		hangingJob   string
+		jobTimeout   int
+		runTimeout   int
+		expectedJobs map[string]gaia.JobStatus
+	}{
+		{
+			name:         "job timeout",
+			hangingJob:   "Job2",
+			jobTimeout:   1,
+			expectedJobs: map[string]gaia.JobStatus{"Job1": gaia.JobSuccess, "Job2": gaia.JobTimedOut, "Job3": gaia.JobSkipped, "Job4": gaia.JobSkipped},
+		},
+		{
+			name:         "run timeout",
+			hangingJob:   "Job1",
+			runTimeout:   1,
+			expectedJobs: map[string]gaia.JobStatus{"Job1": gaia.JobTimedOut, "Job2": gaia.JobFailed, "Job3": gaia.JobFailed, "Job4": gaia.JobFailed},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, r := prepareTestData()
+			r.ID = i + 1
+			r.JobTimeout = tt.jobTimeout
+			r.RunTimeout = tt.runTimeout
+			_ = storeInstance.PipelinePut(&p)
+			s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeTimeout{hangingJob: tt.hangingJob}, &CAFake{}, &VaultFake{}, nil, nil})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.prepareAndExec(r)
+
+			// get pipeline run from store
+			run, err := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.Status != gaia.RunFailed {
+				t.Fatalf("run status should be %s but was %s", gaia.RunFailed, run.Status)
+			}
+			for _, job := range run.Jobs {
+				if job.Status != tt.expectedJobs[job.Title] {
+					t.Fatalf("status of %s should be %s but was %s", job.Title, tt.expectedJobs[job.Title], job.Status)
+				}
+			}
+		})
+	}
+}
+
+type PluginFakeFlaky struct {
+	sync.Mutex
+	failingJob string
+	failures   int
+	infraError bool
+	executions int
+	inits      int
+}
+
+func (p *PluginFakeFlaky) NewPlugin(ca security.CAAPI) plugin.Plugin { return p }
+func (p *PluginFakeFlaky) Init(cmd *exec.Cmd, log io.WriteCloser, combinedLog io.Writer) error {
+	p.Lock()
+	defer p.Unlock()
+	p.inits++
+	return nil
+}
+func (p *PluginFakeFlaky) Validate() error { return nil }
+func (p *PluginFakeFlaky) Execute(ctx context.Context, j *gaia.Job) error {
+	p.Lock()
+	defer p.Unlock()
+	if j.Title != p.failingJob {
+		j.Status = gaia.JobSuccess
+		return nil
+	}
+	p.executions++
+	if p.executions > p.failures {
+		j.Status = gaia.JobSuccess
+		return nil
+	}
+	j.Status = gaia.JobFailed
+	if p.infraError {
+		return errors.New("connection broken")
+	}
+	j.FailPipeline = true
+	return nil
+}
+func (p *PluginFakeFlaky) GetJobs() ([]*gaia.Job, error) { return prepareJobs(), nil }
+func (p *PluginFakeFlaky) FlushLogs() error              { return nil }
+func (p *PluginFakeFlaky) Close()                        {}
+
+func TestPrepareAndExecRetry(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestPrepareAndExecRetry")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		retry            *gaia.RetryPolicy
+		failures         int
+		infraError       bool
+		expectedStatus   gaia.PipelineRunStatus
+		expectedAttempts int
+		expectedInits    int
+	}{
+		{
+			name:             "infrastructure error retried",
+			retry:            &gaia.RetryPolicy{MaxAttempts: 2, InfraErrorsOnly: true},
+			failures:         1,
+			infraError:       true,
+			expectedStatus:   gaia.RunSuccess,
+			expectedAttempts: 2,
+			expectedInits:    2,
+		},
+		{
+			name:             "job error not retried",
+			retry:            &gaia.RetryPolicy{MaxAttempts: 2, InfraErrorsOnly: true},
+			failures:         1,
+			expectedStatus:   gaia.RunFailed,
+			expectedAttempts: 1,
+			expectedInits:    1,
+		},
+		{
+			name:             "job error retried",
+			retry:            &gaia.RetryPolicy{MaxAttempts: 3},
+			failures:         2,
+			expectedStatus:   gaia.RunSuccess,
+			expectedAttempts: 3,
+			expectedInits:    1,
+		},
+		{
+			name:             "max attempts reached",
+			retry:            &gaia.RetryPolicy{MaxAttempts: 2},
+			failures:         5,
+			expectedStatus:   gaia.RunFailed,
+			expectedAttempts: 2,
+			expectedInits:    1,
+		},
+		{
+			name:             "no retry policy",
+			failures:         1,
+			expectedStatus:   gaia.RunFailed,
+			expectedAttempts: 1,
+			expectedInits:    1,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, r := prepareTestData()
+			r.ID = i + 1
+			for _, job := range r.Jobs {
+				if job.Title == "Job2" {
+					job.Retry = tt.retry
+				}
+			}
+			_ = storeInstance.PipelinePut(&p)
+			pS := &PluginFakeFlaky{failingJob: "Job2", failures: tt.failures, infraError: tt.infraError}
+			s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, pS, &CAFake{}, &VaultFake{}, nil, nil})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.prepareAndExec(r)
+
+			// get pipeline run from store
+			run, err := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.Status != tt.expectedStatus {
+				t.Fatalf("run status should be %s but was %s", tt.expectedStatus, run.Status)
+			}
+
+			// All jobs share one plugin which is only restarted after an infrastructure error
+			if pS.inits != tt.expectedInits {
+				t.Fatalf("expected %d plugin starts but got %d", tt.expectedInits, pS.inits)
+			}
+			for _, job := range run.Jobs {
+				if job.Title != "Job2" {
+					continue
+				}
+				if len(job.Attempts) != tt.expectedAttempts {
+					t.Fatalf("expected %d attempts but got %d", tt.expectedAttempts, len(job.Attempts))
+				}
+				first := job.Attempts[0]
+				if first.Attempt != 1 || first.Status != gaia.JobFailed || first.InfraError != tt.infraError {
+					t.Fatalf("unexpected first attempt %#v", first)
+				}
+			}
+		})
+	}
+}
+
+type PluginFakeOutputs struct {
+	sync.Mutex
+	args map[string][]*gaia.Argument
+}
+
+func (p *PluginFakeOutputs) NewPlugin(ca security.CAAPI) plugin.Plugin { return p }
+func (p *PluginFakeOutputs) Init(cmd *exec.Cmd, log io.WriteCloser, combinedLog io.Writer) error {
+	return nil
+}
+func (p *PluginFakeOutputs) Validate() error { return nil }
+func (p *PluginFakeOutputs) Execute(ctx context.Context, j *gaia.Job) error {
+	p.Lock()
+	defer p.Unlock()
+	p.args[j.Title] = j.Args
+	j.Outputs = []*gaia.Argument{{Key: "producer", Value: j.Title}}
+	j.Status = gaia.JobSuccess
+	return nil
+}
+func (p *PluginFakeOutputs) GetJobs() ([]*gaia.Job, error) { return prepareJobs(), nil }
+func (p *PluginFakeOutputs) FlushLogs() error              { return nil }
+func (p *PluginFakeOutputs) Close()                        {}
+
+func TestPrepareAndExecOutputs(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestPrepareAndExecOutputs")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, r := prepareTestData()
+	_ = storeInstance.PipelinePut(&p)
+	pS := &PluginFakeOutputs{args: make(map[string][]*gaia.Argument)}
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, pS, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.prepareAndExec(r)
+
+	// Every job receives the output of the job it depends on
+	for consumer, producer := range map[string]string{"Job2": "Job1", "Job3": "Job2", "Job4": "Job3"} {
+		var found bool
+		for _, arg := range pS.args[consumer] {
+			if arg.Key == "producer" {
+				found = true
+				if arg.Value != producer || arg.Type != argTypeOutput {
+					t.Fatalf("expected %s to receive output of %s but got %#v", consumer, producer, arg)
+				}
+			}
+		}
+		if !found {
+			t.Fatalf("expected %s to receive output of %s", consumer, producer)
+		}
+	}
+	for _, arg := range pS.args["Job1"] {
+		if arg.Key == "producer" {
+			t.Fatal("expected Job1 to receive no outputs")
+		}
+	}
+
+	// Outputs are persisted on the run
+	run, err := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, job := range run.Jobs {
+		if len(job.Outputs) != 1 || job.Outputs[0].Value != job.Title {
+			t.Fatalf("expected output of %s to be persisted but got %v", job.Title, job.Outputs)
+		}
+	}
+
+	// Every job receives its artifacts folder
+	for _, job := range run.Jobs {
+		var artifactsDir string
+		for _, arg := range pS.args[job.Title] {
+			if arg.Key == gaia.ArtifactsDirArg {
+				artifactsDir = arg.Value
+			}
+		}
+		if expected := gaia.JobArtifactsPath(r.PipelineID, r.ID, job.ID); artifactsDir != expected {
+			t.Fatalf("expected %s to receive artifacts folder %s but got %q", job.Title, expected, artifactsDir)
+		}
+		for _, arg := range job.Args {
+			if arg.Key == gaia.ArtifactsDirArg {
+				t.Fatalf("expected artifacts folder not to be stored with the arguments of %s", job.Title)
+			}
+		}
+	}
+}
+
+func TestPrepareAndExecRunConditions(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestPrepareAndExecRunConditions")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		job2Fails      bool
+		conditions     map[string]gaia.JobRunCondition
+		expectedStatus gaia.PipelineRunStatus
+		expectedJobs   map[string]gaia.JobStatus
+	}{
+		{
+			name:           "always job runs after failure",
+			job2Fails:      true,
+			conditions:     map[string]gaia.JobRunCondition{"Job4": gaia.RunConditionAlways},
+			expectedStatus: gaia.RunFailed,
+			expectedJobs: map[string]gaia.JobStatus{
+				"Job1": gaia.JobSuccess,
+				"Job2": gaia.JobFailed,
+				"Job3": gaia.JobSkipped,
+				"Job4": gaia.JobSuccess,
+			},
+		},
+		{
+			name:           "failure job runs after failure",
+			job2Fails:      true,
+			conditions:     map[string]gaia.JobRunCondition{"Job3": gaia.RunConditionOnFailure},
+			expectedStatus: gaia.RunFailed,
+			expectedJobs: map[string]gaia.JobStatus{
+				"Job1": gaia.JobSuccess,
+				"Job2": gaia.JobFailed,
+				"Job3": gaia.JobSuccess,
+				"Job4": gaia.JobSkipped,
+			},
+		},
+		{
+			name:           "failure job skipped on success",
+			conditions:     map[string]gaia.JobRunCondition{"Job3": gaia.RunConditionOnFailure},
+			expectedStatus: gaia.RunSuccess,
+			expectedJobs: map[string]gaia.JobStatus{
+				"Job1": gaia.JobSuccess,
+				"Job2": gaia.JobSuccess,
+				"Job3": gaia.JobSkipped,
+				"Job4": gaia.JobSuccess,
+			},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, r := prepareTestData()
+			r.ID = i + 1
+			for _, job := range r.Jobs {
+				job.RunCondition = tt.conditions[job.Title]
+			}
+			_ = storeInstance.PipelinePut(&p)
+			pS := &PluginFakeFlaky{failingJob: "Job2"}
+			if tt.job2Fails {
+				pS.failures = 1
+			}
+			s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, pS, &CAFake{}, &VaultFake{}, nil, nil})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.prepareAndExec(r)
+
+			// get pipeline run from store
+			run, err := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.Status != tt.expectedStatus {
+				t.Fatalf("run status should be %s but was %s", tt.expectedStatus, run.Status)
+			}
+			for _, job := range run.Jobs {
+				if job.Status != tt.expectedJobs[job.Title] {
+					t.Fatalf("status of %s should be %s but was %s", job.Title, tt.expectedJobs[job.Title], job.Status)
+				}
+			}
+		})
+	}
+}
+
 func TestPrepareAndExecInvalidType(t *testing.T) {
 	gaia.Cfg = &gaia.Config{}
 	storeInstance := store.NewBoltStore()
@@ -177,7 +832,7 @@ func TestPrepareAndExecInvalidType(t *testing.T) {
 	p, r := prepareTestData()
 	p.Type = gaia.PTypeUnknown
 	_ = storeInstance.PipelinePut(&p)
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -215,7 +870,7 @@ func TestPrepareAndExecJavaType(t *testing.T) {
 	javaExecName = "go"
 	p.Type = gaia.PTypeJava
 	_ = storeInstance.PipelinePut(&p)
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -262,7 +917,7 @@ func TestPrepareAndExecPythonType(t *testing.T) {
 	pythonExecName = "go"
 	p.Type = gaia.PTypePython
 	_ = storeInstance.PipelinePut(&p)
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -308,7 +963,7 @@ func TestPrepareAndExecCppType(t *testing.T) {
 	p, r := prepareTestData()
 	p.Type = gaia.PTypeCpp
 	_ = storeInstance.PipelinePut(&p)
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -357,7 +1012,7 @@ func TestPrepareAndExecRubyType(t *testing.T) {
 	rubyGemName = "echo"
 	findRubyGemCommands = []string{"name: rubytest"}
 	_ = storeInstance.PipelinePut(&p)
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -402,7 +1057,7 @@ func TestSchedulePipeline(t *testing.T) {
 	}
 	p, _ := prepareTestData()
 	_ = storeInstance.PipelinePut(&p)
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -413,6 +1068,189 @@ func TestSchedulePipeline(t *testing.T) {
 	}
 }
 
+func TestSchedulePipelineTimeouts(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestSchedulePipelineTimeouts")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.New(&hclog.LoggerOptions{
+		Level:  hclog.Trace,
+		Output: hclog.DefaultOutput,
+		Name:   "Gaia",
+	})
+	gaia.Cfg.JobTimeout = 10 * time.Minute
+	gaia.Cfg.RunTimeout = time.Hour
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := prepareTestData()
+	p.JobTimeout = 30
+	_ = storeInstance.PipelinePut(&p)
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.SchedulePipeline(&p, gaia.StartReasonManual, prepareArgs())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pipeline settings take precedence over the global defaults
+	if r.JobTimeout != 30 {
+		t.Fatalf("expected job timeout 30 but got %d", r.JobTimeout)
+	}
+	if r.RunTimeout != 3600 {
+		t.Fatalf("expected run timeout 3600 but got %d", r.RunTimeout)
+	}
+}
+
+func TestSchedulePipelineParameters(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestSchedulePipelineParameters")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := prepareTestData()
+	p.Parameters = []*gaia.Parameter{
+		{Name: "env", Type: gaia.ParameterChoice, Default: "staging", Allowed: []string{"staging", "prod"}},
+		{Name: "version", Type: gaia.ParameterString, Required: true},
+	}
+	_ = storeInstance.PipelinePut(&p)
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := s.SchedulePipeline(&p, gaia.StartReasonManual, []*gaia.Argument{
+		{Key: "version", Value: "1.2.3"},
+		{Key: "firstarg", Value: "first"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The effective values are recorded on the run
+	expected := []*gaia.Argument{
+		{Type: "choice", Key: "env", Value: "staging"},
+		{Type: "string", Key: "version", Value: "1.2.3"},
+		{Key: "firstarg", Value: "first"},
+	}
+	if !reflect.DeepEqual(r.Parameters, expected) {
+		t.Fatalf("expected parameters %v but got %v", expected, r.Parameters)
+	}
+
+	// All jobs receive the parameters and only related job arguments
+	for _, job := range r.Jobs {
+		args := make(map[string]string)
+		for _, arg := range job.Args {
+			args[arg.Key] = arg.Value
+		}
+		if args["env"] != "staging" || args["version"] != "1.2.3" {
+			t.Fatalf("expected parameters to be passed to %s but got %v", job.Title, args)
+		}
+		if job.Title == "Job1" && args["firstarg"] != "first" {
+			t.Fatalf("expected job argument to be passed to %s but got %v", job.Title, args)
+		}
+	}
+
+	// Invalid arguments are rejected
+	for _, args := range [][]*gaia.Argument{
+		{{Key: "version", Value: "1.2.3"}, {Key: "unknown", Value: "value"}},
+		{{Key: "env", Value: "prod"}},
+		{{Key: "version", Value: "1.2.3"}, {Key: "env", Value: "dev"}},
+	} {
+		if _, err := s.SchedulePipeline(&p, gaia.StartReasonManual, args); err == nil {
+			t.Fatalf("expected error for arguments %v", args)
+		}
+	}
+}
+
+func TestSchedulePipelineSecretParameters(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestSchedulePipelineSecretParameters")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := prepareTestData()
+	p.Parameters = []*gaia.Parameter{{Name: "token", Type: gaia.ParameterSecret, Required: true}}
+	_ = storeInstance.PipelinePut(&p)
+	pS := &PluginFakeOutputs{args: make(map[string][]*gaia.Argument)}
+	v := &VaultFakeMemory{data: map[string][]byte{"vaultarg": []byte("vault")}}
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, pS, &CAFake{}, v, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := "s3cr=t\nvalue"
+	r, err := s.SchedulePipeline(&p, gaia.StartReasonManual, []*gaia.Argument{{Key: "token", Value: secret}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The secret value is kept in the vault instead of the run
+	assertNoSecret := func(r *gaia.PipelineRun) {
+		for _, param := range r.Parameters {
+			if param.Value != "" {
+				t.Fatalf("expected secret parameter to be removed from the run but got %v", param)
+			}
+		}
+		for _, job := range r.Jobs {
+			for _, arg := range job.Args {
+				if arg.Key == "token" && arg.Value != "" {
+					t.Fatalf("expected secret parameter to be removed from %s but got %v", job.Title, arg)
+				}
+			}
+		}
+	}
+	assertNoSecret(r)
+	if _, err := v.Get(pipelinehelper.SecretParameterKey(r.UniqueID, "token")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The jobs receive the value from the vault
+	s.prepareAndExec(*r)
+	for _, job := range r.Jobs {
+		var found bool
+		for _, arg := range pS.args[job.Title] {
+			found = found || (arg.Key == "token" && arg.Value == secret)
+		}
+		if !found {
+			t.Fatalf("expected %s to receive the secret parameter but got %v", job.Title, pS.args[job.Title])
+		}
+	}
+	run, err := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoSecret(run)
+
+	// A rerun takes over the secret value from the vault
+	rerun, err := s.RerunPipeline(&p, run, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoSecret(rerun)
+	args := []*gaia.Argument{{Type: string(gaia.ParameterSecret), Key: "token"}}
+	if err := pipelinehelper.ResolveSecretParameters(v, rerun.UniqueID, args); err != nil {
+		t.Fatal(err)
+	}
+	if args[0].Value != secret {
+		t.Fatalf("expected secret %q of rerun in vault but got %q", secret, args[0].Value)
+	}
+}
+
 func TestSchedulePipelineParallel(t *testing.T) {
 	gaia.Cfg = &gaia.Config{}
 	storeInstance := store.NewBoltStore()
@@ -443,7 +1281,7 @@ func TestSchedulePipelineParallel(t *testing.T) {
 	}
 	_ = storeInstance.PipelinePut(&p1)
 	_ = storeInstance.PipelinePut(&p2)
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -484,7 +1322,7 @@ func TestSchedule(t *testing.T) {
 	}
 	p, _ := prepareTestData()
 	_ = storeInstance.PipelinePut(&p)
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -502,6 +1340,52 @@ func TestSchedule(t *testing.T) {
 	}
 }
 
+func TestSchedulePipelinePriority(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestSchedulePipelinePriority")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := prepareTestData()
+	p.Priority = 2
+	_ = storeInstance.PipelinePut(&p)
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The priority of the pipeline is used by default
+	r, err := s.SchedulePipeline(&p, gaia.StartReasonManual, prepareArgs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Priority != 2 {
+		t.Fatalf("expected priority 2 but got %d", r.Priority)
+	}
+
+	// The priority of an overwritten pipeline is used
+	high := p
+	high.Priority = 10
+	r, err = s.SchedulePipeline(&high, gaia.StartReasonManual, prepareArgs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Priority != 10 {
+		t.Fatalf("expected priority 10 but got %d", r.Priority)
+	}
+
+	// The run with the higher priority is executed first
+	s.schedule()
+	if next := s.scheduledRuns.pop(); next.ID != r.ID {
+		t.Fatalf("expected run %d to be executed first but got %d", r.ID, next.ID)
+	}
+}
+
 func TestSetPipelineJobs(t *testing.T) {
 	gaia.Cfg = &gaia.Config{}
 	storeInstance := store.NewBoltStore()
@@ -519,7 +1403,7 @@ func TestSetPipelineJobs(t *testing.T) {
 	}
 	p, _ := prepareTestData()
 	p.Jobs = nil
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFakeFailed{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -532,6 +1416,28 @@ func TestSetPipelineJobs(t *testing.T) {
 	}
 }
 
+func TestApplyJobSettings(t *testing.T) {
+	pipelineJobs := []*gaia.Job{
+		{ID: 1, Retry: &gaia.RetryPolicy{MaxAttempts: 3}},
+		{ID: 2, RunCondition: gaia.RunConditionAlways},
+	}
+	jobs := []*gaia.Job{{ID: 1}, {ID: 2}, {ID: 3}}
+	applyJobSettings(jobs, pipelineJobs)
+
+	if jobs[0].Retry == nil || jobs[0].Retry.MaxAttempts != 3 {
+		t.Fatalf("expected retry policy for first job but got %#v", jobs[0].Retry)
+	}
+	if jobs[0].Retry == pipelineJobs[0].Retry {
+		t.Fatal("expected retry policy to be copied")
+	}
+	if jobs[1].Retry != nil || jobs[1].RunCondition != gaia.RunConditionAlways {
+		t.Fatalf("expected only run condition for second job but got %#v", jobs[1])
+	}
+	if jobs[2].Retry != nil || jobs[2].RunCondition != "" {
+		t.Fatal("expected no settings for third job")
+	}
+}
+
 func TestStopPipelineRun(t *testing.T) {
 	gaia.Cfg = &gaia.Config{}
 	storeInstance := store.NewBoltStore()
@@ -550,7 +1456,7 @@ func TestStopPipelineRun(t *testing.T) {
 	}
 	p, r := prepareTestData()
 	_ = storeInstance.PipelinePut(&p)
-	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}})
+	s, err := NewScheduler(Dependencies{storeInstance, &MemDBFake{}, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -569,6 +1475,61 @@ func TestStopPipelineRun(t *testing.T) {
 	}
 }
 
+type MemDBFakeDockerWorker struct {
+	MemDBFake
+	deletedWorkers       []string
+	deletedDockerWorkers []string
+}
+
+func (m *MemDBFakeDockerWorker) GetAllWorker() []*gaia.Worker {
+	return []*gaia.Worker{{UniqueID: "worker", Tags: []string{"dockerworker", dockerWorkerTag}}}
+}
+func (m *MemDBFakeDockerWorker) DeleteWorker(id string, persist bool) error {
+	m.deletedWorkers = append(m.deletedWorkers, id)
+	return nil
+}
+func (m *MemDBFakeDockerWorker) DeleteDockerWorker(workerID string) error {
+	m.deletedDockerWorkers = append(m.deletedDockerWorkers, workerID)
+	return nil
+}
+
+func TestStopScheduledDockerRun(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	storeInstance := store.NewBoltStore()
+	tmp, _ := ioutil.TempDir("", "TestStopScheduledDockerRun")
+	gaia.Cfg.DataPath = tmp
+	gaia.Cfg.WorkspacePath = filepath.Join(tmp, "tmp")
+	gaia.Cfg.Bolt.Mode = 0600
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	if err := storeInstance.Init(tmp); err != nil {
+		t.Fatal(err)
+	}
+	p, r := prepareTestData()
+	_ = storeInstance.PipelinePut(&p)
+	db := &MemDBFakeDockerWorker{}
+	s, err := NewScheduler(Dependencies{storeInstance, db, &PluginFake{}, &CAFake{}, &VaultFake{}, nil, nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The container of the docker worker has been started by the schedule
+	r.Status = gaia.RunScheduled
+	r.Docker = true
+	r.DockerWorkerID = "dockerworker"
+	_ = storeInstance.PipelinePutRun(&r)
+
+	if err := s.StopPipelineRun(&p, r.ID); err != nil {
+		t.Fatal(err)
+	}
+	run, _ := storeInstance.PipelineGetRunByPipelineIDAndID(p.ID, r.ID)
+	if run.Status != gaia.RunCancelled {
+		t.Fatalf("expected pipeline run to be cancelled but got %s", run.Status)
+	}
+	if !reflect.DeepEqual(db.deletedDockerWorkers, []string{"dockerworker"}) || !reflect.DeepEqual(db.deletedWorkers, []string{"worker"}) {
+		t.Fatalf("expected docker worker to be removed but got %v and %v", db.deletedDockerWorkers, db.deletedWorkers)
+	}
+}
+
 func prepareArgs() []*gaia.Argument {
 	arg1 := gaia.Argument{
 		Description: "First Arg",
@@ -7,10 +7,12 @@ import (
 )
 
 // workload is a wrapper around a single job object.
+// A workload is parked while its job waits for approval.
 type workload struct {
 	finishedSig chan bool
 	done        bool
 	started     bool
+	parked      bool
 	job         *gaia.Job
 }
 
@@ -92,3 +94,46 @@ func (mw *managedWorkloads) Iter() <-chan workload {
 
 	return c
 }
+
+// Blocked returns true if all workloads which are not done are parked
+// or depend directly or indirectly on a parked workload.
+func (mw *managedWorkloads) Blocked() bool {
+	mw.RLock()
+	defer mw.RUnlock()
+
+	workloads := make(map[uint32]workload, len(mw.workloads))
+	for _, wl := range mw.workloads {
+		workloads[wl.job.ID] = wl
+	}
+
+	blocked := make(map[uint32]bool, len(workloads))
+	var isBlocked func(id uint32) bool
+	isBlocked = func(id uint32) bool {
+		if b, ok := blocked[id]; ok {
+			return b
+		}
+		wl, ok := workloads[id]
+		if !ok || wl.done {
+			return false
+		}
+		blocked[id] = wl.parked
+		for _, depJob := range wl.job.DependsOn {
+			if !blocked[id] && isBlocked(depJob.ID) {
+				blocked[id] = true
+			}
+		}
+		return blocked[id]
+	}
+
+	var pending bool
+	for _, wl := range mw.workloads {
+		if wl.done {
+			continue
+		}
+		if !isBlocked(wl.job.ID) {
+			return false
+		}
+		pending = true
+	}
+	return pending
+}
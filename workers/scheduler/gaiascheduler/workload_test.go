@@ -108,3 +108,37 @@ func TestReplaceWorkloadFlow(t *testing.T) {
 		}
 	})
 }
+
+func TestBlockedWorkloads(t *testing.T) {
+	mw := newManagedWorkloads()
+	jobs := prepareJobs()
+	for _, job := range jobs {
+		mw.Append(workload{job: job, finishedSig: make(chan bool)})
+	}
+	setWorkload := func(id uint32, done, parked bool) {
+		wl := mw.GetByID(id)
+		wl.done = done
+		wl.parked = parked
+		mw.Replace(*wl)
+	}
+
+	// Job1 is still pending
+	setWorkload(jobs[1].ID, false, true)
+	if mw.Blocked() {
+		t.Fatal("expected workloads not to be blocked while Job1 is pending")
+	}
+
+	// Job3 and Job4 depend on the parked Job2
+	setWorkload(jobs[0].ID, true, false)
+	if !mw.Blocked() {
+		t.Fatal("expected workloads to be blocked by Job2")
+	}
+
+	// Nothing is blocked when all workloads are done
+	for _, job := range jobs {
+		setWorkload(job.ID, true, false)
+	}
+	if mw.Blocked() {
+		t.Fatal("expected finished workloads not to be blocked")
+	}
+}
@@ -1,6 +1,10 @@
 package service
 
-import "github.com/gaia-pipeline/gaia"
+import (
+	"time"
+
+	"github.com/gaia-pipeline/gaia"
+)
 
 // GaiaScheduler is a job scheduler for gaia pipeline runs.
 type GaiaScheduler interface {
@@ -8,6 +12,13 @@ type GaiaScheduler interface {
 	SchedulePipeline(p *gaia.Pipeline, startReason string, args []*gaia.Argument) (*gaia.PipelineRun, error)
 	SetPipelineJobs(p *gaia.Pipeline) error
 	StopPipelineRun(p *gaia.Pipeline, runID int) error
+	RerunPipeline(p *gaia.Pipeline, run *gaia.PipelineRun, failedOnly bool) (*gaia.PipelineRun, error)
+	DecideApproval(p *gaia.Pipeline, runID int, jobID uint32, username string, approved bool) error
+	RecoverWorkerRuns(workerID string) error
+	Drain()
+	Resume()
+	Draining() bool
+	Shutdown(gracePeriod time.Duration)
 	GetFreeWorkers() int32
 	CountScheduledRuns() int
 }
@@ -11,7 +11,9 @@ import (
 	"github.com/gaia-pipeline/gaia/security"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/tracing"
 	pb "github.com/gaia-pipeline/gaia/workers/proto"
+	"github.com/gaia-pipeline/gaia/workers/scheduler/service"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 )
@@ -24,6 +26,10 @@ const (
 // Dependencies defines dependencies of this service.
 type Dependencies struct {
 	Certificate security.CAAPI
+
+	// Scheduler is optional. If set, no work is handed out to
+	// workers while the scheduler is draining.
+	Scheduler service.GaiaScheduler
 }
 
 // WorkerServer represents an instance of the worker server implementation
@@ -96,8 +102,10 @@ func (w *WorkerServer) Start() error {
 		return err
 	}
 
-	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
-	pb.RegisterWorkerServer(s, &WorkServer{})
+	// Continue the traces propagated by the workers
+	opts := append(tracing.ServerOptions(), grpc.Creds(credentials.NewTLS(tlsConfig)))
+	s := grpc.NewServer(opts...)
+	pb.RegisterWorkerServer(s, &WorkServer{scheduler: w.Scheduler})
 	if err := s.Serve(lis); err != nil {
 		gaia.Cfg.Logger.Error("cannot start worker gRPC server", "error", err)
 		return err
@@ -6,16 +6,19 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
-	"strconv"
 	"time"
 
 	"github.com/gaia-pipeline/gaia/helper/stringhelper"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/event"
+	"github.com/gaia-pipeline/gaia/helper/pipelinehelper"
+	"github.com/gaia-pipeline/gaia/runstorage"
 	"github.com/gaia-pipeline/gaia/services"
+	"github.com/gaia-pipeline/gaia/store"
 	"github.com/gaia-pipeline/gaia/workers/pipeline"
 	pb "github.com/gaia-pipeline/gaia/workers/proto"
+	"github.com/gaia-pipeline/gaia/workers/scheduler/service"
 	"github.com/golang/protobuf/ptypes/empty"
 	"google.golang.org/grpc/metadata"
 )
@@ -27,7 +30,10 @@ const chunkSize = 64 * 1024 // 64 KiB
 var errNotRegistered = errors.New("worker is not registered")
 
 // WorkServer is the implementation of the worker gRPC server interface.
-type WorkServer struct{}
+type WorkServer struct {
+	// scheduler is the scheduler of this instance. It is optional.
+	scheduler service.GaiaScheduler
+}
 
 // GetWork gets pipeline runs from the store which are not scheduled yet and streams them
 // back to the requesting worker. Pipeline runs are filtered by their tags.
@@ -64,6 +70,11 @@ func (w *WorkServer) GetWork(workInst *pb.WorkerInstance, serv pb.Worker_GetWork
 		}
 	}()
 
+	// A draining instance does not hand out new work
+	if w.scheduler != nil && w.scheduler.Draining() {
+		return nil
+	}
+
 	// Get scheduled work from memdb
 	for i := int32(0); i < workInst.WorkerSlots; i++ {
 		scheduled, err := db.PopPipelineRun(worker.Tags)
@@ -83,6 +94,61 @@ func (w *WorkServer) GetWork(workInst *pb.WorkerInstance, serv pb.Worker_GetWork
 			Status:       string(scheduled.Status),
 			PipelineId:   int64(scheduled.PipelineID),
 			ScheduleDate: scheduled.ScheduleDate.Unix(),
+			JobTimeout:   int64(scheduled.JobTimeout),
+			RunTimeout:   int64(scheduled.RunTimeout),
+			TraceId:      scheduled.TraceID,
+			TraceSpanId:  scheduled.TraceSpanID,
+		}
+
+		// A resumed run keeps the start date its deadline is based on
+		if !scheduled.StartDate.IsZero() {
+			gRPCPipelineRun.StartDate = scheduled.StartDate.Unix()
+		}
+
+		// Transfer the run specific settings of the jobs. All other
+		// job information is received by the worker from the pipeline itself.
+		for _, job := range scheduled.Jobs {
+			j := &pb.Job{
+				UniqueId:     job.ID,
+				Title:        job.Title,
+				Status:       string(job.Status),
+				RunCondition: string(job.RunCondition),
+				Approval:     job.Approval != nil && !job.ApprovalInfo.Decided(),
+			}
+			args, err := jobArgs(scheduled.UniqueID, job.Args)
+			if err != nil {
+				gaia.Cfg.Logger.Error("failed to resolve secret parameters via GetWork", "error", err.Error(), "pipelinerun", scheduled.UniqueID)
+
+				// Insert pipeline run back into memdb since we have popped it
+				if errtwo := db.InsertPipelineRun(scheduled); errtwo != nil {
+					gaia.Cfg.Logger.Error("failed to insert pipeline run into memdb", "error", errtwo, "originalerr", err)
+				}
+				return err
+			}
+			for _, arg := range args {
+				j.Args = append(j.Args, &pb.Argument{
+					Description: arg.Description,
+					Type:        arg.Type,
+					Key:         arg.Key,
+					Value:       arg.Value,
+				})
+			}
+			for _, output := range job.Outputs {
+				j.Outputs = append(j.Outputs, &pb.Argument{
+					Description: output.Description,
+					Type:        output.Type,
+					Key:         output.Key,
+					Value:       output.Value,
+				})
+			}
+			if job.Retry != nil {
+				j.Retry = &pb.RetryPolicy{
+					MaxAttempts:     int64(job.Retry.MaxAttempts),
+					Backoff:         int64(job.Retry.Backoff),
+					InfraErrorsOnly: job.Retry.InfraErrorsOnly,
+				}
+			}
+			gRPCPipelineRun.Jobs = append(gRPCPipelineRun.Jobs, j)
 		}
 
 		// Lookup pipeline from run dependent on the current mode
@@ -122,9 +188,17 @@ func (w *WorkServer) GetWork(workInst *pb.WorkerInstance, serv pb.Worker_GetWork
 			return errors.New("unsupported mode detected")
 		}
 
+		// Remember the worker which executes the pipeline run
+		if err = setRunWorker(store, scheduled.UniqueID, worker.UniqueID); err != nil {
+			gaia.Cfg.Logger.Error("failed to store worker of pipeline run via GetWork", "error", err.Error(), "pipelinerun", scheduled)
+		}
+
 		// Stream pipeline run back to worker
 		if err = serv.Send(&gRPCPipelineRun); err != nil {
 			gaia.Cfg.Logger.Error("failed to stream pipeline run to worker instance", "error", err.Error(), "worker", workInst)
+			if errtwo := setRunWorker(store, scheduled.UniqueID, ""); errtwo != nil {
+				gaia.Cfg.Logger.Error("failed to reset worker of pipeline run", "error", errtwo, "originalerr", err)
+			}
 
 			// Insert pipeline run back into memdb since we have popped it
 			if errtwo := db.InsertPipelineRun(scheduled); errtwo != nil {
@@ -136,6 +210,51 @@ func (w *WorkServer) GetWork(workInst *pb.WorkerInstance, serv pb.Worker_GetWork
 	return nil
 }
 
+// jobArgs returns a copy of the given job arguments of the pipeline run with the
+// given unique id. The values of secret parameters are taken from the vault.
+func jobArgs(uniqueID string, args []*gaia.Argument) ([]*gaia.Argument, error) {
+	resolved := make([]*gaia.Argument, 0, len(args))
+	unresolved := false
+	for _, arg := range args {
+		a := *arg
+		resolved = append(resolved, &a)
+		unresolved = unresolved || (a.Type == string(gaia.ParameterSecret) && a.Value == "")
+	}
+	if !unresolved {
+		return resolved, nil
+	}
+
+	v, err := services.DefaultVaultService()
+	if err != nil {
+		return nil, err
+	}
+	return resolved, pipelinehelper.ResolveSecretParameters(v, uniqueID, resolved)
+}
+
+// setRunWorker stores the id of the worker which executes the pipeline run with the given unique id.
+func setRunWorker(s store.GaiaStore, uniqueID, workerID string) error {
+	run, err := s.PipelineGetRunByID(uniqueID)
+	if err != nil {
+		return err
+	}
+	if run == nil {
+		return fmt.Errorf("unable to find pipeline run in store: %s", uniqueID)
+	}
+	run.WorkerID = workerID
+	return s.PipelinePutRun(run)
+}
+
+// executedByWorker returns true if the given pipeline run is executed by the worker
+// with the given id. Runs which have been handed out before the executing worker
+// was recorded carry no worker id. Their worker is unknown and they are treated
+// as executed by the asking worker as long as they are running.
+func executedByWorker(run *gaia.PipelineRun, workerID string) bool {
+	if run.WorkerID == "" {
+		return run.Status == gaia.RunRunning
+	}
+	return run.WorkerID == workerID
+}
+
 // GetGitRepo retrieves repository information associated with a pipline.
 func (w *WorkServer) GetGitRepo(ctx context.Context, in *pb.PipelineID) (*pb.GitRepo, error) {
 	repo := &pb.GitRepo{}
@@ -176,6 +295,51 @@ func (w *WorkServer) GetGitRepo(ctx context.Context, in *pb.PipelineID) (*pb.Git
 	return repo, err
 }
 
+// GetCancelledWork returns the given pipeline runs which have been cancelled at
+// this instance or which have been taken away from the worker. The worker stops
+// the execution of these runs.
+func (w *WorkServer) GetCancelledWork(ctx context.Context, in *pb.PipelineRunIDs) (*pb.PipelineRunIDs, error) {
+	cancelled := &pb.PipelineRunIDs{}
+
+	// Check if worker is registered
+	isRegistered, worker := workerRegistered(ctx)
+	if !isRegistered {
+		md, _ := metadata.FromIncomingContext(ctx)
+		gaia.Cfg.Logger.Warn("worker tries to get cancelled work but is not registered", "metadata", md)
+		return cancelled, errNotRegistered
+	}
+
+	store, err := services.StorageService()
+	if err != nil {
+		gaia.Cfg.Logger.Error("failed to get storage service via getcancelledwork", "error", err.Error())
+		return cancelled, err
+	}
+
+	for _, id := range in.UniqueIds {
+		run, err := store.PipelineGetRunByID(id)
+		if err != nil {
+			gaia.Cfg.Logger.Error("failed to load pipeline run via getcancelledwork", "error", err.Error(), "uniqueid", id)
+			return cancelled, err
+		}
+		if run == nil {
+			continue
+		}
+		if run.Status == gaia.RunCancelled || !executedByWorker(run, worker.UniqueID) {
+			cancelled.UniqueIds = append(cancelled.UniqueIds, id)
+			continue
+		}
+
+		// Record the worker of runs which have been handed out without it
+		if run.WorkerID == "" {
+			run.WorkerID = worker.UniqueID
+			if err := store.PipelinePutRun(run); err != nil {
+				gaia.Cfg.Logger.Error("failed to store worker of pipeline run via getcancelledwork", "error", err.Error(), "uniqueid", id)
+			}
+		}
+	}
+	return cancelled, nil
+}
+
 // UpdateWork updates work from a worker.
 func (w *WorkServer) UpdateWork(ctx context.Context, pipelineRun *pb.PipelineRun) (*empty.Empty, error) {
 	e := &empty.Empty{}
@@ -213,8 +377,15 @@ func (w *WorkServer) UpdateWork(ctx context.Context, pipelineRun *pb.PipelineRun
 			return e, fmt.Errorf("unable to find pipeline run in store: %#v", pipelineRun)
 		}
 
+		// The run has been cancelled or recovered in the meantime and must not be executed again
+		if run.Status == gaia.RunCancelled || !executedByWorker(run, worker.UniqueID) {
+			gaia.Cfg.Logger.Debug("pipeline run is not rescheduled since it is not executed by the worker anymore", "runid", run.ID)
+			return e, nil
+		}
+
 		// Set new status
 		run.Status = gaia.RunScheduled
+		run.WorkerID = ""
 		if err = store.PipelinePutRun(run); err != nil {
 			gaia.Cfg.Logger.Error("failed to store pipeline run via updatework", "error", err.Error(), "pipelinerun", run)
 			return e, err
@@ -239,6 +410,8 @@ func (w *WorkServer) UpdateWork(ctx context.Context, pipelineRun *pb.PipelineRun
 			StartDate:    time.Unix(pipelineRun.StartDate, 0),
 			FinishDate:   time.Unix(pipelineRun.FinishDate, 0),
 			Docker:       pipelineRun.Docker,
+			TraceID:      pipelineRun.TraceId,
+			TraceSpanID:  pipelineRun.TraceSpanId,
 		}
 		run.Jobs = make([]*gaia.Job, 0, len(pipelineRun.Jobs))
 
@@ -253,10 +426,11 @@ func (w *WorkServer) UpdateWork(ctx context.Context, pipelineRun *pb.PipelineRun
 		jobsMap := make(map[uint32]*gaia.Job)
 		for _, job := range pipelineRun.Jobs {
 			j := &gaia.Job{
-				ID:          job.UniqueId,
-				Title:       job.Title,
-				Status:      gaia.JobStatus(job.Status),
-				Description: job.Description,
+				ID:           job.UniqueId,
+				Title:        job.Title,
+				Status:       gaia.JobStatus(job.Status),
+				Description:  job.Description,
+				RunCondition: gaia.JobRunCondition(job.RunCondition),
 			}
 			run.Jobs = append(run.Jobs, j)
 
@@ -272,8 +446,58 @@ func (w *WorkServer) UpdateWork(ctx context.Context, pipelineRun *pb.PipelineRun
 					Key:         arg.Key,
 					Value:       arg.Value,
 				}
+
+				// The values of secret parameters are only kept in the vault
+				if a.Type == string(gaia.ParameterSecret) {
+					a.Value = ""
+				}
 				j.Args = append(j.Args, a)
 			}
+
+			// Convert retry policy and attempts
+			if job.Retry != nil {
+				j.Retry = &gaia.RetryPolicy{
+					MaxAttempts:     int(job.Retry.MaxAttempts),
+					Backoff:         int(job.Retry.Backoff),
+					InfraErrorsOnly: job.Retry.InfraErrorsOnly,
+				}
+			}
+			for _, attempt := range job.Attempts {
+				j.Attempts = append(j.Attempts, &gaia.JobAttempt{
+					Attempt:    int(attempt.Attempt),
+					StartDate:  time.Unix(attempt.StartDate, 0),
+					FinishDate: time.Unix(attempt.FinishDate, 0),
+					Status:     gaia.JobStatus(attempt.Status),
+					Error:      attempt.Error,
+					InfraError: attempt.InfraError,
+				})
+			}
+
+			// Convert artifacts. Artifacts must stay inside the artifacts folder of the job.
+			for _, artifact := range job.Artifacts {
+				name, err := runstorage.CleanArtifactName(artifact.Name)
+				if err != nil {
+					gaia.Cfg.Logger.Warn("invalid artifact name reported via updatework", "name", artifact.Name, "worker", worker.UniqueID)
+					continue
+				}
+				j.Artifacts = append(j.Artifacts, &gaia.Artifact{
+					JobID:  artifact.JobId,
+					Name:   name,
+					Size:   artifact.Size,
+					SHA256: artifact.Sha256,
+					Date:   time.Unix(artifact.Date, 0),
+				})
+			}
+
+			// Convert outputs
+			for _, output := range job.Outputs {
+				j.Outputs = append(j.Outputs, &gaia.Argument{
+					Description: output.Description,
+					Type:        output.Type,
+					Key:         output.Key,
+					Value:       output.Value,
+				})
+			}
 		}
 
 		// Convert dependencies
@@ -304,9 +528,47 @@ func (w *WorkServer) UpdateWork(ctx context.Context, pipelineRun *pb.PipelineRun
 			return e, err
 		}
 
+		// The run has been recovered from the worker in the meantime
+		if oldPipelineRun == nil || !executedByWorker(oldPipelineRun, worker.UniqueID) {
+			gaia.Cfg.Logger.Warn("worker updates pipeline run which it does not execute anymore", "worker", worker.UniqueID, "pipelinerun", run.UniqueID)
+			return e, nil
+		}
+
 		// The old status is always correct since the status from the worker might be wrong
 		run.Docker = oldPipelineRun.Docker
 		run.DockerWorkerID = oldPipelineRun.DockerWorkerID
+		run.JobTimeout = oldPipelineRun.JobTimeout
+		run.RunTimeout = oldPipelineRun.RunTimeout
+		run.StartReason = oldPipelineRun.StartReason
+		run.Parameters = oldPipelineRun.Parameters
+		run.SHA256Sum = oldPipelineRun.SHA256Sum
+		run.RerunOf = oldPipelineRun.RerunOf
+		run.Concurrency = oldPipelineRun.Concurrency
+		run.Priority = oldPipelineRun.Priority
+		run.WorkerID = worker.UniqueID
+		run.Recovery = oldPipelineRun.Recovery
+		run.Reschedules = oldPipelineRun.Reschedules
+
+		// A run cancelled at this instance stays cancelled while the worker stops it
+		reportedStatus := run.Status
+		if oldPipelineRun.Status == gaia.RunCancelled {
+			run.Status = gaia.RunCancelled
+		}
+
+		// Approvals are decided at the primary instance
+		oldJobs := make(map[uint32]*gaia.Job, len(oldPipelineRun.Jobs))
+		for _, job := range oldPipelineRun.Jobs {
+			oldJobs[job.ID] = job
+		}
+		for _, job := range run.Jobs {
+			if oldJob, ok := oldJobs[job.ID]; ok {
+				job.Approval = oldJob.Approval
+				job.ApprovalInfo = oldJob.ApprovalInfo
+			}
+			if job.Status == gaia.JobWaitingApproval && job.ApprovalInfo == nil {
+				job.ApprovalInfo = &gaia.JobApproval{RequestDate: time.Now()}
+			}
+		}
 
 		// Store pipeline run
 		if err = store.PipelinePutRun(run); err != nil {
@@ -314,9 +576,12 @@ func (w *WorkServer) UpdateWork(ctx context.Context, pipelineRun *pb.PipelineRun
 			return e, err
 		}
 
+		// Publish the status transitions reported by the worker
+		publishRunEvents(services.EventBus(), oldPipelineRun, run)
+
 		// Update worker information if needed
-		switch run.Status {
-		case gaia.RunSuccess, gaia.RunFailed, gaia.RunCancelled:
+		switch reportedStatus {
+		case gaia.RunSuccess, gaia.RunFailed, gaia.RunCancelled, gaia.RunWaitingApproval:
 			// Check if this was a docker worker run
 			if run.Docker {
 				gaia.Cfg.Logger.Info("Cleaning up docker resources from docker pipeline run...")
@@ -330,6 +595,7 @@ func (w *WorkServer) UpdateWork(ctx context.Context, pipelineRun *pb.PipelineRun
 					// Check if we found the right docker worker
 					if dockerWorker == nil {
 						gaia.Cfg.Logger.Error("failed to find pipeline run docker worker in memdb via updatework", "pipeline", run)
+						return
 					}
 
 					// Find the worker which is our docker worker
@@ -448,6 +714,9 @@ func (w *WorkServer) StreamBinary(pipelineRun *pb.PipelineRun, serv pb.Worker_St
 }
 
 // StreamLogs streams logs in chunks from the client to the primary instance.
+// Every chunk is written at its offset so that workers can ship only the
+// new parts of a log file while the pipeline run is still running.
+// Chunks with a job id are written to the log file of the related job.
 func (w *WorkServer) StreamLogs(stream pb.Worker_StreamLogsServer) error {
 	defer stream.SendAndClose(&empty.Empty{})
 
@@ -469,28 +738,27 @@ func (w *WorkServer) StreamLogs(stream pb.Worker_StreamLogsServer) error {
 		return err
 	}
 
-	// Create logs folder for this run
-	logFolderPath := filepath.Join(gaia.Cfg.WorkspacePath, strconv.Itoa(int(firstLogChunk.PipelineId)), strconv.Itoa(int(firstLogChunk.RunId)), gaia.LogsFolderName)
-	err = os.MkdirAll(logFolderPath, 0700)
+	// Open log file. The object is truncated to the offset of the first chunk,
+	// so a chunk with offset zero means that the worker starts shipping the logs from scratch.
+	runStorage, err := services.RunStorageService()
 	if err != nil {
-		gaia.Cfg.Logger.Error("cannot create pipeline run log folder", "error", err.Error(), "path", logFolderPath)
 		return err
 	}
-
-	// Open output file
-	logFilePath := filepath.Join(logFolderPath, gaia.LogsFileName)
-	logFile, err := os.Create(logFilePath)
+	logKey := runstorage.LogKey(int(firstLogChunk.PipelineId), int(firstLogChunk.RunId), firstLogChunk.JobId)
+	logFile, err := runStorage.Writer(logKey, firstLogChunk.Offset)
 	if err != nil {
-		gaia.Cfg.Logger.Error("failed to create new log file via streamlogs", "error", err.Error(), "logobj", firstLogChunk)
+		gaia.Cfg.Logger.Error("failed to open log file via streamlogs", "error", err.Error(), "logobj", firstLogChunk)
 		return err
 	}
 	defer logFile.Close()
 
 	// Write chunk to file
+	offset := firstLogChunk.Offset
 	if _, err := logFile.Write(firstLogChunk.Chunk); err != nil {
-		gaia.Cfg.Logger.Error("failed to write chunk to local disk during streamlogs", "error", err.Error(), "logobj", firstLogChunk)
+		gaia.Cfg.Logger.Error("failed to write chunk to run storage during streamlogs", "error", err.Error(), "logobj", firstLogChunk)
 		return err
 	}
+	offset += int64(len(firstLogChunk.Chunk))
 
 	// Read whole stream
 	for {
@@ -506,18 +774,107 @@ func (w *WorkServer) StreamLogs(stream pb.Worker_StreamLogsServer) error {
 		}
 
 		// Defense in depth check. Should never happen!
-		if logChunk.RunId != firstLogChunk.RunId || logChunk.PipelineId != firstLogChunk.PipelineId {
+		if logChunk.RunId != firstLogChunk.RunId || logChunk.PipelineId != firstLogChunk.PipelineId || logChunk.Offset != offset {
 			gaia.Cfg.Logger.Error("corrupted chunk found in stream during streamlogs", "logobj", logChunk, "firstlogobj", firstLogChunk)
 			return errors.New("corrupted chunk found in stream")
 		}
 
 		// Write chunk to file
 		if _, err := logFile.Write(logChunk.Chunk); err != nil {
-			gaia.Cfg.Logger.Error("failed to write chunk to local disk during streamlogs", "error", err.Error(), "logobj", logChunk)
+			gaia.Cfg.Logger.Error("failed to write chunk to run storage during streamlogs", "error", err.Error(), "logobj", logChunk)
 			return err
 		}
+		offset += int64(len(logChunk.Chunk))
 	}
-	return nil
+	return logFile.Close()
+}
+
+// StreamArtifacts streams job artifacts from a worker to this primary instance.
+// Every stream transfers the chunks of a single artifact.
+func (w *WorkServer) StreamArtifacts(stream pb.Worker_StreamArtifactsServer) error {
+	defer stream.SendAndClose(&empty.Empty{})
+
+	// Check if worker is registered
+	if isRegistered, _ := workerRegistered(stream.Context()); !isRegistered {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		gaia.Cfg.Logger.Warn("worker tries to stream artifacts but is not registered", "metadata", md)
+		return errNotRegistered
+	}
+
+	// Read first chunk which must have content
+	firstChunk, err := stream.Recv()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+
+		gaia.Cfg.Logger.Error("corrupted stream opened via streamartifacts", "error", err.Error())
+		return err
+	}
+
+	// Artifacts must stay inside the artifacts folder of the job
+	key, err := artifactKey(firstChunk)
+	if err != nil {
+		gaia.Cfg.Logger.Error("invalid artifact name via streamartifacts", "error", err.Error(), "name", firstChunk.Name)
+		return err
+	}
+
+	// Open artifact file. The object is truncated to the offset of the first chunk,
+	// so a chunk with offset zero means that the worker starts shipping the artifact from scratch.
+	runStorage, err := services.RunStorageService()
+	if err != nil {
+		return err
+	}
+	artifactFile, err := runStorage.Writer(key, firstChunk.Offset)
+	if err != nil {
+		gaia.Cfg.Logger.Error("failed to open artifact file via streamartifacts", "error", err.Error(), "key", key)
+		return err
+	}
+	defer artifactFile.Close()
+
+	// Write chunk to file
+	offset := firstChunk.Offset
+	if _, err := artifactFile.Write(firstChunk.Chunk); err != nil {
+		gaia.Cfg.Logger.Error("failed to write chunk to run storage during streamartifacts", "error", err.Error(), "key", key)
+		return err
+	}
+	offset += int64(len(firstChunk.Chunk))
+
+	// Read whole stream
+	for {
+		chunk, err := stream.Recv()
+
+		// Check if stream was closed remotely
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			gaia.Cfg.Logger.Error("failed to stream artifact from remote instance", "error", err.Error(), "key", key)
+			return err
+		}
+
+		// Defense in depth check. Should never happen!
+		if chunk.RunId != firstChunk.RunId || chunk.PipelineId != firstChunk.PipelineId ||
+			chunk.JobId != firstChunk.JobId || chunk.Name != firstChunk.Name || chunk.Offset != offset {
+			gaia.Cfg.Logger.Error("corrupted chunk found in stream during streamartifacts", "name", chunk.Name, "firstname", firstChunk.Name)
+			return errors.New("corrupted chunk found in stream")
+		}
+
+		// Write chunk to file
+		if _, err := artifactFile.Write(chunk.Chunk); err != nil {
+			gaia.Cfg.Logger.Error("failed to write chunk to run storage during streamartifacts", "error", err.Error(), "key", key)
+			return err
+		}
+		offset += int64(len(chunk.Chunk))
+	}
+	return artifactFile.Close()
+}
+
+// artifactKey returns the run storage key of the artifact of the given chunk.
+// An error is returned if the artifact name points outside the artifacts
+// folder of the job.
+func artifactKey(chunk *pb.ArtifactChunk) (string, error) {
+	return runstorage.ArtifactKey(int(chunk.PipelineId), int(chunk.RunId), chunk.JobId, chunk.Name)
 }
 
 // Deregister removes a worker from this primary instance by deleting the object from store.
@@ -585,3 +942,48 @@ func workerRegistered(ctx context.Context) (bool, *gaia.Worker) {
 	}
 	return true, w
 }
+
+// publishRunEvents publishes the lifecycle events of all status transitions
+// between the old and the updated pipeline run. The worker sends the same
+// state multiple times, therefore only transitions are published.
+func publishRunEvents(bus *event.Bus, old, run *gaia.PipelineRun) {
+	if run.Status == gaia.RunRunning && old.Status != gaia.RunRunning && !runFinished(old.Status) {
+		bus.Publish(event.NewRunEvent(event.RunStarted, run))
+	}
+
+	oldJobs := make(map[uint32]gaia.JobStatus, len(old.Jobs))
+	for _, job := range old.Jobs {
+		oldJobs[job.ID] = job.Status
+	}
+	for _, job := range run.Jobs {
+		oldStatus := oldJobs[job.ID]
+		switch {
+		case job.Status == gaia.JobRunning && oldStatus != gaia.JobRunning:
+			bus.Publish(event.NewJobEvent(event.JobStarted, run, job))
+		case jobFinished(job.Status) && oldStatus != job.Status:
+			bus.Publish(event.NewJobEvent(event.JobFinished, run, job))
+		}
+	}
+
+	if runFinished(run.Status) && !runFinished(old.Status) {
+		bus.Publish(event.NewRunEvent(event.RunFinished, run))
+	}
+}
+
+// runFinished returns true if the given pipeline run status is final.
+func runFinished(status gaia.PipelineRunStatus) bool {
+	switch status {
+	case gaia.RunSuccess, gaia.RunFailed, gaia.RunCancelled:
+		return true
+	}
+	return false
+}
+
+// jobFinished returns true if the given job status is final.
+func jobFinished(status gaia.JobStatus) bool {
+	switch status {
+	case gaia.JobSuccess, gaia.JobFailed, gaia.JobTimedOut, gaia.JobSkipped:
+		return true
+	}
+	return false
+}
@@ -8,14 +8,18 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/gaia-pipeline/gaia"
+	"github.com/gaia-pipeline/gaia/event"
+	"github.com/gaia-pipeline/gaia/runstorage"
 	"github.com/gaia-pipeline/gaia/services"
 	"github.com/gaia-pipeline/gaia/store"
 	"github.com/gaia-pipeline/gaia/store/memdb"
 	"github.com/gaia-pipeline/gaia/workers/pipeline"
 	pb "github.com/gaia-pipeline/gaia/workers/proto"
+	"github.com/gaia-pipeline/gaia/workers/scheduler/service"
 	"github.com/golang/protobuf/ptypes/empty"
 	hclog "github.com/hashicorp/go-hclog"
 	"google.golang.org/grpc"
@@ -44,17 +48,30 @@ func (mm *mockMemDBService) DeleteWorker(id string, persist bool) error {
 type mockStorageService struct {
 	store.GaiaStore
 	mockPipeline *gaia.Pipeline
+	cancelled    map[string]bool
+	recovered    map[string]bool
+	legacy       map[string]bool
+	storedRun    *gaia.PipelineRun
 }
 
 func (s *mockStorageService) PipelineGetRunByPipelineIDAndID(pipelineid int, runid int) (*gaia.PipelineRun, error) {
 	return generateTestData(), nil
 }
-func (s *mockStorageService) PipelinePutRun(r *gaia.PipelineRun) error { return nil }
+func (s *mockStorageService) PipelinePutRun(r *gaia.PipelineRun) error { s.storedRun = r; return nil }
 func (s *mockStorageService) PipelineGet(id int) (pipeline *gaia.Pipeline, err error) {
 	return s.mockPipeline, nil
 }
 func (s *mockStorageService) PipelineGetRunByID(runID string) (*gaia.PipelineRun, error) {
-	return &gaia.PipelineRun{}, nil
+	if s.cancelled[runID] {
+		return &gaia.PipelineRun{UniqueID: runID, Status: gaia.RunCancelled, WorkerID: "test-worker"}, nil
+	}
+	if s.recovered[runID] {
+		return &gaia.PipelineRun{UniqueID: runID, Status: gaia.RunNotScheduled}, nil
+	}
+	if s.legacy[runID] {
+		return &gaia.PipelineRun{UniqueID: runID, Status: gaia.RunRunning}, nil
+	}
+	return &gaia.PipelineRun{UniqueID: runID, Status: gaia.RunRunning, WorkerID: "test-worker"}, nil
 }
 
 type mockGetWorkServ struct {
@@ -78,6 +95,20 @@ func (mw mockGetWorkServ) Context() context.Context {
 	return metadata.NewIncomingContext(context.Background(), metadata.New(md))
 }
 
+type mockDrainingGetWorkServ struct {
+	mockGetWorkServ
+}
+
+func (mw mockDrainingGetWorkServ) Send(pR *pb.PipelineRun) error {
+	return fmt.Errorf("no work must be handed out while draining")
+}
+
+type mockDrainingScheduler struct {
+	service.GaiaScheduler
+}
+
+func (ms *mockDrainingScheduler) Draining() bool { return true }
+
 type mockStreamBinaryServ struct {
 	grpc.ServerStream
 }
@@ -104,10 +135,12 @@ func (ml mockStreamLogsServ) Recv() (*pb.LogChunk, error) {
 	counter++
 
 	if counter < 3 {
+		data := []byte("test log data")
 		return &pb.LogChunk{
-			Chunk:      []byte("test log data"),
+			Chunk:      data,
 			PipelineId: 1,
 			RunId:      1,
+			Offset:     int64((counter - 1) * len(data)),
 		}, nil
 	}
 	return nil, io.EOF
@@ -121,11 +154,26 @@ func (ml mockStreamLogsServ) SendAndClose(e *empty.Empty) error {
 	return nil
 }
 
+type mockStreamArtifactsServ struct {
+	mockStreamLogsServ
+	chunks []*pb.ArtifactChunk
+}
+
+func (ma *mockStreamArtifactsServ) Recv() (*pb.ArtifactChunk, error) {
+	if len(ma.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := ma.chunks[0]
+	ma.chunks = ma.chunks[1:]
+	return chunk, nil
+}
+
 func generateTestData() *gaia.PipelineRun {
 	return &gaia.PipelineRun{
 		UniqueID:   "first-pipeline-run",
 		ID:         1,
 		PipelineID: 1,
+		WorkerID:   "test-worker",
 		Jobs: []*gaia.Job{
 			{
 				ID:     1,
@@ -186,6 +234,21 @@ func TestGetWorkWorker(t *testing.T) {
 	}
 }
 
+func TestGetWorkDraining(t *testing.T) {
+	gaia.Cfg = &gaia.Config{
+		Mode: gaia.ModeServer,
+	}
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	services.MockMemDBService(&mockMemDBService{})
+	services.MockStorageService(&mockStorageService{})
+
+	// Run GetWork
+	ws := WorkServer{scheduler: &mockDrainingScheduler{}}
+	if err := ws.GetWork(&pb.WorkerInstance{UniqueId: "test", WorkerSlots: 1}, mockDrainingGetWorkServ{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestUpdateWork(t *testing.T) {
 	gaia.Cfg = &gaia.Config{}
 	gaia.Cfg.Logger = hclog.New(&hclog.LoggerOptions{
@@ -256,6 +319,40 @@ func TestUpdateWork(t *testing.T) {
 							Description: "desc",
 						},
 					},
+					Outputs: []*pb.Argument{
+						{
+							Key:   "imagetag",
+							Value: "v1.2.3",
+						},
+					},
+				},
+			},
+		}
+
+		// Run UpdateWork
+		ws := WorkServer{}
+		if _, err := ws.UpdateWork(mw.Context(), pbRun); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("invalid-artifact-names", func(t *testing.T) {
+		ms := &mockStorageService{}
+		services.MockStorageService(ms)
+		defer services.MockStorageService(&mockStorageService{})
+		pbRun := &pb.PipelineRun{
+			UniqueId: "first-pipeline-run",
+			Id:       1,
+			Status:   string(gaia.RunSuccess),
+			Jobs: []*pb.Job{
+				{
+					UniqueId: 1,
+					Title:    "first-job",
+					Artifacts: []*pb.Artifact{
+						{JobId: 1, Name: "reports/../coverage.out"},
+						{JobId: 1, Name: "../../../../etc/passwd"},
+						{JobId: 1, Name: "/etc/passwd"},
+					},
 				},
 			},
 		}
@@ -265,9 +362,178 @@ func TestUpdateWork(t *testing.T) {
 		if _, err := ws.UpdateWork(mw.Context(), pbRun); err != nil {
 			t.Fatal(err)
 		}
+		artifacts := ms.storedRun.Jobs[0].Artifacts
+		if len(artifacts) != 1 || artifacts[0].Name != "coverage.out" {
+			t.Fatalf("expected only the artifact inside the artifacts folder but got %v", artifacts)
+		}
 	})
 }
 
+func TestGetCancelledWork(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	ms := &mockStorageService{cancelled: map[string]bool{"second-pipeline-run": true}}
+	services.MockMemDBService(&mockMemDBService{})
+	services.MockStorageService(ms)
+
+	// Mock gRPC server
+	mw := mockGetWorkServ{}
+
+	ws := WorkServer{}
+	cancelled, err := ws.GetCancelledWork(mw.Context(), &pb.PipelineRunIDs{UniqueIds: []string{"first-pipeline-run", "second-pipeline-run"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cancelled.UniqueIds) != 1 || cancelled.UniqueIds[0] != "second-pipeline-run" {
+		t.Fatalf("expected only 'second-pipeline-run' to be cancelled but got %v", cancelled.UniqueIds)
+	}
+
+	// A running update must not overwrite the cancellation
+	pbRun := &pb.PipelineRun{
+		UniqueId: "second-pipeline-run",
+		Id:       2,
+		Status:   string(gaia.RunRunning),
+	}
+	if _, err := ws.UpdateWork(mw.Context(), pbRun); err != nil {
+		t.Fatal(err)
+	}
+	if ms.storedRun.Status != gaia.RunCancelled {
+		t.Fatalf("expected run to stay cancelled but got %s", ms.storedRun.Status)
+	}
+}
+
+func TestUpdateWorkRecoveredRun(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	ms := &mockStorageService{recovered: map[string]bool{"first-pipeline-run": true}}
+	services.MockMemDBService(&mockMemDBService{})
+	services.MockStorageService(ms)
+
+	// Mock gRPC server
+	mw := mockGetWorkServ{}
+
+	// The worker is told to stop the run which has been taken away from it
+	ws := WorkServer{}
+	cancelled, err := ws.GetCancelledWork(mw.Context(), &pb.PipelineRunIDs{UniqueIds: []string{"first-pipeline-run"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cancelled.UniqueIds) != 1 {
+		t.Fatalf("expected recovered run to be cancelled at the worker but got %v", cancelled.UniqueIds)
+	}
+
+	// Updates of the worker are ignored
+	pbRun := &pb.PipelineRun{
+		UniqueId: "first-pipeline-run",
+		Id:       1,
+		Status:   string(gaia.RunSuccess),
+	}
+	if _, err := ws.UpdateWork(mw.Context(), pbRun); err != nil {
+		t.Fatal(err)
+	}
+	if ms.storedRun != nil {
+		t.Fatalf("expected update of recovered run to be ignored but got %#v", ms.storedRun)
+	}
+}
+
+func TestGetCancelledWorkLegacyRun(t *testing.T) {
+	gaia.Cfg = &gaia.Config{}
+	gaia.Cfg.Logger = hclog.NewNullLogger()
+	ms := &mockStorageService{legacy: map[string]bool{"first-pipeline-run": true}}
+	services.MockMemDBService(&mockMemDBService{})
+	services.MockStorageService(ms)
+
+	// Mock gRPC server
+	mw := mockGetWorkServ{}
+
+	// A running run without a worker is kept at the asking worker
+	ws := WorkServer{}
+	cancelled, err := ws.GetCancelledWork(mw.Context(), &pb.PipelineRunIDs{UniqueIds: []string{"first-pipeline-run"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cancelled.UniqueIds) != 0 {
+		t.Fatalf("expected run without worker not to be cancelled but got %v", cancelled.UniqueIds)
+	}
+	if ms.storedRun == nil || ms.storedRun.WorkerID != "test-worker" {
+		t.Fatalf("expected worker to be recorded for the run but got %#v", ms.storedRun)
+	}
+
+	// Updates of the worker are stored together with the worker
+	ms.storedRun = nil
+	pbRun := &pb.PipelineRun{
+		UniqueId: "first-pipeline-run",
+		Id:       1,
+		Status:   string(gaia.RunSuccess),
+	}
+	if _, err := ws.UpdateWork(mw.Context(), pbRun); err != nil {
+		t.Fatal(err)
+	}
+	if ms.storedRun == nil || ms.storedRun.Status != gaia.RunSuccess || ms.storedRun.WorkerID != "test-worker" {
+		t.Fatalf("expected update of run without worker to be stored but got %#v", ms.storedRun)
+	}
+}
+
+func TestPublishRunEvents(t *testing.T) {
+	gaia.Cfg = &gaia.Config{Logger: hclog.NewNullLogger()}
+	newRun := func(status gaia.PipelineRunStatus, jobs ...gaia.JobStatus) *gaia.PipelineRun {
+		r := &gaia.PipelineRun{UniqueID: "run", ID: 1, PipelineID: 1, Status: status}
+		for i, jobStatus := range jobs {
+			r.Jobs = append(r.Jobs, &gaia.Job{ID: uint32(i + 1), Status: jobStatus})
+		}
+		return r
+	}
+
+	tests := []struct {
+		name     string
+		old      *gaia.PipelineRun
+		run      *gaia.PipelineRun
+		expected []event.Type
+	}{
+		{
+			name:     "run started",
+			old:      newRun(gaia.RunScheduled, gaia.JobWaitingExec),
+			run:      newRun(gaia.RunRunning, gaia.JobRunning),
+			expected: []event.Type{event.RunStarted, event.JobStarted},
+		},
+		{
+			name:     "unchanged",
+			old:      newRun(gaia.RunRunning, gaia.JobRunning),
+			run:      newRun(gaia.RunRunning, gaia.JobRunning),
+			expected: nil,
+		},
+		{
+			name:     "run finished",
+			old:      newRun(gaia.RunRunning, gaia.JobSuccess, gaia.JobRunning),
+			run:      newRun(gaia.RunFailed, gaia.JobSuccess, gaia.JobFailed),
+			expected: []event.Type{event.JobFinished, event.RunFinished},
+		},
+		{
+			name:     "already finished",
+			old:      newRun(gaia.RunFailed, gaia.JobFailed),
+			run:      newRun(gaia.RunFailed, gaia.JobFailed),
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := event.NewBus()
+			sub := bus.Subscribe()
+			publishRunEvents(bus, tt.old, tt.run)
+			sub.Close()
+
+			var types []event.Type
+			for e := range sub.Events() {
+				types = append(types, e.Type)
+			}
+			if !reflect.DeepEqual(types, tt.expected) {
+				t.Fatalf("expected events %v but got %v", tt.expected, types)
+			}
+		})
+	}
+}
+
 func TestStreamBinary(t *testing.T) {
 	tmp, err := ioutil.TempDir("", "TestStreamBinary")
 	if err != nil {
@@ -338,6 +604,49 @@ func TestStreamLogs(t *testing.T) {
 	}
 }
 
+func TestStreamArtifacts(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "TestStreamArtifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	gaia.Cfg = &gaia.Config{WorkspacePath: tmp, Logger: hclog.NewNullLogger()}
+	services.MockMemDBService(&mockMemDBService{})
+
+	ws := WorkServer{}
+	mw := &mockStreamArtifactsServ{chunks: []*pb.ArtifactChunk{
+		{PipelineId: 1, RunId: 2, JobId: 3, Name: "reports/coverage.out", Chunk: []byte("cover"), Offset: 0},
+		{PipelineId: 1, RunId: 2, JobId: 3, Name: "reports/coverage.out", Chunk: []byte("age"), Offset: 5},
+	}}
+	if err := ws.StreamArtifacts(mw); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(gaia.JobArtifactsPath(1, 2, 3), "reports", "coverage.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "coverage" {
+		t.Fatalf("expected 'coverage' but got '%s'", string(content))
+	}
+
+	// Chunks must be contiguous
+	mw = &mockStreamArtifactsServ{chunks: []*pb.ArtifactChunk{
+		{PipelineId: 1, RunId: 2, JobId: 3, Name: "app", Chunk: []byte("bin"), Offset: 0},
+		{PipelineId: 1, RunId: 2, JobId: 3, Name: "app", Chunk: []byte("ary"), Offset: 4},
+	}}
+	if err := ws.StreamArtifacts(mw); err == nil {
+		t.Fatal("expected error for non-contiguous chunks")
+	}
+
+	// Artifacts must not escape the artifacts folder of the job
+	for _, name := range []string{"../../logs/output.log", "/etc/passwd", ""} {
+		mw = &mockStreamArtifactsServ{chunks: []*pb.ArtifactChunk{{PipelineId: 1, RunId: 2, JobId: 3, Name: name}}}
+		if err := ws.StreamArtifacts(mw); err != runstorage.ErrInvalidArtifactName {
+			t.Fatalf("expected invalid artifact name error for %q but got %v", name, err)
+		}
+	}
+}
+
 func TestDeregister(t *testing.T) {
 	gaia.Cfg = &gaia.Config{}
 	gaia.Cfg.Logger = hclog.New(&hclog.LoggerOptions{